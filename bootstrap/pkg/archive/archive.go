@@ -0,0 +1,405 @@
+// Package archive moves messages that left the retention window into
+// S3-compatible object storage and reads them back for historical queries.
+//
+// Messages are stored as gzipped NDJSON chunks, one series per data
+// structure, and a single index object lists every chunk with its time span
+// and hashes.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	defaultChunkSize = 1000
+	defaultRegion    = "us-east-1"
+	indexKey         = "index.json"
+	// chunkCacheSize is how many decoded chunks stay in memory for repeated
+	// historical lookups.
+	chunkCacheSize = 16
+)
+
+// Config enables archiving when Bucket is set.
+type Config struct {
+	// Endpoint defaults to AWS S3 in Region.
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	// ChunkSize is the most messages written to one object.
+	ChunkSize int `yaml:"chunk_size"`
+}
+
+func (c Config) Enabled() bool {
+	return c.Bucket != ""
+}
+
+// Chunk describes one archived object.
+type Chunk struct {
+	Key    string   `json:"key"`
+	From   int64    `json:"from"`
+	To     int64    `json:"to"`
+	Count  int      `json:"count"`
+	Hashes []string `json:"hashes"`
+}
+
+// Series is the archive of one data structure. Every message with a
+// timestamp before Until has been archived.
+type Series struct {
+	Until  int64   `json:"until"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+type index struct {
+	Structures map[int]*Series `json:"structures"`
+}
+
+// Archive reads and appends to an archive. It caches the index, so only one
+// process may append to a given bucket and prefix.
+type Archive struct {
+	objects   ObjectStore
+	prefix    string
+	chunkSize int
+
+	mu    sync.Mutex
+	index *index
+	// hashes maps every archived hash to the key of its chunk.
+	hashes map[string]string
+
+	chunks *lru.Cache[string, []store.Message]
+}
+
+// Open connects to the archive described by cfg.
+func Open(cfg Config) (*Archive, error) {
+	region := cfg.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	objects, err := NewS3Store(endpoint, region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	return New(objects, cfg.Prefix, cfg.ChunkSize), nil
+}
+
+// New archives into objects, naming every key with prefix.
+func New(objects ObjectStore, prefix string, chunkSize int) *Archive {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	chunks, _ := lru.New[string, []store.Message](chunkCacheSize)
+	return &Archive{
+		objects:   objects,
+		prefix:    prefix,
+		chunkSize: chunkSize,
+		chunks:    chunks,
+	}
+}
+
+// loadIndex fetches the index on first use. a.mu must be held.
+func (a *Archive) loadIndex(ctx context.Context) error {
+	if a.index != nil {
+		return nil
+	}
+
+	idx := &index{}
+	data, err := a.objects.Get(ctx, a.prefix+indexKey)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to load archive index: %w", err)
+	default:
+		if err := json.Unmarshal(data, idx); err != nil {
+			return fmt.Errorf("failed to decode archive index: %w", err)
+		}
+	}
+	if idx.Structures == nil {
+		idx.Structures = make(map[int]*Series)
+	}
+
+	a.index = idx
+	a.hashes = make(map[string]string)
+	for _, series := range idx.Structures {
+		for _, chunk := range series.Chunks {
+			for _, hash := range chunk.Hashes {
+				a.hashes[hash] = chunk.Key
+			}
+		}
+	}
+	return nil
+}
+
+// Until returns the timestamp before which a data structure is archived.
+func (a *Archive) Until(ctx context.Context, dataStructureID int) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.loadIndex(ctx); err != nil {
+		return 0, err
+	}
+	if series, ok := a.index.Structures[dataStructureID]; ok {
+		return series.Until, nil
+	}
+	return 0, nil
+}
+
+// Series returns a copy of the archive index of a data structure.
+func (a *Archive) Series(ctx context.Context, dataStructureID int) (Series, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.loadIndex(ctx); err != nil {
+		return Series{}, err
+	}
+	series, ok := a.index.Structures[dataStructureID]
+	if !ok {
+		return Series{}, nil
+	}
+	return Series{Until: series.Until, Chunks: append([]Chunk(nil), series.Chunks...)}, nil
+}
+
+// Writer appends messages of one data structure, oldest first. Chunks are
+// uploaded as they fill, but none is visible until Commit writes the index.
+type Writer struct {
+	archive         *Archive
+	dataStructureID int
+	next            int
+	pending         []store.Message
+	written         []Chunk
+}
+
+// NewWriter starts appending to the series of a data structure.
+func (a *Archive) NewWriter(ctx context.Context, dataStructureID int) (*Writer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.loadIndex(ctx); err != nil {
+		return nil, err
+	}
+	next := 0
+	if series, ok := a.index.Structures[dataStructureID]; ok {
+		next = len(series.Chunks)
+	}
+	return &Writer{archive: a, dataStructureID: dataStructureID, next: next}, nil
+}
+
+func (w *Writer) Add(ctx context.Context, msg store.Message) error {
+	w.pending = append(w.pending, msg)
+	if len(w.pending) >= w.archive.chunkSize {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// Count returns how many messages were added.
+func (w *Writer) Count() int {
+	n := len(w.pending)
+	for _, chunk := range w.written {
+		n += chunk.Count
+	}
+	return n
+}
+
+func (w *Writer) flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	// A chunk left behind by a run that never committed has the same key
+	// and is overwritten.
+	key := fmt.Sprintf("%schunks/%d/%08d.ndjson.gz", w.archive.prefix, w.dataStructureID, w.next)
+	body, err := encodeChunk(w.pending)
+	if err != nil {
+		return err
+	}
+	if err := w.archive.objects.Put(ctx, key, body); err != nil {
+		return fmt.Errorf("failed to upload chunk: %w", err)
+	}
+
+	chunk := Chunk{Key: key, From: w.pending[0].Timestamp, To: w.pending[0].Timestamp, Count: len(w.pending)}
+	for _, msg := range w.pending {
+		chunk.From = min(chunk.From, msg.Timestamp)
+		chunk.To = max(chunk.To, msg.Timestamp)
+		chunk.Hashes = append(chunk.Hashes, msg.Hash)
+	}
+	w.written = append(w.written, chunk)
+	w.next++
+	w.pending = nil
+	return nil
+}
+
+// Commit uploads the last chunk and publishes the chunks in the index,
+// recording that everything before until is archived.
+func (w *Writer) Commit(ctx context.Context, until int64) error {
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+
+	a := w.archive
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	series, ok := a.index.Structures[w.dataStructureID]
+	if !ok {
+		series = &Series{}
+	}
+	updated := &Series{
+		Until:  max(series.Until, until),
+		Chunks: append(append([]Chunk(nil), series.Chunks...), w.written...),
+	}
+
+	structures := make(map[int]*Series, len(a.index.Structures)+1)
+	for id, s := range a.index.Structures {
+		structures[id] = s
+	}
+	structures[w.dataStructureID] = updated
+
+	data, err := json.Marshal(index{Structures: structures})
+	if err != nil {
+		return fmt.Errorf("failed to encode archive index: %w", err)
+	}
+	if err := a.objects.Put(ctx, a.prefix+indexKey, data); err != nil {
+		return fmt.Errorf("failed to upload archive index: %w", err)
+	}
+
+	a.index.Structures = structures
+	for _, chunk := range w.written {
+		for _, hash := range chunk.Hashes {
+			a.hashes[hash] = chunk.Key
+		}
+	}
+	w.written = nil
+	return nil
+}
+
+// Message looks up an archived message by hash.
+func (a *Archive) Message(ctx context.Context, hash string) (store.Message, bool, error) {
+	a.mu.Lock()
+	if err := a.loadIndex(ctx); err != nil {
+		a.mu.Unlock()
+		return store.Message{}, false, err
+	}
+	key, ok := a.hashes[hash]
+	a.mu.Unlock()
+	if !ok {
+		return store.Message{}, false, nil
+	}
+
+	messages, err := a.readChunk(ctx, key)
+	if err != nil {
+		return store.Message{}, false, err
+	}
+	for _, msg := range messages {
+		if msg.Hash == hash {
+			return msg, true, nil
+		}
+	}
+	return store.Message{}, false, nil
+}
+
+// Iterate calls fn for each archived message of a data structure with a
+// timestamp in [from, to], oldest first, until fn returns false. A zero to
+// means no upper bound, as in store.Database.IterateMessages.
+func (a *Archive) Iterate(ctx context.Context, dataStructureID int, from, to int64, fn func(store.Message) bool) error {
+	series, err := a.Series(ctx, dataStructureID)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range series.Chunks {
+		if chunk.To < from || (to > 0 && chunk.From > to) {
+			continue
+		}
+		messages, err := a.readChunk(ctx, chunk.Key)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if msg.Timestamp < from || (to > 0 && msg.Timestamp > to) {
+				continue
+			}
+			if !fn(msg) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Archive) readChunk(ctx context.Context, key string) ([]store.Message, error) {
+	if messages, ok := a.chunks.Get(key); ok {
+		return messages, nil
+	}
+
+	body, err := a.objects.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chunk %s: %w", key, err)
+	}
+	messages, err := decodeChunk(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode chunk %s: %w", key, err)
+	}
+
+	a.chunks.Add(key, messages)
+	return messages, nil
+}
+
+func encodeChunk(messages []store.Message) ([]byte, error) {
+	sorted := append([]store.Message(nil), messages...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, msg := range sorted {
+		if err := enc.Encode(msg); err != nil {
+			return nil, fmt.Errorf("failed to encode message %s: %w", msg.Hash, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress chunk: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeChunk(body []byte) ([]store.Message, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var messages []store.Message
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	// Keep large integers exact so archived hashes still verify.
+	dec.UseNumber()
+	for dec.More() {
+		var msg store.Message
+		if err := dec.Decode(&msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
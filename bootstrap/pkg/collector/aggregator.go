@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+type PriceSource interface {
+	FetchPrice(ctx context.Context) (float64, error)
+}
+
+type PriceAggregator struct {
+	Sources []PriceSource
+	Timeout time.Duration
+	// MaxStaleness enables the fallback cache: when a source fails, its last
+	// good price is used instead as long as it is younger than this. Zero
+	// disables the fallback.
+	MaxStaleness time.Duration
+
+	cacheMu sync.Mutex
+	cache   map[int]cachedPrice
+}
+
+type cachedPrice struct {
+	price float64
+	at    time.Time
+}
+
+// SourceQuote is the contribution of a single source to an aggregation.
+type SourceQuote struct {
+	Source string  `json:"source"`
+	Price  float64 `json:"price,omitempty"`
+	Stale  bool    `json:"stale,omitempty"`
+	AgeMs  int64   `json:"age_ms,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// PriceBreakdown is the outcome of one aggregation. Spread is the max-min of
+// the contributing prices and SourceCount how many of them were fresh, so
+// consumers can judge the quality of Average. A stale fallback price keeps
+// Average going through a source's blip but is not counted as a source.
+type PriceBreakdown struct {
+	Average     float64       `json:"average"`
+	Spread      float64       `json:"spread"`
+	SourceCount int           `json:"source_count"`
+	Quotes      []SourceQuote `json:"quotes"`
+}
+
+// NewPriceBreakdown summarizes the prices that contributed to an aggregation.
+// Quotes flagged stale are among prices but not among the sources counted.
+func NewPriceBreakdown(prices []float64, quotes []SourceQuote) PriceBreakdown {
+	breakdown := PriceBreakdown{SourceCount: len(prices), Quotes: quotes}
+	for _, quote := range quotes {
+		if quote.Stale {
+			breakdown.SourceCount--
+		}
+	}
+	if len(prices) == 0 {
+		return breakdown
+	}
+
+	var total float64
+	low, high := prices[0], prices[0]
+	for _, price := range prices {
+		total += price
+		low = math.Min(low, price)
+		high = math.Max(high, price)
+	}
+	breakdown.Average = total / float64(len(prices))
+	breakdown.Spread = high - low
+	return breakdown
+}
+
+func (a *PriceAggregator) GetAveragePrice(ctx context.Context) (float64, error) {
+	breakdown, err := a.GetPriceBreakdown(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return breakdown.Average, nil
+}
+
+// GetPriceBreakdown queries all sources concurrently and averages the
+// results, reporting what each source contributed.
+func (a *PriceAggregator) GetPriceBreakdown(ctx context.Context) (PriceBreakdown, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+
+	type result struct {
+		index int
+		price float64
+		err   error
+	}
+
+	quotes := make([]SourceQuote, len(a.Sources))
+	received := make([]bool, len(a.Sources))
+	resultChan := make(chan result, len(a.Sources))
+
+	// Fetch prices concurrently
+	for i, source := range a.Sources {
+		quotes[i].Source = sourceName(source)
+		go func(i int, s PriceSource) {
+			price, err := s.FetchPrice(ctx)
+			resultChan <- result{index: i, price: price, err: err}
+		}(i, source)
+	}
+
+	// Collect results
+collect:
+	for n := 0; n < len(a.Sources); n++ {
+		select {
+		case res := <-resultChan:
+			received[res.index] = true
+			if res.err != nil {
+				log.Printf("Price source error: %v", res.err)
+				quotes[res.index].Error = res.err.Error()
+				continue
+			}
+			quotes[res.index].Price = res.price
+			a.storeCached(res.index, res.price)
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	var prices []float64
+	for i := range quotes {
+		if !received[i] {
+			quotes[i].Error = "timed out"
+		}
+		if quotes[i].Error != "" {
+			cached, ok := a.loadCached(i)
+			if !ok {
+				continue
+			}
+			quotes[i].Price = cached.price
+			quotes[i].Stale = true
+			quotes[i].AgeMs = time.Since(cached.at).Milliseconds()
+			log.Printf("Using stale price from %s (age %dms)", quotes[i].Source, quotes[i].AgeMs)
+		}
+		prices = append(prices, quotes[i].Price)
+	}
+
+	if len(prices) == 0 {
+		if ctx.Err() != nil {
+			return PriceBreakdown{Quotes: quotes}, fmt.Errorf("price aggregation timed out")
+		}
+		return PriceBreakdown{Quotes: quotes}, fmt.Errorf("no valid prices received from any source")
+	}
+
+	breakdown := NewPriceBreakdown(prices, quotes)
+	if breakdown.SourceCount == 0 {
+		return breakdown, fmt.Errorf("no fresh prices received, only stale ones")
+	}
+	return breakdown, nil
+}
+
+func (a *PriceAggregator) storeCached(index int, price float64) {
+	if a.MaxStaleness <= 0 {
+		return
+	}
+
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+
+	if a.cache == nil {
+		a.cache = make(map[int]cachedPrice)
+	}
+	a.cache[index] = cachedPrice{price: price, at: time.Now()}
+}
+
+func (a *PriceAggregator) loadCached(index int) (cachedPrice, bool) {
+	if a.MaxStaleness <= 0 {
+		return cachedPrice{}, false
+	}
+
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+
+	cached, ok := a.cache[index]
+	if !ok || time.Since(cached.at) > a.MaxStaleness {
+		return cachedPrice{}, false
+	}
+	return cached, true
+}
+
+func sourceName(source PriceSource) string {
+	if named, ok := source.(NamedSource); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", source)
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubSource returns price until it is told to fail.
+type stubSource struct {
+	price float64
+	fail  bool
+}
+
+func (s *stubSource) FetchPrice(context.Context) (float64, error) {
+	if s.fail {
+		return 0, errors.New("upstream down")
+	}
+	return s.price, nil
+}
+
+func TestStalePricesDoNotCountAsSources(t *testing.T) {
+	a, b := &stubSource{price: 100}, &stubSource{price: 102}
+	agg := &PriceAggregator{Sources: []PriceSource{a, b}, Timeout: time.Second, MaxStaleness: time.Minute}
+
+	breakdown, err := agg.GetPriceBreakdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breakdown.SourceCount != 2 {
+		t.Fatalf("both sources fresh: source count %d, want 2", breakdown.SourceCount)
+	}
+
+	b.fail = true
+	breakdown, err = agg.GetPriceBreakdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breakdown.SourceCount != 1 {
+		t.Fatalf("one source stale: source count %d, want 1", breakdown.SourceCount)
+	}
+	if !breakdown.Quotes[1].Stale || breakdown.Average != 101 {
+		t.Fatalf("one source stale: got %+v, want its cached price averaged in and flagged", breakdown)
+	}
+
+	a.fail = true
+	if breakdown, err := agg.GetPriceBreakdown(context.Background()); err == nil {
+		t.Fatalf("only stale prices: got %+v, want an error", breakdown)
+	}
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrOutOfBounds is wrapped by PriceBounds.Check.
+var ErrOutOfBounds = errors.New("price out of bounds")
+
+// PriceBounds guard a job against publishing prices no sane market quotes,
+// such as a candle in the wrong unit. Zero fields are not checked.
+type PriceBounds struct {
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// MaxChangePercent bounds the change from the previous price the job
+	// published. A genuine move past it needs the bound raised.
+	MaxChangePercent float64 `json:"max_change_percent,omitempty"`
+}
+
+// Check returns an error wrapping ErrOutOfBounds when price falls outside
+// b. previous is the last price published, zero when there was none.
+func (b PriceBounds) Check(price, previous float64) error {
+	if b.Min > 0 && price < b.Min {
+		return fmt.Errorf("%w: %g below minimum %g", ErrOutOfBounds, price, b.Min)
+	}
+	if b.Max > 0 && price > b.Max {
+		return fmt.Errorf("%w: %g above maximum %g", ErrOutOfBounds, price, b.Max)
+	}
+	if b.MaxChangePercent > 0 && previous > 0 {
+		change := math.Abs(price-previous) / previous * 100
+		if change > b.MaxChangePercent {
+			return fmt.Errorf("%w: %g moved %.2f%% from %g, more than %g%%", ErrOutOfBounds, price, change, previous, b.MaxChangePercent)
+		}
+	}
+	return nil
+}
+
+// Validate reports bounds that would reject every price.
+func (b PriceBounds) Validate() error {
+	if b.Min < 0 || b.Max < 0 || b.MaxChangePercent < 0 {
+		return fmt.Errorf("bounds must not be negative")
+	}
+	if b.Max > 0 && b.Min > b.Max {
+		return fmt.Errorf("minimum %g above maximum %g", b.Min, b.Max)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultSymbolMap resolves tickers to the identifiers used by aggregator
+// sites. Entries can be overridden or extended via SYMBOL_MAP_PATH.
+var defaultSymbolMap = map[string]map[string]string{
+	"coingecko": {
+		"BTC":  "bitcoin",
+		"ETH":  "ethereum",
+		"USDT": "tether",
+		"USDC": "usd-coin",
+		"BNB":  "binancecoin",
+		"SOL":  "solana",
+		"XRP":  "ripple",
+		"TON":  "the-open-network",
+		"TRX":  "tron",
+		"DOGE": "dogecoin",
+	},
+	"coinmarketcap": {},
+}
+
+var (
+	symbolMapOnce sync.Once
+	symbolMap     map[string]map[string]string
+)
+
+// resolveSymbol returns the provider-specific ID for a ticker. Tickers without
+// a mapping fall back to the ticker itself.
+func resolveSymbol(provider, ticker string) string {
+	symbolMapOnce.Do(loadSymbolMap)
+
+	if id, ok := symbolMap[provider][strings.ToUpper(ticker)]; ok {
+		return id
+	}
+	return ticker
+}
+
+func loadSymbolMap() {
+	symbolMap = make(map[string]map[string]string)
+	for provider, entries := range defaultSymbolMap {
+		symbolMap[provider] = make(map[string]string)
+		for ticker, id := range entries {
+			symbolMap[provider][ticker] = id
+		}
+	}
+
+	path := os.Getenv("SYMBOL_MAP_PATH")
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Warning: Failed to read symbol map: %v", err)
+		return
+	}
+
+	var overrides map[string]map[string]string
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		log.Printf("Warning: Failed to parse symbol map: %v", err)
+		return
+	}
+
+	for provider, entries := range overrides {
+		if symbolMap[provider] == nil {
+			symbolMap[provider] = make(map[string]string)
+		}
+		for ticker, id := range entries {
+			symbolMap[provider][strings.ToUpper(ticker)] = id
+		}
+	}
+}
+
+func cryptoQuoteCurrency() string {
+	if currency := os.Getenv("CRYPTO_QUOTE_CURRENCY"); currency != "" {
+		return strings.ToLower(currency)
+	}
+	return "usd"
+}
+
+var (
+	coinGeckoRateOnce sync.Once
+	coinGeckoRate     *rateGate
+
+	coinMarketCapRateOnce sync.Once
+	coinMarketCapRate     *rateGate
+)
+
+func coinGeckoRateGate() *rateGate {
+	coinGeckoRateOnce.Do(func() {
+		coinGeckoRate = newRateGate(envInt("COINGECKO_RATE_PER_MIN", 30))
+	})
+	return coinGeckoRate
+}
+
+func coinMarketCapRateGate() *rateGate {
+	coinMarketCapRateOnce.Do(func() {
+		coinMarketCapRate = newRateGate(envInt("COINMARKETCAP_RATE_PER_MIN", 30))
+	})
+	return coinMarketCapRate
+}
+
+type CoinGeckoPriceSource struct {
+	CoinID   string
+	Currency string
+	APIKey   string
+	client   *http.Client
+	rate     *rateGate
+}
+
+func NewCoinGeckoPriceSource(ticker string, client *http.Client) *CoinGeckoPriceSource {
+	return &CoinGeckoPriceSource{
+		CoinID:   resolveSymbol("coingecko", ticker),
+		Currency: cryptoQuoteCurrency(),
+		APIKey:   os.Getenv("COINGECKO_API_KEY"),
+		client:   client,
+		rate:     coinGeckoRateGate(),
+	}
+}
+
+func (s *CoinGeckoPriceSource) Name() string {
+	return "coingecko"
+}
+
+func (s *CoinGeckoPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	if err := s.rate.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.coingecko.com/api/v3/simple/price", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("ids", s.CoinID)
+	q.Add("vs_currencies", s.Currency)
+	req.URL.RawQuery = q.Encode()
+	if s.APIKey != "" {
+		req.Header.Set("x-cg-demo-api-key", s.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var data map[string]map[string]float64
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	price, ok := data[s.CoinID][s.Currency]
+	if !ok || price <= 0 {
+		return 0, fmt.Errorf("no CoinGecko price for %s", s.CoinID)
+	}
+	return price, nil
+}
+
+type CoinMarketCapPriceSource struct {
+	Symbol   string
+	Currency string
+	APIKey   string
+	client   *http.Client
+	rate     *rateGate
+}
+
+func NewCoinMarketCapPriceSource(ticker string, client *http.Client) *CoinMarketCapPriceSource {
+	return &CoinMarketCapPriceSource{
+		Symbol:   strings.ToUpper(resolveSymbol("coinmarketcap", ticker)),
+		Currency: strings.ToUpper(cryptoQuoteCurrency()),
+		APIKey:   os.Getenv("COINMARKETCAP_API_KEY"),
+		client:   client,
+		rate:     coinMarketCapRateGate(),
+	}
+}
+
+func (s *CoinMarketCapPriceSource) Name() string {
+	return "coinmarketcap"
+}
+
+type coinMarketCapResponse struct {
+	Status struct {
+		ErrorCode    int    `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"status"`
+	Data map[string]struct {
+		Quote map[string]struct {
+			Price float64 `json:"price"`
+		} `json:"quote"`
+	} `json:"data"`
+}
+
+func (s *CoinMarketCapPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	if s.APIKey == "" {
+		return 0, fmt.Errorf("COINMARKETCAP_API_KEY is not set")
+	}
+	if err := s.rate.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://pro-api.coinmarketcap.com/v1/cryptocurrency/quotes/latest", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("symbol", s.Symbol)
+	q.Add("convert", s.Currency)
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("X-CMC_PRO_API_KEY", s.APIKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var data coinMarketCapResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if data.Status.ErrorCode != 0 {
+		return 0, fmt.Errorf("coinmarketcap error %d: %s", data.Status.ErrorCode, data.Status.ErrorMessage)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	quote, ok := data.Data[s.Symbol].Quote[s.Currency]
+	if !ok || quote.Price <= 0 {
+		return 0, fmt.Errorf("no CoinMarketCap price for %s", s.Symbol)
+	}
+	return quote.Price, nil
+}
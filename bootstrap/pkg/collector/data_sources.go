@@ -1,4 +1,5 @@
-package main
+// Package collector fetches prices from external sources and aggregates them.
+package collector
 
 import (
 	"context"
@@ -7,6 +8,8 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -17,17 +20,19 @@ type MoexPriceSource struct {
 	client   *http.Client
 }
 
-func NewMoexPriceSource(date string, interval int, ticker string) *MoexPriceSource {
+func NewMoexPriceSource(date string, interval int, ticker string, client *http.Client) *MoexPriceSource {
 	return &MoexPriceSource{
 		Date:     date,
 		Interval: interval,
 		Ticker:   ticker,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:   client,
 	}
 }
 
+func (s *MoexPriceSource) Name() string {
+	return "moex"
+}
+
 type moexResponse struct {
 	Candles struct {
 		Columns []string        `json:"columns"`
@@ -125,27 +130,78 @@ func NewMockPriceSource(basePrice float64, variation float64) *MockPriceSource {
 	}
 }
 
+func (s *MockPriceSource) Name() string {
+	return "mock"
+}
+
 func (s *MockPriceSource) FetchPrice(ctx context.Context) (float64, error) {
 	variation := (rand.Float64()*2 - 1) * s.Variation
 	return s.BasePrice * (1 + variation), nil
 }
 
-func CreatePriceSources(ticker string) []PriceSource {
-	today := time.Now().UTC().AddDate(0, 0, -2).Format("2006-01-02")
+// priceSourceFactories maps the names accepted in PRICE_SOURCES to
+// constructors for a given ticker.
+var priceSourceFactories = map[string]func(ticker string, client *http.Client) PriceSource{
+	"moex": func(ticker string, client *http.Client) PriceSource {
+		today := time.Now().UTC().AddDate(0, 0, -2).Format("2006-01-02")
+		return NewMoexPriceSource(today, 10, ticker, client)
+	},
+	"binance": func(ticker string, client *http.Client) PriceSource {
+		return NewBinancePriceSource(ticker, client)
+	},
+	"yahoo": func(ticker string, client *http.Client) PriceSource {
+		return NewYahooPriceSource(ticker, client)
+	},
+	"alphavantage": func(ticker string, client *http.Client) PriceSource {
+		return NewAlphaVantagePriceSource(ticker, os.Getenv("ALPHAVANTAGE_API_KEY"), client)
+	},
+	"coingecko": func(ticker string, client *http.Client) PriceSource {
+		return NewCoinGeckoPriceSource(ticker, client)
+	},
+	"coinmarketcap": func(ticker string, client *http.Client) PriceSource {
+		return NewCoinMarketCapPriceSource(ticker, client)
+	},
+	"mock": func(ticker string, client *http.Client) PriceSource {
+		var basePrice float64
+		switch ticker {
+		case "SBER":
+			basePrice = 300
+		default:
+			basePrice = 100.0
+		}
+		return NewMockPriceSource(basePrice, 0.01)
+	},
+}
 
-	sources := []PriceSource{
-		NewMoexPriceSource(today, 10, ticker),
-	}
+// CreatePriceSources builds the configured sources for a ticker, giving each
+// an HTTP client from its entry in config. Mock sources produce random prices
+// and are refused unless explicitly enabled.
+func CreatePriceSources(ticker string, names []string, enableMock bool, config SourceConfig) ([]PriceSource, error) {
+	var sources []PriceSource
 
-	var basePrice float64
-	switch ticker {
-	case "SBER":
-		basePrice = 300
-	default:
-		basePrice = 100.0
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "mock" && !enableMock {
+			return nil, fmt.Errorf("mock source requested but ENABLE_MOCK_SOURCES is not set")
+		}
+
+		factory, ok := priceSourceFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown price source: %s", name)
+		}
+		client, err := config[name].HTTPClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure %s source: %w", name, err)
+		}
+		sources = append(sources, factory(ticker, client))
 	}
 
-	sources = append(sources, NewMockPriceSource(basePrice, 0.01))
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no price sources configured for %s", ticker)
+	}
 
-	return sources
+	return sources, nil
 }
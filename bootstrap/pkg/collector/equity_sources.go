@@ -0,0 +1,242 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateGate spaces out requests to an upstream API so that every source of the
+// same provider, across all tickers, stays within the provider's quota.
+type rateGate struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateGate(perMinute int) *rateGate {
+	if perMinute <= 0 {
+		perMinute = 1
+	}
+	return &rateGate{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until a request may be made. The slot is only taken once it
+// is due, so callers that give up waiting leave nothing reserved behind them.
+func (g *rateGate) Wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		now := time.Now()
+		if !now.Before(g.next) {
+			g.next = now.Add(g.interval)
+			g.mu.Unlock()
+			return nil
+		}
+		delay := g.next.Sub(now)
+		g.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+	return def
+}
+
+var (
+	yahooRateOnce sync.Once
+	yahooRate     *rateGate
+
+	alphaVantageRateOnce sync.Once
+	alphaVantageRate     *rateGate
+)
+
+func yahooRateGate() *rateGate {
+	yahooRateOnce.Do(func() {
+		yahooRate = newRateGate(envInt("YAHOO_RATE_PER_MIN", 60))
+	})
+	return yahooRate
+}
+
+func alphaVantageRateGate() *rateGate {
+	alphaVantageRateOnce.Do(func() {
+		alphaVantageRate = newRateGate(envInt("ALPHAVANTAGE_RATE_PER_MIN", 5))
+	})
+	return alphaVantageRate
+}
+
+type YahooPriceSource struct {
+	Symbol string
+	client *http.Client
+	rate   *rateGate
+}
+
+func NewYahooPriceSource(symbol string, client *http.Client) *YahooPriceSource {
+	return &YahooPriceSource{
+		Symbol: symbol,
+		client: client,
+		rate:   yahooRateGate(),
+	}
+}
+
+func (s *YahooPriceSource) Name() string {
+	return "yahoo"
+}
+
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				RegularMarketPrice float64 `json:"regularMarketPrice"`
+			} `json:"meta"`
+		} `json:"result"`
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+func (s *YahooPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	if err := s.rate.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	url := fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s", s.Symbol)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("interval", "1m")
+	q.Add("range", "1d")
+	req.URL.RawQuery = q.Encode()
+	// Yahoo rejects requests without a browser-like user agent.
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; l0proof)")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var data yahooChartResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if data.Chart.Error != nil {
+		return 0, fmt.Errorf("yahoo error: %s", data.Chart.Error.Description)
+	}
+	if len(data.Chart.Result) == 0 || data.Chart.Result[0].Meta.RegularMarketPrice <= 0 {
+		return 0, fmt.Errorf("empty Yahoo response")
+	}
+
+	return data.Chart.Result[0].Meta.RegularMarketPrice, nil
+}
+
+type AlphaVantagePriceSource struct {
+	Symbol string
+	APIKey string
+	client *http.Client
+	rate   *rateGate
+}
+
+func NewAlphaVantagePriceSource(symbol, apiKey string, client *http.Client) *AlphaVantagePriceSource {
+	return &AlphaVantagePriceSource{
+		Symbol: symbol,
+		APIKey: apiKey,
+		client: client,
+		rate:   alphaVantageRateGate(),
+	}
+}
+
+func (s *AlphaVantagePriceSource) Name() string {
+	return "alphavantage"
+}
+
+type alphaVantageQuoteResponse struct {
+	GlobalQuote struct {
+		Price string `json:"05. price"`
+	} `json:"Global Quote"`
+	Note         string `json:"Note"`
+	Information  string `json:"Information"`
+	ErrorMessage string `json:"Error Message"`
+}
+
+func (s *AlphaVantagePriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	if s.APIKey == "" {
+		return 0, fmt.Errorf("ALPHAVANTAGE_API_KEY is not set")
+	}
+	if err := s.rate.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.alphavantage.co/query", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("function", "GLOBAL_QUOTE")
+	q.Add("symbol", s.Symbol)
+	q.Add("apikey", s.APIKey)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var data alphaVantageQuoteResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// Quota and key problems come back as 200 with a message instead of data.
+	switch {
+	case data.ErrorMessage != "":
+		return 0, fmt.Errorf("alpha vantage error: %s", data.ErrorMessage)
+	case data.Note != "":
+		return 0, fmt.Errorf("alpha vantage rate limited: %s", data.Note)
+	case data.Information != "":
+		return 0, fmt.Errorf("alpha vantage: %s", data.Information)
+	}
+
+	price, err := strconv.ParseFloat(data.GlobalQuote.Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price format: %w", err)
+	}
+	return price, nil
+}
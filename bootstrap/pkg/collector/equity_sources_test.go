@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateGateCancelledWaitsReserveNothing(t *testing.T) {
+	gate := newRateGate(600) // one request every 100ms
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		err := gate.Wait(ctx)
+		cancel()
+		if err == nil {
+			t.Fatal("wait ahead of the quota returned without error")
+		}
+	}
+
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("next request waited %s behind cancelled callers, want about 100ms", elapsed)
+	}
+}
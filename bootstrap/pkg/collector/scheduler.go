@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// JobConfig holds per-job settings keyed by ticker or basket name.
+type JobConfig struct {
+	// Schedule is a standard five-field cron expression or a descriptor such
+	// as "@every 30s" or "@daily".
+	Schedule string `json:"schedule,omitempty"`
+	// Timezone is an IANA zone the schedule is evaluated in, e.g.
+	// "Europe/Moscow" for settlement at the local market close.
+	Timezone string `json:"timezone,omitempty"`
+	// IntervalSeconds runs the job at a fixed interval when no schedule is
+	// set, overriding DATA_COLLECTION_INTERVAL and SCHEDULE.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// TimeoutSeconds bounds how long price aggregation may take.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// MaxRetries and RetryDelaySeconds control publishing retries.
+	MaxRetries        int `json:"max_retries,omitempty"`
+	RetryDelaySeconds int `json:"retry_delay_seconds,omitempty"`
+	// Bounds are checked before each price is published.
+	Bounds PriceBounds `json:"bounds,omitempty"`
+	// Metadata describes the job's prices, overriding the defaults of the
+	// data structure.
+	Metadata QuoteMetadata `json:"metadata,omitempty"`
+}
+
+// QuoteMetadata says what a price is quoted in, so that consumers do not mix
+// units across feeds: Currency like "RUB" or "USD", Unit like "share" or
+// "lot", and the Venue it trades on.
+type QuoteMetadata struct {
+	Currency string `json:"currency,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	Venue    string `json:"venue,omitempty"`
+}
+
+// Or fills the fields m leaves empty from defaults.
+func (m QuoteMetadata) Or(defaults QuoteMetadata) QuoteMetadata {
+	if m.Currency == "" {
+		m.Currency = defaults.Currency
+	}
+	if m.Unit == "" {
+		m.Unit = defaults.Unit
+	}
+	if m.Venue == "" {
+		m.Venue = defaults.Venue
+	}
+	return m
+}
+
+const (
+	defaultAggregationTimeout = 15 * time.Second
+	defaultPublishRetries     = 3
+	defaultPublishRetryDelay  = 2 * time.Second
+)
+
+// LoadJobConfigs reads per-job settings. A missing file means every job uses
+// the defaults.
+func LoadJobConfigs(path string) (map[string]JobConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]JobConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs file: %w", err)
+	}
+
+	var jobs map[string]JobConfig
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal jobs: %w", err)
+	}
+	for name, job := range jobs {
+		if err := job.Bounds.Validate(); err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+	}
+	return jobs, nil
+}
+
+// parseSchedule builds a schedule from a cron expression, falling back to a
+// fixed interval when expr is empty.
+func parseSchedule(expr, timezone string, fallback time.Duration) (cron.Schedule, error) {
+	if expr == "" {
+		return cron.Every(fallback), nil
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		expr = "CRON_TZ=" + timezone + " " + expr
+	}
+
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	return schedule, nil
+}
+
+// ScheduleOr resolves the job's schedule, using defaults for unset fields.
+func (j JobConfig) ScheduleOr(defaultExpr, defaultTimezone string, fallback time.Duration) (cron.Schedule, error) {
+	expr, timezone := j.Schedule, j.Timezone
+	if expr == "" && j.IntervalSeconds > 0 {
+		return cron.Every(j.Interval(fallback)), nil
+	}
+	if expr == "" {
+		expr = defaultExpr
+	}
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	return parseSchedule(expr, timezone, fallback)
+}
+
+// Interval returns the job's fixed interval, or fallback when unset.
+func (j JobConfig) Interval(fallback time.Duration) time.Duration {
+	if j.IntervalSeconds > 0 {
+		return time.Duration(j.IntervalSeconds) * time.Second
+	}
+	return fallback
+}
+
+func (j JobConfig) AggregationTimeout() time.Duration {
+	if j.TimeoutSeconds > 0 {
+		return time.Duration(j.TimeoutSeconds) * time.Second
+	}
+	return defaultAggregationTimeout
+}
+
+func (j JobConfig) PublishRetries() (int, time.Duration) {
+	retries, delay := defaultPublishRetries, defaultPublishRetryDelay
+	if j.MaxRetries > 0 {
+		retries = j.MaxRetries
+	}
+	if j.RetryDelaySeconds > 0 {
+		delay = time.Duration(j.RetryDelaySeconds) * time.Second
+	}
+	return retries, delay
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const defaultSourceTimeout = 10 * time.Second
+
+// SourceHTTPConfig controls how a price source reaches its upstream API.
+// Operators in restricted networks can route a source through a proxy and
+// trust a private CA bundle.
+type SourceHTTPConfig struct {
+	Proxy              string `json:"proxy,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	TimeoutSeconds     int    `json:"timeout_seconds,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// SourceConfig maps source names (as used in PRICE_SOURCES) to their HTTP
+// settings. Sources without an entry use the defaults.
+type SourceConfig map[string]SourceHTTPConfig
+
+// LoadSourceConfig reads the per-source config file. A missing file yields an
+// empty config so the file stays optional.
+func LoadSourceConfig(path string) (SourceConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return SourceConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source config: %w", err)
+	}
+
+	var config SourceConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse source config: %w", err)
+	}
+	return config, nil
+}
+
+func (c SourceHTTPConfig) timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return defaultSourceTimeout
+}
+
+// HTTPClient builds a client honouring the proxy, CA bundle and timeout.
+// Without an explicit proxy the standard HTTP_PROXY/HTTPS_PROXY variables
+// still apply.
+func (c SourceHTTPConfig) HTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if c.CAFile != "" || c.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: c.InsecureSkipVerify,
+		}
+		if c.CAFile != "" {
+			pem, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.timeout(),
+	}, nil
+}
+
+// websocketDialer derives a dialer that shares the client's proxy and TLS
+// settings, so streaming sources follow the same network rules.
+func websocketDialer(client *http.Client) *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: client.Timeout,
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		dialer.Proxy = transport.Proxy
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	return dialer
+}
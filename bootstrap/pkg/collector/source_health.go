@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	breakerFailureThreshold = 3
+	breakerCooldown         = 1 * time.Minute
+	breakerMaxCooldown      = 15 * time.Minute
+	healthEWMAAlpha         = 0.2
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// NamedSource is implemented by price sources that can identify themselves
+// in health reports.
+type NamedSource interface {
+	Name() string
+}
+
+type SourceHealth struct {
+	Name                string       `json:"name"`
+	Ticker              string       `json:"ticker"`
+	State               BreakerState `json:"state"`
+	Score               float64      `json:"score"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Successes           int64        `json:"successes"`
+	Failures            int64        `json:"failures"`
+	AvgLatencyMs        float64      `json:"avg_latency_ms"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastSuccess         int64        `json:"last_success,omitempty"`
+	OpenUntil           int64        `json:"open_until,omitempty"`
+}
+
+// MonitoredSource wraps a PriceSource with a circuit breaker. After
+// breakerFailureThreshold consecutive failures the source is skipped until
+// its cooldown elapses; then a single probe request decides whether it
+// closes again or stays open with a doubled cooldown.
+type MonitoredSource struct {
+	source PriceSource
+
+	mu       sync.Mutex
+	health   SourceHealth
+	cooldown time.Duration
+	probing  bool
+}
+
+func (m *MonitoredSource) Name() string {
+	return m.health.Name
+}
+
+func (m *MonitoredSource) FetchPrice(ctx context.Context) (float64, error) {
+	if err := m.acquire(); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	price, err := m.source.FetchPrice(ctx)
+	m.record(time.Since(start), err)
+
+	return price, err
+}
+
+func (m *MonitoredSource) acquire() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.health.State {
+	case BreakerOpen:
+		if time.Now().Unix() < m.health.OpenUntil {
+			return fmt.Errorf("%s: %w", m.health.Name, ErrCircuitOpen)
+		}
+		m.health.State = BreakerHalfOpen
+		m.probing = true
+	case BreakerHalfOpen:
+		if m.probing {
+			return fmt.Errorf("%s: %w", m.health.Name, ErrCircuitOpen)
+		}
+		m.probing = true
+	}
+	return nil
+}
+
+func (m *MonitoredSource) record(latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ms := float64(latency.Milliseconds())
+	if m.health.Successes+m.health.Failures == 0 {
+		m.health.AvgLatencyMs = ms
+	} else {
+		m.health.AvgLatencyMs += healthEWMAAlpha * (ms - m.health.AvgLatencyMs)
+	}
+
+	m.probing = false
+
+	if err == nil {
+		m.health.Successes++
+		m.health.ConsecutiveFailures = 0
+		m.health.LastError = ""
+		m.health.LastSuccess = time.Now().Unix()
+		m.health.Score += healthEWMAAlpha * (1 - m.health.Score)
+		m.health.State = BreakerClosed
+		m.health.OpenUntil = 0
+		m.cooldown = breakerCooldown
+		return
+	}
+
+	m.health.Failures++
+	m.health.ConsecutiveFailures++
+	m.health.LastError = err.Error()
+	m.health.Score -= healthEWMAAlpha * m.health.Score
+
+	switch {
+	case m.health.State == BreakerHalfOpen:
+		m.cooldown *= 2
+		if m.cooldown > breakerMaxCooldown {
+			m.cooldown = breakerMaxCooldown
+		}
+		m.trip()
+	case m.health.ConsecutiveFailures >= breakerFailureThreshold:
+		m.trip()
+	}
+}
+
+func (m *MonitoredSource) trip() {
+	m.health.State = BreakerOpen
+	m.health.OpenUntil = time.Now().Add(m.cooldown).Unix()
+}
+
+func (m *MonitoredSource) Health() SourceHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+// SourceRegistry keeps every monitored source so their health can be
+// reported in one place.
+type SourceRegistry struct {
+	mu      sync.RWMutex
+	sources []*MonitoredSource
+}
+
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{}
+}
+
+// Wrap puts a circuit breaker around source and registers it.
+func (r *SourceRegistry) Wrap(ticker string, source PriceSource) *MonitoredSource {
+	name := sourceName(source)
+
+	m := &MonitoredSource{
+		source:   source,
+		cooldown: breakerCooldown,
+		health: SourceHealth{
+			Name:   name,
+			Ticker: ticker,
+			State:  BreakerClosed,
+			Score:  1,
+		},
+	}
+
+	r.mu.Lock()
+	r.sources = append(r.sources, m)
+	r.mu.Unlock()
+
+	return m
+}
+
+func (r *SourceRegistry) Health() []SourceHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]SourceHealth, 0, len(r.sources))
+	for _, m := range r.sources {
+		result = append(result, m.Health())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Ticker != result[j].Ticker {
+			return result[i].Ticker < result[j].Ticker
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
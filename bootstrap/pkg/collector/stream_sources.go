@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PriceUpdate is a single price observed on a stream.
+type PriceUpdate struct {
+	Source string
+	Price  float64
+	At     time.Time
+}
+
+// StreamingPriceSource pushes prices as they happen instead of being polled.
+// Stream blocks until ctx is cancelled, reconnecting on its own.
+type StreamingPriceSource interface {
+	PriceSource
+	NamedSource
+	Stream(ctx context.Context, updates chan<- PriceUpdate)
+}
+
+const (
+	binanceRESTURL   = "https://api.binance.com/api/v3/ticker/price"
+	binanceStreamURL = "wss://stream.binance.com:9443/ws"
+
+	// streamReconnectDelay grows with each failed attempt up to
+	// streamMaxReconnectDelay.
+	streamReconnectDelay    = 5 * time.Second
+	streamMaxReconnectDelay = 30 * time.Second
+)
+
+// BinancePriceSource reads spot trades from Binance. FetchPrice uses the REST
+// ticker endpoint so the source also works with polling workers.
+type BinancePriceSource struct {
+	Symbol string
+	client *http.Client
+}
+
+func NewBinancePriceSource(symbol string, client *http.Client) *BinancePriceSource {
+	return &BinancePriceSource{
+		Symbol: strings.ToUpper(symbol),
+		client: client,
+	}
+}
+
+func (s *BinancePriceSource) Name() string {
+	return "binance"
+}
+
+func (s *BinancePriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", binanceRESTURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	q := req.URL.Query()
+	q.Add("symbol", s.Symbol)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var data struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return strconv.ParseFloat(data.Price, 64)
+}
+
+func (s *BinancePriceSource) Stream(ctx context.Context, updates chan<- PriceUpdate) {
+	url := fmt.Sprintf("%s/%s@trade", binanceStreamURL, strings.ToLower(s.Symbol))
+
+	for attempt := 0; ; attempt++ {
+		if err := s.streamOnce(ctx, url, updates); err != nil && ctx.Err() == nil {
+			log.Printf("Binance stream for %s dropped: %v", s.Symbol, err)
+		}
+
+		delay := streamReconnectDelay * time.Duration(attempt+1)
+		if delay > streamMaxReconnectDelay {
+			delay = streamMaxReconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *BinancePriceSource) streamOnce(ctx context.Context, url string, updates chan<- PriceUpdate) error {
+	conn, _, err := websocketDialer(s.client).DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	log.Printf("✅ Binance stream connected for %s", s.Symbol)
+
+	for {
+		var trade struct {
+			Price string `json:"p"`
+		}
+		if err := conn.ReadJSON(&trade); err != nil {
+			return err
+		}
+
+		price, err := strconv.ParseFloat(trade.Price, 64)
+		if err != nil {
+			continue
+		}
+
+		select {
+		case updates <- PriceUpdate{Source: s.Name(), Price: price, At: time.Now()}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
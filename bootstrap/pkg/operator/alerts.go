@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+	webhookBackoff  = 5 * time.Second
+)
+
+// errPublicationPaused is returned for new sign requests while the quorum
+// is at risk and publication is paused.
+var errPublicationPaused = errors.New("publication paused: too few live signers")
+
+// AlertsConfig decides what happens when fewer trusted signers are live
+// than the default threshold needs.
+type AlertsConfig struct {
+	// WebhookURLs receive quorum events as JSON POSTs.
+	WebhookURLs []string `yaml:"webhook_urls"`
+	// PauseWhenAtRisk stops publishing new sign requests until the quorum
+	// is restored, rather than storing data no one will sign.
+	PauseWhenAtRisk bool `yaml:"pause_when_at_risk"`
+}
+
+// Webhooks delivers quorum events to the configured URLs.
+type Webhooks struct {
+	URLs   []string
+	Client *http.Client
+}
+
+// Subscribe delivers the quorum events of b. Delivery runs on a goroutine
+// of its own per event, as event handlers must not block.
+func (h *Webhooks) Subscribe(ctx context.Context, b *EventBus) {
+	deliver := func(e Event) {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		for _, url := range h.URLs {
+			go h.deliver(ctx, url, payload)
+		}
+	}
+	b.Subscribe(EventQuorumAtRisk, deliver)
+	b.Subscribe(EventQuorumRestored, deliver)
+}
+
+func (h *Webhooks) deliver(ctx context.Context, url string, payload []byte) {
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if err = h.post(ctx, url, payload); err == nil {
+			webhookDeliveriesTotal.WithLabelValues("delivered").Inc()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(webhookBackoff):
+		}
+	}
+	webhookDeliveriesTotal.WithLabelValues("failed").Inc()
+	log.Printf("Error delivering alert to %s after %d attempts: %v", url, webhookAttempts, err)
+}
+
+func (h *Webhooks) post(ctx context.Context, url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// SetPauseWhenAtRisk makes the operator refuse new sign requests while the
+// quorum is at risk.
+func (o *OperatorNode) SetPauseWhenAtRisk(pause bool) {
+	o.liveness.mu.Lock()
+	o.liveness.pauseWhenAtRisk = pause
+	o.liveness.mu.Unlock()
+}
+
+// publicationPaused reports whether new sign requests are held back.
+func (o *OperatorNode) publicationPaused() bool {
+	o.liveness.mu.Lock()
+	defer o.liveness.mu.Unlock()
+
+	return o.liveness.pauseWhenAtRisk && o.liveness.atRisk
+}
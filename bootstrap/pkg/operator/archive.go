@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"bootstrap/pkg/archive"
+	"bootstrap/pkg/store"
+)
+
+// SetArchive makes lookups fall back to archived messages once they are gone
+// from the database. It must be called before the RPC server starts.
+func (o *OperatorNode) SetArchive(a *archive.Archive) {
+	o.archive = a
+}
+
+// findMessage returns a message from the database, or from the archive if it
+// was pruned.
+func (o *OperatorNode) findMessage(ctx context.Context, hash string) (store.Message, bool) {
+	if msg, ok := o.db.GetMessage(ctx, hash); ok {
+		return msg, true
+	}
+	if o.archive == nil {
+		return store.Message{}, false
+	}
+
+	msg, ok, err := o.archive.Message(ctx, hash)
+	if err != nil {
+		log.Printf("⚠️ Archive lookup of %s failed: %v", hash, err)
+		archiveReadsTotal.WithLabelValues("error").Inc()
+		return store.Message{}, false
+	}
+	if !ok {
+		archiveReadsTotal.WithLabelValues("miss").Inc()
+		return store.Message{}, false
+	}
+	archiveReadsTotal.WithLabelValues("hit").Inc()
+	verifyMessage(&msg)
+	return msg, true
+}
+
+// iterateMessages is IterateMessages over the archive and the database.
+// Archived messages come first and are skipped while the database still
+// holds them, as it does for those archived ahead of their expiry.
+func (o *OperatorNode) iterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(store.Message) bool) error {
+	if o.archive != nil {
+		stopped := false
+		err := o.archive.Iterate(ctx, dataStructureID, from, to, func(msg store.Message) bool {
+			if o.db.HasData(ctx, msg.Hash) {
+				return true
+			}
+			verifyMessage(&msg)
+			if !fn(msg) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			archiveReadsTotal.WithLabelValues("error").Inc()
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return o.db.IterateMessages(ctx, dataStructureID, from, to, fn)
+}
+
+// archiveLead is how far ahead of the retention cutoff the pruner archives.
+// Badger drops messages on their TTL without waiting for the pruner, so each
+// one is archived at least a run before it expires.
+func archiveLead(interval time.Duration) time.Duration {
+	return 2 * interval
+}
+
+// archiveBefore copies every message of a data structure with a timestamp
+// before until that is not archived yet into the archive.
+func (p *Pruner) archiveBefore(ctx context.Context, dataStructureID int, until int64) error {
+	from, err := p.archive.Until(ctx, dataStructureID)
+	if err != nil {
+		return err
+	}
+	if from >= until {
+		return nil
+	}
+
+	w, err := p.archive.NewWriter(ctx, dataStructureID)
+	if err != nil {
+		return err
+	}
+	var addErr error
+	err = p.db.IterateMessages(ctx, dataStructureID, from, until-1, func(msg store.Message) bool {
+		// Verification is redone when the message is read back.
+		msg.Verified = nil
+		addErr = w.Add(ctx, msg)
+		return addErr == nil
+	})
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Commit(ctx, until); err != nil {
+		return err
+	}
+
+	if n := w.Count(); n > 0 {
+		archivedMessagesTotal.Add(float64(n))
+		log.Printf("📦 Archived %d messages of structure %d", n, dataStructureID)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package operator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	auditOpStoreData      = "store_data"
+	auditOpStoreSignature = "store_signature"
+	auditOpKeyRotation    = "key_rotation"
+	auditOpTrustedSet     = "trusted_set"
+	auditOpAbort          = "abort"
+
+	// auditGenesis is the Prev of the first entry.
+	auditGenesis = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+	defaultAuditAnchorInterval = time.Hour
+)
+
+// AuditEntry is one line of the audit log. Hash is the keccak256 of the
+// other fields (see auditEntryHash), and Prev is the Hash of the entry
+// before it, so rewriting or dropping an entry breaks every hash after it.
+type AuditEntry struct {
+	Seq     uint64          `json:"seq"`
+	Time    int64           `json:"time"`
+	Op      string          `json:"op"`
+	Payload json.RawMessage `json:"payload"`
+	Prev    string          `json:"prev"`
+	Hash    string          `json:"hash"`
+}
+
+func auditEntryHash(e AuditEntry) string {
+	preimage := fmt.Sprintf("%d|%d|%s|%s|%s", e.Seq, e.Time, e.Op, e.Payload, e.Prev)
+	return cryptoeth.Keccak256Hash([]byte(preimage)).Hex()
+}
+
+// AuditLog is an append-only, hash-chained record of state mutations. Entries
+// are written ahead of the mutation they describe; a mutation that then fails
+// is followed by an abort entry naming its sequence number.
+type AuditLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	sync bool
+	seq  uint64
+	head string
+	// trusted is the signer set of the last trusted_set entry.
+	trusted []string
+}
+
+// OpenAuditLog opens or creates the log at path after verifying the chain
+// already in it. With sync set every entry is fsynced before the mutation
+// goes ahead.
+func OpenAuditLog(path string, sync bool) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	a := &AuditLog{f: f, sync: sync, head: auditGenesis}
+	err = verifyAuditLog(f, func(e AuditEntry) {
+		a.seq, a.head = e.Seq, e.Hash
+		if e.Op == auditOpTrustedSet {
+			json.Unmarshal(e.Payload, &a.trusted)
+		}
+	})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %s is corrupt: %w", path, err)
+	}
+	return a, nil
+}
+
+// verifyAuditLog checks every entry in r against its predecessor and calls fn
+// for each one in order.
+func verifyAuditLog(r io.Reader, fn func(AuditEntry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	prev, seq := auditGenesis, uint64(0)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("entry after %d: %w", seq, err)
+		}
+		if e.Seq != seq+1 {
+			return fmt.Errorf("entry %d follows %d", e.Seq, seq)
+		}
+		if e.Prev != prev {
+			return fmt.Errorf("entry %d does not chain to %s", e.Seq, prev)
+		}
+		if auditEntryHash(e) != e.Hash {
+			return fmt.Errorf("entry %d hash mismatch", e.Seq)
+		}
+		if fn != nil {
+			fn(e)
+		}
+		prev, seq = e.Hash, e.Seq
+	}
+	return scanner.Err()
+}
+
+// Append writes an entry for op and returns its sequence number.
+func (a *AuditLog) Append(op string, payload interface{}) (uint64, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal audit payload: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e := AuditEntry{Seq: a.seq + 1, Time: time.Now().Unix(), Op: op, Payload: raw, Prev: a.head}
+	e.Hash = auditEntryHash(e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	if _, err := a.f.Write(append(line, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if a.sync {
+		if err := a.f.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+
+	a.seq, a.head = e.Seq, e.Hash
+	auditEntriesTotal.WithLabelValues(op).Inc()
+	return e.Seq, nil
+}
+
+// abort records that the mutation logged as seq did not happen.
+func (a *AuditLog) abort(seq uint64, cause error) {
+	payload := map[string]interface{}{"seq": seq, "error": cause.Error()}
+	if _, err := a.Append(auditOpAbort, payload); err != nil {
+		log.Printf("Error writing audit abort for entry %d: %v", seq, err)
+	}
+}
+
+// Head returns the sequence number and hash of the newest entry.
+func (a *AuditLog) Head() (uint64, string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seq, a.head
+}
+
+// RecordTrustedSet logs the configured signer set when it differs from the
+// one last recorded, so restarts with a changed TRUSTED_ADDRESSES show up.
+func (a *AuditLog) RecordTrustedSet(addrs []string) error {
+	set := make([]string, len(addrs))
+	for i, addr := range addrs {
+		set[i] = strings.ToLower(addr)
+	}
+	sort.Strings(set)
+
+	a.mu.Lock()
+	unchanged := strings.Join(set, ",") == strings.Join(a.trusted, ",")
+	a.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	if _, err := a.Append(auditOpTrustedSet, set); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.trusted = set
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *AuditLog) Close() error {
+	return a.f.Close()
+}
+
+// auditingDatabase writes an audit entry ahead of each audited mutation.
+type auditingDatabase struct {
+	store.Database
+	audit *AuditLog
+}
+
+func newAuditingDatabase(db store.Database, audit *AuditLog) store.Database {
+	return &auditingDatabase{Database: db, audit: audit}
+}
+
+// record runs mutate after logging it, refusing the mutation when the entry
+// cannot be written.
+func (a *auditingDatabase) record(op string, payload interface{}, mutate func() error) error {
+	seq, err := a.audit.Append(op, payload)
+	if err != nil {
+		return err
+	}
+	if err := mutate(); err != nil {
+		a.audit.abort(seq, err)
+		return err
+	}
+	return nil
+}
+
+func (a *auditingDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	payload := map[string]interface{}{
+		"hash":                hash,
+		"data":                data,
+		"data_structure":      dataStructure,
+		"data_structure_meta": dataStructureMeta,
+		"timestamp":           timestamp,
+		"data_structure_id":   dataStructureID,
+		"hash_version":        hashVersion,
+		"request_id":          requestID,
+		"sequence":            sequence,
+	}
+	if epoch > 0 {
+		payload["epoch"] = epoch
+	}
+	return a.record(auditOpStoreData, payload, func() error {
+		return a.Database.StoreData(ctx, hash, data, dataStructure, dataStructureMeta, timestamp, dataStructureID, hashVersion, requestID, sequence, epoch)
+	})
+}
+
+func (a *auditingDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	payload := map[string]string{"hash": hash, "signer": signer, "signature": signature}
+	return a.record(auditOpStoreSignature, payload, func() error {
+		return a.Database.StoreSignature(ctx, hash, signer, signature)
+	})
+}
+
+func (a *auditingDatabase) StoreKeyRotation(ctx context.Context, rotation store.KeyRotation) error {
+	return a.record(auditOpKeyRotation, rotation, func() error {
+		return a.Database.StoreKeyRotation(ctx, rotation)
+	})
+}
+
+func (a *auditingDatabase) Close() error {
+	err := a.Database.Close()
+	if cerr := a.audit.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// AuditAnchor periodically publishes the audit log head as a message of
+// Structure, which needs "seq" (uint), "head" (bytes32) and "timestamp"
+// fields. Once signed, the head is attested by the signer set itself.
+type AuditAnchor struct {
+	Log         *AuditLog
+	PubSub      *PubSubService
+	StructureID string
+	Structure   DataStructure
+	Interval    time.Duration
+}
+
+func (a *AuditAnchor) Run(ctx context.Context) {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = defaultAuditAnchorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var anchored uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			seq, _ := a.Log.Head()
+			if seq == anchored {
+				continue
+			}
+			published, err := a.publish(ctx)
+			if err != nil {
+				log.Printf("Error anchoring audit log: %v", err)
+				continue
+			}
+			anchored = published
+		}
+	}
+}
+
+// publish anchors the current head and returns the sequence number it covers.
+func (a *AuditAnchor) publish(ctx context.Context) (uint64, error) {
+	seq, head := a.Log.Head()
+	timestamp := time.Now().Unix()
+
+	fields, err := a.Structure.NormalizeFields(map[string]interface{}{
+		"seq":       strconv.FormatUint(seq, 10),
+		"head":      head,
+		"timestamp": timestamp,
+	})
+	if err != nil {
+		return 0, err
+	}
+	sr, err := buildSignRequest(a.StructureID, a.Structure, fields, timestamp)
+	if err != nil {
+		return 0, err
+	}
+	if err := a.PubSub.PublishSignRequest(ctx, sr); err != nil {
+		return 0, err
+	}
+
+	log.Printf("⚓ Anchored audit log entry %d (%s) as %s", seq, head, sr.Hash)
+	return seq, nil
+}
+
+// AuditVerifyCommand checks the hash chain of an audit log file and prints its
+// head.
+func AuditVerifyCommand(args []string) error {
+	fs := flag.NewFlagSet("audit-verify", flag.ContinueOnError)
+	path := fs.String("path", "data/audit.log", "audit log file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var last AuditEntry
+	if err := verifyAuditLog(f, func(e AuditEntry) { last = e }); err != nil {
+		return err
+	}
+	if last.Seq == 0 {
+		fmt.Println("audit log is empty")
+		return nil
+	}
+	fmt.Printf("%d entries verified, head %s at %s\n", last.Seq, last.Hash, time.Unix(last.Time, 0).UTC().Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,170 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// BasketConfig describes an index computed from other feeds. Each component
+// ticker is weighted by its entry in Components and priced from the latest
+// confirmed message of ComponentStructure.
+type BasketConfig struct {
+	ComponentStructure string             `json:"component_structure,omitempty"`
+	Components         map[string]float64 `json:"components"`
+	// MaxAgeSeconds rejects component prices older than this. Zero accepts
+	// any confirmed price.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// loadBaskets reads basket definitions. A missing file means no baskets.
+func loadBaskets(path string) (map[string]BasketConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baskets file: %w", err)
+	}
+
+	var baskets map[string]BasketConfig
+	if err := json.Unmarshal(data, &baskets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal baskets: %w", err)
+	}
+
+	for name, basket := range baskets {
+		if len(basket.Components) == 0 {
+			return nil, fmt.Errorf("basket %s has no components", name)
+		}
+		if basket.ComponentStructure == "" {
+			basket.ComponentStructure = "stock_quote"
+			baskets[name] = basket
+		}
+	}
+
+	return baskets, nil
+}
+
+// BasketPriceSource prices a basket from the confirmed prices of its
+// components, so an index is only ever derived from data the signers have
+// already agreed on.
+type BasketPriceSource struct {
+	Basket string
+	Config BasketConfig
+	db     store.Database
+	// componentID is the numeric ID of Config.ComponentStructure.
+	componentID int
+}
+
+func NewBasketPriceSource(name string, config BasketConfig, structures map[string]DataStructure, db store.Database) (*BasketPriceSource, error) {
+	componentID, err := structureNumericID(structures, config.ComponentStructure)
+	if err != nil {
+		return nil, fmt.Errorf("basket %s: %w", name, err)
+	}
+	return &BasketPriceSource{
+		Basket:      name,
+		Config:      config,
+		db:          db,
+		componentID: componentID,
+	}, nil
+}
+
+func (s *BasketPriceSource) Name() string {
+	return "basket"
+}
+
+func (s *BasketPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	dataStructureID := s.componentID
+
+	var value float64
+	for ticker, weight := range s.Config.Components {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		_, price, err := confirmedPrice(ctx, s.db, dataStructureID, ticker, s.Config.MaxAgeSeconds)
+		if err != nil {
+			return 0, fmt.Errorf("component %s: %w", ticker, err)
+		}
+		value += weight * price
+	}
+
+	return value, nil
+}
+
+// confirmedPrice loads the latest confirmed message of ticker and its price.
+// A positive maxAgeSeconds refuses older messages.
+func confirmedPrice(ctx context.Context, db store.Database, dataStructureID int, ticker string, maxAgeSeconds int) (store.Message, float64, error) {
+	msg, found, err := db.GetLatestByField(ctx, dataStructureID, "ticker", ticker)
+	if err != nil {
+		return store.Message{}, 0, fmt.Errorf("failed to load %s: %w", ticker, err)
+	}
+	if !found {
+		return store.Message{}, 0, fmt.Errorf("no confirmed price for %s", ticker)
+	}
+
+	if maxAgeSeconds > 0 {
+		age := time.Since(time.Unix(msg.Timestamp, 0))
+		if age > time.Duration(maxAgeSeconds)*time.Second {
+			return store.Message{}, 0, fmt.Errorf("price for %s is %s old", ticker, age.Round(time.Second))
+		}
+	}
+
+	price, err := messagePrice(msg)
+	if err != nil {
+		return store.Message{}, 0, err
+	}
+	return msg, price, nil
+}
+
+// messagePrice reads the scaled price field of a stored message back into a
+// float, reversing FloatToWei.
+func messagePrice(msg store.Message) (float64, error) {
+	return messageValue(msg, "price")
+}
+
+// messageValue reads a scaled field of a stored message back into a float.
+func messageValue(msg store.Message, field string) (float64, error) {
+	for i, name := range msg.DataStructureMeta {
+		if name != field || i >= len(msg.Data) {
+			continue
+		}
+
+		scaled, ok := new(big.Float).SetString(fmt.Sprint(msg.Data[i]))
+		if !ok {
+			return 0, fmt.Errorf("invalid %s value %v", field, msg.Data[i])
+		}
+		divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+		value, _ := new(big.Float).Quo(scaled, divisor).Float64()
+		return value, nil
+	}
+	return 0, fmt.Errorf("message %s has no %s field", msg.Hash, field)
+}
+
+// IndexBasketMessageBuilder publishes the value of a basket. The basket name
+// goes in the ticker field so index values can be queried like any quote.
+type IndexBasketMessageBuilder struct {
+	Basket      string
+	StructureID string
+	Structure   DataStructure
+	Metadata    collector.QuoteMetadata
+}
+
+func (b *IndexBasketMessageBuilder) BuildMessage(price collector.PriceBreakdown) (*SignRequest, error) {
+	timestamp := time.Now().Unix()
+
+	fieldValues := map[string]interface{}{
+		"ticker":    b.Basket,
+		"value":     FloatToWei(price.Average).String(),
+		"timestamp": timestamp,
+	}
+
+	return buildSignRequest(b.StructureID, b.Structure, withMetadata(fieldValues, b.Structure, b.Metadata), timestamp)
+}
@@ -0,0 +1,1312 @@
+package operator
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"bootstrap/pkg/archive"
+	"bootstrap/pkg/store"
+)
+
+const (
+	reconnectTimeout         = 5 * time.Second
+	maxReconnectAttempts     = 10
+	publishTimeout           = 10 * time.Second
+	subscriptionReadTimeout  = 60 * time.Second
+	peerDiscoveryInterval    = 60 * time.Second
+	peerGarbageCollectorTime = 5 * time.Minute
+	dataCollectionInterval   = 3
+	rebroadcastMaxDelay      = 2 * time.Minute
+	maxRebroadcastsPerTick   = 50
+	defaultMaxPending        = 10000
+
+	defaultPendingExpiry       = 5 * time.Minute
+	defaultRebroadcastInterval = 5 * time.Second
+	defaultCleanupInterval     = 1 * time.Minute
+)
+
+const (
+	MsgTypeSignRequest  = "sign_request"
+	MsgTypeSignResponse = "sign_response"
+)
+
+type SignRequest struct {
+	Type              string            `json:"type"`
+	Hash              string            `json:"hash"`
+	Data              []interface{}     `json:"data"`
+	DataStructure     []string          `json:"data_structure"`
+	DataStructureMeta []string          `json:"data_structure_meta"`
+	DataStructureId   int               `json:"data_structure_id"`
+	Timestamp         int64             `json:"timestamp"`
+	HashVersion       int               `json:"hash_version,omitempty"`
+	TraceContext      map[string]string `json:"trace_context,omitempty"`
+	RequestID         string            `json:"request_id,omitempty"`
+	ProtocolVersion   string            `json:"protocol_version,omitempty"`
+	// Sequence numbers the requests of a data structure 1, 2, 3, ... so
+	// receivers can tell when they missed one. It is not part of the hash.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// Epoch names the staking epoch whose signer set the request is for,
+	// zero without staking. It is not part of the hash.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// Origin is the address of the node that collected the data and
+	// OriginSignature its signature of the hash; both are empty when an
+	// operator built the request.
+	Origin          string `json:"origin,omitempty"`
+	OriginSignature string `json:"origin_signature,omitempty"`
+}
+
+type SignResponse struct {
+	Type      string `json:"type"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+	PeerID    string `json:"peer_id"`
+	Timestamp int64  `json:"timestamp"`
+	RequestID string `json:"request_id,omitempty"`
+
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	// TraceContext carries the signer's span, which verification continues.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+type PendingRequest struct {
+	timestamp time.Time
+	signers   map[string]bool
+	data      SignRequest
+	spanCtx   trace.SpanContext
+	// attempts and nextBroadcast drive the per-hash rebroadcast backoff.
+	attempts      int
+	nextBroadcast time.Time
+	// weight is the summed weight of signers.
+	weight uint64
+	// confirmed is set once the threshold event has fired.
+	confirmed bool
+	// storing are the signers whose signatures are being written.
+	storing map[string]bool
+}
+
+type OperatorNode struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	host          host.Host
+	pubsub        *pubsub.PubSub
+	topic         *pubsub.Topic
+	sub           *pubsub.Subscription
+	subMux        sync.Mutex
+	db            store.Database
+	latency       *LatencyTracker
+	events        *EventBus
+	pending       map[string]*PendingRequest
+	pendingExpiry time.Duration
+	maxPending    int
+	pendingMux    sync.RWMutex
+	trustedAddrs  []string
+	thresholds    map[int]int
+	// weightThresholds are the per-structure signed weight overrides,
+	// guarded by thresholdsMux like thresholds.
+	weightThresholds map[int]uint64
+	thresholdsMux    sync.RWMutex
+	address          common.Address
+	knownPeers       map[peer.ID]time.Time
+	knownPeersMux    sync.RWMutex
+	// reputation is the peer book saved across restarts, guarded by
+	// knownPeersMux.
+	reputation      map[peer.ID]*store.PeerRecord
+	lastMessageTime time.Time
+
+	// Sign requests are only tracked when originated by this operator or by
+	// one of trustedOperators, or signed by one of originators, unless
+	// acceptForeign is set. maxSkew bounds how far request and response
+	// timestamps may be from the local clock.
+	trustedOperators map[peer.ID]bool
+	originators      []string
+	acceptForeign    bool
+	maxSkew          time.Duration
+	acceptMux        sync.RWMutex
+
+	// trustedMux guards trustedAddrs, which key rotations rewrite,
+	// rotations, keyed by the lowercased new address, successors, the new
+	// address of each completed rotation keyed by the lowercased old one,
+	// the per-structure signer committees, the signer weights, keyed by
+	// lowercased seat, and the staking epoch. Like thresholdsMux, it is
+	// taken under pendingMux, never the other way round.
+	rotations       map[string]*store.KeyRotation
+	successors      map[string]string
+	committees      map[int]committee
+	weights         signerWeights
+	epoch           uint64
+	rotationOverlap time.Duration
+	trustedMux      sync.RWMutex
+
+	// expiryOverrides, rebroadcastEvery and cleanupEvery are guarded by
+	// pendingMux. timingsChanged tells the retry loop to pick up new
+	// intervals.
+	expiryOverrides  map[int]time.Duration
+	rebroadcastEvery time.Duration
+	cleanupEvery     time.Duration
+	timingsChanged   chan struct{}
+
+	// rounds remembers recent requests per structure and ticker so two
+	// hashes for one round can be caught; conflicted holds the hashes that
+	// must not be confirmed. Both are guarded by pendingMux.
+	rounds         map[roundKey][]roundEntry
+	conflicted     map[string]bool
+	conflictWindow time.Duration
+
+	// rewardPool and rewardPeriod decide how participation is counted and
+	// paid out; both are guarded by pendingMux. A nil pool reports
+	// participation only.
+	rewardPool   *big.Int
+	rewardPeriod time.Duration
+
+	// sequences holds the last sequence number handed out per data
+	// structure.
+	sequences    map[int]uint64
+	sequencesMux sync.Mutex
+
+	// versions is the signer version matrix.
+	versions *signerVersions
+	// liveness holds the signers' last heartbeats.
+	liveness *livenessRegistry
+
+	// replication fans state changes out to standbys. replicationMux guards
+	// replicas, the standby peers allowed to replicate from us, and
+	// primary, the operator we replicate from while we are a standby.
+	// leaseUntil is when the lease our standbys granted us lapses, and
+	// leaseGranted when the last lease we granted our primary surely has.
+	replication    *replicationHub
+	replicas       map[peer.ID]bool
+	primary        *peer.AddrInfo
+	replicaInSync  bool
+	leaseUntil     time.Time
+	leaseGranted   time.Time
+	replicationMux sync.RWMutex
+
+	// tenantTopics are the topics of the tenants served besides our own,
+	// and structureTenants the tenant owning each of their structures.
+	tenantTopics     map[string]*tenantTopic
+	structureTenants map[int]string
+	tenantsMux       sync.RWMutex
+
+	// archive holds messages pruned from db; nil when archiving is off.
+	archive *archive.Archive
+
+	// intake buffers gossiped messages between the subscription reader and
+	// HandleMessage.
+	intake       chan incomingMessage
+	intakePolicy string
+
+	// clock drives pending expiry, rebroadcast backoff and the maintenance
+	// tickers, so tests can advance time with clock.NewMock().
+	clock clock.Clock
+}
+
+func NewOperatorNode(ctx context.Context, cancel context.CancelFunc, privKey crypto.PrivKey, db store.Database, topicName string, trustedAddrs []string, network NetworkConfig, gossip GossipConfig, intake IntakeConfig) (*OperatorNode, error) {
+	hostOpts, err := network.Options()
+	if err != nil {
+		return nil, err
+	}
+	host, err := libp2p.New(append(hostOpts, libp2p.Identity(privKey))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host: %w", err)
+	}
+
+	return NewOperatorNodeWithHost(ctx, cancel, host, privKey, db, topicName, trustedAddrs, gossip, intake, clock.New())
+}
+
+// NewOperatorNodeWithHost runs an operator on an existing libp2p host, such
+// as one from an in-memory mock network, reading time from clk.
+func NewOperatorNodeWithHost(ctx context.Context, cancel context.CancelFunc, host host.Host, privKey crypto.PrivKey, db store.Database, topicName string, trustedAddrs []string, gossip GossipConfig, intake IntakeConfig, clk clock.Clock) (*OperatorNode, error) {
+	intake, err := intake.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := ethAddressFromKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("✅ Bootstrap node started.")
+
+	for _, addr := range host.Addrs() {
+		fullAddr := fmt.Sprintf("%s/p2p/%s", addr, host.ID().String())
+		log.Println("🛰️ Listening on:", fullAddr)
+	}
+
+	gossipOpts, err := gossip.Options()
+	if err != nil {
+		return nil, err
+	}
+	ps, err := pubsub.NewGossipSub(ctx, host, gossipOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub: %w", err)
+	}
+
+	topic, err := ps.Join(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join topic: %w", err)
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	replication := newReplicationHub()
+	db = newReplicatingDatabase(db, replication)
+
+	operator := &OperatorNode{
+		ctx:              ctx,
+		cancel:           cancel,
+		host:             host,
+		pubsub:           ps,
+		topic:            topic,
+		sub:              sub,
+		db:               db,
+		latency:          NewLatencyTracker(db),
+		events:           NewEventBus(),
+		pending:          make(map[string]*PendingRequest),
+		trustedAddrs:     append([]string(nil), trustedAddrs...),
+		thresholds:       make(map[int]int),
+		weightThresholds: make(map[int]uint64),
+		address:          address,
+		knownPeers:       make(map[peer.ID]time.Time),
+		reputation:       make(map[peer.ID]*store.PeerRecord),
+		pendingExpiry:    defaultPendingExpiry,
+		maxPending:       defaultMaxPending,
+		maxSkew:          defaultMaxTimestampSkew,
+		clock:            clk,
+
+		expiryOverrides:  make(map[int]time.Duration),
+		rebroadcastEvery: defaultRebroadcastInterval,
+		cleanupEvery:     defaultCleanupInterval,
+		timingsChanged:   make(chan struct{}, 1),
+
+		rounds:         make(map[roundKey][]roundEntry),
+		conflicted:     make(map[string]bool),
+		conflictWindow: defaultConflictWindow,
+		rewardPeriod:   defaultRewardPeriod,
+
+		sequences: make(map[int]uint64),
+		versions:  newSignerVersions(),
+		liveness:  newLivenessRegistry(clk.Now()),
+
+		replication: replication,
+
+		intake:       make(chan incomingMessage, intake.Size),
+		intakePolicy: intake.Policy,
+
+		rotations:       make(map[string]*store.KeyRotation),
+		successors:      make(map[string]string),
+		committees:      make(map[int]committee),
+		rotationOverlap: defaultKeyRotationOverlap,
+
+		tenantTopics:     make(map[string]*tenantTopic),
+		structureTenants: make(map[int]string),
+	}
+	operator.restoreKeyRotations()
+	operator.restorePeers()
+	subscribeLatency(operator.events, operator.latency)
+	subscribeMetrics(operator.events)
+	host.SetStreamHandler(SyncProtocolID, operator.handleSyncStream)
+	host.SetStreamHandler(ReplicationProtocolID, operator.handleReplicationStream)
+	host.SetStreamHandler(HelloProtocolID, operator.handleHelloStream)
+
+	// Setup network notifiers
+	host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(net network.Network, conn network.Conn) {
+			peerID := conn.RemotePeer()
+			operator.notePeerConnected(peerID)
+			log.Printf("🔗 New peer connected: %s", peerID)
+		},
+		DisconnectedF: func(net network.Network, conn network.Conn) {
+			peerID := conn.RemotePeer()
+			log.Printf("❌ Peer disconnected: %s", peerID)
+		},
+	})
+
+	go operator.processIntake()
+	go operator.listen()
+	go operator.retryPendingRequests()
+	go operator.peerDiscovery()
+	go operator.peerGarbageCollector()
+	go operator.healthMonitor()
+	go operator.livenessMonitor()
+	go operator.peerPersister()
+
+	return operator, nil
+}
+
+func (o *OperatorNode) peerDiscovery() {
+	ticker := o.clock.Ticker(peerDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.knownPeersMux.RLock()
+			peerCount := len(o.knownPeers)
+			o.knownPeersMux.RUnlock()
+
+			log.Printf("🌐 Known peers: %d", peerCount)
+
+			if peerCount == 0 {
+				// Attempt to find peers through DHT or other discovery mechanisms
+				log.Println("⚠️ No peers connected, attempting active peer discovery...")
+
+				peersToTry := o.reconnectCandidates()
+				if len(peersToTry) > 0 {
+					log.Printf("Attempting to reconnect to %d known peers in peerstore", len(peersToTry))
+					for _, peerID := range peersToTry {
+						addrs := o.host.Peerstore().Addrs(peerID)
+						if len(addrs) == 0 {
+							continue
+						}
+
+						ctx, cancel := context.WithTimeout(o.ctx, 5*time.Second)
+						err := o.host.Connect(ctx, peer.AddrInfo{
+							ID:    peerID,
+							Addrs: addrs,
+						})
+						cancel()
+
+						if err != nil {
+							o.notePeerDialFailed(peerID)
+							log.Printf("Failed to reconnect to peer %s: %v", peerID, err)
+						} else {
+							log.Printf("Successfully reconnected to peer %s", peerID)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (o *OperatorNode) peerGarbageCollector() {
+	ticker := o.clock.Ticker(peerGarbageCollectorTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			now := o.clock.Now()
+			o.knownPeersMux.Lock()
+			for p, lastSeen := range o.knownPeers {
+				if now.Sub(lastSeen) > peerGarbageCollectorTime {
+					delete(o.knownPeers, p)
+				}
+			}
+			o.knownPeersMux.Unlock()
+		}
+	}
+}
+
+func ethAddressFromKey(privKey crypto.PrivKey) (common.Address, error) {
+	raw, err := privKey.Raw()
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get raw private key: %w", err)
+	}
+
+	ecdsaKey, err := cryptoeth.ToECDSA(raw)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to convert to ECDSA key: %w", err)
+	}
+
+	return cryptoeth.PubkeyToAddress(ecdsaKey.PublicKey), nil
+}
+
+// threshold returns the number of signatures required by default: a
+// majority, or with signer weights the fewest signers that can hold a
+// majority of the weight.
+func (o *OperatorNode) threshold() int {
+	trusted := o.trustedSigners()
+	if !o.weighted() {
+		return len(trusted)/2 + 1
+	}
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+	return o.weights.minSigners(trusted, o.weights.required(trusted, 0))
+}
+
+// weightThreshold returns the signed weight required by default.
+func (o *OperatorNode) weightThreshold() uint64 {
+	trusted := o.trustedSigners()
+
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+	return o.weights.required(trusted, 0)
+}
+
+// Threshold is the number of signatures a message of a data structure needs.
+func (o *OperatorNode) Threshold(dataStructureID int) int {
+	return o.thresholdFor(dataStructureID)
+}
+
+// TopicPeers are the peers seen on the operator's gossip topic.
+func (o *OperatorNode) TopicPeers() []peer.ID {
+	return o.topic.ListPeers()
+}
+
+// Latency follows the operator's requests from build to confirmation.
+func (o *OperatorNode) Latency() *LatencyTracker {
+	return o.latency
+}
+
+// thresholdFor returns the number of signatures required to confirm a
+// message of the given data structure: the override if set, otherwise a
+// majority, bounded by the signers eligible for the structure. With signer
+// weights and no override it is the fewest signers that can reach the
+// weight threshold, so it stays a lower bound for code counting signatures.
+func (o *OperatorNode) thresholdFor(dataStructureID int) int {
+	eligible := o.eligibleSigners(dataStructureID)
+
+	o.thresholdsMux.RLock()
+	t, ok := o.thresholds[dataStructureID]
+	o.thresholdsMux.RUnlock()
+
+	if ok {
+		return max(min(t, len(eligible)), 1)
+	}
+	if !o.weighted() {
+		return len(eligible)/2 + 1
+	}
+	required := o.weightThresholdFor(dataStructureID)
+
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+	return o.weights.minSigners(eligible, required)
+}
+
+// SetThresholdOverride makes messages of a data structure require a custom
+// number of signatures, bounded by the trusted set size.
+func (o *OperatorNode) SetThresholdOverride(dataStructureID, threshold int) {
+	if threshold < 1 {
+		threshold = 1
+	}
+	if signers := len(o.trustedSigners()); threshold > signers {
+		threshold = signers
+	}
+
+	o.thresholdsMux.Lock()
+	o.thresholds[dataStructureID] = threshold
+	o.thresholdsMux.Unlock()
+}
+
+func (o *OperatorNode) thresholdOverrides() map[int]int {
+	o.thresholdsMux.RLock()
+	defer o.thresholdsMux.RUnlock()
+
+	overrides := make(map[int]int, len(o.thresholds))
+	for id, t := range o.thresholds {
+		overrides[id] = t
+	}
+	return overrides
+}
+
+func (o *OperatorNode) listen() {
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		default:
+			ctx, cancel := context.WithTimeout(o.ctx, subscriptionReadTimeout)
+			msg, err := o.subscription().Next(ctx)
+			cancel()
+
+			if err != nil {
+				if o.ctx.Err() == nil {
+					if err == context.DeadlineExceeded {
+						log.Printf("Чтение из подписки превысило таймаут (%v). Переподключение...", subscriptionReadTimeout)
+					} else {
+						log.Printf("Ошибка при чтении из подписки: %v. Переподключение...", err)
+					}
+
+					if err := o.resubscribe(); err != nil {
+						log.Printf("Критическая ошибка при переподключении: %v", err)
+						o.clock.Sleep(5 * time.Second)
+					}
+					continue
+				}
+				return // Exit if context is done
+			}
+
+			o.enqueueIncoming(msg.GetFrom(), msg.Data)
+		}
+	}
+}
+
+// subscription returns the current subscription. subMux guards it, since
+// both the reader and the health monitor resubscribe.
+func (o *OperatorNode) subscription() *pubsub.Subscription {
+	o.subMux.Lock()
+	defer o.subMux.Unlock()
+	return o.sub
+}
+
+func (o *OperatorNode) resubscribe() error {
+	o.subMux.Lock()
+	defer o.subMux.Unlock()
+
+	if o.sub != nil {
+		o.sub.Cancel()
+	}
+
+	var err error
+	for i := 0; i < maxReconnectAttempts; i++ {
+		o.sub, err = o.topic.Subscribe()
+		if err == nil {
+			log.Println("✅ Успешно переподключились к топику")
+			return nil
+		}
+
+		log.Printf("Попытка переподключения %d/%d не удалась: %v",
+			i+1, maxReconnectAttempts, err)
+
+		sleepTime := reconnectTimeout * time.Duration(i+1)
+		if sleepTime > 30*time.Second {
+			sleepTime = 30 * time.Second
+		}
+
+		select {
+		case <-o.ctx.Done():
+			return fmt.Errorf("Контекст отменен при переподключении: %w", o.ctx.Err())
+		case <-o.clock.After(sleepTime):
+			// Continue to next attempt
+		}
+	}
+
+	return fmt.Errorf("Не удалось переподключиться после %d попыток: %w", maxReconnectAttempts, err)
+}
+
+func (o *OperatorNode) healthMonitor() {
+	healthCheckTicker := o.clock.Ticker(30 * time.Second)
+	defer healthCheckTicker.Stop()
+
+	consecutiveTimeouts := 0
+	maxConsecutiveTimeouts := 3
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-healthCheckTicker.C:
+			o.logIntakeDrops()
+			o.knownPeersMux.RLock()
+			hasRecentMessage := !o.lastMessageTime.IsZero() && o.clock.Since(o.lastMessageTime) <= 5*time.Minute
+			o.knownPeersMux.RUnlock()
+
+			if !hasRecentMessage {
+				log.Printf("⚠️ No messages received in 5 minutes, health check triggered")
+
+				o.knownPeersMux.RLock()
+				peerCount := len(o.knownPeers)
+				o.knownPeersMux.RUnlock()
+
+				if peerCount == 0 {
+					log.Println("🔄 No peers connected, forcing peer discovery")
+					peersToTry := o.reconnectCandidates()
+					for _, peerID := range peersToTry {
+						addrs := o.host.Peerstore().Addrs(peerID)
+						if len(addrs) == 0 {
+							continue
+						}
+
+						ctx, cancel := context.WithTimeout(o.ctx, 5*time.Second)
+						err := o.host.Connect(ctx, peer.AddrInfo{
+							ID:    peerID,
+							Addrs: addrs,
+						})
+						cancel()
+
+						if err != nil {
+							o.notePeerDialFailed(peerID)
+						} else {
+							log.Printf("✅ Successfully reconnected to peer %s", peerID)
+						}
+					}
+
+					if consecutiveTimeouts >= maxConsecutiveTimeouts {
+						log.Println("🔄 Multiple timeouts detected, attempting to reset subscription")
+						if err := o.resubscribe(); err != nil {
+							log.Printf("❌ Failed to resubscribe: %v", err)
+						} else {
+							consecutiveTimeouts = 0
+						}
+					} else {
+						consecutiveTimeouts++
+					}
+				} else {
+					consecutiveTimeouts = 0
+				}
+			} else {
+				consecutiveTimeouts = 0
+			}
+		}
+	}
+}
+
+func (o *OperatorNode) retryPendingRequests() {
+	rebroadcastEvery, cleanupEvery := o.retryIntervals()
+
+	ticker := o.clock.Ticker(rebroadcastEvery)
+	defer ticker.Stop()
+
+	tickerExpired := o.clock.Ticker(cleanupEvery)
+	defer tickerExpired.Stop()
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-o.timingsChanged:
+			rebroadcastEvery, cleanupEvery = o.retryIntervals()
+			ticker.Reset(rebroadcastEvery)
+			tickerExpired.Reset(cleanupEvery)
+		case <-ticker.C:
+			// A standby leaves rebroadcasts to the primary until it takes
+			// over, and a primary stops when its lease lapses.
+			if o.fenced() {
+				continue
+			}
+			for _, hash := range o.dueRebroadcasts(o.clock.Now(), maxRebroadcastsPerTick) {
+				if err := o.BroadcastSignRequest(hash); err != nil {
+					log.Printf("Failed to rebroadcast %s: %v", hash, err)
+				}
+			}
+		case <-tickerExpired.C:
+			o.cleanupExpiredRequests()
+			o.finalizeRotations()
+		}
+
+	}
+}
+
+func (o *OperatorNode) retryIntervals() (rebroadcast, cleanup time.Duration) {
+	o.pendingMux.RLock()
+	defer o.pendingMux.RUnlock()
+
+	return o.rebroadcastEvery, o.cleanupEvery
+}
+
+// SetPendingTimings changes how long requests stay pending, how often
+// unconfirmed requests are rebroadcast and how often expired ones are swept.
+// Zero leaves a value unchanged.
+func (o *OperatorNode) SetPendingTimings(expiry, rebroadcast, cleanup time.Duration) {
+	o.pendingMux.Lock()
+	if expiry > 0 {
+		o.pendingExpiry = expiry
+	}
+	if rebroadcast > 0 {
+		o.rebroadcastEvery = rebroadcast
+	}
+	if cleanup > 0 {
+		o.cleanupEvery = cleanup
+	}
+	o.pendingMux.Unlock()
+
+	select {
+	case o.timingsChanged <- struct{}{}:
+	default:
+	}
+}
+
+// SetPendingExpiryOverride gives requests of one data structure their own
+// confirmation window.
+func (o *OperatorNode) SetPendingExpiryOverride(dataStructureID int, expiry time.Duration) {
+	o.pendingMux.Lock()
+	o.expiryOverrides[dataStructureID] = expiry
+	o.pendingMux.Unlock()
+}
+
+// expiryFor returns the confirmation window of a data structure. Callers
+// hold pendingMux.
+func (o *OperatorNode) expiryFor(dataStructureID int) time.Duration {
+	if expiry, ok := o.expiryOverrides[dataStructureID]; ok {
+		return expiry
+	}
+	return o.pendingExpiry
+}
+
+// dueRebroadcasts picks at most limit pending hashes whose backoff has
+// elapsed. Requests closest to their threshold go first, then the freshest,
+// since those are the most likely to still be confirmed. Each picked request
+// doubles its delay until the next rebroadcast. A rebroadcast carries the
+// original timestamp, so requests past the skew window, which signers would
+// refuse, are left for cleanupExpiredRequests.
+func (o *OperatorNode) dueRebroadcasts(now time.Time, limit int) []string {
+	skew := o.timestampSkew()
+	thresholds := o.pendingThresholds()
+
+	o.pendingMux.Lock()
+	defer o.pendingMux.Unlock()
+
+	type candidate struct {
+		hash    string
+		missing int
+		req     *PendingRequest
+	}
+
+	var due []candidate
+	for hash, req := range o.pending {
+		if now.Before(req.nextBroadcast) || !timestampWithinSkew(req.data.Timestamp, now, skew) {
+			continue
+		}
+		threshold, ok := thresholds[req.data.DataStructureId]
+		if !ok {
+			// Added since the thresholds were read; due on the next tick.
+			continue
+		}
+		missing := threshold - len(req.signers)
+		due = append(due, candidate{hash: hash, missing: missing, req: req})
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].missing != due[j].missing {
+			return due[i].missing < due[j].missing
+		}
+		return due[i].req.timestamp.After(due[j].req.timestamp)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	hashes := make([]string, len(due))
+	for i, c := range due {
+		maxDelay := rebroadcastMaxDelay
+		if o.rebroadcastEvery > maxDelay {
+			maxDelay = o.rebroadcastEvery
+		}
+		delay := o.rebroadcastEvery << c.req.attempts
+		if delay > maxDelay || delay <= 0 {
+			delay = maxDelay
+		}
+		c.req.attempts++
+		c.req.nextBroadcast = now.Add(delay)
+		hashes[i] = c.hash
+	}
+	return hashes
+}
+
+// pendingThresholds returns the threshold of every data structure with a
+// pending request, read before dueRebroadcasts takes pendingMux so that the
+// scan over all pending requests does not hold trustedMux and thresholdsMux
+// up behind it.
+func (o *OperatorNode) pendingThresholds() map[int]int {
+	o.pendingMux.RLock()
+	ids := make(map[int]struct{})
+	for _, req := range o.pending {
+		ids[req.data.DataStructureId] = struct{}{}
+	}
+	o.pendingMux.RUnlock()
+
+	thresholds := make(map[int]int, len(ids))
+	for id := range ids {
+		thresholds[id] = o.thresholdFor(id)
+	}
+	return thresholds
+}
+
+// cleanupExpiredRequests drops requests pending longer than their
+// confirmation window, or whose timestamp has left the skew window: no
+// signer would answer a rebroadcast of those any more.
+func (o *OperatorNode) cleanupExpiredRequests() {
+	skew := o.timestampSkew()
+
+	o.pendingMux.Lock()
+	defer o.pendingMux.Unlock()
+
+	now := o.clock.Now()
+	for hash, req := range o.pending {
+		if now.Sub(req.timestamp) > o.expiryFor(req.data.DataStructureId) || !timestampWithinSkew(req.data.Timestamp, now, skew) {
+			delete(o.pending, hash)
+			o.emitExpired(req, now)
+			log.Printf("Expired pending request: %s", hash)
+		}
+	}
+	o.pruneRounds(now)
+	pendingRequestsGauge.Set(float64(len(o.pending)))
+}
+
+// emitExpired reports a request dropped from the pending set. The caller
+// holds pendingMux.
+func (o *OperatorNode) emitExpired(req *PendingRequest, at time.Time) {
+	data := req.data
+	o.events.Emit(Event{
+		Type:            EventRequestExpired,
+		Hash:            req.data.Hash,
+		RequestID:       req.data.RequestID,
+		DataStructureID: req.data.DataStructureId,
+		Signatures:      len(req.signers),
+		Threshold:       o.thresholdFor(req.data.DataStructureId),
+		At:              at,
+		Request:         &data,
+	})
+}
+
+// Shutdown stops the operator, saves its known peers and closes its host and
+// database.
+func (o *OperatorNode) Shutdown() {
+	log.Println("Shutting down...")
+
+	o.cancel()
+
+	o.subMux.Lock()
+	if o.sub != nil {
+		o.sub.Cancel()
+	}
+	o.subMux.Unlock()
+	o.closeTenantTopics()
+
+	if o.host != nil {
+		o.persistPeers(context.Background())
+		if err := o.host.Close(); err != nil {
+			log.Printf("Error closing host: %v", err)
+		}
+	}
+
+	o.latency.Close()
+	if err := o.db.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+}
+
+// BroadcastSignRequest republishes the pending request for hash. The
+// rebroadcast carries the request's data and structure like the original,
+// so signers apply the same bounds and key policies to it; a hash that is
+// no longer pending is not rebroadcast.
+func (o *OperatorNode) BroadcastSignRequest(hash string) error {
+	o.pendingMux.RLock()
+	p, ok := o.pending[hash]
+	var req SignRequest
+	var spanCtx trace.SpanContext
+	if ok {
+		req, spanCtx = p.data, p.spanCtx
+	}
+	o.pendingMux.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s is not pending", hash)
+	}
+
+	req.Type = MsgTypeSignRequest
+	req.ProtocolVersion = ProtocolVersion
+	req.TraceContext = nil
+	if spanCtx.IsValid() {
+		req.TraceContext = injectTraceContext(trace.ContextWithSpanContext(o.ctx, spanCtx))
+	}
+	topic := o.topicFor(req.DataStructureId)
+
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, publishTimeout)
+	defer cancel()
+
+	return topic.Publish(ctx, msg)
+}
+
+func verifySignature(message []byte, signatureHex string) (common.Address, error) {
+	sigBytes, err := hexutil.Decode(signatureHex)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature hex: %v", err)
+	}
+
+	if len(sigBytes) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature length, expected 65 got %d", len(sigBytes))
+	}
+
+	pubKey, err := cryptoeth.SigToPub(message, sigBytes)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("signature recovery failed: %v", err)
+	}
+
+	recoveredAddr := cryptoeth.PubkeyToAddress(*pubKey)
+
+	return recoveredAddr, nil
+}
+
+// handleSignResponse records a signature towards its pending request. It
+// reports false when the response is malformed or its signature does not
+// verify, which counts against the peer that published it.
+func (o *OperatorNode) handleSignResponse(ctx context.Context, resp *SignResponse) bool {
+	log.Printf("Received signature response for hash: %s from %s [req=%s]", resp.Hash, resp.PeerID, resp.RequestID)
+
+	var opts []trace.SpanStartOption
+	o.pendingMux.RLock()
+	if p, ok := o.pending[resp.Hash]; ok && p.spanCtx.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: p.spanCtx}))
+	}
+	o.pendingMux.RUnlock()
+	opts = append(opts, trace.WithAttributes(attribute.String("hash", resp.Hash), attribute.String("peer_id", resp.PeerID), attribute.String("request_id", resp.RequestID)))
+
+	ctx, span := tracer.Start(ctx, "verify_signature", opts...)
+	defer span.End()
+
+	hash, err := hex.DecodeString(resp.Hash)
+	if err != nil {
+		signResponsesRejected.WithLabelValues("malformed_hash").Inc()
+		recordSpanError(span, err)
+		log.Printf("Malformed hash in sign response from %s: %v", resp.PeerID, err)
+		return false
+	}
+
+	message := accounts.TextHash(hash)
+
+	signerAddress, err := verifySignature(message, resp.Signature)
+	if err != nil {
+		signResponsesRejected.WithLabelValues("bad_signature").Inc()
+		recordSpanError(span, err)
+		log.Printf("Signature verification failed: %v", err)
+		return false
+	}
+	span.SetAttributes(attribute.String("signer", signerAddress.Hex()))
+
+	if !o.isTrusted(signerAddress) {
+		signResponsesRejected.WithLabelValues("untrusted_signer").Inc()
+		span.SetStatus(codes.Error, "untrusted signer")
+		log.Printf("Untrusted signer: %s", signerAddress.Hex())
+		return true
+	}
+	o.versions.observeMessage(signerAddress.Hex(), resp.ProtocolVersion, o.clock.Now())
+	seat := o.seatOf(signerAddress)
+
+	o.pendingMux.Lock()
+	req, exists := o.pending[resp.Hash]
+	if !exists {
+		o.pendingMux.Unlock()
+		signResponsesRejected.WithLabelValues("unknown_hash").Inc()
+		return true
+	}
+	if !o.isEligible(req.data.DataStructureId, seat) {
+		o.pendingMux.Unlock()
+		signResponsesRejected.WithLabelValues("not_eligible").Inc()
+		span.SetStatus(codes.Error, "signer not in committee")
+		log.Printf("Signer %s is not eligible for structure %d [req=%s]", signerAddress.Hex(), req.data.DataStructureId, req.data.RequestID)
+		return true
+	}
+	if req.signers[seat] || req.storing[seat] {
+		o.pendingMux.Unlock()
+		signResponsesRejected.WithLabelValues("duplicate").Inc()
+		return true
+	}
+	if o.isConflicted(resp.Hash) {
+		o.pendingMux.Unlock()
+		signResponsesRejected.WithLabelValues("conflict").Inc()
+		span.SetStatus(codes.Error, "conflicting round")
+		return true
+	}
+	req.storing[seat] = true
+	o.pendingMux.Unlock()
+
+	// The signature is written without pendingMux held, so a slow or batched
+	// write does not stall other responses, new requests and rebroadcasts.
+	err = o.db.StoreSignature(ctx, resp.Hash, signerAddress.Hex(), resp.Signature)
+
+	o.pendingMux.Lock()
+	delete(req.storing, seat)
+	if err != nil {
+		o.pendingMux.Unlock()
+		recordSpanError(span, err)
+		log.Printf("Error storing signature: %v", err)
+		return true
+	}
+	if o.pending[resp.Hash] != req {
+		o.pendingMux.Unlock()
+		log.Printf("Stored signature for %s from %s after it left the pending set [req=%s]", resp.Hash, signerAddress.Hex(), req.data.RequestID)
+		return true
+	}
+	confirmation, participants := o.countSignature(req, resp.Hash, seat, signerAddress, span)
+	o.pendingMux.Unlock()
+
+	if confirmation != nil {
+		if err := o.db.MarkConfirmed(ctx, req.data.DataStructureId, resp.Hash, req.data.Timestamp, confirmation); err != nil {
+			log.Printf("Error updating stats for %s: %v", resp.Hash, err)
+		}
+	}
+	if len(participants) > 0 {
+		o.recordParticipation(ctx, participants)
+	}
+	return true
+}
+
+// countSignature adds the stored signature of seat to req and checks the
+// threshold. It returns the confirmation to record when the signature
+// confirms req, and the signers whose participation to record. The caller
+// holds pendingMux and does the writes after releasing it.
+func (o *OperatorNode) countSignature(req *PendingRequest, hash, seat string, signerAddress common.Address, span trace.Span) (*store.Confirmation, []string) {
+	req.signers[seat] = true
+	req.weight += o.weightOf(seat)
+	threshold := o.thresholdFor(req.data.DataStructureId)
+	// Without signer weights every signer weighs 1 and the count alone
+	// decides.
+	var weight, weightThreshold uint64
+	if o.weighted() {
+		weight, weightThreshold = req.weight, o.weightThresholdFor(req.data.DataStructureId)
+	}
+	o.events.Emit(Event{
+		Type:            EventSignatureReceived,
+		Hash:            hash,
+		RequestID:       req.data.RequestID,
+		DataStructureID: req.data.DataStructureId,
+		Signer:          signerAddress.Hex(),
+		Signatures:      len(req.signers),
+		Threshold:       threshold,
+		Weight:          weight,
+		WeightThreshold: weightThreshold,
+		At:              o.clock.Now(),
+	})
+	log.Printf("Stored signature for %s from %s (total: %d, weight: %d) [req=%s]", hash, signerAddress.Hex(), len(req.signers), req.weight, req.data.RequestID)
+
+	span.SetAttributes(attribute.Int("signers", len(req.signers)), attribute.Int64("weight", int64(req.weight)))
+
+	if len(req.signers) < threshold || weight < weightThreshold {
+		return nil, nil
+	}
+	span.AddEvent("threshold_reached")
+
+	var confirmation *store.Confirmation
+	var participants []string
+	if !req.confirmed {
+		req.confirmed = true
+		confirmation = o.confirmationFor(req.data.DataStructureId, threshold, weightThreshold)
+		o.events.Emit(Event{
+			Type:            EventThresholdReached,
+			Hash:            hash,
+			RequestID:       req.data.RequestID,
+			DataStructureID: req.data.DataStructureId,
+			Signatures:      len(req.signers),
+			Threshold:       threshold,
+			Weight:          weight,
+			WeightThreshold: weightThreshold,
+			At:              o.clock.Now(),
+		})
+		participants = make([]string, 0, len(req.signers))
+		for signer := range req.signers {
+			participants = append(participants, signer)
+		}
+	} else {
+		// Signatures after the threshold still count towards the
+		// signer's participation.
+		participants = []string{seat}
+	}
+	signers := len(o.eligibleSigners(req.data.DataStructureId))
+	log.Printf("✅ Reached threshold %d of %d for %s [req=%s]", len(req.signers), signers, hash, req.data.RequestID)
+	if len(req.signers) == signers {
+		delete(o.pending, hash)
+		pendingRequestsGauge.Set(float64(len(o.pending)))
+	}
+	return confirmation, participants
+}
+
+// SetTrustedOperators lets sign requests gossiped by other operator peers
+// into the pending set alongside our own.
+func (o *OperatorNode) SetTrustedOperators(peers []peer.ID) {
+	o.acceptMux.Lock()
+	defer o.acceptMux.Unlock()
+
+	o.trustedOperators = make(map[peer.ID]bool, len(peers))
+	for _, p := range peers {
+		o.trustedOperators[p] = true
+	}
+}
+
+// SetAcceptForeignRequests disables origin checks on sign requests.
+func (o *OperatorNode) SetAcceptForeignRequests(accept bool) {
+	o.acceptMux.Lock()
+	o.acceptForeign = accept
+	o.acceptMux.Unlock()
+}
+
+// acceptsSignRequestFrom reports whether a sign request originated by from
+// may enter the pending set. The origin is authenticated by pubsub message
+// signing, so peers cannot claim to be us.
+func (o *OperatorNode) acceptsSignRequestFrom(from peer.ID) bool {
+	if from == o.host.ID() {
+		return true
+	}
+
+	o.acceptMux.RLock()
+	defer o.acceptMux.RUnlock()
+
+	return o.acceptForeign || o.trustedOperators[from]
+}
+
+func (o *OperatorNode) HandleMessage(from peer.ID, data []byte) {
+	valid := true
+	defer func() { o.notePeerMessage(from, valid) }()
+
+	var msg struct {
+		Type            string            `json:"type"`
+		ProtocolVersion string            `json:"protocol_version"`
+		TraceContext    map[string]string `json:"trace_context"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Error unmarshaling message: %v", err)
+		valid = false
+		return
+	}
+	if !protocolCompatible(msg.ProtocolVersion) {
+		// Not the peer's fault, so it does not count against it.
+		incompatibleMessagesTotal.WithLabelValues(msg.Type).Inc()
+		log.Printf("Refusing %s from %s: protocol %s is incompatible with %s", msg.Type, from, msg.ProtocolVersion, ProtocolVersion)
+		return
+	}
+
+	o.knownPeersMux.Lock()
+	o.lastMessageTime = o.clock.Now()
+	o.knownPeersMux.Unlock()
+
+	ctx, span := tracer.Start(extractTraceContext(o.ctx, msg.TraceContext), "pubsub.receive "+msg.Type, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	switch msg.Type {
+	case MsgTypeSignRequest:
+		var req SignRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			recordSpanError(span, err)
+			log.Printf("Error unmarshaling sign request: %v", err)
+			valid = false
+			return
+		}
+		if !o.timestampAcceptable(req.Timestamp) {
+			timestampSkewRejections.WithLabelValues(MsgTypeSignRequest).Inc()
+			span.SetStatus(codes.Error, "timestamp skew")
+			log.Printf("Rejecting sign request %s: timestamp %d outside allowed skew [req=%s]", req.Hash, req.Timestamp, req.RequestID)
+			valid = false
+			return
+		}
+		if req.Origin != "" && !o.acceptsSignRequestFrom(from) {
+			valid = o.handleOriginatedRequest(ctx, &req)
+			return
+		}
+		if !o.acceptsSignRequestFrom(from) {
+			foreignSignRequestsDropped.Inc()
+			span.SetStatus(codes.Error, "foreign sign request")
+			log.Printf("Dropping sign request %s from foreign peer %s [req=%s]", req.Hash, from, req.RequestID)
+			return
+		}
+		o.handleSignRequest(ctx, &req)
+	case MsgTypeSignResponse:
+		var resp SignResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			recordSpanError(span, err)
+			log.Printf("Error unmarshaling sign response: %v", err)
+			valid = false
+			return
+		}
+		if !o.timestampAcceptable(resp.Timestamp) {
+			timestampSkewRejections.WithLabelValues(MsgTypeSignResponse).Inc()
+			span.SetStatus(codes.Error, "timestamp skew")
+			log.Printf("Rejecting sign response for %s from %s: timestamp %d outside allowed skew [req=%s]", resp.Hash, resp.PeerID, resp.Timestamp, resp.RequestID)
+			valid = false
+			return
+		}
+		valid = o.handleSignResponse(ctx, &resp)
+	case MsgTypeKeyRotation:
+		o.handleKeyRotationMessage(data)
+	case MsgTypeSignerHeartbeat:
+		valid = o.handleSignerHeartbeat(data)
+	default:
+		log.Printf("Unknown message type: %s", msg.Type)
+		valid = false
+	}
+}
+
+// alreadyConfirmed reports whether req's hash is stored with enough
+// signatures, as happens when a request is gossiped again after it left
+// the pending set. Tracking it again would only rebroadcast it.
+func (o *OperatorNode) alreadyConfirmed(ctx context.Context, req *SignRequest) bool {
+	sigs, ok := o.db.GetSignatures(ctx, req.Hash)
+	return ok && o.confirms(req.DataStructureId, sigs)
+}
+
+// isPending reports whether hash is still collecting signatures.
+func (o *OperatorNode) isPending(hash string) bool {
+	o.pendingMux.RLock()
+	defer o.pendingMux.RUnlock()
+
+	_, ok := o.pending[hash]
+	return ok
+}
+
+// SetMaxPending caps how many sign requests are tracked at once. When the cap
+// is reached the oldest request is evicted to make room. Zero disables the
+// cap.
+func (o *OperatorNode) SetMaxPending(max int) {
+	o.pendingMux.Lock()
+	o.maxPending = max
+	o.pendingMux.Unlock()
+}
+
+// evictOldestPending drops the oldest pending request. Callers hold
+// pendingMux.
+func (o *OperatorNode) evictOldestPending() {
+	var oldestHash string
+	var oldest time.Time
+	for hash, req := range o.pending {
+		if oldestHash == "" || req.timestamp.Before(oldest) {
+			oldestHash, oldest = hash, req.timestamp
+		}
+	}
+	if oldestHash == "" {
+		return
+	}
+
+	o.emitExpired(o.pending[oldestHash], o.clock.Now())
+	delete(o.pending, oldestHash)
+	pendingEvictionsTotal.Inc()
+	log.Printf("⚠️ Pending map full (%d), evicted %s", o.maxPending, oldestHash)
+}
+
+func (o *OperatorNode) handleSignRequest(ctx context.Context, req *SignRequest) {
+	if !o.isPending(req.Hash) && o.alreadyConfirmed(ctx, req) {
+		signRequestsDeduplicated.WithLabelValues("already confirmed").Inc()
+		log.Printf("Ignoring sign request %s: already confirmed [req=%s]", req.Hash, req.RequestID)
+		return
+	}
+
+	o.pendingMux.Lock()
+	if _, exists := o.pending[req.Hash]; !exists {
+		o.checkConflict(req, o.clock.Now())
+		for o.maxPending > 0 && len(o.pending) >= o.maxPending {
+			o.evictOldestPending()
+		}
+		o.pending[req.Hash] = &PendingRequest{
+			timestamp: o.clock.Now(),
+			signers:   make(map[string]bool),
+			storing:   make(map[string]bool),
+			data:      *req,
+			spanCtx:   trace.SpanContextFromContext(extractTraceContext(o.ctx, req.TraceContext)),
+		}
+	}
+	pendingRequestsGauge.Set(float64(len(o.pending)))
+	o.pendingMux.Unlock()
+}
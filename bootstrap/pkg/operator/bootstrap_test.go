@@ -0,0 +1,355 @@
+package operator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"bootstrap/pkg/store"
+)
+
+const testTopic = "l0proof-test"
+
+func newTestKey() (crypto.PrivKey, *ecdsa.PrivateKey, error) {
+	priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := priv.Raw()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := cryptoeth.ToECDSA(raw)
+	return priv, key, err
+}
+
+// testAddr gives each test peer a distinct address; mocknet never dials it.
+func testAddr(i int) ma.Multiaddr {
+	return ma.StringCast(fmt.Sprintf("/ip4/10.0.%d.%d/tcp/4001", i/256, i%256))
+}
+
+// testOperator is an operator on an in-memory network with trusted signer
+// keys to answer its requests with.
+type testOperator struct {
+	*OperatorNode
+	signers []*ecdsa.PrivateKey
+}
+
+// newTestOperator starts an operator trusting signers fresh keys. wrap, when
+// set, wraps its database.
+func newTestOperator(t *testing.T, clk clock.Clock, signers int, wrap func(store.Database) store.Database) *testOperator {
+	t.Helper()
+	net := mocknet.New()
+	t.Cleanup(func() { net.Close() })
+	return startTestOperator(t, net, 0, clk, newTestSigners(t, signers), wrap)
+}
+
+func newTestSigners(t *testing.T, n int) []*ecdsa.PrivateKey {
+	t.Helper()
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		_, key, err := newTestKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// startTestOperator starts the i-th operator of net, trusting signers.
+func startTestOperator(t *testing.T, net mocknet.Mocknet, i int, clk clock.Clock, signers []*ecdsa.PrivateKey, wrap func(store.Database) store.Database) *testOperator {
+	t.Helper()
+	op := &testOperator{signers: signers}
+	var trusted []string
+	for _, key := range signers {
+		trusted = append(trusted, cryptoeth.PubkeyToAddress(key.PublicKey).Hex())
+	}
+
+	priv, _, err := newTestKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := net.AddPeer(priv, testAddr(i))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var db store.Database
+	db, err = store.NewLevelDBDatabase(filepath.Join(t.TempDir(), "leveldb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if wrap != nil {
+		db = wrap(db)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	op.OperatorNode, err = NewOperatorNodeWithHost(ctx, cancel, h, priv, db, testTopic, trusted, GossipConfig{}, IntakeConfig{}, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(op.latency.Close)
+	return op
+}
+
+// request adds a sign request for price to the pending set.
+func (op *testOperator) request(t *testing.T, price string) *SignRequest {
+	t.Helper()
+	req := testSignRequest(t, price, op.clock.Now().Unix())
+	op.handleSignRequest(context.Background(), req)
+	return req
+}
+
+// testSignRequest builds a sign request of structure 1 for price.
+func testSignRequest(t *testing.T, price string, timestamp int64) *SignRequest {
+	t.Helper()
+	structure := DataStructure{ID: 1, Fields: []struct {
+		Name         string `json:"name"`
+		SolidityType string `json:"solidity_type"`
+	}{{Name: "ticker", SolidityType: "string"}, {Name: "price", SolidityType: "uint256"}}}
+	req, err := buildSignRequest("1", structure, map[string]interface{}{"ticker": "SBER", "price": price}, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// publisher returns a PubSubService publishing for op.
+func (op *testOperator) publisher() *PubSubService {
+	return &PubSubService{
+		topic:          op.topic,
+		db:             op.db,
+		state:          op.OperatorNode,
+		latency:        op.latency,
+		events:         op.events,
+		clock:          op.clock,
+		publishTimeout: 5 * time.Second,
+		maxRetries:     1,
+	}
+}
+
+// respond answers req as signer i.
+func (op *testOperator) respond(t *testing.T, req *SignRequest, i int) {
+	t.Helper()
+	op.handleSignResponse(context.Background(), op.signResponse(t, req, i))
+}
+
+// signResponse signs req as signer i.
+func (op *testOperator) signResponse(t *testing.T, req *SignRequest, i int) *SignResponse {
+	t.Helper()
+	hash, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := cryptoeth.Sign(accounts.TextHash(hash), op.signers[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SignResponse{
+		Type:            MsgTypeSignResponse,
+		Hash:            req.Hash,
+		Signature:       hexutil.Encode(signature),
+		PeerID:          cryptoeth.PubkeyToAddress(op.signers[i].PublicKey).Hex(),
+		Timestamp:       op.clock.Now().Unix(),
+		RequestID:       req.RequestID,
+		ProtocolVersion: ProtocolVersion,
+	}
+}
+
+func (op *testOperator) pendingSigners(hash string) (int, bool) {
+	op.pendingMux.RLock()
+	defer op.pendingMux.RUnlock()
+	p, ok := op.pending[hash]
+	if !ok {
+		return 0, false
+	}
+	return len(p.signers), true
+}
+
+// lockProbeDatabase records whether pendingMux is held while signatures
+// are written.
+type lockProbeDatabase struct {
+	store.Database
+	op     *OperatorNode
+	held   atomic.Bool
+	stored atomic.Int32
+}
+
+func (d *lockProbeDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	if d.op.pendingMux.TryLock() {
+		d.op.pendingMux.Unlock()
+	} else {
+		d.held.Store(true)
+	}
+	d.stored.Add(1)
+	return d.Database.StoreSignature(ctx, hash, signer, signature)
+}
+
+func TestSignatureStoredWithoutPendingLock(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	probe := &lockProbeDatabase{}
+	op := newTestOperator(t, mock, 3, func(db store.Database) store.Database {
+		probe.Database = db
+		return probe
+	})
+	probe.op = op.OperatorNode
+
+	req := op.request(t, "100")
+	op.respond(t, req, 0)
+	op.respond(t, req, 0)
+	op.respond(t, req, 1)
+
+	if probe.held.Load() {
+		t.Fatal("signature stored while pendingMux was held")
+	}
+	if n := probe.stored.Load(); n != 2 {
+		t.Fatalf("stored %d signatures, want 2 (the duplicate refused)", n)
+	}
+	if signers, ok := op.pendingSigners(req.Hash); !ok || signers != 2 {
+		t.Fatalf("pending signers: got %d (pending %v), want 2", signers, ok)
+	}
+	if signatures, _ := op.db.GetSignatures(context.Background(), req.Hash); len(signatures) != 2 {
+		t.Fatalf("stored signatures: got %d, want 2", len(signatures))
+	}
+}
+
+// advanceUntil moves mock on by step until cond holds, for work done by the
+// operator's own goroutines on their tickers. Stepping repeatedly also
+// covers tickers their goroutine had not created yet at the first step.
+func advanceUntil(t *testing.T, mock *clock.Mock, step time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		mock.Add(step)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (op *testOperator) rebroadcastAttempts(hash string) int {
+	op.pendingMux.RLock()
+	defer op.pendingMux.RUnlock()
+	if p, ok := op.pending[hash]; ok {
+		return p.attempts
+	}
+	return -1
+}
+
+func TestPendingRequestsExpireOnTheClock(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetMaxTimestampSkew(time.Hour)
+	op.SetPendingTimings(time.Minute, 0, 0)
+
+	req := op.request(t, "100")
+	mock.Add(time.Minute)
+	op.cleanupExpiredRequests()
+	if !op.isPending(req.Hash) {
+		t.Fatal("request expired at the end of its confirmation window")
+	}
+	mock.Add(time.Second)
+	op.cleanupExpiredRequests()
+	if op.isPending(req.Hash) {
+		t.Fatal("request still pending past its confirmation window")
+	}
+
+	op.SetPendingExpiryOverride(1, 2*time.Minute)
+	req = op.request(t, "101")
+	mock.Add(90 * time.Second)
+	op.cleanupExpiredRequests()
+	if !op.isPending(req.Hash) {
+		t.Fatal("request expired within its structure's own window")
+	}
+	mock.Add(31 * time.Second)
+	op.cleanupExpiredRequests()
+	if op.isPending(req.Hash) {
+		t.Fatal("request still pending past its structure's own window")
+	}
+}
+
+func TestRebroadcastBacksOff(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetMaxTimestampSkew(time.Hour)
+	op.SetPendingTimings(time.Hour, time.Second, time.Hour)
+
+	req := op.request(t, "100")
+	if due := op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick); len(due) != 1 {
+		t.Fatalf("new request: got %v due, want it rebroadcast", due)
+	}
+
+	delay := time.Second
+	for i := 1; i <= 10; i++ {
+		mock.Add(delay - time.Millisecond)
+		if due := op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick); len(due) != 0 {
+			t.Fatalf("attempt %d: due before its %v backoff elapsed", i, delay)
+		}
+		mock.Add(time.Millisecond)
+		if due := op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick); len(due) != 1 || due[0] != req.Hash {
+			t.Fatalf("attempt %d: got %v due after %v, want %s", i, due, delay, req.Hash)
+		}
+		delay = min(2*delay, rebroadcastMaxDelay)
+	}
+}
+
+func TestRebroadcastReadsThresholdsBeforePendingLock(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetMaxTimestampSkew(time.Hour)
+	op.SetPendingTimings(time.Hour, time.Second, time.Hour)
+	req := op.request(t, "100")
+
+	// With the signer set held, picking rebroadcasts waits for it without
+	// holding up the pending set.
+	op.trustedMux.Lock()
+	due := make(chan []string, 1)
+	go func() { due <- op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick) }()
+	time.Sleep(50 * time.Millisecond)
+	if !op.pendingMux.TryLock() {
+		op.trustedMux.Unlock()
+		t.Fatal("pendingMux held while waiting for trustedMux")
+	}
+	op.pendingMux.Unlock()
+	op.trustedMux.Unlock()
+
+	if hashes := <-due; len(hashes) != 1 || hashes[0] != req.Hash {
+		t.Fatalf("got %v due, want %s", hashes, req.Hash)
+	}
+}
+
+func TestRetryLoopRunsOnTheClock(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+
+	start := mock.Now()
+	req := op.request(t, "100")
+	advanceUntil(t, mock, time.Second, "the first rebroadcast", func() bool { return op.rebroadcastAttempts(req.Hash) > 0 })
+	advanceUntil(t, mock, defaultCleanupInterval, "the request to expire", func() bool { return !op.isPending(req.Hash) })
+	if pending := mock.Now().Sub(start); pending <= defaultPendingExpiry {
+		t.Fatalf("request expired after %v, within its %v window", pending, defaultPendingExpiry)
+	}
+}
@@ -0,0 +1,63 @@
+package operator
+
+import (
+	"log"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+const (
+	defaultMaxTimestampSkew = 5 * time.Minute
+	defaultNTPServer        = "pool.ntp.org"
+)
+
+// timestampWithinSkew reports whether a unix timestamp is within skew of now
+// in either direction.
+func timestampWithinSkew(timestamp int64, now time.Time, skew time.Duration) bool {
+	diff := now.Sub(time.Unix(timestamp, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= skew
+}
+
+// SetMaxTimestampSkew changes how far message timestamps may deviate from
+// local time before the message is rejected.
+func (o *OperatorNode) SetMaxTimestampSkew(skew time.Duration) {
+	o.acceptMux.Lock()
+	o.maxSkew = skew
+	o.acceptMux.Unlock()
+}
+
+func (o *OperatorNode) timestampSkew() time.Duration {
+	o.acceptMux.RLock()
+	defer o.acceptMux.RUnlock()
+
+	return o.maxSkew
+}
+
+func (o *OperatorNode) timestampAcceptable(timestamp int64) bool {
+	return timestampWithinSkew(timestamp, o.clock.Now(), o.timestampSkew())
+}
+
+// checkClockDrift compares the local clock with an NTP server and warns when
+// the offset eats into the allowed timestamp skew. Failures only warn, since
+// many deployments have no outbound NTP.
+func checkClockDrift(server string, skew time.Duration) {
+	resp, err := ntp.Query(server)
+	if err != nil {
+		log.Printf("⚠️ NTP drift check against %s failed: %v", server, err)
+		return
+	}
+
+	offset := resp.ClockOffset
+	if offset < 0 {
+		offset = -offset
+	}
+	if offset > skew/2 {
+		log.Printf("⚠️ Local clock is off by %s according to %s; timestamps may be rejected (max skew %s)", resp.ClockOffset, server, skew)
+		return
+	}
+	log.Printf("✅ Clock offset %s according to %s", resp.ClockOffset, server)
+}
@@ -0,0 +1,32 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+func TestPendingRequestsLeaveWithTheSkewWindow(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetMaxTimestampSkew(5 * time.Minute)
+	op.SetPendingTimings(time.Hour, time.Second, time.Second)
+
+	req := op.request(t, "100")
+
+	mock.Add(4 * time.Minute)
+	if due := op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick); len(due) != 1 || due[0] != req.Hash {
+		t.Fatalf("rebroadcasts within the skew window: got %v, want %s", due, req.Hash)
+	}
+
+	mock.Add(2 * time.Minute)
+	if due := op.dueRebroadcasts(mock.Now(), maxRebroadcastsPerTick); len(due) != 0 {
+		t.Fatalf("rebroadcasts past the skew window: got %v, want none", due)
+	}
+	op.cleanupExpiredRequests()
+	if op.isPending(req.Hash) {
+		t.Fatal("request past the skew window is still pending")
+	}
+}
@@ -0,0 +1,36 @@
+package operator
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescer runs one database read for a burst of identical API queries,
+// such as the clients that all ask for the latest price right after a new
+// confirmation. Callers get the same value and must not modify it.
+type coalescer struct {
+	group singleflight.Group
+}
+
+// do returns the result of fn for key, sharing it with concurrent callers of
+// the same key. The read is detached from the cancellation of the caller
+// that started it, so one client hanging up does not fail the others; each
+// caller still stops waiting when its own ctx ends.
+func (c *coalescer) do(ctx context.Context, endpoint, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Shared {
+			coalescedRequestsTotal.WithLabelValues(endpoint).Inc()
+		}
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// committee restricts which trusted signers may sign a data structure. An
+// empty allow list admits every trusted signer; deny removes signers from
+// whatever allow admits.
+type committee struct {
+	allow []string
+	deny  []string
+}
+
+// validateCommittee checks the signer lists of a structure.
+func (ds DataStructure) validateCommittee(name string) error {
+	for _, list := range [][]string{ds.Signers, ds.ExcludeSigners} {
+		for _, addr := range list {
+			if !common.IsHexAddress(addr) {
+				return fmt.Errorf("structure %s lists invalid signer address %q", name, addr)
+			}
+		}
+	}
+	return nil
+}
+
+// committeeOf returns the trusted addresses eligible to sign ds, for use
+// without a running operator.
+func (ds DataStructure) committeeOf(trusted []string) []string {
+	c := committee{allow: ds.Signers, deny: ds.ExcludeSigners}
+	var eligible []string
+	for _, addr := range trusted {
+		if c.admits(addr, nil) {
+			eligible = append(eligible, addr)
+		}
+	}
+	return eligible
+}
+
+// admits reports whether the signer holding seat is in the committee.
+// successors maps the lowercased old address of each completed key rotation
+// to its replacement, so committees keep naming signers by the address they
+// were configured with.
+func (c committee) admits(seat string, successors map[string]string) bool {
+	matches := func(list []string) bool {
+		for _, addr := range list {
+			if strings.EqualFold(resolveSuccessor(addr, successors), seat) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(c.allow) > 0 && !matches(c.allow) {
+		return false
+	}
+	return !matches(c.deny)
+}
+
+// resolveSuccessor follows completed rotations from addr to the address
+// currently holding its seat.
+func resolveSuccessor(addr string, successors map[string]string) string {
+	// A chain is at most as long as there are rotations.
+	for range successors {
+		next, ok := successors[strings.ToLower(addr)]
+		if !ok {
+			break
+		}
+		addr = next
+	}
+	return addr
+}
+
+// SetCommittee limits the signers counted for a data structure. Addresses
+// outside the trusted set are ignored.
+func (o *OperatorNode) SetCommittee(dataStructureID int, allow, deny []string) {
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	o.committees[dataStructureID] = committee{
+		allow: append([]string(nil), allow...),
+		deny:  append([]string(nil), deny...),
+	}
+}
+
+// eligibleSigners returns the seats of the trusted set that may sign a data
+// structure.
+func (o *OperatorNode) eligibleSigners(dataStructureID int) []string {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	c, ok := o.committees[dataStructureID]
+	if !ok {
+		return append([]string(nil), o.trustedAddrs...)
+	}
+	var eligible []string
+	for _, addr := range o.trustedAddrs {
+		if c.admits(addr, o.successors) {
+			eligible = append(eligible, addr)
+		}
+	}
+	return eligible
+}
+
+// isEligible reports whether the signer holding seat may sign a data
+// structure.
+func (o *OperatorNode) isEligible(dataStructureID int, seat string) bool {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	c, ok := o.committees[dataStructureID]
+	return !ok || c.admits(seat, o.successors)
+}
+
+// committeeSeats returns the eligible seats of every structure with a committee.
+func (o *OperatorNode) committeeSeats() map[int][]string {
+	o.trustedMux.RLock()
+	ids := make([]int, 0, len(o.committees))
+	for id := range o.committees {
+		ids = append(ids, id)
+	}
+	o.trustedMux.RUnlock()
+
+	seats := make(map[int][]string, len(ids))
+	for _, id := range ids {
+		seats[id] = o.eligibleSigners(id)
+	}
+	return seats
+}
@@ -0,0 +1,382 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// ComputedInput names the confirmed messages of one feed that a computed
+// feed reads: Field of the latest message of Ticker, or its average over
+// the latest Window messages for moving averages.
+type ComputedInput struct {
+	Ticker    string `json:"ticker"`
+	Structure string `json:"structure,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Window    int    `json:"window,omitempty"`
+}
+
+// ComputedFeedConfig describes a feed whose value is Expression evaluated
+// over its inputs, e.g. "sber / vtbr" or "brent - urals". Expressions use
+// the input names, numbers, + - * /, parentheses and min, max and abs.
+type ComputedFeedConfig struct {
+	Inputs     map[string]ComputedInput `json:"inputs"`
+	Expression string                   `json:"expression"`
+	// MaxAgeSeconds rejects inputs whose latest message is older than
+	// this. Zero accepts any confirmed message.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+
+	expr ast.Expr
+}
+
+// loadComputedFeeds reads computed feed definitions. A missing file means no
+// computed feeds.
+func loadComputedFeeds(path string) (map[string]ComputedFeedConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read computed feeds file: %w", err)
+	}
+
+	var feeds map[string]ComputedFeedConfig
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal computed feeds: %w", err)
+	}
+
+	for name, feed := range feeds {
+		if len(feed.Inputs) == 0 {
+			return nil, fmt.Errorf("computed feed %s has no inputs", name)
+		}
+		for input, cfg := range feed.Inputs {
+			if cfg.Ticker == "" {
+				return nil, fmt.Errorf("computed feed %s: input %s has no ticker", name, input)
+			}
+			if cfg.Structure == "" {
+				cfg.Structure = "stock_quote"
+			}
+			if cfg.Field == "" {
+				cfg.Field = "price"
+			}
+			if cfg.Window < 1 {
+				cfg.Window = 1
+			}
+			feed.Inputs[input] = cfg
+		}
+
+		expr, err := parser.ParseExpr(feed.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("computed feed %s: invalid expression: %w", name, err)
+		}
+		if err := checkExpr(expr, feed.Inputs); err != nil {
+			return nil, fmt.Errorf("computed feed %s: %w", name, err)
+		}
+		feed.expr = expr
+		feeds[name] = feed
+	}
+
+	return feeds, nil
+}
+
+// exprFuncs are the functions expressions may call.
+var exprFuncs = map[string]func(args []float64) (float64, error){
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("min needs arguments")
+		}
+		v := args[0]
+		for _, arg := range args[1:] {
+			v = math.Min(v, arg)
+		}
+		return v, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("max needs arguments")
+		}
+		v := args[0]
+		for _, arg := range args[1:] {
+			v = math.Max(v, arg)
+		}
+		return v, nil
+	},
+	"abs": func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("abs takes one argument")
+		}
+		return math.Abs(args[0]), nil
+	},
+}
+
+// checkExpr refuses expressions that could never be evaluated, so that a
+// typo fails at startup rather than on every round.
+func checkExpr(expr ast.Expr, inputs map[string]ComputedInput) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Ident:
+			if _, ok := inputs[n.Name]; !ok {
+				err = fmt.Errorf("unknown input %q", n.Name)
+			}
+		case *ast.CallExpr:
+			fn, ok := n.Fun.(*ast.Ident)
+			if !ok || exprFuncs[fn.Name] == nil {
+				err = fmt.Errorf("unknown function %s", types.ExprString(n.Fun))
+				return false
+			}
+			for _, arg := range n.Args {
+				if err = checkExpr(arg, inputs); err != nil {
+					return false
+				}
+			}
+			return false
+		case *ast.BinaryExpr:
+			switch n.Op {
+			case token.ADD, token.SUB, token.MUL, token.QUO:
+			default:
+				err = fmt.Errorf("unsupported operator %s", n.Op)
+			}
+		case *ast.UnaryExpr:
+			if n.Op != token.SUB && n.Op != token.ADD {
+				err = fmt.Errorf("unsupported operator %s", n.Op)
+			}
+		case *ast.BasicLit:
+			if n.Kind != token.INT && n.Kind != token.FLOAT {
+				err = fmt.Errorf("unsupported literal %s", n.Value)
+			}
+		case *ast.ParenExpr, nil:
+		default:
+			err = fmt.Errorf("unsupported expression %s", types.ExprString(n.(ast.Expr)))
+		}
+		return true
+	})
+	return err
+}
+
+// evalExpr evaluates a checked expression.
+func evalExpr(expr ast.Expr, vars map[string]float64) (float64, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.Ident:
+		v, ok := vars[e.Name]
+		if !ok {
+			return 0, fmt.Errorf("unknown input %q", e.Name)
+		}
+		return v, nil
+	case *ast.ParenExpr:
+		return evalExpr(e.X, vars)
+	case *ast.UnaryExpr:
+		v, err := evalExpr(e.X, vars)
+		if e.Op == token.SUB {
+			v = -v
+		}
+		return v, err
+	case *ast.BinaryExpr:
+		x, err := evalExpr(e.X, vars)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evalExpr(e.Y, vars)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero in %s", types.ExprString(e))
+			}
+			return x / y, nil
+		}
+	case *ast.CallExpr:
+		args := make([]float64, len(e.Args))
+		for i, arg := range e.Args {
+			v, err := evalExpr(arg, vars)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		if fn, ok := e.Fun.(*ast.Ident); ok && exprFuncs[fn.Name] != nil {
+			return exprFuncs[fn.Name](args)
+		}
+	}
+	return 0, fmt.Errorf("unsupported expression %s", types.ExprString(expr))
+}
+
+// computedInputs are the confirmed messages a computed value was evaluated
+// over, as "input=0xhash" references in input name order.
+type computedInputs struct {
+	value float64
+	refs  []string
+}
+
+// ComputedPriceSource evaluates a computed feed over confirmed messages only
+// and remembers which ones, so that the derived message can name them.
+type ComputedPriceSource struct {
+	Feed   string
+	Config ComputedFeedConfig
+	db     store.Database
+	// structureIDs are the numeric IDs of the structures the inputs read.
+	structureIDs map[string]int
+
+	mu   sync.Mutex
+	last computedInputs
+}
+
+func NewComputedPriceSource(name string, config ComputedFeedConfig, structures map[string]DataStructure, db store.Database) (*ComputedPriceSource, error) {
+	structureIDs := make(map[string]int)
+	for input, cfg := range config.Inputs {
+		id, err := structureNumericID(structures, cfg.Structure)
+		if err != nil {
+			return nil, fmt.Errorf("computed feed %s: input %s: %w", name, input, err)
+		}
+		structureIDs[cfg.Structure] = id
+	}
+	return &ComputedPriceSource{
+		Feed:         name,
+		Config:       config,
+		db:           db,
+		structureIDs: structureIDs,
+	}, nil
+}
+
+func (s *ComputedPriceSource) Name() string {
+	return "computed"
+}
+
+func (s *ComputedPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	names := make([]string, 0, len(s.Config.Inputs))
+	for name := range s.Config.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make(map[string]float64, len(names))
+	var refs []string
+	for _, name := range names {
+		input := s.Config.Inputs[name]
+		value, hashes, err := s.load(ctx, input)
+		if err != nil {
+			return 0, fmt.Errorf("input %s: %w", name, err)
+		}
+		vars[name] = value
+		for _, hash := range hashes {
+			refs = append(refs, name+"=0x"+hash)
+		}
+	}
+
+	value, err := evalExpr(s.Config.expr, vars)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("expression evaluated to %g", value)
+	}
+
+	s.mu.Lock()
+	s.last = computedInputs{value: value, refs: refs}
+	s.mu.Unlock()
+	return value, nil
+}
+
+// load averages the field of the input's latest confirmed messages and
+// returns their hashes.
+func (s *ComputedPriceSource) load(ctx context.Context, input ComputedInput) (float64, []string, error) {
+	dataStructureID := s.structureIDs[input.Structure]
+	messages, err := s.db.GetConfirmedMessages(ctx, dataStructureID, nil,
+		[]store.FieldFilter{{Field: "ticker", Value: input.Ticker}}, 1, input.Window)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to load %s: %w", input.Ticker, err)
+	}
+	if len(messages) < input.Window {
+		return 0, nil, fmt.Errorf("%d of %d confirmed messages for %s", len(messages), input.Window, input.Ticker)
+	}
+	if s.Config.MaxAgeSeconds > 0 {
+		age := time.Since(time.Unix(messages[0].Timestamp, 0))
+		if age > time.Duration(s.Config.MaxAgeSeconds)*time.Second {
+			return 0, nil, fmt.Errorf("%s is %s old", input.Ticker, age.Round(time.Second))
+		}
+	}
+
+	var total float64
+	hashes := make([]string, len(messages))
+	for i, msg := range messages {
+		value, err := messageValue(msg, input.Field)
+		if err != nil {
+			return 0, nil, err
+		}
+		total += value
+		hashes[i] = msg.Hash
+	}
+	return total / float64(len(messages)), hashes, nil
+}
+
+// inputs returns the references value was computed from. It fails when the
+// last fetch produced another value, which a fetch outliving its round can.
+func (s *ComputedPriceSource) inputs(value float64) (computedInputs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.last.refs) == 0 || s.last.value != value {
+		return computedInputs{}, fmt.Errorf("no computed inputs for value %g", value)
+	}
+	return s.last, nil
+}
+
+// ComputedFeedMessageBuilder publishes a computed value together with its
+// expression and the hashes of the messages it was evaluated over, so the
+// signed message can be audited against them.
+type ComputedFeedMessageBuilder struct {
+	Ticker      string
+	StructureID string
+	Structure   DataStructure
+	Metadata    collector.QuoteMetadata
+	Computed    *ComputedPriceSource
+}
+
+func (b *ComputedFeedMessageBuilder) BuildMessage(price collector.PriceBreakdown) (*SignRequest, error) {
+	if b.Computed == nil {
+		return nil, fmt.Errorf("%s is not a computed feed", b.Ticker)
+	}
+	inputs, err := b.Computed.inputs(price.Average)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+
+	fieldValues := map[string]interface{}{
+		"ticker":     b.Ticker,
+		"value":      FloatToWei(price.Average).String(),
+		"expression": b.Computed.Config.Expression,
+		"inputs":     strings.Join(inputs.refs, ","),
+		"timestamp":  timestamp,
+	}
+
+	return buildSignRequest(b.StructureID, b.Structure, withMetadata(fieldValues, b.Structure, b.Metadata), timestamp)
+}
@@ -0,0 +1,818 @@
+package operator
+
+import (
+	"bytes"
+	"encoding"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"gopkg.in/yaml.v3"
+
+	"bootstrap/pkg/archive"
+	"bootstrap/pkg/store"
+)
+
+// Config is the operator configuration. LoadConfig fills it from, in
+// increasing precedence, the defaults, a YAML file, environment variables
+// and command-line flags.
+//
+// Durations are written like "90s" in the file and as whole seconds in
+// environment variables and flags, as they always have been.
+type Config struct {
+	PrivateKey       string   `yaml:"private_key"`
+	Topic            string   `yaml:"topic"`
+	TrustedAddresses []string `yaml:"trusted_addresses"`
+	// SignerWeights gives trusted addresses a voting weight; unlisted ones
+	// weigh 1. Empty keeps one address, one vote.
+	SignerWeights      map[string]uint64 `yaml:"signer_weights"`
+	MaxTimestampSkew   time.Duration     `yaml:"max_timestamp_skew"`
+	KeyRotationOverlap time.Duration     `yaml:"key_rotation_overlap"`
+	// SignerLivenessWindow is how long a signer counts as live after its
+	// last heartbeat.
+	SignerLivenessWindow time.Duration `yaml:"signer_liveness_window"`
+	NTPServer            string        `yaml:"ntp_server"`
+
+	Network     NetworkConfig     `yaml:"network"`
+	DB          store.Config      `yaml:"db"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Heartbeat   HeartbeatConfig   `yaml:"heartbeat"`
+	Staking     StakingConfig     `yaml:"staking"`
+	Rewards     RewardsConfig     `yaml:"rewards"`
+	Alerts      AlertsConfig      `yaml:"alerts"`
+	Tenants     []Tenant          `yaml:"tenants"`
+	Gossip      GossipConfig      `yaml:"gossip"`
+	Intake      IntakeConfig      `yaml:"intake"`
+	Pending     PendingConfig     `yaml:"pending"`
+	Peers       PeersConfig       `yaml:"peers"`
+	Replication ReplicationConfig `yaml:"replication"`
+	Archive     archive.Config    `yaml:"archive"`
+	API         APIConfig         `yaml:"api"`
+	Collector   CollectorConfig   `yaml:"collector"`
+}
+
+type AuditConfig struct {
+	// Path enables the audit log when set.
+	Path            string        `yaml:"path"`
+	Sync            bool          `yaml:"sync"`
+	AnchorStructure string        `yaml:"anchor_structure"`
+	AnchorInterval  time.Duration `yaml:"anchor_interval"`
+}
+
+type PendingConfig struct {
+	// MaxRequests caps the pending map; zero means unlimited.
+	MaxRequests int `yaml:"max_requests"`
+	// Expiry is how long a request stays pending. Signers refuse requests
+	// outside max_timestamp_skew, so one expires sooner once its timestamp
+	// has left that window.
+	Expiry              time.Duration `yaml:"expiry"`
+	RebroadcastInterval time.Duration `yaml:"rebroadcast_interval"`
+	CleanupInterval     time.Duration `yaml:"cleanup_interval"`
+	// ConflictWindow is how close two requests' timestamps must be to count
+	// as the same round.
+	ConflictWindow time.Duration `yaml:"conflict_window"`
+}
+
+type PeersConfig struct {
+	// TrustedOperators are peer IDs whose sign requests are accepted.
+	TrustedOperators          []string `yaml:"trusted_operators"`
+	AcceptForeignSignRequests bool     `yaml:"accept_foreign_sign_requests"`
+	// Producers are peer IDs allowed to submit data over SubmitProtocolID.
+	Producers []string `yaml:"producers"`
+	// Originators are addresses of nodes whose signed sign requests are
+	// accepted from any peer.
+	Originators []string `yaml:"originators"`
+}
+
+type ReplicationConfig struct {
+	// Primary makes this operator a standby of the operator at this
+	// multiaddr, which must end in /p2p/<peer ID>.
+	Primary string `yaml:"primary"`
+	// Replicas are the peer IDs of standbys allowed to replicate from us.
+	// With replicas set we publish only while one of them grants us a lease.
+	Replicas []string `yaml:"replicas"`
+	// FailoverAfter is how long a standby waits for an unreachable primary
+	// before taking over; zero never takes over.
+	FailoverAfter time.Duration `yaml:"failover_after"`
+}
+
+type APIConfig struct {
+	Port         string      `yaml:"port"`
+	SubmitAPIKey string      `yaml:"submit_api_key"`
+	PublicLimit  RateLimit   `yaml:"rate_limit_public"`
+	PublicPerIP  RateLimit   `yaml:"rate_limit_public_per_ip"`
+	AdminLimit   RateLimit   `yaml:"rate_limit_admin"`
+	AdminPerIP   RateLimit   `yaml:"rate_limit_admin_per_ip"`
+	TrustProxy   bool        `yaml:"rate_limit_trust_proxy"`
+	Usage        UsageConfig `yaml:"usage"`
+	// HTTP2 serves cleartext HTTP/2 (h2c) next to HTTP/1.1.
+	HTTP2 bool `yaml:"http2"`
+	// MaxConnections caps concurrently open connections, so that a client
+	// pool cannot exhaust the file descriptors of a small VM.
+	MaxConnections int `yaml:"max_connections"`
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+	// MaxStreams caps concurrent HTTP/2 requests on one connection.
+	MaxStreams        int           `yaml:"max_streams_per_connection"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	// WriteTimeout must leave buffered handlers their request timeout to
+	// answer in.
+	WriteTimeout       time.Duration `yaml:"write_timeout"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout"`
+	StreamWriteTimeout time.Duration `yaml:"stream_write_timeout"`
+	// Admins are the addresses that may sign in to admin endpoints with
+	// Sign-In with Ethereum. Without them or an admin API key, admin
+	// endpoints are disabled.
+	Admins []string `yaml:"admin_addresses"`
+	// Consumers are the addresses that may sign in with the consumer role.
+	Consumers []string `yaml:"consumer_addresses"`
+	// AdminDomain is the domain SIWE messages must name, e.g.
+	// "operator.example.com". It is required with admin or consumer
+	// addresses.
+	AdminDomain     string        `yaml:"admin_domain"`
+	AdminSessionTTL time.Duration `yaml:"admin_session_ttl"`
+	// RouteRoles raise the role a route requires above its default, keyed
+	// by route pattern, e.g. "/data/{id}/export": consumer. Public routes
+	// default to public-read and admin routes to admin.
+	RouteRoles map[string]string `yaml:"route_roles"`
+	// TrustedProxies are the reverse proxies, as IPs or CIDRs, whose
+	// X-Forwarded-For hops are believed when resolving client IPs.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// AdminAllow and AdminDeny are IPs or CIDRs admin routes accept or
+	// refuse. Deny wins; without allow rules every other IP is accepted.
+	AdminAllow []string `yaml:"admin_ip_allow"`
+	AdminDeny  []string `yaml:"admin_ip_deny"`
+}
+
+// Roles returns the configured route roles.
+func (c APIConfig) Roles() map[string]role {
+	roles := make(map[string]role, len(c.RouteRoles))
+	for pattern, name := range c.RouteRoles {
+		roles[pattern], _ = parseRole(name, rolePublicRead)
+	}
+	return roles
+}
+
+// ServerLimits returns the connection limits of the API server.
+func (c APIConfig) ServerLimits() ServerLimits {
+	return ServerLimits{
+		HTTP2:                c.HTTP2,
+		MaxConnections:       c.MaxConnections,
+		MaxHeaderBytes:       c.MaxHeaderBytes,
+		MaxConcurrentStreams: c.MaxStreams,
+		ReadHeaderTimeout:    c.ReadHeaderTimeout,
+		ReadTimeout:          c.ReadTimeout,
+		WriteTimeout:         c.WriteTimeout,
+		IdleTimeout:          c.IdleTimeout,
+		StreamWriteTimeout:   c.StreamWriteTimeout,
+	}
+}
+
+func (c APIConfig) validate() error {
+	if c.MaxConnections < 0 || c.MaxHeaderBytes < 0 || c.MaxStreams < 0 {
+		return fmt.Errorf("API connection limits must not be negative")
+	}
+	if c.ReadHeaderTimeout < 0 || c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.StreamWriteTimeout < 0 {
+		return fmt.Errorf("API timeouts must not be negative")
+	}
+	for _, addrs := range [][]string{c.Admins, c.Consumers} {
+		for _, addr := range addrs {
+			if !common.IsHexAddress(addr) {
+				return fmt.Errorf("invalid SIWE address %q", addr)
+			}
+		}
+	}
+	if (len(c.Admins) > 0 || len(c.Consumers) > 0) && c.AdminDomain == "" {
+		return fmt.Errorf("admin_domain is required with admin or consumer addresses")
+	}
+	if _, err := NewTrustedProxies(c.TrustProxy, c.TrustedProxies); err != nil {
+		return err
+	}
+	if _, err := NewIPFilter(rateLimitGroupAdmin, c.AdminAllow, c.AdminDeny, nil); err != nil {
+		return fmt.Errorf("admin IP rules: %w", err)
+	}
+	for pattern, name := range c.RouteRoles {
+		if !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("route_roles: invalid route pattern %q", pattern)
+		}
+		if _, err := parseRole(name, rolePublicRead); err != nil {
+			return fmt.Errorf("route_roles: %s: %w", pattern, err)
+		}
+	}
+	if c.AdminSessionTTL < 0 {
+		return fmt.Errorf("admin_session_ttl must not be negative")
+	}
+	if c.WriteTimeout > 0 && c.WriteTimeout <= requestTimeout {
+		return fmt.Errorf("write_timeout %s must exceed the request timeout of %s", c.WriteTimeout, requestTimeout)
+	}
+	return nil
+}
+
+// Proxies returns the reverse proxies client IPs are resolved through.
+func (c APIConfig) Proxies() *TrustedProxies {
+	proxies, _ := NewTrustedProxies(c.TrustProxy, c.TrustedProxies)
+	return proxies
+}
+
+// AdminIPFilter returns the IP rules of the admin route group, or nil.
+func (c APIConfig) AdminIPFilter() *IPFilter {
+	filter, _ := NewIPFilter(rateLimitGroupAdmin, c.AdminAllow, c.AdminDeny, c.Proxies())
+	return filter
+}
+
+// RateLimits returns the limits of the public and admin route groups.
+func (c APIConfig) RateLimits() (public, admin RateLimitConfig) {
+	proxies := c.Proxies()
+	public = RateLimitConfig{Global: c.PublicLimit, PerIP: c.PublicPerIP, Proxies: proxies}
+	admin = RateLimitConfig{Global: c.AdminLimit, PerIP: c.AdminPerIP, Proxies: proxies}
+	return public, admin
+}
+
+type CollectorConfig struct {
+	// Disabled runs no workers; the operator only aggregates signatures
+	// for data submitted to it.
+	Disabled           bool          `yaml:"disabled"`
+	Interval           time.Duration `yaml:"interval"`
+	Tickers            []string      `yaml:"tickers"`
+	Sources            []string      `yaml:"sources"`
+	EnableMockSources  bool          `yaml:"enable_mock_sources"`
+	SourceConfigPath   string        `yaml:"source_config_path"`
+	StalePriceMaxAge   time.Duration `yaml:"stale_price_max_age"`
+	WorkerMode         string        `yaml:"worker_mode"`
+	StreamWindow       time.Duration `yaml:"stream_window"`
+	DeviationBps       float64       `yaml:"deviation_bps"`
+	DataStructuresPath string        `yaml:"data_structures_path"`
+	JobsPath           string        `yaml:"jobs_path"`
+	BasketsPath        string        `yaml:"baskets_path"`
+	ConversionsPath    string        `yaml:"conversions_path"`
+	ComputedFeedsPath  string        `yaml:"computed_feeds_path"`
+	Schedule           string        `yaml:"schedule"`
+	ScheduleTZ         string        `yaml:"schedule_tz"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		MaxTimestampSkew:     defaultMaxTimestampSkew,
+		KeyRotationOverlap:   defaultKeyRotationOverlap,
+		SignerLivenessWindow: defaultLivenessWindow,
+		NTPServer:            defaultNTPServer,
+		DB:                   store.Config{Backend: "leveldb"},
+		Pending:              PendingConfig{MaxRequests: defaultMaxPending},
+		Replication:          ReplicationConfig{FailoverAfter: defaultFailoverAfter},
+		Heartbeat:            HeartbeatConfig{Structure: "heartbeat", Interval: defaultHeartbeatInterval},
+		Staking:              StakingConfig{EpochLength: defaultEpochLength, PollInterval: defaultStakingPollInterval, StakeUnit: defaultStakeUnit},
+		Rewards:              RewardsConfig{Period: defaultRewardPeriod},
+		API: APIConfig{
+			Port:               "8080",
+			HTTP2:              true,
+			MaxConnections:     defaultMaxConnections,
+			MaxHeaderBytes:     defaultMaxHeaderBytes,
+			MaxStreams:         defaultMaxStreams,
+			ReadHeaderTimeout:  defaultReadHeaderTimeout,
+			ReadTimeout:        defaultReadTimeout,
+			WriteTimeout:       defaultWriteTimeout,
+			IdleTimeout:        defaultIdleTimeout,
+			StreamWriteTimeout: defaultStreamWriteTimeout,
+		},
+		Collector: CollectorConfig{
+			Interval:           dataCollectionInterval * time.Second,
+			Tickers:            []string{"SBER"},
+			Sources:            []string{"moex"},
+			SourceConfigPath:   "config/sources.json",
+			WorkerMode:         "poll",
+			StreamWindow:       10 * time.Second,
+			DeviationBps:       50,
+			DataStructuresPath: "config/data_structures.json",
+			JobsPath:           "config/jobs.json",
+			BasketsPath:        "config/baskets.json",
+			ConversionsPath:    "config/conversions.json",
+			ComputedFeedsPath:  "config/computed_feeds.json",
+		},
+	}
+}
+
+// configVar binds one setting to its environment variable and flag. Both
+// take the same syntax.
+type configVar struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+}
+
+func (c *Config) vars() []configVar {
+	return []configVar{
+		{"PRIVATE_KEY", "private-key", "hex secp256k1 key; a fresh one is generated when empty", stringSetter(&c.PrivateKey)},
+		{"TOPIC", "topic", "pubsub topic", stringSetter(&c.Topic)},
+		{"TRUSTED_ADDRESSES", "trusted-addresses", "comma-separated signer addresses", listSetter(&c.TrustedAddresses)},
+		{"SIGNER_WEIGHTS", "signer-weights", "comma-separated address=weight voting weights of trusted signers", weightsSetter(&c.SignerWeights)},
+		{"MAX_TIMESTAMP_SKEW", "max-timestamp-skew", "seconds a sign request timestamp may be off", secondsSetter(&c.MaxTimestampSkew)},
+		{"KEY_ROTATION_OVERLAP", "key-rotation-overlap", "seconds both keys of a rotation are trusted", secondsSetter(&c.KeyRotationOverlap)},
+		{"SIGNER_LIVENESS_WINDOW", "signer-liveness-window", "seconds a signer counts as live after its last heartbeat", secondsSetter(&c.SignerLivenessWindow)},
+		{"NTP_SERVER", "ntp-server", "NTP server for the clock drift check", stringSetter(&c.NTPServer)},
+
+		{"LISTEN_ADDRESSES", "listen-addresses", "comma-separated libp2p listen multiaddrs", listSetter(&c.Network.ListenAddresses)},
+		{"ANNOUNCE_ADDRESSES", "announce-addresses", "comma-separated multiaddrs advertised to peers instead of the listen addresses", listSetter(&c.Network.AnnounceAddresses)},
+
+		{"DB_BACKEND", "db-backend", "leveldb or badger", stringSetter(&c.DB.Backend)},
+		{"DB_PATH", "db-path", "database directory (default data/<backend>)", stringSetter(&c.DB.Path)},
+		{"BADGER_VALUE_THRESHOLD", "badger-value-threshold", "values larger than this many bytes go to the badger value log", int64Setter(&c.DB.Badger.ValueThreshold)},
+		{"BADGER_GC_INTERVAL", "badger-gc-interval", "seconds between badger value log GC runs", secondsSetter(&c.DB.Badger.GCInterval)},
+		{"SIGNATURE_BATCH_DELAY", "signature-batch-delay", "milliseconds a signature waits to be written together with others", millisecondsSetter(&c.DB.SignatureBatch.MaxDelay)},
+		{"SIGNATURE_BATCH_SIZE", "signature-batch-size", "most signatures written in one batch; 1 writes each on its own", intSetter(&c.DB.SignatureBatch.MaxSize)},
+		{"SIGNATURE_CACHE_SIZE", "signature-cache-size", "hashes whose signatures are kept in memory", intSetter(&c.DB.SignatureCacheSize)},
+		{"RESTORE_SNAPSHOT", "restore-snapshot", "snapshot to load into an empty database at startup", stringSetter(&c.DB.RestoreSnapshot)},
+
+		{"AUDIT_LOG_PATH", "audit-log-path", "audit log file; empty disables the audit log", stringSetter(&c.Audit.Path)},
+		{"AUDIT_LOG_SYNC", "audit-log-sync", "fsync every audit entry", boolSetter(&c.Audit.Sync)},
+		{"AUDIT_ANCHOR_STRUCTURE", "audit-anchor-structure", "structure the audit log head is published as", stringSetter(&c.Audit.AnchorStructure)},
+		{"AUDIT_ANCHOR_INTERVAL", "audit-anchor-interval", "seconds between audit anchors", secondsSetter(&c.Audit.AnchorInterval)},
+
+		{"HEARTBEAT_STRUCTURE", "heartbeat-structure", "structure heartbeats are published as", stringSetter(&c.Heartbeat.Structure)},
+		{"HEARTBEAT_INTERVAL", "heartbeat-interval", "seconds per heartbeat window; 0 disables heartbeats", secondsSetter(&c.Heartbeat.Interval)},
+
+		{"STAKING_RPC_URL", "staking-rpc-url", "Ethereum JSON-RPC endpoint; derives the trusted set from the staking contract", stringSetter(&c.Staking.RPCURL)},
+		{"STAKING_CONTRACT", "staking-contract", "staking contract address", stringSetter(&c.Staking.Contract)},
+		{"STAKING_EPOCH_LENGTH", "staking-epoch-length", "seconds per staking epoch; stake changes apply at epoch boundaries", secondsSetter(&c.Staking.EpochLength)},
+		{"STAKING_POLL_INTERVAL", "staking-poll-interval", "seconds between reads of the staking contract", secondsSetter(&c.Staking.PollInterval)},
+		{"STAKING_STAKE_UNIT", "staking-stake-unit", "stake in the token's smallest unit that weighs 1", stringSetter(&c.Staking.StakeUnit)},
+
+		{"REWARDS_POOL", "rewards-pool", "reward token amount shared between signers per period", stringSetter(&c.Rewards.Pool)},
+		{"REWARDS_PERIOD", "rewards-period", "seconds per reward period; staking epochs are used with staking", secondsSetter(&c.Rewards.Period)},
+
+		{"ALERT_WEBHOOK_URLS", "alert-webhook-urls", "comma-separated URLs quorum alerts are POSTed to", listSetter(&c.Alerts.WebhookURLs)},
+		{"PAUSE_WHEN_QUORUM_AT_RISK", "pause-when-quorum-at-risk", "publish no new sign requests while too few signers are live", boolSetter(&c.Alerts.PauseWhenAtRisk)},
+
+		{"GOSSIP_MESSAGE_ID", "gossip-message-id", "gossipsub message ID scheme", stringSetter(&c.Gossip.MessageID)},
+		{"GOSSIP_SEEN_TTL", "gossip-seen-ttl", "seconds gossipsub remembers seen messages", secondsSetter(&c.Gossip.SeenTTL)},
+		{"INTAKE_POLICY", "intake-policy", "what to do when the intake queue is full", stringSetter(&c.Intake.Policy)},
+		{"INTAKE_QUEUE_SIZE", "intake-queue-size", "sign response intake queue size", intSetter(&c.Intake.Size)},
+
+		{"MAX_PENDING_REQUESTS", "max-pending-requests", "pending request cap; 0 means unlimited", intSetter(&c.Pending.MaxRequests)},
+		{"PENDING_EXPIRY", "pending-expiry", "seconds before an unconfirmed request expires", secondsSetter(&c.Pending.Expiry)},
+		{"REBROADCAST_INTERVAL", "rebroadcast-interval", "seconds between rebroadcasts of pending requests", secondsSetter(&c.Pending.RebroadcastInterval)},
+		{"PENDING_CLEANUP_INTERVAL", "pending-cleanup-interval", "seconds between pending cleanups", secondsSetter(&c.Pending.CleanupInterval)},
+		{"CONFLICT_WINDOW", "conflict-window", "seconds within which two hashes for one structure and ticker conflict", secondsSetter(&c.Pending.ConflictWindow)},
+
+		{"TRUSTED_OPERATOR_PEERS", "trusted-operator-peers", "comma-separated peer IDs of other operators", listSetter(&c.Peers.TrustedOperators)},
+		{"ACCEPT_FOREIGN_SIGN_REQUESTS", "accept-foreign-sign-requests", "accept sign requests from any peer", boolSetter(&c.Peers.AcceptForeignSignRequests)},
+		{"ORIGINATOR_ADDRESSES", "originator-addresses", "comma-separated addresses of nodes allowed to originate sign requests", listSetter(&c.Peers.Originators)},
+		{"PRODUCER_PEERS", "producer-peers", "comma-separated peer IDs allowed to submit data over the submit stream", listSetter(&c.Peers.Producers)},
+
+		{"REPLICATION_PRIMARY", "replication-primary", "multiaddr of the primary operator to run as a standby of", stringSetter(&c.Replication.Primary)},
+		{"REPLICATION_REPLICAS", "replication-replicas", "comma-separated peer IDs of standbys allowed to replicate from this operator", listSetter(&c.Replication.Replicas)},
+		{"REPLICATION_FAILOVER_AFTER", "replication-failover-after", "seconds a standby waits for an unreachable primary before taking over; 0 never", secondsSetter(&c.Replication.FailoverAfter)},
+
+		{"ARCHIVE_BUCKET", "archive-bucket", "object storage bucket pruned messages are archived to; empty disables archiving", stringSetter(&c.Archive.Bucket)},
+		{"ARCHIVE_ENDPOINT", "archive-endpoint", "S3-compatible endpoint URL (default AWS S3 in the archive region)", stringSetter(&c.Archive.Endpoint)},
+		{"ARCHIVE_REGION", "archive-region", "archive bucket region", stringSetter(&c.Archive.Region)},
+		{"ARCHIVE_PREFIX", "archive-prefix", "key prefix of archive objects", stringSetter(&c.Archive.Prefix)},
+		{"ARCHIVE_ACCESS_KEY", "archive-access-key", "archive access key ID", stringSetter(&c.Archive.AccessKey)},
+		{"ARCHIVE_SECRET_KEY", "archive-secret-key", "archive secret access key", stringSetter(&c.Archive.SecretKey)},
+		{"ARCHIVE_CHUNK_SIZE", "archive-chunk-size", "most messages in one archive object", intSetter(&c.Archive.ChunkSize)},
+
+		{"RPC_PORT", "rpc-port", "HTTP API port", stringSetter(&c.API.Port)},
+		{"SUBMIT_API_KEY", "submit-api-key", "API key enabling manual submission", stringSetter(&c.API.SubmitAPIKey)},
+		{"RATE_LIMIT_PUBLIC", "rate-limit-public", "public routes limit as rate[:burst]", textSetter(&c.API.PublicLimit)},
+		{"RATE_LIMIT_PUBLIC_PER_IP", "rate-limit-public-per-ip", "public routes limit per client as rate[:burst]", textSetter(&c.API.PublicPerIP)},
+		{"RATE_LIMIT_ADMIN", "rate-limit-admin", "admin routes limit as rate[:burst]", textSetter(&c.API.AdminLimit)},
+		{"RATE_LIMIT_ADMIN_PER_IP", "rate-limit-admin-per-ip", "admin routes limit per client as rate[:burst]", textSetter(&c.API.AdminPerIP)},
+		{"HTTP2", "http2", "serve cleartext HTTP/2 next to HTTP/1.1", boolSetter(&c.API.HTTP2)},
+		{"MAX_CONNECTIONS", "max-connections", "open API connection cap; 0 means unlimited", intSetter(&c.API.MaxConnections)},
+		{"MAX_HEADER_BYTES", "max-header-bytes", "largest API request header", intSetter(&c.API.MaxHeaderBytes)},
+		{"MAX_STREAMS_PER_CONNECTION", "max-streams-per-connection", "concurrent HTTP/2 requests per connection", intSetter(&c.API.MaxStreams)},
+		{"READ_HEADER_TIMEOUT", "read-header-timeout", "seconds a client may take to send request headers", secondsSetter(&c.API.ReadHeaderTimeout)},
+		{"READ_TIMEOUT", "read-timeout", "seconds a client may take to send a request", secondsSetter(&c.API.ReadTimeout)},
+		{"WRITE_TIMEOUT", "write-timeout", "seconds a buffered response may take to write", secondsSetter(&c.API.WriteTimeout)},
+		{"IDLE_TIMEOUT", "idle-timeout", "seconds an idle keep-alive connection stays open", secondsSetter(&c.API.IdleTimeout)},
+		{"STREAM_WRITE_TIMEOUT", "stream-write-timeout", "seconds a streaming client may stop reading before it is disconnected", secondsSetter(&c.API.StreamWriteTimeout)},
+		{"ADMIN_ADDRESSES", "admin-addresses", "comma-separated addresses allowed to sign in to admin endpoints", listSetter(&c.API.Admins)},
+		{"CONSUMER_ADDRESSES", "consumer-addresses", "comma-separated addresses allowed to sign in as consumers", listSetter(&c.API.Consumers)},
+		{"ROUTE_ROLES", "route-roles", "roles routes require, as pattern=role,...", routeRolesSetter(&c.API.RouteRoles)},
+		{"ADMIN_DOMAIN", "admin-domain", "domain admin sign-in messages must name", stringSetter(&c.API.AdminDomain)},
+		{"ADMIN_SESSION_TTL", "admin-session-ttl", "seconds an admin session lasts", secondsSetter(&c.API.AdminSessionTTL)},
+		{"RATE_LIMIT_TRUST_PROXY", "rate-limit-trust-proxy", "take client IPs from X-Forwarded-For of any peer, as behind one proxy", boolSetter(&c.API.TrustProxy)},
+		{"TRUSTED_PROXIES", "trusted-proxies", "comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For is believed", listSetter(&c.API.TrustedProxies)},
+		{"ADMIN_IP_ALLOW", "admin-ip-allow", "comma-separated IPs or CIDRs admin routes accept", listSetter(&c.API.AdminAllow)},
+		{"ADMIN_IP_DENY", "admin-ip-deny", "comma-separated IPs or CIDRs admin routes refuse", listSetter(&c.API.AdminDeny)},
+		{"API_KEYS", "api-keys", "API keys accounted separately, as name:key[:tier[:tenant[:role]]],...", apiKeysSetter(&c.API.Usage.Keys)},
+		{"API_REQUIRE_KEY", "api-require-key", "refuse public requests without an API key", boolSetter(&c.API.Usage.RequireKey)},
+
+		{"COLLECTOR_DISABLED", "collector-disabled", "run no workers and only sign submitted data", boolSetter(&c.Collector.Disabled)},
+		{"DATA_COLLECTION_INTERVAL", "data-collection-interval", "seconds between collections of unscheduled jobs", secondsSetter(&c.Collector.Interval)},
+		{"TICKERS", "tickers", "comma-separated tickers to collect", listSetter(&c.Collector.Tickers)},
+		{"PRICE_SOURCES", "price-sources", "comma-separated price sources", listSetter(&c.Collector.Sources)},
+		{"ENABLE_MOCK_SOURCES", "enable-mock-sources", "allow the mock price source", boolSetter(&c.Collector.EnableMockSources)},
+		{"SOURCE_CONFIG_PATH", "source-config-path", "price source config file", stringSetter(&c.Collector.SourceConfigPath)},
+		{"STALE_PRICE_MAX_AGE", "stale-price-max-age", "seconds after which a source price is stale", secondsSetter(&c.Collector.StalePriceMaxAge)},
+		{"WORKER_MODE", "worker-mode", "poll or stream", stringSetter(&c.Collector.WorkerMode)},
+		{"STREAM_WINDOW", "stream-window", "seconds of streamed prices aggregated per round", secondsSetter(&c.Collector.StreamWindow)},
+		{"DEVIATION_BPS", "deviation-bps", "price move in basis points that triggers a streamed round", floatSetter(&c.Collector.DeviationBps)},
+		{"DATA_STRUCTURES_PATH", "data-structures-path", "data structures file", stringSetter(&c.Collector.DataStructuresPath)},
+		{"JOBS_PATH", "jobs-path", "job config file", stringSetter(&c.Collector.JobsPath)},
+		{"BASKETS_PATH", "baskets-path", "baskets file", stringSetter(&c.Collector.BasketsPath)},
+		{"CONVERSIONS_PATH", "conversions-path", "currency conversions file", stringSetter(&c.Collector.ConversionsPath)},
+		{"COMPUTED_FEEDS_PATH", "computed-feeds-path", "computed feeds file", stringSetter(&c.Collector.ComputedFeedsPath)},
+		{"SCHEDULE", "schedule", "default cron schedule of jobs", stringSetter(&c.Collector.Schedule)},
+		{"SCHEDULE_TZ", "schedule-tz", "time zone of the default schedule", stringSetter(&c.Collector.ScheduleTZ)},
+	}
+}
+
+// LoadConfig reads the configuration. The file is named by the -config flag
+// or CONFIG_PATH and is optional.
+func LoadConfig(args []string) (Config, error) {
+	cfg := defaultConfig()
+	vars := cfg.vars()
+
+	fs := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_PATH"), "YAML config file")
+	type flagValue struct {
+		v     configVar
+		value string
+	}
+	var flagValues []flagValue
+	for _, v := range vars {
+		v := v
+		fs.Func(v.flag, v.usage, func(value string) error {
+			flagValues = append(flagValues, flagValue{v, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return cfg, err
+		}
+	}
+	for _, v := range vars {
+		if value := os.Getenv(v.env); value != "" {
+			if err := v.set(value); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", v.env, err)
+			}
+		}
+	}
+	for _, f := range flagValues {
+		if err := f.v.set(f.value); err != nil {
+			return cfg, fmt.Errorf("invalid -%s: %w", f.v.flag, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// AllTrustedAddresses returns the operator's trusted addresses together with
+// those of its tenants, each once.
+func (c Config) AllTrustedAddresses() []string {
+	all := append([]string(nil), c.TrustedAddresses...)
+	for _, tenant := range c.Tenants {
+		for _, addr := range tenant.TrustedAddresses {
+			if !containsAddress(all, addr) {
+				all = append(all, addr)
+			}
+		}
+	}
+	return all
+}
+
+// Validate reports the first setting that would stop the operator from
+// starting.
+func (c Config) Validate() error {
+	if c.Topic == "" {
+		return fmt.Errorf("topic is not set")
+	}
+	if len(c.TrustedAddresses) == 0 && c.Staking.RPCURL == "" {
+		return fmt.Errorf("no trusted addresses set")
+	}
+	for _, addr := range c.TrustedAddresses {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid Ethereum address: %s", addr)
+		}
+	}
+	for addr, weight := range c.SignerWeights {
+		if !containsAddress(c.AllTrustedAddresses(), addr) {
+			return fmt.Errorf("signer weight set for untrusted address %s", addr)
+		}
+		if weight == 0 {
+			return fmt.Errorf("signer weight of %s must be positive", addr)
+		}
+	}
+	if err := c.Staking.validate(); err != nil {
+		return err
+	}
+	if c.Staking.RPCURL != "" && len(c.SignerWeights) > 0 {
+		return fmt.Errorf("signer weights come from the staking contract; unset signer_weights")
+	}
+	if err := c.Rewards.validate(); err != nil {
+		return err
+	}
+	if err := c.API.validate(); err != nil {
+		return err
+	}
+	if err := c.API.Usage.validate(); err != nil {
+		return err
+	}
+	tenants := make(map[string]bool, len(c.Tenants))
+	for _, tenant := range c.Tenants {
+		if err := tenant.validate(); err != nil {
+			return err
+		}
+		if tenants[tenant.Name] {
+			return fmt.Errorf("tenant %s is configured twice", tenant.Name)
+		}
+		tenants[tenant.Name] = true
+	}
+	if len(c.Tenants) > 0 && c.Staking.RPCURL != "" {
+		return fmt.Errorf("tenants bring their own trusted sets; they cannot be combined with staking")
+	}
+	for _, key := range c.API.Usage.Keys {
+		if key.Tenant != "" && !tenants[key.Tenant] {
+			return fmt.Errorf("API key %s belongs to unknown tenant %q", key.Name, key.Tenant)
+		}
+	}
+	if _, err := c.TrustedOperatorPeers(); err != nil {
+		return err
+	}
+	if _, err := c.ProducerPeers(); err != nil {
+		return err
+	}
+	for _, addr := range c.Peers.Originators {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid originator address %q", addr)
+		}
+	}
+	if c.Collector.Disabled && c.API.SubmitAPIKey == "" && len(c.Peers.Producers) == 0 {
+		return fmt.Errorf("data collection is disabled but neither a submit API key nor producer peers are set")
+	}
+	if _, err := c.ReplicaPeers(); err != nil {
+		return err
+	}
+	if c.Replication.Primary != "" {
+		addr, err := ma.NewMultiaddr(c.Replication.Primary)
+		if err != nil {
+			return fmt.Errorf("invalid replication primary: %w", err)
+		}
+		if _, err := peer.AddrInfoFromP2pAddr(addr); err != nil {
+			return fmt.Errorf("invalid replication primary: %w", err)
+		}
+	}
+	if _, err := c.Network.Options(); err != nil {
+		return err
+	}
+	switch c.DB.Backend {
+	case "leveldb", "badger":
+	default:
+		return fmt.Errorf("unknown db backend %q, expected leveldb or badger", c.DB.Backend)
+	}
+	if _, err := c.Gossip.Options(); err != nil {
+		return err
+	}
+	if _, err := c.Intake.withDefaults(); err != nil {
+		return err
+	}
+	switch c.Collector.WorkerMode {
+	case "", "poll", "stream":
+	default:
+		return fmt.Errorf("unknown worker mode %q, expected poll or stream", c.Collector.WorkerMode)
+	}
+	if c.Collector.Interval <= 0 {
+		return fmt.Errorf("data collection interval must be positive")
+	}
+	if c.Archive.Enabled() {
+		if _, err := archive.Open(c.Archive); err != nil {
+			return err
+		}
+	}
+	if c.Audit.AnchorStructure != "" && c.Audit.Path == "" {
+		return fmt.Errorf("audit anchoring needs an audit log path")
+	}
+	if c.Heartbeat.Interval < 0 {
+		return fmt.Errorf("heartbeat interval must not be negative")
+	}
+	if c.Heartbeat.Interval > 0 && c.Heartbeat.Interval%time.Second != 0 {
+		return fmt.Errorf("heartbeat interval must be whole seconds")
+	}
+	return nil
+}
+
+// ReplicaPeers decodes the configured standby peer IDs.
+func (c Config) ReplicaPeers() ([]peer.ID, error) {
+	var ids []peer.ID
+	for _, s := range c.Replication.Replicas {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replica peer ID %q: %w", s, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ProducerPeers decodes the configured producer peer IDs.
+func (c Config) ProducerPeers() ([]peer.ID, error) {
+	var ids []peer.ID
+	for _, s := range c.Peers.Producers {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid producer peer ID %q: %w", s, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// TrustedOperatorPeers decodes the configured operator peer IDs.
+func (c Config) TrustedOperatorPeers() ([]peer.ID, error) {
+	var ids []peer.ID
+	for _, s := range c.Peers.TrustedOperators {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operator peer ID %q: %w", s, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func stringSetter(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func listSetter(p *[]string) func(string) error {
+	return func(s string) error {
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+		return nil
+	}
+}
+
+// weightsSetter parses "address=weight,address=weight".
+func weightsSetter(p *map[string]uint64) func(string) error {
+	return func(s string) error {
+		weights := make(map[string]uint64)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			addr, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected address=weight, got %q", item)
+			}
+			weight, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return err
+			}
+			weights[strings.TrimSpace(addr)] = weight
+		}
+		*p = weights
+		return nil
+	}
+}
+
+// routeRolesSetter parses "pattern=role,pattern=role".
+func routeRolesSetter(p *map[string]string) func(string) error {
+	return func(s string) error {
+		roles := make(map[string]string)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			pattern, name, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected pattern=role, got %q", item)
+			}
+			roles[strings.TrimSpace(pattern)] = strings.TrimSpace(name)
+		}
+		*p = roles
+		return nil
+	}
+}
+
+// apiKeysSetter parses "name:key[:tier[:tenant[:role]]],...".
+func apiKeysSetter(p *[]APIKey) func(string) error {
+	return func(s string) error {
+		var keys []APIKey
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			parts := strings.Split(item, ":")
+			if len(parts) < 2 || len(parts) > 5 {
+				return fmt.Errorf("expected name:key[:tier[:tenant[:role]]], got %q", parts[0]+":...")
+			}
+			key := APIKey{Name: parts[0], Key: parts[1]}
+			if len(parts) > 2 {
+				key.Tier = parts[2]
+			}
+			if len(parts) > 3 {
+				key.Tenant = parts[3]
+			}
+			if len(parts) > 4 {
+				key.Role = parts[4]
+			}
+			keys = append(keys, key)
+		}
+		*p = keys
+		return nil
+	}
+}
+
+func intSetter(p *int) func(string) error {
+	return func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func int64Setter(p *int64) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func floatSetter(p *float64) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func boolSetter(p *bool) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func secondsSetter(p *time.Duration) func(string) error {
+	return func(s string) error {
+		seconds, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = time.Duration(seconds) * time.Second
+		return nil
+	}
+}
+
+func millisecondsSetter(p *time.Duration) func(string) error {
+	return func(s string) error {
+		ms, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = time.Duration(ms) * time.Millisecond
+		return nil
+	}
+}
+
+func textSetter(u encoding.TextUnmarshaler) func(string) error {
+	return func(s string) error {
+		return u.UnmarshalText([]byte(s))
+	}
+}
@@ -0,0 +1,91 @@
+package operator
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"bootstrap/pkg/store"
+)
+
+// confirmationFor snapshots the signer set and thresholds a message of the
+// data structure is being confirmed under.
+func (o *OperatorNode) confirmationFor(dataStructureID, threshold int, weightThreshold uint64) *store.Confirmation {
+	eligible := o.eligibleSigners(dataStructureID)
+	sort.Slice(eligible, func(i, j int) bool { return strings.ToLower(eligible[i]) < strings.ToLower(eligible[j]) })
+
+	c := &store.Confirmation{
+		Signers:     eligible,
+		Threshold:   threshold,
+		Epoch:       o.currentEpoch(),
+		ConfirmedAt: o.clock.Now().Unix(),
+	}
+
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	if len(o.weights) > 0 {
+		c.WeightThreshold = weightThreshold
+		c.Weights = make(map[string]uint64, len(eligible))
+		for _, seat := range eligible {
+			c.Weights[seat] = o.weights.of(seat)
+		}
+	}
+	for newKey, rotation := range o.rotations {
+		if containsAddress(eligible, rotation.OldAddress) {
+			if c.Aliases == nil {
+				c.Aliases = make(map[string]string)
+			}
+			c.Aliases[common.HexToAddress(newKey).Hex()] = rotation.OldAddress
+		}
+	}
+	return c
+}
+
+// confirmationOf returns the recorded confirmation of hash, or nil.
+func (o *OperatorNode) confirmationOf(ctx context.Context, hash string) *store.Confirmation {
+	c, found, err := o.db.GetConfirmation(ctx, hash)
+	if err != nil {
+		log.Printf("Error reading confirmation of %s: %v", hash, err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return &c
+}
+
+// confirmedSigners is the signerView of a recorded confirmation.
+type confirmedSigners struct {
+	c       store.Confirmation
+	weights signerWeights
+}
+
+func newConfirmedSigners(c store.Confirmation) confirmedSigners {
+	weights := make(signerWeights, len(c.Weights))
+	for addr, weight := range c.Weights {
+		weights[strings.ToLower(addr)] = weight
+	}
+	return confirmedSigners{c: c, weights: weights}
+}
+
+func (s confirmedSigners) isTrusted(addr common.Address) bool {
+	return containsAddress(s.c.Signers, s.seatOf(addr))
+}
+
+func (s confirmedSigners) seatOf(addr common.Address) string {
+	for alias, seat := range s.c.Aliases {
+		if strings.EqualFold(alias, addr.Hex()) {
+			return common.HexToAddress(seat).Hex()
+		}
+	}
+	return addr.Hex()
+}
+
+func (s confirmedSigners) weighted() bool              { return s.c.WeightThreshold > 0 }
+func (s confirmedSigners) weightOf(seat string) uint64 { return s.weights.of(seat) }
+func (s confirmedSigners) threshold() int              { return s.c.Threshold }
+func (s confirmedSigners) weightThreshold() uint64     { return s.c.WeightThreshold }
@@ -0,0 +1,120 @@
+package operator
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultConflictWindow treats two requests as the same round when their
+// timestamps fall in the same second.
+const defaultConflictWindow = time.Second
+
+// roundKey identifies what a request is a round of: a data structure and,
+// when the structure has one, the ticker it reports on.
+type roundKey struct {
+	dataStructureID int
+	ticker          string
+}
+
+type roundEntry struct {
+	hash      string
+	timestamp int64
+	seen      time.Time
+}
+
+func roundKeyOf(req *SignRequest) roundKey {
+	key := roundKey{dataStructureID: req.DataStructureId}
+	for i, name := range req.DataStructureMeta {
+		if name == "ticker" && i < len(req.Data) {
+			key.ticker = fmt.Sprint(req.Data[i])
+			break
+		}
+	}
+	return key
+}
+
+// SetConflictWindow changes how close the timestamps of two requests for the
+// same structure and ticker must be for them to count as one round.
+func (o *OperatorNode) SetConflictWindow(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	o.pendingMux.Lock()
+	o.conflictWindow = window
+	o.pendingMux.Unlock()
+}
+
+// checkConflict records req as a round. When another hash was already seen
+// for the same round, both are refused confirmation; one that is already
+// confirmed cannot be taken back, but the incident is raised all the same.
+// Callers hold pendingMux.
+func (o *OperatorNode) checkConflict(req *SignRequest, now time.Time) {
+	key := roundKeyOf(req)
+	window := o.conflictWindow.Seconds()
+
+	var conflicting []string
+	for _, entry := range o.rounds[key] {
+		if entry.hash == req.Hash {
+			return
+		}
+		delta := float64(entry.timestamp - req.Timestamp)
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta < window {
+			conflicting = append(conflicting, entry.hash)
+		}
+	}
+	o.rounds[key] = append(o.rounds[key], roundEntry{hash: req.Hash, timestamp: req.Timestamp, seen: now})
+
+	if len(conflicting) == 0 {
+		return
+	}
+	o.conflicted[req.Hash] = true
+	for _, hash := range conflicting {
+		o.conflicted[hash] = true
+		if other, ok := o.pending[hash]; ok && other.confirmed {
+			log.Printf("🚨 Conflicting request %s for a round already confirmed as %s", req.Hash, hash)
+		}
+		conflictsDetected.Inc()
+		o.events.Emit(Event{
+			Type:            EventConflictDetected,
+			Hash:            req.Hash,
+			ConflictsWith:   hash,
+			RequestID:       req.RequestID,
+			DataStructureID: req.DataStructureId,
+			At:              now,
+		})
+		log.Printf("🚨 Conflicting data for structure %d %q at %d: %s and %s; refusing to confirm either [req=%s]",
+			key.dataStructureID, key.ticker, req.Timestamp, hash, req.Hash, req.RequestID)
+	}
+}
+
+// isConflicted reports whether hash shares its round with another hash.
+// Callers hold pendingMux.
+func (o *OperatorNode) isConflicted(hash string) bool {
+	return o.conflicted[hash]
+}
+
+// pruneRounds forgets rounds too old for a conflicting request to still pass
+// the timestamp skew check. Callers hold pendingMux.
+func (o *OperatorNode) pruneRounds(now time.Time) {
+	skew := o.timestampSkew()
+
+	for key, entries := range o.rounds {
+		keep := entries[:0]
+		for _, entry := range entries {
+			if now.Sub(entry.seen) <= o.expiryFor(key.dataStructureID)+skew {
+				keep = append(keep, entry)
+			} else {
+				delete(o.conflicted, entry.hash)
+			}
+		}
+		if len(keep) == 0 {
+			delete(o.rounds, key)
+		} else {
+			o.rounds[key] = keep
+		}
+	}
+}
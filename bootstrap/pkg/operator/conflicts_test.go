@@ -0,0 +1,26 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/benbjohnson/clock"
+)
+
+func TestConflictIgnoresFieldNames(t *testing.T) {
+	op := newTestOperator(t, clock.NewMock(), 1, nil)
+	now := op.clock.Now()
+	first := testSignRequest(t, "100", now.Unix())
+	second := testSignRequest(t, "101", now.Unix())
+	// A relabeled layout is still a round of the same structure and ticker.
+	second.DataStructureMeta = append([]string(nil), second.DataStructureMeta...)
+	second.DataStructureMeta[len(second.DataStructureMeta)-1] = "last"
+
+	op.pendingMux.Lock()
+	op.checkConflict(first, now)
+	op.checkConflict(second, now)
+	conflicted := op.isConflicted(first.Hash) && op.isConflicted(second.Hash)
+	op.pendingMux.Unlock()
+	if !conflicted {
+		t.Fatal("requests differing only in field names were not flagged as conflicting")
+	}
+}
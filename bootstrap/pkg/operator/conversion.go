@@ -0,0 +1,184 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// ConversionConfig describes a feed derived by converting the confirmed
+// price of Source into Currency with the confirmed FX rate of Rate, e.g.
+// SBER in RUB into USD with USDRUB. The rate is multiplied in, or divided
+// out when Divide is set because it quotes the source currency per unit of
+// Currency, as USDRUB does.
+type ConversionConfig struct {
+	Source          string `json:"source"`
+	SourceStructure string `json:"source_structure,omitempty"`
+	Rate            string `json:"rate"`
+	RateStructure   string `json:"rate_structure,omitempty"`
+	Divide          bool   `json:"divide,omitempty"`
+	Currency        string `json:"currency"`
+	// MaxAgeSeconds rejects input prices older than this. Zero accepts any
+	// confirmed price.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// loadConversions reads conversion definitions. A missing file means no
+// conversions.
+func loadConversions(path string) (map[string]ConversionConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversions file: %w", err)
+	}
+
+	var conversions map[string]ConversionConfig
+	if err := json.Unmarshal(data, &conversions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversions: %w", err)
+	}
+
+	for name, conversion := range conversions {
+		if conversion.Source == "" || conversion.Rate == "" {
+			return nil, fmt.Errorf("conversion %s needs a source and a rate", name)
+		}
+		if conversion.Currency == "" {
+			return nil, fmt.Errorf("conversion %s has no target currency", name)
+		}
+		if conversion.SourceStructure == "" {
+			conversion.SourceStructure = "stock_quote"
+		}
+		if conversion.RateStructure == "" {
+			conversion.RateStructure = "stock_quote"
+		}
+		conversions[name] = conversion
+	}
+
+	return conversions, nil
+}
+
+// conversionInputs are the confirmed messages a converted price was
+// computed from.
+type conversionInputs struct {
+	price      float64
+	rate       float64
+	sourceHash string
+	rateHash   string
+}
+
+// ConversionPriceSource prices a conversion from confirmed messages only,
+// like a basket, and remembers which ones so that the derived message can
+// name them.
+type ConversionPriceSource struct {
+	Feed   string
+	Config ConversionConfig
+	db     store.Database
+	// sourceID and rateID are the numeric IDs of Config.SourceStructure and
+	// Config.RateStructure.
+	sourceID, rateID int
+
+	mu   sync.Mutex
+	last conversionInputs
+}
+
+func NewConversionPriceSource(name string, config ConversionConfig, structures map[string]DataStructure, db store.Database) (*ConversionPriceSource, error) {
+	sourceID, err := structureNumericID(structures, config.SourceStructure)
+	if err != nil {
+		return nil, fmt.Errorf("conversion %s: source: %w", name, err)
+	}
+	rateID, err := structureNumericID(structures, config.RateStructure)
+	if err != nil {
+		return nil, fmt.Errorf("conversion %s: rate: %w", name, err)
+	}
+	return &ConversionPriceSource{
+		Feed:     name,
+		Config:   config,
+		db:       db,
+		sourceID: sourceID,
+		rateID:   rateID,
+	}, nil
+}
+
+func (s *ConversionPriceSource) Name() string {
+	return "conversion"
+}
+
+func (s *ConversionPriceSource) FetchPrice(ctx context.Context) (float64, error) {
+	sourceID := s.sourceID
+	sourceMsg, price, err := confirmedPrice(ctx, s.db, sourceID, s.Config.Source, s.Config.MaxAgeSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("source: %w", err)
+	}
+	rateID := s.rateID
+	rateMsg, rate, err := confirmedPrice(ctx, s.db, rateID, s.Config.Rate, s.Config.MaxAgeSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("rate: %w", err)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("rate %s is %g", s.Config.Rate, rate)
+	}
+
+	converted := price * rate
+	if s.Config.Divide {
+		converted = price / rate
+	}
+
+	s.mu.Lock()
+	s.last = conversionInputs{price: converted, rate: rate, sourceHash: sourceMsg.Hash, rateHash: rateMsg.Hash}
+	s.mu.Unlock()
+	return converted, nil
+}
+
+// inputs returns the messages price was converted from. It fails when the
+// last fetch produced another price, which a fetch outliving its round can.
+func (s *ConversionPriceSource) inputs(price float64) (conversionInputs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last.sourceHash == "" || s.last.price != price {
+		return conversionInputs{}, fmt.Errorf("no conversion inputs for price %g", price)
+	}
+	return s.last, nil
+}
+
+// ConvertedQuoteMessageBuilder publishes a converted price together with
+// the hashes of the source and rate messages it was computed from, so the
+// signed message carries its own provenance.
+type ConvertedQuoteMessageBuilder struct {
+	Ticker      string
+	StructureID string
+	Structure   DataStructure
+	Metadata    collector.QuoteMetadata
+	Conversion  *ConversionPriceSource
+}
+
+func (b *ConvertedQuoteMessageBuilder) BuildMessage(price collector.PriceBreakdown) (*SignRequest, error) {
+	if b.Conversion == nil {
+		return nil, fmt.Errorf("%s is not a conversion", b.Ticker)
+	}
+	inputs, err := b.Conversion.inputs(price.Average)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+
+	fieldValues := map[string]interface{}{
+		"ticker":      b.Ticker,
+		"price":       FloatToWei(price.Average).String(),
+		"rate":        FloatToWei(inputs.rate).String(),
+		"source_hash": "0x" + inputs.sourceHash,
+		"rate_hash":   "0x" + inputs.rateHash,
+		"timestamp":   timestamp,
+	}
+	metadata := collector.QuoteMetadata{Currency: b.Conversion.Config.Currency}.Or(b.Metadata)
+
+	return buildSignRequest(b.StructureID, b.Structure, withMetadata(fieldValues, b.Structure, metadata), timestamp)
+}
@@ -0,0 +1,283 @@
+package operator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+const (
+	// feedClientBuffer is how many events a slow feed client may lag
+	// behind before events are dropped for it.
+	feedClientBuffer = 64
+	// recentConfirmations is how many threshold events the feed keeps for
+	// clients that have just connected.
+	recentConfirmations = 50
+	feedKeepAlive       = 15 * time.Second
+)
+
+// SignerParticipation counts the signatures a signer contributed since the
+// operator started.
+type SignerParticipation struct {
+	Signer     string    `json:"signer"`
+	Signatures int       `json:"signatures"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// EventFeed fans operator events out to streaming HTTP clients and keeps the
+// recent history the dashboard shows on load.
+type EventFeed struct {
+	mu            sync.Mutex
+	clients       map[chan Event]struct{}
+	confirmations []Event
+	participation map[string]*SignerParticipation
+	// closed ends open streams on shutdown.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewEventFeed(bus *EventBus) *EventFeed {
+	f := &EventFeed{
+		clients:       make(map[chan Event]struct{}),
+		participation: make(map[string]*SignerParticipation),
+		closed:        make(chan struct{}),
+	}
+	if bus != nil {
+		bus.SubscribeAll(f.publish)
+	}
+	return f
+}
+
+// publish runs on the emitting goroutine, so it never blocks: clients whose
+// buffer is full miss the event.
+func (f *EventFeed) publish(e Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch e.Type {
+	case EventThresholdReached:
+		f.confirmations = append(f.confirmations, e)
+		if len(f.confirmations) > recentConfirmations {
+			f.confirmations = f.confirmations[len(f.confirmations)-recentConfirmations:]
+		}
+	case EventSignatureReceived:
+		p, ok := f.participation[e.Signer]
+		if !ok {
+			p = &SignerParticipation{Signer: e.Signer}
+			f.participation[e.Signer] = p
+		}
+		p.Signatures++
+		p.LastSeen = e.At
+	}
+
+	for ch := range f.clients {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (f *EventFeed) subscribe() chan Event {
+	ch := make(chan Event, feedClientBuffer)
+	f.mu.Lock()
+	f.clients[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch
+}
+
+func (f *EventFeed) unsubscribe(ch chan Event) {
+	f.mu.Lock()
+	delete(f.clients, ch)
+	f.mu.Unlock()
+}
+
+// Close ends every open stream, which would otherwise hold server shutdown
+// until its deadline.
+func (f *EventFeed) Close() {
+	f.closeOnce.Do(func() { close(f.closed) })
+}
+
+// recent returns the latest confirmations, newest first.
+func (f *EventFeed) recent() []Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]Event, len(f.confirmations))
+	for i, e := range f.confirmations {
+		out[len(out)-1-i] = e
+	}
+	return out
+}
+
+func (f *EventFeed) signers() []SignerParticipation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]SignerParticipation, 0, len(f.participation))
+	for _, p := range f.participation {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Signer < out[j].Signer })
+	return out
+}
+
+// PendingStatus is the dashboard view of one request awaiting signatures.
+type PendingStatus struct {
+	Hash            string    `json:"hash"`
+	RequestID       string    `json:"request_id,omitempty"`
+	DataStructureID int       `json:"data_structure_id"`
+	Signatures      int       `json:"signatures"`
+	Threshold       int       `json:"threshold"`
+	Attempts        int       `json:"attempts"`
+	Since           time.Time `json:"since"`
+}
+
+// pendingStatus lists unconfirmed pending requests, oldest first.
+func (o *OperatorNode) pendingStatus() []PendingStatus {
+	o.pendingMux.RLock()
+	out := make([]PendingStatus, 0, len(o.pending))
+	for hash, req := range o.pending {
+		if req.confirmed {
+			continue
+		}
+		out = append(out, PendingStatus{
+			Hash:            hash,
+			RequestID:       req.data.RequestID,
+			DataStructureID: req.data.DataStructureId,
+			Signatures:      len(req.signers),
+			Attempts:        req.attempts,
+			Since:           req.timestamp,
+		})
+	}
+	o.pendingMux.RUnlock()
+
+	for i := range out {
+		out[i].Threshold = o.thresholdFor(out[i].DataStructureID)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.Before(out[j].Since) })
+	return out
+}
+
+// connectedPeers lists the peer IDs of open connections.
+func (o *OperatorNode) connectedPeers() []string {
+	peers := o.host.Network().Peers()
+	out := make([]string, len(peers))
+	for i, p := range peers {
+		out[i] = p.String()
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *RPCServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/dashboard" && r.URL.Path != "/dashboard/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// handleStatus reports the operator state the dashboard polls: peers,
+// pending requests, signer participation and recent confirmations.
+func (s *RPCServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"peer_id":          s.operator.host.ID().String(),
+		"operator_address": s.operator.address.Hex(),
+		"peers":            s.operator.connectedPeers(),
+		"pending":          s.operator.pendingStatus(),
+		"trusted_signers":  s.operator.trustedSigners(),
+		"participation":    s.feed.signers(),
+		"confirmations":    s.feed.recent(),
+		"replication":      s.operator.ReplicationStatus(),
+		"time":             time.Now().Format(time.RFC3339),
+	})
+}
+
+// handleEvents streams operator events as server-sent events until the
+// client goes away.
+func (s *RPCServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	draining, done, ok := s.streams.track("events")
+	if !ok {
+		refuseDraining(w)
+		return
+	}
+	defer done()
+
+	// Each write gets its own deadline; the server write timeout would
+	// otherwise cut the stream.
+	rc := http.NewResponseController(w)
+	if err := s.streamDeadline(rc); err != nil {
+		refuseStream(w, "events", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := s.feed.subscribe()
+	defer s.feed.unsubscribe(ch)
+
+	keepAlive := time.NewTicker(feedKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.feed.closed:
+			return
+		case <-draining:
+			// EventSource clients reconnect after the retry delay, by which
+			// time the operator should be back.
+			if s.streamDeadline(rc) == nil {
+				fmt.Fprintf(w, "retry: %d\nevent: shutdown\ndata: {}\n\n", drainRetryAfter.Milliseconds())
+				rc.Flush()
+			}
+			return
+		case <-keepAlive.C:
+			if err = s.streamDeadline(rc); err == nil {
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			}
+		case e := <-ch:
+			if !s.canRead(r, e.DataStructureID) {
+				continue
+			}
+			payload, jsonErr := json.Marshal(e)
+			if jsonErr != nil {
+				continue
+			}
+			if err = s.streamDeadline(rc); err == nil {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, payload)
+			}
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			streamAborted("events", err)
+			return
+		}
+	}
+}
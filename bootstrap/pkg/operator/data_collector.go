@@ -0,0 +1,702 @@
+package operator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/robfig/cron/v3"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/sha3"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+type DataStructure struct {
+	// ID is the numeric ID messages of the structure are stored and signed
+	// under. Structures named by a numeric ID may leave it out.
+	ID     int `json:"id,omitempty"`
+	Fields []struct {
+		Name         string `json:"name"`
+		SolidityType string `json:"solidity_type"`
+	} `json:"fields"`
+	// RetentionDays bounds how long messages of this structure are kept.
+	// Zero keeps them forever.
+	RetentionDays  int      `json:"retention_days,omitempty"`
+	RequiredFields []string `json:"required_fields,omitempty"`
+	// Schema is an optional JSON Schema applied to the field values of every
+	// message before it is hashed and published.
+	Schema   json.RawMessage `json:"schema,omitempty"`
+	compiled *jsonschema.Schema
+	// HashVersion selects the hashing scheme for new messages, defaulting to
+	// HashVersionJSON.
+	HashVersion int `json:"hash_version,omitempty"`
+	// Threshold overrides the default majority threshold for this structure.
+	Threshold int `json:"threshold,omitempty"`
+	// PendingExpirySeconds overrides how long a message of this structure
+	// may collect signatures before it is dropped.
+	PendingExpirySeconds int `json:"pending_expiry_seconds,omitempty"`
+	// Signers is the committee of trusted addresses allowed to sign this
+	// structure; empty allows all. ExcludeSigners are never counted. The
+	// default threshold is a majority of the committee.
+	Signers        []string `json:"signers,omitempty"`
+	ExcludeSigners []string `json:"exclude_signers,omitempty"`
+	// WeightThreshold overrides the signed weight required to confirm a
+	// message when signer weights are configured.
+	WeightThreshold uint64 `json:"weight_threshold,omitempty"`
+	// Metadata is what the prices of this structure are quoted in unless a
+	// job says otherwise. Fields named currency, unit and venue carry it in
+	// each message, under the signatures.
+	Metadata collector.QuoteMetadata `json:"metadata,omitempty"`
+}
+
+// withMetadata adds the quote metadata to the field values of a message;
+// structures without such fields ignore it. Unknown metadata is an empty
+// string, never a missing value.
+func withMetadata(fieldValues map[string]interface{}, structure DataStructure, job collector.QuoteMetadata) map[string]interface{} {
+	metadata := job.Or(structure.Metadata)
+	fieldValues["currency"] = metadata.Currency
+	fieldValues["unit"] = metadata.Unit
+	fieldValues["venue"] = metadata.Venue
+	return fieldValues
+}
+
+// structureNumericID returns the numeric ID of the structure named name,
+// which is used as the storage key.
+func structureNumericID(structures map[string]DataStructure, name string) (int, error) {
+	structure, ok := structures[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown data structure %q", name)
+	}
+	return structure.ID, nil
+}
+
+type MessageBuilder interface {
+	BuildMessage(price collector.PriceBreakdown) (*SignRequest, error)
+}
+
+type StockQuoteMessageBuilder struct {
+	Ticker           string
+	StructureID      string
+	DestinationChain int
+	Structure        DataStructure
+	Metadata         collector.QuoteMetadata
+}
+
+func SolidityKeccak256(types []string, values []interface{}) ([]byte, error) {
+	packed, err := SolidityPack(types, values)
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(packed)
+	return hasher.Sum(nil), nil
+}
+
+// SolidityPack concatenates values the way SolidityKeccak256 hashes them.
+// Types come from data structure definitions, so an unsupported type or a
+// value of the wrong Go type is an error rather than a panic.
+func SolidityPack(types []string, values []interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("types and values length mismatch")
+	}
+
+	var packed []byte
+
+	for i, typ := range types {
+		switch {
+		case typ == "bytes32":
+			val, ok := values[i].([32]byte)
+			if !ok {
+				return nil, fmt.Errorf("invalid bytes32 value")
+			}
+			packed = append(packed, val[:]...)
+
+		case typ == "string":
+			val, ok := values[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid string value")
+			}
+			packed = append(packed, []byte(val)...)
+
+		case typ == "uint256":
+			val, ok := values[i].(*big.Int)
+			if !ok || val.Sign() < 0 {
+				return nil, fmt.Errorf("invalid uint256 value")
+			}
+			b, err := padTo32Bytes(val.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			packed = append(packed, b...)
+
+		case typ == "uint64":
+			val, ok := values[i].(uint64)
+			if !ok {
+				return nil, fmt.Errorf("invalid uint64 value")
+			}
+			packed = binary.BigEndian.AppendUint64(packed, val)
+
+		case strings.HasPrefix(typ, "int"):
+			val, ok := values[i].(*big.Int)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s value", typ)
+			}
+			b, err := packSigned(typ, val)
+			if err != nil {
+				return nil, err
+			}
+			packed = append(packed, b...)
+
+		case typ == "address":
+			val, ok := values[i].([20]byte)
+			if !ok {
+				return nil, fmt.Errorf("invalid address value")
+			}
+			packed = append(packed, val[:]...)
+
+		case typ == "bool":
+			val, ok := values[i].(bool)
+			if !ok {
+				return nil, fmt.Errorf("invalid bool value")
+			}
+			if val {
+				packed = append(packed, 1)
+			} else {
+				packed = append(packed, 0)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported type: %s", typ)
+		}
+	}
+
+	return packed, nil
+}
+
+func padTo32Bytes(data []byte) ([]byte, error) {
+	if len(data) > 32 {
+		return nil, fmt.Errorf("data too long for 32 bytes")
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(data):], data)
+	return padded, nil
+}
+
+// packSigned encodes val as abi.encodePacked does an intN: N/8 bytes of
+// two's complement.
+func packSigned(typ string, val *big.Int) ([]byte, error) {
+	bits, err := intBits(typ)
+	if err != nil {
+		return nil, err
+	}
+	if !fitsSigned(val, bits) {
+		return nil, fmt.Errorf("value overflows %s", typ)
+	}
+	n := new(big.Int).Set(val)
+	if n.Sign() < 0 {
+		n.Add(n, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	}
+	return n.FillBytes(make([]byte, bits/8)), nil
+}
+
+func calculateHash(data []interface{}, timestamp int64) (string, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data: %w", err)
+	}
+	timestampBig := big.NewInt(timestamp)
+	hash, err := SolidityKeccak256([]string{"string", "uint256"}, []interface{}{string(jsonData), timestampBig})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash), nil
+}
+
+func FloatToWei(price float64) *big.Int {
+	priceBig := new(big.Float).SetFloat64(price)
+	multiplier := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+	wei := new(big.Float).Mul(priceBig, multiplier)
+	result := new(big.Int)
+	wei.Int(result)
+	return result
+}
+
+func (b *StockQuoteMessageBuilder) BuildMessage(price collector.PriceBreakdown) (*SignRequest, error) {
+	priceScaled := FloatToWei(price.Average)
+	timestamp := time.Now().Unix()
+
+	fieldValues := map[string]interface{}{
+		"ticker":               b.Ticker,
+		"price":                priceScaled.String(),
+		"spread":               FloatToWei(price.Spread).String(),
+		"source_count":         price.SourceCount,
+		"destination_chain_id": b.DestinationChain,
+		"timestamp":            timestamp,
+	}
+
+	return buildSignRequest(b.StructureID, b.Structure, withMetadata(fieldValues, b.Structure, b.Metadata), timestamp)
+}
+
+// buildSignRequest lays out field values in structure order, normalized as
+// NormalizeFields does, and hashes them into a SignRequest. Every message
+// source goes through here so hashes are reproducible regardless of where
+// the data came from.
+func buildSignRequest(structureID string, structure DataStructure, fieldValues map[string]interface{}, timestamp int64) (*SignRequest, error) {
+	dataStructure := make([]string, len(structure.Fields))
+	dataStructureMeta := make([]string, len(structure.Fields))
+	data := make([]interface{}, len(structure.Fields))
+
+	for i, f := range structure.Fields {
+		dataStructure[i] = f.SolidityType
+		dataStructureMeta[i] = f.Name
+		if value, ok := fieldValues[f.Name]; ok && value != nil {
+			normalized, err := normalizeSolidityValue(f.SolidityType, value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			data[i] = normalized
+		}
+	}
+
+	hashVersion := structure.HashVersion
+	if hashVersion == 0 {
+		hashVersion = HashVersionJSON
+	}
+
+	hash, err := computeHash(hashVersion, dataStructure, data, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash message: %w", err)
+	}
+	log.Printf("Data: %v, Ts: %d, Hash: %s", data, timestamp, hash)
+
+	return &SignRequest{
+		Type:              MsgTypeSignRequest,
+		Hash:              hash,
+		Data:              data,
+		DataStructure:     dataStructure,
+		DataStructureMeta: dataStructureMeta,
+		DataStructureId:   structure.ID,
+		Timestamp:         timestamp,
+		HashVersion:       hashVersion,
+		RequestID:         newRequestID(),
+	}, nil
+}
+
+// newRequestID returns a random correlation ID for one feed round. It travels
+// with the request through pubsub, signer responses, logs and storage, but is
+// not part of the hash.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+type MessageFactory struct {
+	Ticker      string
+	Builders    map[string]func(string, string, DataStructure, int) MessageBuilder
+	Structures  map[string]DataStructure
+	StructureID string
+	// Metadata is the job's quote metadata, handed to the builders.
+	Metadata collector.QuoteMetadata
+	// Conversion is the price source of a converted_quote job.
+	Conversion *ConversionPriceSource
+	// Computed is the price source of a computed_feed job.
+	Computed *ComputedPriceSource
+}
+
+func NewMessageFactory(structureID, ticker string, structures map[string]DataStructure) *MessageFactory {
+	f := &MessageFactory{
+		Ticker:      ticker,
+		StructureID: structureID,
+		Structures:  structures,
+	}
+	f.Builders = map[string]func(string, string, DataStructure, int) MessageBuilder{
+		"stock_quote": func(ticker, structureID string, structure DataStructure, destChain int) MessageBuilder {
+			return &StockQuoteMessageBuilder{
+				Ticker:           ticker,
+				StructureID:      structureID,
+				Structure:        structure,
+				DestinationChain: destChain,
+				Metadata:         f.Metadata,
+			}
+		},
+		"index_basket": func(ticker, structureID string, structure DataStructure, destChain int) MessageBuilder {
+			return &IndexBasketMessageBuilder{
+				Basket:      ticker,
+				StructureID: structureID,
+				Structure:   structure,
+				Metadata:    f.Metadata,
+			}
+		},
+		"converted_quote": func(ticker, structureID string, structure DataStructure, destChain int) MessageBuilder {
+			return &ConvertedQuoteMessageBuilder{
+				Ticker:      ticker,
+				StructureID: structureID,
+				Structure:   structure,
+				Metadata:    f.Metadata,
+				Conversion:  f.Conversion,
+			}
+		},
+		"computed_feed": func(ticker, structureID string, structure DataStructure, destChain int) MessageBuilder {
+			return &ComputedFeedMessageBuilder{
+				Ticker:      ticker,
+				StructureID: structureID,
+				Structure:   structure,
+				Metadata:    f.Metadata,
+				Computed:    f.Computed,
+			}
+		},
+	}
+	return f
+}
+
+func (f *MessageFactory) GetBuilder() (MessageBuilder, error) {
+	if builderFunc, ok := f.Builders[f.StructureID]; ok {
+		if structure, ok := f.Structures[f.StructureID]; ok {
+			return builderFunc(f.Ticker, f.StructureID, structure, 1), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown structure_id: %s", f.StructureID)
+}
+
+// LoadDataStructures reads a data structures file. Every structure must
+// have a positive numeric ID of its own: either its name, or the id it
+// declares.
+func LoadDataStructures(filePath string) (map[string]DataStructure, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data structures file: %w", err)
+	}
+
+	var structures map[string]DataStructure
+	if err := json.Unmarshal(data, &structures); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data structures: %v", err)
+	}
+
+	names := make(map[int]string, len(structures))
+	for name, structure := range structures {
+		if id, err := strconv.Atoi(name); err == nil {
+			if structure.ID != 0 && structure.ID != id {
+				return nil, fmt.Errorf("structure %s declares ID %d", name, structure.ID)
+			}
+			structure.ID = id
+		}
+		if structure.ID <= 0 {
+			return nil, fmt.Errorf("structure %s must have a positive numeric ID", name)
+		}
+		if other, taken := names[structure.ID]; taken {
+			return nil, fmt.Errorf("structures %s and %s share ID %d", min(name, other), max(name, other), structure.ID)
+		}
+		names[structure.ID] = name
+		if err := structure.compileSchema(name); err != nil {
+			return nil, err
+		}
+		if err := structure.validateCommittee(name); err != nil {
+			return nil, err
+		}
+		structures[name] = structure
+	}
+
+	return structures, nil
+}
+
+type Worker struct {
+	Aggregator     *collector.PriceAggregator
+	PubSub         *PubSubService
+	MessageFactory *MessageFactory
+	Ticker         string
+	StructureID    string
+	Schedule       cron.Schedule
+	Shutdown       chan struct{}
+	// Bounds hold back prices outside the job's sanity bounds.
+	Bounds collector.PriceBounds
+	// Clock defaults to the wall clock when nil.
+	Clock clock.Clock
+
+	// lastPrice is the last price published, which Bounds limit the change
+	// from.
+	lastPrice float64
+}
+
+// orWallClock returns c, or the wall clock when c is nil.
+func orWallClock(c clock.Clock) clock.Clock {
+	if c == nil {
+		return clock.New()
+	}
+	return c
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	builder, err := w.MessageFactory.GetBuilder()
+	if err != nil {
+		return fmt.Errorf("failed to get message builder: %w", err)
+	}
+
+	clk := orWallClock(w.Clock)
+	timer := clk.Timer(clk.Until(w.Schedule.Next(clk.Now())))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Shutdown:
+			return nil
+		case <-timer.C:
+			w.tick(ctx, builder)
+			timer.Reset(clk.Until(w.Schedule.Next(clk.Now())))
+		}
+	}
+}
+
+func (w *Worker) tick(ctx context.Context, builder MessageBuilder) {
+	ctx, span := tracer.Start(ctx, "worker.tick", trace.WithAttributes(
+		attribute.String("ticker", w.Ticker),
+		attribute.String("structure_id", w.StructureID),
+	))
+	defer span.End()
+
+	breakdown, err := w.Aggregator.GetPriceBreakdown(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		log.Printf("Error getting average price: %v", err)
+		return
+	}
+
+	if err := w.publishPrice(ctx, builder, breakdown); err != nil {
+		recordSpanError(span, err)
+		log.Printf("Error publishing price for %s: %v", w.Ticker, err)
+	}
+}
+
+// publishPrice builds, validates and publishes a SignRequest for price.
+func (w *Worker) publishPrice(ctx context.Context, builder MessageBuilder, price collector.PriceBreakdown) error {
+	signRequest, err := builder.BuildMessage(price)
+	if err != nil {
+		return fmt.Errorf("failed to build SignRequest: %w", err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("hash", signRequest.Hash))
+
+	if err := w.Bounds.Check(price.Average, w.lastPrice); err != nil {
+		priceBoundsRejections.WithLabelValues(w.Ticker).Inc()
+		return w.reject(signRequest, err)
+	}
+	if structure, ok := w.MessageFactory.Structures[w.StructureID]; ok {
+		if err := structure.ValidateRequest(signRequest); err != nil {
+			return w.reject(signRequest, err)
+		}
+	}
+	w.PubSub.latency.MarkBuilt(signRequest.Hash, orWallClock(w.Clock).Now())
+
+	if err := w.PubSub.PublishSignRequest(ctx, signRequest); err != nil {
+		return err
+	}
+	w.lastPrice = price.Average
+	return nil
+}
+
+// reject reports a built request that will not be published.
+func (w *Worker) reject(signRequest *SignRequest, err error) error {
+	w.PubSub.events.Emit(Event{
+		Type:            EventRequestRejected,
+		Hash:            signRequest.Hash,
+		RequestID:       signRequest.RequestID,
+		DataStructureID: signRequest.DataStructureId,
+		Error:           err.Error(),
+		At:              orWallClock(w.Clock).Now(),
+		Request:         signRequest,
+	})
+	return fmt.Errorf("rejected SignRequest: %w", err)
+}
+
+// publishState is the operator's view of hashes already in flight, used to
+// avoid re-broadcasting requests that need no more work, and the source of
+// sequence numbers and the staking epoch of new requests. A fenced operator,
+// a standby or a primary without a lease, publishes nothing.
+type publishState interface {
+	confirms(dataStructureID int, signatures map[string]string) bool
+	isPending(hash string) bool
+	fenced() bool
+	timestampAcceptable(timestamp int64) bool
+	nextSequence(ctx context.Context, dataStructureID int) (uint64, error)
+	currentEpoch() uint64
+	topicFor(dataStructureID int) *pubsub.Topic
+	publicationPaused() bool
+}
+
+type PubSubService struct {
+	topic          *pubsub.Topic
+	db             store.Database
+	state          publishState
+	latency        *LatencyTracker
+	events         *EventBus
+	publishTimeout time.Duration
+	maxRetries     int
+	retryDelay     time.Duration
+	// clock times publish retries; nil means the wall clock.
+	clock clock.Clock
+}
+
+// NewPubSubService publishes the sign requests of operator, storing them in
+// db. A failed publish is retried maxRetries times, retryDelay apart.
+func NewPubSubService(operator *OperatorNode, db store.Database, publishTimeout time.Duration, maxRetries int, retryDelay time.Duration) *PubSubService {
+	return &PubSubService{
+		topic:          operator.topic,
+		db:             db,
+		state:          operator,
+		latency:        operator.latency,
+		events:         operator.events,
+		clock:          operator.clock,
+		publishTimeout: publishTimeout,
+		maxRetries:     maxRetries,
+		retryDelay:     retryDelay,
+	}
+}
+
+func (s *PubSubService) PublishSignRequest(ctx context.Context, sr *SignRequest) error {
+	ctx, span := tracer.Start(ctx, "pubsub.publish "+MsgTypeSignRequest,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("hash", sr.Hash), attribute.String("request_id", sr.RequestID)),
+	)
+	defer span.End()
+
+	// A request that does not go out leaves nothing for the latency tracker
+	// to follow, unless it is pending from an earlier publish. One that went
+	// out and failed is forgotten on EventPublishFailed.
+	published := false
+	defer func() {
+		if !published && s.latency != nil && (s.state == nil || !s.state.isPending(sr.Hash)) {
+			s.latency.Drop(sr.Hash)
+		}
+	}()
+
+	if s.state != nil && s.state.fenced() {
+		span.SetAttributes(attribute.String("dedup", "fenced"))
+		log.Printf("Fenced, not publishing %s [req=%s]", sr.Hash, sr.RequestID)
+		return nil
+	}
+	if s.state != nil && s.state.publicationPaused() {
+		publishPausedTotal.Inc()
+		span.SetAttributes(attribute.String("dedup", "paused"))
+		return errPublicationPaused
+	}
+
+	if existing, stored := s.db.GetMessage(ctx, sr.Hash); stored {
+		if reason := s.duplicateReason(existing, sr); reason != "" {
+			publishDeduplicatedTotal.WithLabelValues(reason).Inc()
+			span.SetAttributes(attribute.String("dedup", reason))
+			log.Printf("Skipping publish of %s: %s [req=%s]", sr.Hash, reason, sr.RequestID)
+			return nil
+		}
+		log.Printf("Re-publishing stored but unconfirmed request %s [req=%s]", sr.Hash, sr.RequestID)
+		sr.Sequence = existing.Sequence
+		sr.Epoch = existing.Epoch
+	} else {
+		if s.state != nil {
+			seq, err := s.state.nextSequence(ctx, sr.DataStructureId)
+			if err != nil {
+				recordSpanError(span, err)
+				return err
+			}
+			sr.Sequence = seq
+			sr.Epoch = s.state.currentEpoch()
+		}
+		err := s.db.StoreData(ctx, sr.Hash, sr.Data, sr.DataStructure, sr.DataStructureMeta, sr.Timestamp, sr.DataStructureId, sr.HashVersion, sr.RequestID, sr.Sequence, sr.Epoch)
+		if errors.Is(err, store.ErrAlreadyStored) {
+			// A concurrent publish of the same hash stored it first and
+			// publishes it; the sequence number taken here stays unused.
+			publishDeduplicatedTotal.WithLabelValues("already stored").Inc()
+			span.SetAttributes(attribute.String("dedup", "already stored"))
+			log.Printf("Skipping publish of %s: already stored [req=%s]", sr.Hash, sr.RequestID)
+			return nil
+		}
+		if err != nil {
+			recordSpanError(span, err)
+			return fmt.Errorf("failed to store data: %w", err)
+		}
+	}
+
+	sr.TraceContext = injectTraceContext(ctx)
+	sr.ProtocolVersion = ProtocolVersion
+
+	payloadBytes, err := json.Marshal(sr)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SignRequest: %w", err)
+	}
+
+	topic := s.topic
+	if s.state != nil {
+		topic = s.state.topicFor(sr.DataStructureId)
+	}
+
+	var lastErr error
+	for i := 0; i < s.maxRetries; i++ {
+		pubCtx, cancel := context.WithTimeout(ctx, s.publishTimeout)
+		err := topic.Publish(pubCtx, payloadBytes)
+		cancel()
+
+		if err == nil {
+			s.events.Emit(Event{
+				Type:            EventMessagePublished,
+				Hash:            sr.Hash,
+				RequestID:       sr.RequestID,
+				DataStructureID: sr.DataStructureId,
+				At:              orWallClock(s.clock).Now(),
+			})
+			log.Printf("Published SignRequest %s [req=%s]", sr.Hash, sr.RequestID)
+			published = true
+			return nil
+		}
+
+		lastErr = err
+		log.Printf("Publish attempt %d/%d failed [req=%s]: %v", i+1, s.maxRetries, sr.RequestID, err)
+		orWallClock(s.clock).Sleep(s.retryDelay)
+	}
+
+	recordSpanError(span, lastErr)
+	err = fmt.Errorf("failed to publish after %d attempts: %w", s.maxRetries, lastErr)
+	s.events.Emit(Event{
+		Type:            EventPublishFailed,
+		Hash:            sr.Hash,
+		RequestID:       sr.RequestID,
+		DataStructureID: sr.DataStructureId,
+		Error:           err.Error(),
+		At:              orWallClock(s.clock).Now(),
+		Request:         sr,
+	})
+	return err
+}
+
+// duplicateReason explains why an already stored request needs no publish,
+// or returns "" when it should go out again, e.g. after a restart dropped it
+// from the pending set before it was confirmed.
+func (s *PubSubService) duplicateReason(existing store.Message, sr *SignRequest) string {
+	if s.state == nil {
+		return "already stored"
+	}
+	if s.state.confirms(sr.DataStructureId, existing.Signatures) {
+		return "already confirmed"
+	}
+	if s.state.isPending(sr.Hash) {
+		return "already pending"
+	}
+	return ""
+}
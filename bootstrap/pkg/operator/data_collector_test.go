@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeStructures(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data_structures.json")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDataStructuresAssignsUniqueIDs(t *testing.T) {
+	structures, err := LoadDataStructures("../../config/data_structures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[int]string)
+	for name, structure := range structures {
+		if structure.ID <= 0 {
+			t.Errorf("structure %s has ID %d", name, structure.ID)
+		}
+		if other, taken := names[structure.ID]; taken {
+			t.Errorf("structures %s and %s share ID %d", name, other, structure.ID)
+		}
+		names[structure.ID] = name
+	}
+
+	numeric, err := LoadDataStructures(writeStructures(t, `{"7": {"fields": []}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numeric["7"].ID != 7 {
+		t.Fatalf("structure named 7 has ID %d", numeric["7"].ID)
+	}
+}
+
+func TestLoadDataStructuresRejectsBadIDs(t *testing.T) {
+	for name, contents := range map[string]string{
+		"missing":      `{"stock_quote": {"fields": []}}`,
+		"negative":     `{"stock_quote": {"id": -1, "fields": []}}`,
+		"duplicate":    `{"stock_quote": {"id": 1, "fields": []}, "index_basket": {"id": 1, "fields": []}}`,
+		"name clash":   `{"stock_quote": {"id": 7, "fields": []}, "7": {"fields": []}}`,
+		"name differs": `{"7": {"id": 8, "fields": []}}`,
+	} {
+		if _, err := LoadDataStructures(writeStructures(t, contents)); err == nil {
+			t.Errorf("%s: loaded %s", name, contents)
+		}
+	}
+}
+
+func TestDerivedSourcesResolveStructureIDs(t *testing.T) {
+	structures := map[string]DataStructure{"stock_quote": {ID: 1}}
+
+	basket, err := NewBasketPriceSource("B", BasketConfig{ComponentStructure: "stock_quote"}, structures, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if basket.componentID != 1 {
+		t.Fatalf("basket reads structure %d, want 1", basket.componentID)
+	}
+	if _, err := NewBasketPriceSource("B", BasketConfig{ComponentStructure: "unknown"}, structures, nil); err == nil {
+		t.Fatal("basket over an unknown structure accepted")
+	}
+}
@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"bootstrap/pkg/store"
+)
+
+// Reasons a request ends up a dead letter.
+const (
+	deadLetterPublishFailed = "publish_failed"
+	deadLetterRejected      = "rejected"
+	deadLetterExpired       = "expired"
+
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+var (
+	errDeadLetterNotFound = errors.New("dead letter not found")
+	errStandbyReplay      = errors.New("a standby or a primary without a lease publishes nothing")
+)
+
+// DeadLetters keeps the requests that failed to publish, failed validation
+// or expired unconfirmed, so they can be inspected and replayed instead of
+// surviving only as log lines.
+type DeadLetters struct {
+	db        store.Database
+	publisher *PubSubService
+}
+
+func NewDeadLetters(db store.Database, publisher *PubSubService) *DeadLetters {
+	return &DeadLetters{db: db, publisher: publisher}
+}
+
+// Subscribe records the failed, rejected and expired requests of b. They
+// are stored on a goroutine of their own, as event handlers must not block.
+func (d *DeadLetters) Subscribe(b *EventBus) {
+	for t, reason := range map[EventType]string{
+		EventPublishFailed:   deadLetterPublishFailed,
+		EventRequestRejected: deadLetterRejected,
+		EventRequestExpired:  deadLetterExpired,
+	} {
+		reason := reason
+		b.Subscribe(t, func(e Event) {
+			if e.Request == nil {
+				return
+			}
+			request, err := json.Marshal(e.Request)
+			if err != nil {
+				log.Printf("Error marshaling dead letter %s: %v", e.Hash, err)
+				return
+			}
+			letter := store.DeadLetter{
+				ID:              store.DeadLetterID(e.At.UnixMilli(), e.Hash),
+				Reason:          reason,
+				Error:           e.Error,
+				Hash:            e.Hash,
+				RequestID:       e.RequestID,
+				DataStructureID: e.DataStructureID,
+				Signatures:      e.Signatures,
+				Request:         request,
+				RecordedAt:      e.At.UnixMilli(),
+			}
+			go d.record(letter)
+		})
+	}
+}
+
+func (d *DeadLetters) record(letter store.DeadLetter) {
+	if err := d.db.StoreDeadLetter(context.Background(), letter); err != nil {
+		log.Printf("Error storing dead letter %s: %v", letter.ID, err)
+		return
+	}
+	deadLettersTotal.WithLabelValues(letter.Reason).Inc()
+}
+
+// List returns up to limit dead letters recorded for reason, or for any
+// reason when it is empty, oldest first.
+func (d *DeadLetters) List(ctx context.Context, reason string, limit int) ([]store.DeadLetter, error) {
+	return d.db.GetDeadLetters(ctx, reason, limit)
+}
+
+// Replay publishes the request of dead letter id again, as it was, and
+// drops the dead letter once it went out. A request that was rejected is
+// published without being validated again.
+func (d *DeadLetters) Replay(ctx context.Context, id string) (*SignRequest, error) {
+	if state := d.publisher.state; state != nil && state.fenced() {
+		return nil, errStandbyReplay
+	}
+	letter, found, err := d.db.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errDeadLetterNotFound
+	}
+
+	var sr SignRequest
+	dec := json.NewDecoder(bytes.NewReader(letter.Request))
+	dec.UseNumber()
+	if err := dec.Decode(&sr); err != nil {
+		return nil, fmt.Errorf("malformed dead letter request: %w", err)
+	}
+	if err := d.publisher.PublishSignRequest(ctx, &sr); err != nil {
+		return nil, err
+	}
+
+	if err := d.db.DeleteDeadLetter(ctx, id); err != nil {
+		return nil, err
+	}
+	log.Printf("♻️ Replayed dead letter %s (%s) [req=%s]", id, letter.Reason, sr.RequestID)
+	return &sr, nil
+}
+
+// Discard drops dead letter id without replaying it.
+func (d *DeadLetters) Discard(ctx context.Context, id string) error {
+	if _, found, err := d.db.GetDeadLetter(ctx, id); err != nil {
+		return err
+	} else if !found {
+		return errDeadLetterNotFound
+	}
+	return d.db.DeleteDeadLetter(ctx, id)
+}
@@ -0,0 +1,94 @@
+package operator
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// drainRetryAfter is how long clients cut off by a shutdown are asked to
+// wait before reconnecting, long enough for a restart.
+const drainRetryAfter = 5 * time.Second
+
+// streamTracker keeps count of the long-running responses (event streams,
+// exports and backups) that http.Server.Shutdown would otherwise wait on
+// blindly. On shutdown it asks them to end, each telling its client why,
+// and waits for them within the shutdown deadline.
+type streamTracker struct {
+	mu       sync.Mutex
+	active   map[string]int
+	wg       sync.WaitGroup
+	draining chan struct{}
+	once     sync.Once
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{
+		active:   make(map[string]int),
+		draining: make(chan struct{}),
+	}
+}
+
+// track registers a stream of kind. The returned channel closes when the
+// stream should end; done must be called once it has. It refuses new
+// streams while draining.
+func (t *streamTracker) track(kind string) (draining <-chan struct{}, done func(), ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.draining:
+		return nil, nil, false
+	default:
+	}
+	t.active[kind]++
+	t.wg.Add(1)
+	apiActiveStreams.WithLabelValues(kind).Inc()
+
+	var once sync.Once
+	return t.draining, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.active[kind]--
+			t.mu.Unlock()
+			apiActiveStreams.WithLabelValues(kind).Dec()
+			t.wg.Done()
+		})
+	}, true
+}
+
+// drain tells every stream to end and waits for them until ctx ends. It
+// returns how many streams of each kind are still open.
+func (t *streamTracker) drain(ctx context.Context) map[string]int {
+	t.mu.Lock()
+	t.once.Do(func() { close(t.draining) })
+	t.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	open := make(map[string]int)
+	for kind, n := range t.active {
+		if n > 0 {
+			open[kind] = n
+		}
+	}
+	return open
+}
+
+// refuseDraining answers a stream request that arrives during shutdown.
+func refuseDraining(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(drainRetryAfter.Seconds())))
+	http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventMessagePublished  EventType = "message_published"
+	EventSignatureReceived EventType = "signature_received"
+	EventThresholdReached  EventType = "threshold_reached"
+	// EventRequestExpired fires when a request leaves the pending set
+	// without full signatures, either by expiry or eviction.
+	EventRequestExpired EventType = "request_expired"
+	// EventConflictDetected fires when two hashes are published for the
+	// same structure, ticker and timestamp window.
+	EventConflictDetected EventType = "conflict_detected"
+	// EventPublishFailed fires when a request could not be published on
+	// any retry, and EventRequestRejected when a built request failed
+	// validation and was never published.
+	EventPublishFailed   EventType = "publish_failed"
+	EventRequestRejected EventType = "request_rejected"
+	// EventQuorumAtRisk fires when fewer trusted signers are live than the
+	// default threshold needs, and EventQuorumRestored once enough are
+	// back. Neither concerns a message.
+	EventQuorumAtRisk   EventType = "quorum_at_risk"
+	EventQuorumRestored EventType = "quorum_restored"
+)
+
+// Event describes one step in a message's signature collection. Fields that
+// do not apply to the event type are left zero.
+type Event struct {
+	Type            EventType `json:"type"`
+	Hash            string    `json:"hash"`
+	RequestID       string    `json:"request_id,omitempty"`
+	DataStructureID int       `json:"data_structure_id"`
+	Signer          string    `json:"signer,omitempty"`
+	ConflictsWith   string    `json:"conflicts_with,omitempty"`
+	Signatures      int       `json:"signatures"`
+	Threshold       int       `json:"threshold,omitempty"`
+	// Live is the number of live trusted signers of quorum events.
+	Live int `json:"live,omitempty"`
+	// Weight and WeightThreshold are set when signer weights are configured.
+	Weight          uint64    `json:"weight,omitempty"`
+	WeightThreshold uint64    `json:"weight_threshold,omitempty"`
+	At              time.Time `json:"at"`
+	// Error says why a request failed or was rejected.
+	Error string `json:"error,omitempty"`
+	// Request is the request of expired, failed and rejected events. It is
+	// not sent to event stream clients.
+	Request *SignRequest `json:"-"`
+}
+
+// EventHandler receives events on the emitting goroutine, possibly while
+// operator locks are held. Handlers must not block or call back into the
+// operator; anything slow, like network delivery, belongs on a goroutine of
+// its own.
+type EventHandler func(Event)
+
+// EventBus fans operator events out to registered handlers. A nil bus drops
+// every event.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[EventType][]EventHandler
+	all      []EventHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[EventType][]EventHandler)}
+}
+
+// Subscribe registers h for events of type t.
+func (b *EventBus) Subscribe(t EventType, h EventHandler) {
+	b.mu.Lock()
+	b.handlers[t] = append(b.handlers[t], h)
+	b.mu.Unlock()
+}
+
+// SubscribeAll registers h for every event.
+func (b *EventBus) SubscribeAll(h EventHandler) {
+	b.mu.Lock()
+	b.all = append(b.all, h)
+	b.mu.Unlock()
+}
+
+// Emit delivers e to every matching handler in registration order.
+func (b *EventBus) Emit(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := append(append([]EventHandler(nil), b.handlers[e.Type]...), b.all...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// subscribeLatency drives the latency tracker from the bus.
+func subscribeLatency(b *EventBus, t *LatencyTracker) {
+	b.Subscribe(EventMessagePublished, func(e Event) {
+		t.MarkPublished(e.Hash, e.At)
+	})
+	b.Subscribe(EventSignatureReceived, func(e Event) {
+		if e.Signatures == 1 {
+			t.MarkFirstSignature(e.Hash, e.At)
+		}
+	})
+	b.Subscribe(EventThresholdReached, func(e Event) {
+		t.MarkThreshold(e.Hash, e.At)
+	})
+	b.Subscribe(EventRequestExpired, func(e Event) {
+		t.Forget(e.Hash)
+	})
+	b.Subscribe(EventPublishFailed, func(e Event) {
+		t.Forget(e.Hash)
+	})
+}
+
+// subscribeMetrics counts every event by type.
+func subscribeMetrics(b *EventBus) {
+	b.SubscribeAll(func(e Event) {
+		operatorEventsTotal.WithLabelValues(string(e.Type)).Inc()
+	})
+}
@@ -0,0 +1,76 @@
+package operator
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"bootstrap/pkg/store"
+)
+
+// signatureCountField selects the number of signatures instead of the
+// signature map.
+const signatureCountField = "signature_count"
+
+// fieldSelection is the fields= parameter of list and latest queries: the
+// message keys to return, e.g. "hash,price,timestamp,signature_count".
+// Names may be message keys, data fields of the structure or
+// signature_count; names a message lacks are left out. A nil
+// fieldSelection returns whole messages.
+type fieldSelection []string
+
+func parseFieldSelection(query url.Values) fieldSelection {
+	var fields fieldSelection
+	seen := make(map[string]bool)
+	for _, value := range query["fields"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" && !seen[field] {
+				seen[field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// message returns msg reduced to the selected fields.
+func (f fieldSelection) message(msg store.Message) interface{} {
+	if f == nil {
+		return msg
+	}
+
+	var keys map[string]json.RawMessage
+	if raw, err := json.Marshal(msg); err == nil {
+		json.Unmarshal(raw, &keys)
+	}
+	sparse := make(map[string]interface{}, len(f))
+	for _, field := range f {
+		if field == signatureCountField {
+			sparse[field] = len(msg.Signatures)
+			continue
+		}
+		if value, ok := keys[field]; ok {
+			sparse[field] = value
+			continue
+		}
+		for i, name := range msg.DataStructureMeta {
+			if name == field && i < len(msg.Data) {
+				sparse[field] = msg.Data[i]
+				break
+			}
+		}
+	}
+	return sparse
+}
+
+// messages returns messages reduced to the selected fields.
+func (f fieldSelection) messages(messages []store.Message) interface{} {
+	if f == nil {
+		return messages
+	}
+	sparse := make([]interface{}, len(messages))
+	for i, msg := range messages {
+		sparse[i] = f.message(msg)
+	}
+	return sparse
+}
@@ -0,0 +1,73 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+const (
+	// GossipMessageIDDefault keys the seen-cache on sender and sequence
+	// number, so every rebroadcast is a new message.
+	GossipMessageIDDefault = "default"
+	// GossipMessageIDContent keys the seen-cache on message type and hash
+	// (plus signer for responses), so repeats of the same request or
+	// signature are dropped by every peer within the seen TTL.
+	GossipMessageIDContent = "content"
+
+	// defaultContentSeenTTL stays under defaultRebroadcastInterval: with
+	// content IDs a rebroadcast inside the TTL would be swallowed by our
+	// own seen-cache and never leave the operator. Lower it along with
+	// REBROADCAST_INTERVAL.
+	defaultContentSeenTTL = 4 * time.Second
+)
+
+// GossipConfig tunes gossipsub duplicate suppression. The zero value keeps
+// the libp2p defaults.
+type GossipConfig struct {
+	MessageID string        `yaml:"message_id"`
+	SeenTTL   time.Duration `yaml:"seen_ttl"`
+}
+
+// Options converts the config into gossipsub options.
+func (c GossipConfig) Options() ([]pubsub.Option, error) {
+	var opts []pubsub.Option
+
+	seenTTL := c.SeenTTL
+	switch c.MessageID {
+	case "", GossipMessageIDDefault:
+	case GossipMessageIDContent:
+		opts = append(opts, pubsub.WithMessageIdFn(contentMessageID))
+		if seenTTL == 0 {
+			seenTTL = defaultContentSeenTTL
+		}
+	default:
+		return nil, fmt.Errorf("unknown gossip message ID mode %q", c.MessageID)
+	}
+
+	if seenTTL > 0 {
+		opts = append(opts, pubsub.WithSeenMessagesTTL(seenTTL))
+	}
+	return opts, nil
+}
+
+// contentMessageID derives a message ID from the sign request or response
+// it carries. Anything else falls back to the libp2p default.
+func contentMessageID(pmsg *pb.Message) string {
+	var msg struct {
+		Type   string `json:"type"`
+		Hash   string `json:"hash"`
+		PeerID string `json:"peer_id"`
+	}
+	if err := json.Unmarshal(pmsg.GetData(), &msg); err != nil || msg.Type == "" || msg.Hash == "" {
+		return pubsub.DefaultMsgIdFn(pmsg)
+	}
+
+	if msg.Type == MsgTypeSignResponse {
+		return msg.Type + "/" + msg.Hash + "/" + msg.PeerID
+	}
+	return msg.Type + "/" + msg.Hash
+}
@@ -0,0 +1,201 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"bootstrap/pkg/store"
+)
+
+// Hash versions tag how a message hash was derived so that data signed under
+// an older scheme stays verifiable after the default changes.
+const (
+	// HashVersionJSON hashes the JSON encoding of the data array followed by
+	// the timestamp. Messages stored without a version use this scheme.
+	HashVersionJSON = 1
+	// HashVersionPacked packs the typed field values in structure order with
+	// SolidityKeccak256, matching keccak256(abi.encodePacked(...)) on-chain.
+	HashVersionPacked = 2
+)
+
+// computeHash derives the message hash for the given scheme.
+func computeHash(version int, types []string, data []interface{}, timestamp int64) (string, error) {
+	switch version {
+	case 0, HashVersionJSON:
+		return calculateHash(data, timestamp)
+	case HashVersionPacked:
+		return calculatePackedHash(types, data)
+	default:
+		return "", fmt.Errorf("unknown hash version %d", version)
+	}
+}
+
+// packMessage returns the exact bytes that are keccak-hashed for the scheme.
+func packMessage(version int, types []string, data []interface{}, timestamp int64) ([]byte, error) {
+	switch version {
+	case 0, HashVersionJSON:
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		return SolidityPack([]string{"string", "uint256"}, []interface{}{string(jsonData), big.NewInt(timestamp)})
+	case HashVersionPacked:
+		values, err := abiValues(types, data)
+		if err != nil {
+			return nil, err
+		}
+		return SolidityPack(types, values)
+	default:
+		return nil, fmt.Errorf("unknown hash version %d", version)
+	}
+}
+
+func calculatePackedHash(types []string, data []interface{}) (string, error) {
+	values, err := abiValues(types, data)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := SolidityKeccak256(types, values)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash), nil
+}
+
+func abiValues(types []string, data []interface{}) ([]interface{}, error) {
+	if len(types) != len(data) {
+		return nil, fmt.Errorf("types and values length mismatch")
+	}
+
+	values := make([]interface{}, len(data))
+	for i, typ := range types {
+		v, err := abiValue(typ, data[i])
+		if err != nil {
+			return nil, fmt.Errorf("field %d (%s): %w", i, typ, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// abiValue converts a stored field value into the Go type SolidityKeccak256
+// expects for the Solidity type.
+func abiValue(typ string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+
+	switch typ {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", v)
+		}
+		return s, nil
+
+	case "uint256":
+		n, err := store.ParseInteger(v)
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() < 0 {
+			return nil, fmt.Errorf("negative uint256")
+		}
+		if n.BitLen() > 256 {
+			return nil, fmt.Errorf("value overflows uint256")
+		}
+		return n, nil
+
+	case "uint64":
+		n, err := store.ParseInteger(v)
+		if err != nil {
+			return nil, err
+		}
+		if !n.IsUint64() {
+			return nil, fmt.Errorf("value overflows uint64")
+		}
+		return n.Uint64(), nil
+
+	case "address":
+		s, ok := v.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("expected hex address")
+		}
+		return [20]byte(common.HexToAddress(s)), nil
+
+	case "bytes32":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected hex string")
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("expected 32 bytes of hex")
+		}
+		var out [32]byte
+		copy(out[:], b)
+		return out, nil
+
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", v)
+		}
+		return b, nil
+
+	default:
+		// intN, N a multiple of 8 up to 256.
+		bits, err := intBits(typ)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported type for packed hashing")
+		}
+		n, err := store.ParseInteger(v)
+		if err != nil {
+			return nil, err
+		}
+		if !fitsSigned(n, bits) {
+			return nil, fmt.Errorf("value overflows %s", typ)
+		}
+		return n, nil
+	}
+}
+
+// intBits returns the width of a signed Solidity integer type: 256 for
+// "int", N for "intN" with N a multiple of 8 up to 256.
+func intBits(typ string) (int, error) {
+	suffix, ok := strings.CutPrefix(typ, "int")
+	if !ok {
+		return 0, fmt.Errorf("unsupported type %s", typ)
+	}
+	if suffix == "" {
+		return 256, nil
+	}
+	bits, err := strconv.Atoi(suffix)
+	if err != nil || bits <= 0 || bits > 256 || bits%8 != 0 {
+		return 0, fmt.Errorf("unsupported type %s", typ)
+	}
+	return bits, nil
+}
+
+// fitsSigned reports whether n lies in the range of a bits wide two's
+// complement integer.
+func fitsSigned(n *big.Int, bits int) bool {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+// hashMatches reports whether a stored message still hashes to its key.
+func hashMatches(msg store.Message) bool {
+	hash, err := computeHash(msg.HashVersion, msg.DataStructure, msg.Data, msg.Timestamp)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimPrefix(msg.Hash, "0x"), hash)
+}
@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"bootstrap/pkg/store"
+)
+
+func TestPackedHashSupportsSignedIntegers(t *testing.T) {
+	for _, tc := range []struct {
+		typ   string
+		value interface{}
+		want  string
+	}{
+		{"int256", "-1", "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"int256", float64(5), "0x0000000000000000000000000000000000000000000000000000000000000005"},
+		{"int", "-2", "0xfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe"},
+		{"int8", "-128", "0x80"},
+		{"int16", "300", "0x012c"},
+	} {
+		packed, err := packMessage(HashVersionPacked, []string{tc.typ}, []interface{}{tc.value}, 0)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tc.typ, tc.value, err)
+		}
+		if got := hexutil.Encode(packed); got != tc.want {
+			t.Errorf("%s %v packed to %s, want %s", tc.typ, tc.value, got, tc.want)
+		}
+	}
+
+	if _, err := normalizeSolidityValue("int8", "-128"); err != nil {
+		t.Fatalf("submitting the smallest int8: %v", err)
+	}
+
+	structures, err := LoadDataStructures("../../config/data_structures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := structures["computed_feed"]
+	var types []string
+	for _, f := range feed.Fields {
+		types = append(types, f.SolidityType)
+	}
+	data := []interface{}{"SPREAD", "-1500000000000000000", "a-b", "", "1700000000"}
+	if _, err := computeHash(HashVersionPacked, types, data, 1700000000); err != nil {
+		t.Fatalf("hashing a computed_feed message: %v", err)
+	}
+}
+
+func TestPackedHashRefusesWhatItCannotEncode(t *testing.T) {
+	overflow := new(big.Int).Lsh(big.NewInt(1), 256).String()
+	for _, tc := range []struct {
+		typ   string
+		value interface{}
+	}{
+		{"int8", "128"},
+		{"int8", "-129"},
+		{"int256", overflow},
+		{"uint256", overflow},
+		{"int12", "1"},
+		{"fixed128x18", "1"},
+	} {
+		if packed, err := packMessage(HashVersionPacked, []string{tc.typ}, []interface{}{tc.value}, 0); err == nil {
+			t.Errorf("%s %v packed to %x", tc.typ, tc.value, packed)
+		}
+	}
+
+	for _, tc := range []struct {
+		typ   string
+		value interface{}
+	}{
+		{"fixed128x18", big.NewInt(1)},
+		{"uint256", "1"},
+		{"uint256", new(big.Int).Lsh(big.NewInt(1), 256)},
+		{"int8", big.NewInt(200)},
+	} {
+		if packed, err := SolidityPack([]string{tc.typ}, []interface{}{tc.value}); err == nil {
+			t.Errorf("SolidityPack %s %v: got %x", tc.typ, tc.value, packed)
+		}
+	}
+	if _, err := SolidityPack([]string{"string"}, nil); err == nil {
+		t.Error("SolidityPack accepted fewer values than types")
+	}
+
+	// Like intN, uint64 and address pack at their native width.
+	packed, err := SolidityPack([]string{"uint64", "address"}, []interface{}{uint64(1), [20]byte{19: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append(make([]byte, 7), 1), append(make([]byte, 19), 2)...)
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("uint64 and address packed to %x, want %x", packed, want)
+	}
+}
+
+func TestVerifyingReadsDoNotLog(t *testing.T) {
+	req := testSignRequest(t, "100", 1700000000)
+	packed, err := buildSignRequest("1", DataStructure{ID: 1, HashVersion: HashVersionPacked, Fields: []struct {
+		Name         string `json:"name"`
+		SolidityType string `json:"solidity_type"`
+	}{{Name: "ticker", SolidityType: "string"}, {Name: "price", SolidityType: "uint256"}}}, map[string]interface{}{"ticker": "SBER", "price": "100"}, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	messages := verifyMessages([]store.Message{
+		{Hash: req.Hash, Data: req.Data, DataStructure: req.DataStructure, Timestamp: req.Timestamp, HashVersion: req.HashVersion},
+		{Hash: packed.Hash, Data: packed.Data, DataStructure: packed.DataStructure, Timestamp: packed.Timestamp, HashVersion: packed.HashVersion},
+	})
+	for _, msg := range messages {
+		if msg.Verified == nil || !*msg.Verified {
+			t.Fatalf("message %s not verified", msg.Hash)
+		}
+	}
+	if out.Len() > 0 {
+		t.Fatalf("verifying stored messages logged %q", out.String())
+	}
+}
@@ -0,0 +1,120 @@
+package operator
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+const defaultHeartbeatInterval = 5 * time.Minute
+
+// Version names the operator build in heartbeats. Release builds set it with
+// -ldflags "-X bootstrap/pkg/operator.Version=v1.2.3"; otherwise it falls
+// back to the VCS revision Go embedded, if any.
+var Version string
+
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				return setting.Value[:12]
+			}
+		}
+	}
+	return "dev"
+}
+
+type HeartbeatConfig struct {
+	// Structure is the data structure heartbeats are published as.
+	Structure string `yaml:"structure"`
+	// Interval is the length of a heartbeat window; zero disables them.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Heartbeat publishes a message of Structure once per window of Interval,
+// attesting the operator's peer count and version. Structure needs "window"
+// (uint, the window start), "window_seconds" (uint), "peer_count" (uint),
+// "version" (string) and "timestamp" fields. Once co-signed, a heartbeat
+// shows on chain that the signer set was alive and quorate in its window,
+// and a missing one that it was not.
+type Heartbeat struct {
+	PubSub      *PubSubService
+	StructureID string
+	Structure   DataStructure
+	Interval    time.Duration
+	// Peers returns the number of peers the operator currently knows.
+	Peers func() int
+	// Clock defaults to the wall clock when nil.
+	Clock clock.Clock
+}
+
+func (h *Heartbeat) Run(ctx context.Context) {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	clk := orWallClock(h.Clock)
+
+	// Windows start on multiples of the interval, so every operator agrees
+	// on their boundaries.
+	next := func() time.Duration {
+		now := clk.Now()
+		return now.Truncate(interval).Add(interval).Sub(now)
+	}
+	timer := clk.Timer(next())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := h.publish(ctx, interval); err != nil {
+				log.Printf("Error publishing heartbeat: %v", err)
+			}
+			timer.Reset(next())
+		}
+	}
+}
+
+func (h *Heartbeat) publish(ctx context.Context, interval time.Duration) error {
+	now := orWallClock(h.Clock).Now()
+	timestamp := now.Unix()
+	window := now.Truncate(interval).Unix()
+	peers := h.Peers()
+
+	fields, err := h.Structure.NormalizeFields(map[string]interface{}{
+		"window":         window,
+		"window_seconds": int64(interval / time.Second),
+		"peer_count":     peers,
+		"version":        buildVersion(),
+		"timestamp":      timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	sr, err := buildSignRequest(h.StructureID, h.Structure, fields, timestamp)
+	if err != nil {
+		return err
+	}
+	if err := h.PubSub.PublishSignRequest(ctx, sr); err != nil {
+		return err
+	}
+
+	log.Printf("💓 Published heartbeat for window %d with %d peers as %s", window, peers, sr.Hash)
+	return nil
+}
+
+// peerCount returns the number of peers seen recently.
+func (o *OperatorNode) peerCount() int {
+	o.knownPeersMux.RLock()
+	defer o.knownPeersMux.RUnlock()
+
+	return len(o.knownPeers)
+}
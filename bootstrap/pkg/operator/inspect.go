@@ -0,0 +1,104 @@
+package operator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"bootstrap/pkg/store"
+)
+
+// InspectCommand checks a LevelDB store offline for inconsistencies left by
+// crashes or manual edits and, with -repair, fixes the indexes. The operator
+// must be stopped, as LevelDB locks its directory.
+func InspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	path := fs.String("path", "", "LevelDB directory (defaults to the configured one)")
+	repair := fs.Bool("repair", false, "delete orphaned keys and rebuild the indexes of stored messages")
+	show := fs.Int("show", 10, "keys or hashes listed per problem, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *path == "" {
+		cfg, err := LoadConfig(nil)
+		if err != nil {
+			return err
+		}
+		if cfg.DB.Backend != "leveldb" {
+			return fmt.Errorf("inspect supports the leveldb backend only, not %q", cfg.DB.Backend)
+		}
+		*path = cfg.DB.Path
+		if *path == "" {
+			*path = "data/leveldb"
+		}
+	}
+
+	var (
+		in     *store.Inspection
+		result store.Repair
+		err    error
+	)
+	if *repair {
+		in, result, err = store.RepairLevelDB(ctx, *path)
+	} else {
+		in, err = store.InspectLevelDB(ctx, *path)
+	}
+	if in != nil {
+		printInspection(in, *show)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *repair {
+		fmt.Printf("\nrepair: %d keys deleted, %d messages reindexed\n", result.Deleted, result.Reindexed)
+		printList("unrecoverable messages, in no structure's index", result.Unrecoverable, *show)
+		fmt.Println("run rebuild-stats to recount the stats of every structure")
+		return nil
+	}
+	if !in.Healthy() {
+		fmt.Println("\nproblems found; run inspect -repair to fix the indexes")
+	}
+	return nil
+}
+
+func printInspection(in *store.Inspection, show int) {
+	fmt.Printf("%d keys, %d messages\n", in.Keys, in.Messages)
+	for _, s := range in.Structures {
+		stats := "no stats"
+		if s.StatsMessages >= 0 {
+			stats = fmt.Sprintf("stats say %d", s.StatsMessages)
+			if s.StatsMessages != s.Messages {
+				stats += " (mismatch)"
+			}
+		}
+		fmt.Printf("structure %d %v: %d messages, %s\n", s.ID, s.Fields, s.Messages, stats)
+	}
+
+	printList("unreadable messages", in.UnreadableMessages, show)
+	printList("unindexed messages", in.UnindexedMessages, show)
+	printList("orphaned index keys", in.OrphanedIndexes, show)
+	printList("signatures without data", in.SignaturesWithoutData, show)
+	printList("latencies without data", in.OrphanedLatencies, show)
+	printList("malformed keys", in.MalformedKeys, show)
+}
+
+func printList(title string, items []string, show int) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("%s: %d\n", title, len(items))
+	for i, item := range items {
+		if show > 0 && i == show {
+			fmt.Printf("  ... %d more\n", len(items)-show)
+			break
+		}
+		fmt.Printf("  %q\n", item)
+	}
+}
@@ -0,0 +1,106 @@
+package operator
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// IntakeShed drops messages arriving while the intake queue is full.
+	IntakeShed = "shed"
+	// IntakeDropOldest evicts the oldest queued message to make room.
+	IntakeDropOldest = "drop_oldest"
+
+	defaultIntakeQueueSize = 4096
+)
+
+// IntakeConfig sizes the queue between the subscription reader and message
+// handling, which can stall on database writes.
+type IntakeConfig struct {
+	Size   int    `yaml:"size"`
+	Policy string `yaml:"policy"`
+}
+
+func (c IntakeConfig) withDefaults() (IntakeConfig, error) {
+	if c.Size <= 0 {
+		c.Size = defaultIntakeQueueSize
+	}
+	switch c.Policy {
+	case "":
+		c.Policy = IntakeDropOldest
+	case IntakeShed, IntakeDropOldest:
+	default:
+		return c, fmt.Errorf("unknown intake policy %q", c.Policy)
+	}
+	return c, nil
+}
+
+type incomingMessage struct {
+	from peer.ID
+	data []byte
+}
+
+// processIntake handles queued messages one at a time, so a sign request is
+// always handled before the responses gossiped after it.
+func (o *OperatorNode) processIntake() {
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case msg := <-o.intake:
+			intakeQueueDepth.Set(float64(len(o.intake)))
+			o.HandleMessage(msg.from, msg.data)
+		}
+	}
+}
+
+// enqueueIncoming hands a gossiped message to processIntake without blocking
+// the subscription reader. Our own messages are never turned away on
+// arrival: losing one would keep our request out of the pending set and off
+// the rebroadcast loop, so they wait for room instead.
+func (o *OperatorNode) enqueueIncoming(from peer.ID, data []byte) {
+	msg := incomingMessage{from: from, data: data}
+	defer func() { intakeQueueDepth.Set(float64(len(o.intake))) }()
+
+	select {
+	case o.intake <- msg:
+		return
+	default:
+	}
+
+	if from == o.host.ID() {
+		select {
+		case o.intake <- msg:
+		case <-o.ctx.Done():
+		}
+		return
+	}
+
+	if o.intakePolicy == IntakeShed {
+		intakeDroppedTotal.WithLabelValues(IntakeShed).Inc()
+		return
+	}
+
+	for {
+		select {
+		case o.intake <- msg:
+			return
+		default:
+		}
+		select {
+		case <-o.intake:
+			intakeDroppedTotal.WithLabelValues(IntakeDropOldest).Inc()
+		default:
+		}
+	}
+}
+
+// logIntakeDrops is called from the health monitor so a saturated queue is
+// visible in the logs without a line per dropped message.
+func (o *OperatorNode) logIntakeDrops() {
+	if depth := len(o.intake); depth == cap(o.intake) {
+		log.Printf("⚠️ Intake queue full (%d messages), policy %s", depth, o.intakePolicy)
+	}
+}
@@ -0,0 +1,100 @@
+package operator
+
+import (
+	"context"
+	"log"
+
+	"bootstrap/pkg/store"
+)
+
+// verifyingDatabase recomputes the hash of every message it reads and sets
+// Verified, so disk corruption or tampering with the local store shows up in
+// API responses instead of being served as signed data.
+type verifyingDatabase struct {
+	store.Database
+}
+
+func newVerifyingDatabase(db store.Database) store.Database {
+	return &verifyingDatabase{Database: db}
+}
+
+// verifyMessage sets msg.Verified from its recomputed hash.
+func verifyMessage(msg *store.Message) {
+	ok := hashMatches(*msg)
+	msg.Verified = &ok
+	if !ok {
+		storedHashMismatches.Inc()
+		log.Printf("🚨 Stored message %s no longer matches its hash", msg.Hash)
+	}
+}
+
+func verifyMessages(messages []store.Message) []store.Message {
+	for i := range messages {
+		verifyMessage(&messages[i])
+	}
+	return messages
+}
+
+func (v *verifyingDatabase) GetMessage(ctx context.Context, hash string) (store.Message, bool) {
+	msg, ok := v.Database.GetMessage(ctx, hash)
+	if ok {
+		verifyMessage(&msg)
+	}
+	return msg, ok
+}
+
+func (v *verifyingDatabase) GetAllMessages(ctx context.Context, dataStructureID int, page, limit int) ([]store.Message, error) {
+	messages, err := v.Database.GetAllMessages(ctx, dataStructureID, page, limit)
+	return verifyMessages(messages), err
+}
+
+func (v *verifyingDatabase) GetLatestMessage(ctx context.Context, dataStructureID int) (store.Message, bool, error) {
+	msg, ok, err := v.Database.GetLatestMessage(ctx, dataStructureID)
+	if ok {
+		verifyMessage(&msg)
+	}
+	return msg, ok, err
+}
+
+func (v *verifyingDatabase) GetLatestConfirmed(ctx context.Context, dataStructureID int) (store.Message, bool, error) {
+	msg, ok, err := v.Database.GetLatestConfirmed(ctx, dataStructureID)
+	if ok {
+		verifyMessage(&msg)
+	}
+	return msg, ok, err
+}
+
+func (v *verifyingDatabase) GetMessagesByField(ctx context.Context, dataStructureID int, field, value string, page, limit int) ([]store.Message, error) {
+	messages, err := v.Database.GetMessagesByField(ctx, dataStructureID, field, value, page, limit)
+	return verifyMessages(messages), err
+}
+
+func (v *verifyingDatabase) GetLatestByField(ctx context.Context, dataStructureID int, field, value string) (store.Message, bool, error) {
+	msg, ok, err := v.Database.GetLatestByField(ctx, dataStructureID, field, value)
+	if ok {
+		verifyMessage(&msg)
+	}
+	return msg, ok, err
+}
+
+func (v *verifyingDatabase) GetMessagesByRange(ctx context.Context, dataStructureID int, ranges []store.RangeFilter, equals []store.FieldFilter, page, limit int) ([]store.Message, error) {
+	messages, err := v.Database.GetMessagesByRange(ctx, dataStructureID, ranges, equals, page, limit)
+	return verifyMessages(messages), err
+}
+
+func (v *verifyingDatabase) GetConfirmedMessages(ctx context.Context, dataStructureID int, ranges []store.RangeFilter, equals []store.FieldFilter, page, limit int) ([]store.Message, error) {
+	messages, err := v.Database.GetConfirmedMessages(ctx, dataStructureID, ranges, equals, page, limit)
+	return verifyMessages(messages), err
+}
+
+func (v *verifyingDatabase) IterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(store.Message) bool) error {
+	return v.Database.IterateMessages(ctx, dataStructureID, from, to, func(msg store.Message) bool {
+		verifyMessage(&msg)
+		return fn(msg)
+	})
+}
+
+func (v *verifyingDatabase) GetMessagesBySequence(ctx context.Context, dataStructureID int, from, to uint64, confirmedOnly bool, limit int) ([]store.Message, error) {
+	messages, err := v.Database.GetMessagesBySequence(ctx, dataStructureID, from, to, confirmedOnly, limit)
+	return verifyMessages(messages), err
+}
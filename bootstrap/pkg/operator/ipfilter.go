@@ -0,0 +1,156 @@
+package operator
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parsePrefixes reads CIDRs, taking a bare IP as a single address.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP or CIDR %q", cidr)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedProxies resolves the client IP of requests that reach the API
+// through reverse proxies. X-Forwarded-For is believed only when the peer
+// is a trusted proxy, and is then read from the right, skipping the hops
+// that are trusted proxies themselves, so a client cannot pick its own IP
+// by sending the header. A nil TrustedProxies trusts no proxy.
+type TrustedProxies struct {
+	// anyPeer trusts whichever peer connects, but no further hops, as a
+	// single proxy in front of the API.
+	anyPeer  bool
+	prefixes []netip.Prefix
+}
+
+// NewTrustedProxies returns nil when no proxy is trusted.
+func NewTrustedProxies(anyPeer bool, cidrs []string) (*TrustedProxies, error) {
+	if !anyPeer && len(cidrs) == 0 {
+		return nil, nil
+	}
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	return &TrustedProxies{anyPeer: anyPeer, prefixes: prefixes}, nil
+}
+
+func (p *TrustedProxies) trusts(addr netip.Addr) bool {
+	return containsAddr(p.prefixes, addr)
+}
+
+// ClientIP returns the IP a request was made from.
+func (p *TrustedProxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if p == nil {
+		return host
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !(p.anyPeer || p.trusts(peer.Unmap())) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := host
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// A hop that is no IP was written by whoever is before the
+			// last trusted proxy; the proxy's view is all that is known.
+			break
+		}
+		client = addr.Unmap().String()
+		if !p.trusts(addr.Unmap()) {
+			break
+		}
+	}
+	return client
+}
+
+// IPFilter allows or denies requests by client IP. Deny rules win; with
+// allow rules, only the IPs they match get through. A nil IPFilter allows
+// everything.
+type IPFilter struct {
+	group   string
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	proxies *TrustedProxies
+}
+
+// NewIPFilter returns nil when there are no rules.
+func NewIPFilter(group string, allow, deny []string, proxies *TrustedProxies) (*IPFilter, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	f := &IPFilter{group: group, proxies: proxies}
+	var err error
+	if f.allow, err = parsePrefixes(allow); err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	if f.deny, err = parsePrefixes(deny); err != nil {
+		return nil, fmt.Errorf("denylist: %w", err)
+	}
+	return f, nil
+}
+
+func (f *IPFilter) allows(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if containsAddr(f.deny, addr) {
+		return false
+	}
+	return len(f.allow) == 0 || containsAddr(f.allow, addr)
+}
+
+// middleware answers 403 to clients the rules refuse.
+func (f *IPFilter) middleware(h http.HandlerFunc) http.HandlerFunc {
+	if f == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !f.allows(f.proxies.ClientIP(r)) {
+			apiIPDenied.WithLabelValues(f.group).Inc()
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
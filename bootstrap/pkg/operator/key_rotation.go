@@ -0,0 +1,216 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	MsgTypeKeyRotation = "key_rotation"
+
+	defaultKeyRotationOverlap = time.Hour
+)
+
+// keyRotationDigest is what the old key signs to authorize a rotation.
+func keyRotationDigest(oldAddress, newAddress string, timestamp int64) []byte {
+	payload := fmt.Sprintf("l0proof key rotation:%s:%s:%d", strings.ToLower(oldAddress), strings.ToLower(newAddress), timestamp)
+	return accounts.TextHash(cryptoeth.Keccak256([]byte(payload)))
+}
+
+// SetKeyRotationOverlap sets how long both keys of a rotating signer are
+// accepted.
+func (o *OperatorNode) SetKeyRotationOverlap(overlap time.Duration) {
+	o.trustedMux.Lock()
+	o.rotationOverlap = overlap
+	o.trustedMux.Unlock()
+}
+
+// trustedSigners returns a copy of the current trusted signer set.
+func (o *OperatorNode) trustedSigners() []string {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	return append([]string(nil), o.trustedAddrs...)
+}
+
+func (o *OperatorNode) isTrusted(addr common.Address) bool {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	if _, rotating := o.rotations[strings.ToLower(addr.Hex())]; rotating {
+		return true
+	}
+	return containsAddress(o.trustedAddrs, addr.Hex())
+}
+
+// seatOf maps a signer to the trusted address whose seat it fills, so the
+// old and new key of a rotating signer count once towards a threshold.
+func (o *OperatorNode) seatOf(addr common.Address) string {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	if rotation, ok := o.rotations[strings.ToLower(addr.Hex())]; ok {
+		return common.HexToAddress(rotation.OldAddress).Hex()
+	}
+	return addr.Hex()
+}
+
+// pendingRotations lists rotations still inside their overlap window.
+func (o *OperatorNode) pendingRotations() []store.KeyRotation {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	rotations := make([]store.KeyRotation, 0, len(o.rotations))
+	for _, rotation := range o.rotations {
+		rotations = append(rotations, *rotation)
+	}
+	return rotations
+}
+
+func containsAddress(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if strings.EqualFold(a, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleKeyRotation validates an announced rotation and opens its overlap
+// window. Repeated announcements of a known rotation are ignored.
+func (o *OperatorNode) handleKeyRotation(rotation *store.KeyRotation) error {
+	if !common.IsHexAddress(rotation.OldAddress) || !common.IsHexAddress(rotation.NewAddress) {
+		return fmt.Errorf("invalid address")
+	}
+
+	recovered, err := verifySignature(keyRotationDigest(rotation.OldAddress, rotation.NewAddress, rotation.Timestamp), rotation.Signature)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(recovered.Hex(), rotation.OldAddress) {
+		return fmt.Errorf("announcement is not signed by %s", rotation.OldAddress)
+	}
+
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	newKey := strings.ToLower(rotation.NewAddress)
+	if existing, ok := o.rotations[newKey]; ok && strings.EqualFold(existing.OldAddress, rotation.OldAddress) {
+		return nil
+	}
+	if !containsAddress(o.trustedAddrs, rotation.OldAddress) {
+		return fmt.Errorf("%s is not a trusted signer", rotation.OldAddress)
+	}
+	if containsAddress(o.trustedAddrs, rotation.NewAddress) || o.rotations[newKey] != nil {
+		return fmt.Errorf("%s is already trusted", rotation.NewAddress)
+	}
+	for _, existing := range o.rotations {
+		if strings.EqualFold(existing.OldAddress, rotation.OldAddress) {
+			return fmt.Errorf("%s is already rotating to %s", rotation.OldAddress, existing.NewAddress)
+		}
+	}
+
+	now := o.clock.Now()
+	record := *rotation
+	record.AnnouncedAt = now.Unix()
+	record.OverlapUntil = now.Add(o.rotationOverlap).Unix()
+	if err := o.db.StoreKeyRotation(o.ctx, record); err != nil {
+		return fmt.Errorf("failed to store key rotation: %w", err)
+	}
+	o.rotations[newKey] = &record
+	keyRotationsTotal.WithLabelValues("announced").Inc()
+
+	log.Printf("🔑 Signer %s rotating to %s, both keys accepted until %s", record.OldAddress, record.NewAddress, time.Unix(record.OverlapUntil, 0).UTC().Format(time.RFC3339))
+	return nil
+}
+
+// finalizeRotations replaces old keys with new ones in the trusted set once
+// their overlap window has passed.
+func (o *OperatorNode) finalizeRotations() {
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	now := o.clock.Now().Unix()
+	for newKey, rotation := range o.rotations {
+		if now < rotation.OverlapUntil {
+			continue
+		}
+
+		o.completeRotation(rotation)
+		delete(o.rotations, newKey)
+
+		rotation.Completed = true
+		if err := o.db.StoreKeyRotation(o.ctx, *rotation); err != nil {
+			log.Printf("Error storing completed key rotation: %v", err)
+		}
+		keyRotationsTotal.WithLabelValues("completed").Inc()
+		log.Printf("🔑 Signer %s replaced by %s", rotation.OldAddress, rotation.NewAddress)
+	}
+}
+
+// completeRotation swaps the old address for the new one. The caller holds
+// trustedMux.
+func (o *OperatorNode) completeRotation(rotation *store.KeyRotation) {
+	o.successors[strings.ToLower(rotation.OldAddress)] = rotation.NewAddress
+	if weight, ok := o.weights[strings.ToLower(rotation.OldAddress)]; ok {
+		delete(o.weights, strings.ToLower(rotation.OldAddress))
+		o.weights[strings.ToLower(rotation.NewAddress)] = weight
+	}
+	for i, addr := range o.trustedAddrs {
+		if strings.EqualFold(addr, rotation.OldAddress) {
+			o.trustedAddrs[i] = rotation.NewAddress
+			return
+		}
+	}
+}
+
+// restoreKeyRotations replays rotations recorded before a restart on top of
+// the configured trusted set.
+func (o *OperatorNode) restoreKeyRotations() {
+	rotations, err := o.db.GetKeyRotations(o.ctx)
+	if err != nil {
+		log.Printf("Error loading key rotations: %v", err)
+		return
+	}
+
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	for i := range rotations {
+		rotation := rotations[i]
+		if !containsAddress(o.trustedAddrs, rotation.OldAddress) {
+			continue
+		}
+		if rotation.Completed {
+			o.completeRotation(&rotation)
+			continue
+		}
+		o.rotations[strings.ToLower(rotation.NewAddress)] = &rotation
+	}
+}
+
+func (o *OperatorNode) handleKeyRotationMessage(data []byte) {
+	var rotation store.KeyRotation
+	if err := json.Unmarshal(data, &rotation); err != nil {
+		log.Printf("Error unmarshaling key rotation: %v", err)
+		return
+	}
+	if !o.timestampAcceptable(rotation.Timestamp) {
+		timestampSkewRejections.WithLabelValues(MsgTypeKeyRotation).Inc()
+		log.Printf("Rejecting key rotation from %s: timestamp %d outside allowed skew", rotation.OldAddress, rotation.Timestamp)
+		return
+	}
+	if err := o.handleKeyRotation(&rotation); err != nil {
+		keyRotationsTotal.WithLabelValues("rejected").Inc()
+		log.Printf("Rejecting key rotation %s -> %s: %v", rotation.OldAddress, rotation.NewAddress, err)
+	}
+}
@@ -0,0 +1,241 @@
+package operator
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	LatencyStagePublish        = "publish"
+	LatencyStageFirstSignature = "first_signature"
+	LatencyStageThreshold      = "threshold"
+
+	latencyWindowSize = 1000
+	// latencyWriteBuffer bounds the latency records waiting to be stored.
+	latencyWriteBuffer = 1024
+)
+
+type LatencyPercentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// LatencyTracker follows every hash from build to threshold confirmation,
+// persists the timestamps alongside the message and keeps a rolling window of
+// recent samples for percentile reporting.
+//
+// It is driven by operator events, which arrive with pendingMux held, so
+// timestamps are stored by a writer goroutine of its own. Records that find
+// the writer too far behind are dropped.
+type LatencyTracker struct {
+	db       store.Database
+	mu       sync.Mutex
+	inflight map[string]*store.MessageLatency
+	samples  map[string][]float64
+
+	writes    chan latencyRecord
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// latencyRecord is a latency waiting to be stored.
+type latencyRecord struct {
+	hash    string
+	latency store.MessageLatency
+}
+
+func NewLatencyTracker(db store.Database) *LatencyTracker {
+	t := &LatencyTracker{
+		db:       db,
+		inflight: make(map[string]*store.MessageLatency),
+		samples:  make(map[string][]float64),
+		writes:   make(chan latencyRecord, latencyWriteBuffer),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go t.writeLoop()
+	return t
+}
+
+// Close stores the records still queued and stops the writer. Records
+// persisted afterwards are dropped.
+func (t *LatencyTracker) Close() {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.stopped
+	})
+}
+
+func (t *LatencyTracker) writeLoop() {
+	defer close(t.stopped)
+	for {
+		select {
+		case r := <-t.writes:
+			t.store(r)
+		case <-t.stop:
+			for {
+				select {
+				case r := <-t.writes:
+					t.store(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *LatencyTracker) store(r latencyRecord) {
+	if err := t.db.StoreLatency(context.Background(), r.hash, r.latency); err != nil {
+		log.Printf("Error storing latency for %s: %v", r.hash, err)
+	}
+}
+
+func (t *LatencyTracker) MarkBuilt(hash string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.inflight[hash]; !exists {
+		t.inflight[hash] = &store.MessageLatency{BuiltAt: at.UnixMilli()}
+	}
+}
+
+func (t *LatencyTracker) MarkPublished(hash string, at time.Time) {
+	t.mark(hash, LatencyStagePublish, at, false)
+}
+
+func (t *LatencyTracker) MarkFirstSignature(hash string, at time.Time) {
+	t.mark(hash, LatencyStageFirstSignature, at, false)
+}
+
+// MarkThreshold records confirmation, persists the timestamps and stops
+// tracking the hash.
+func (t *LatencyTracker) MarkThreshold(hash string, at time.Time) {
+	t.mark(hash, LatencyStageThreshold, at, true)
+}
+
+// Forget persists whatever stages were reached for a hash that will never be
+// confirmed and drops it from the in-flight set.
+func (t *LatencyTracker) Forget(hash string) {
+	t.mu.Lock()
+	lat, exists := t.inflight[hash]
+	delete(t.inflight, hash)
+	t.mu.Unlock()
+
+	if exists {
+		t.persist(hash, *lat)
+	}
+}
+
+// Drop stops tracking a hash that was never published, keeping nothing of it.
+func (t *LatencyTracker) Drop(hash string) {
+	t.mu.Lock()
+	delete(t.inflight, hash)
+	t.mu.Unlock()
+}
+
+func (t *LatencyTracker) mark(hash, stage string, at time.Time, final bool) {
+	t.mu.Lock()
+	lat, exists := t.inflight[hash]
+	if !exists {
+		t.mu.Unlock()
+		return
+	}
+
+	var field *int64
+	switch stage {
+	case LatencyStagePublish:
+		field = &lat.PublishedAt
+	case LatencyStageFirstSignature:
+		field = &lat.FirstSignatureAt
+	case LatencyStageThreshold:
+		field = &lat.ThresholdAt
+	}
+	if *field != 0 {
+		t.mu.Unlock()
+		return
+	}
+	*field = at.UnixMilli()
+
+	elapsed := float64(*field - lat.BuiltAt)
+	t.addSample(stage, elapsed)
+	messageLatencySeconds.WithLabelValues(stage).Observe(elapsed / 1000)
+
+	snapshot := *lat
+	if final {
+		delete(t.inflight, hash)
+	}
+	t.mu.Unlock()
+
+	if final {
+		t.persist(hash, snapshot)
+	}
+}
+
+func (t *LatencyTracker) addSample(stage string, ms float64) {
+	window := append(t.samples[stage], ms)
+	if len(window) > latencyWindowSize {
+		window = window[len(window)-latencyWindowSize:]
+	}
+	t.samples[stage] = window
+}
+
+// persist queues lat for the writer without blocking.
+func (t *LatencyTracker) persist(hash string, lat store.MessageLatency) {
+	select {
+	case <-t.stop:
+		latencyWritesDroppedTotal.Inc()
+		return
+	default:
+	}
+	select {
+	case t.writes <- latencyRecord{hash: hash, latency: lat}:
+	default:
+		latencyWritesDroppedTotal.Inc()
+		log.Printf("Latency writer behind, dropping latency of %s", hash)
+	}
+}
+
+// Percentiles reports latency percentiles per stage over the recent window.
+func (t *LatencyTracker) Percentiles() map[string]LatencyPercentiles {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[string]LatencyPercentiles)
+	for _, stage := range []string{LatencyStagePublish, LatencyStageFirstSignature, LatencyStageThreshold} {
+		result[stage] = NewLatencyPercentiles(t.samples[stage])
+	}
+
+	return result
+}
+
+// NewLatencyPercentiles summarizes latency samples in milliseconds.
+func NewLatencyPercentiles(samples []float64) LatencyPercentiles {
+	if len(samples) == 0 {
+		return LatencyPercentiles{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	return LatencyPercentiles{
+		Count: len(sorted),
+		P50:   percentile(sorted, 0.5),
+		P90:   percentile(sorted, 0.9),
+		P99:   percentile(sorted, 0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
@@ -0,0 +1,122 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"bootstrap/pkg/store"
+)
+
+func (t *LatencyTracker) tracking(hash string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.inflight[hash]
+	return ok
+}
+
+// failingStoreDatabase refuses to store messages.
+type failingStoreDatabase struct {
+	store.Database
+}
+
+func (failingStoreDatabase) StoreData(context.Context, string, []interface{}, []string, []string, int64, int, int, string, uint64, uint64) error {
+	return errors.New("disk full")
+}
+
+func TestLatencyForgetsExpiredRequests(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetPendingTimings(time.Minute, 0, 0)
+
+	req := op.request(t, "100")
+	op.latency.MarkBuilt(req.Hash, mock.Now())
+	mock.Add(2 * time.Minute)
+	op.cleanupExpiredRequests()
+
+	if op.latency.tracking(req.Hash) {
+		t.Fatal("expired request still tracked")
+	}
+	op.latency.Close()
+	if lat, ok := op.db.GetLatency(context.Background(), req.Hash); !ok || lat.BuiltAt == 0 {
+		t.Fatalf("latency of the expired request: got %+v (stored %v), want its build time", lat, ok)
+	}
+}
+
+// blockingLatencyDatabase holds every latency stored until release is
+// closed.
+type blockingLatencyDatabase struct {
+	store.Database
+	release chan struct{}
+}
+
+func (d *blockingLatencyDatabase) StoreLatency(ctx context.Context, hash string, latency store.MessageLatency) error {
+	<-d.release
+	return d.Database.StoreLatency(ctx, hash, latency)
+}
+
+func TestLatencyStoredOffTheConfirmingGoroutine(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	db := &blockingLatencyDatabase{release: make(chan struct{})}
+	op := newTestOperator(t, mock, 3, func(inner store.Database) store.Database {
+		db.Database = inner
+		return db
+	})
+	var release sync.Once
+	t.Cleanup(func() { release.Do(func() { close(db.release) }) })
+
+	req := op.request(t, "100")
+	op.latency.MarkBuilt(req.Hash, mock.Now())
+	confirmed := make(chan struct{})
+	go func() {
+		defer close(confirmed)
+		for i := range op.signers {
+			op.respond(t, req, i)
+		}
+	}()
+	select {
+	case <-confirmed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("confirmation waited for the latency to be stored")
+	}
+	if op.isPending(req.Hash) {
+		t.Fatal("request not confirmed")
+	}
+
+	release.Do(func() { close(db.release) })
+	op.latency.Close()
+	if lat, ok := op.db.GetLatency(context.Background(), req.Hash); !ok || lat.ThresholdAt == 0 {
+		t.Fatalf("latency of the confirmed request: got %+v (stored %v), want its threshold time", lat, ok)
+	}
+}
+
+func TestLatencyForgetsFailedPublishes(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, func(db store.Database) store.Database {
+		return failingStoreDatabase{db}
+	})
+
+	req := testSignRequest(t, "100", mock.Now().Unix())
+	op.latency.MarkBuilt(req.Hash, mock.Now())
+	if err := op.publisher().PublishSignRequest(context.Background(), req); err == nil {
+		t.Fatal("publish without storing succeeded")
+	}
+	if op.latency.tracking(req.Hash) {
+		t.Fatal("request that failed to publish still tracked")
+	}
+
+	// Gossip failures are reported once the retries run out.
+	req = testSignRequest(t, "101", mock.Now().Unix())
+	op.latency.MarkBuilt(req.Hash, mock.Now())
+	op.events.Emit(Event{Type: EventPublishFailed, Hash: req.Hash, At: mock.Now()})
+	if op.latency.tracking(req.Hash) {
+		t.Fatal("request whose publish failed still tracked")
+	}
+}
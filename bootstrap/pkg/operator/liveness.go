@@ -0,0 +1,232 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	MsgTypeSignerHeartbeat = "signer_heartbeat"
+
+	// defaultLivenessWindow is how long a signer counts as live after its
+	// last heartbeat, three of the signers' default heartbeat intervals.
+	defaultLivenessWindow = 90 * time.Second
+	// livenessCheckInterval is how often the quorum is checked against the
+	// liveness registry.
+	livenessCheckInterval = 15 * time.Second
+)
+
+// SignerHeartbeat is published by every signing address of a node at a
+// fixed interval. Timestamp is in Unix milliseconds so the delay it took
+// to arrive can be told apart from the interval.
+type SignerHeartbeat struct {
+	Type      string `json:"type"`
+	Address   string `json:"address"`
+	Software  string `json:"software,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+}
+
+// signerHeartbeatDigest is what a signer signs to vouch for a heartbeat.
+func signerHeartbeatDigest(address, software string, timestamp int64) []byte {
+	payload := fmt.Sprintf("l0proof signer heartbeat:%s:%s:%d", strings.ToLower(address), software, timestamp)
+	return accounts.TextHash(cryptoeth.Keccak256([]byte(payload)))
+}
+
+// SignerLiveness is one row of the liveness registry. Rows for trusted
+// addresses never heard from are not live and have no LastSeen.
+type SignerLiveness struct {
+	Address         string  `json:"address"`
+	Software        string  `json:"software,omitempty"`
+	ProtocolVersion string  `json:"protocol_version,omitempty"`
+	LastSeen        int64   `json:"last_seen,omitempty"`
+	LatencyMs       float64 `json:"latency_ms,omitempty"`
+	Live            bool    `json:"live"`
+	Trusted         bool    `json:"trusted"`
+}
+
+// LivenessReport is the registry together with how it stands against the
+// default threshold.
+type LivenessReport struct {
+	Live         int              `json:"live"`
+	Threshold    int              `json:"threshold"`
+	QuorumAtRisk bool             `json:"quorum_at_risk"`
+	Window       string           `json:"window"`
+	Signers      []SignerLiveness `json:"signers"`
+}
+
+// livenessRegistry holds the last heartbeat of every signer, keyed by
+// lowercased address. The quorum is not judged until a window has passed
+// since startup, giving signers time to be heard from.
+type livenessRegistry struct {
+	mu      sync.Mutex
+	window  time.Duration
+	since   time.Time
+	signers map[string]*SignerLiveness
+	atRisk  bool
+	// pauseWhenAtRisk holds back new sign requests while atRisk.
+	pauseWhenAtRisk bool
+}
+
+func newLivenessRegistry(now time.Time) *livenessRegistry {
+	return &livenessRegistry{window: defaultLivenessWindow, since: now, signers: make(map[string]*SignerLiveness)}
+}
+
+func (l *livenessRegistry) observe(hb SignerHeartbeat, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := strings.ToLower(hb.Address)
+	e, ok := l.signers[key]
+	if !ok {
+		e = &SignerLiveness{Address: hb.Address}
+		l.signers[key] = e
+	}
+	e.Software = hb.Software
+	e.ProtocolVersion = hb.ProtocolVersion
+	e.LastSeen = now.Unix()
+	e.LatencyMs = float64(max(now.UnixMilli()-hb.Timestamp, 0))
+}
+
+// report lists every known signer and every trusted address, sorted by
+// address. A rotating signer's heartbeats keep its seat live.
+func (l *livenessRegistry) report(trusted []string, seatOf func(string) string, threshold int, now time.Time) LivenessReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rows := make(map[string]SignerLiveness, len(l.signers)+len(trusted))
+	for _, address := range trusted {
+		rows[strings.ToLower(address)] = SignerLiveness{Address: address}
+	}
+	for key, e := range l.signers {
+		row := *e
+		row.Live = now.Sub(time.Unix(e.LastSeen, 0)) <= l.window
+		if existing, ok := rows[key]; ok {
+			row.Address = existing.Address
+		}
+		rows[key] = row
+	}
+
+	liveSeats := make(map[string]bool)
+	signers := make([]SignerLiveness, 0, len(rows))
+	for _, row := range rows {
+		seat := seatOf(row.Address)
+		row.Trusted = containsAddress(trusted, seat)
+		if row.Trusted && row.Live {
+			liveSeats[strings.ToLower(seat)] = true
+		}
+		signers = append(signers, row)
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return strings.ToLower(signers[i].Address) < strings.ToLower(signers[j].Address)
+	})
+
+	return LivenessReport{
+		Live:         len(liveSeats),
+		Threshold:    threshold,
+		QuorumAtRisk: len(liveSeats) < threshold,
+		Window:       l.window.String(),
+		Signers:      signers,
+	}
+}
+
+// SetLivenessWindow sets how long a signer counts as live after its last
+// heartbeat.
+func (o *OperatorNode) SetLivenessWindow(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	o.liveness.mu.Lock()
+	o.liveness.window = window
+	o.liveness.mu.Unlock()
+}
+
+// SignerLiveness returns the liveness registry.
+func (o *OperatorNode) SignerLiveness() LivenessReport {
+	seatOf := func(address string) string {
+		return o.seatOf(common.HexToAddress(address))
+	}
+	return o.liveness.report(o.trustedSigners(), seatOf, o.threshold(), o.clock.Now())
+}
+
+func (o *OperatorNode) handleSignerHeartbeat(data []byte) bool {
+	var hb SignerHeartbeat
+	if err := json.Unmarshal(data, &hb); err != nil {
+		log.Printf("Error unmarshaling signer heartbeat: %v", err)
+		return false
+	}
+	if !o.timestampAcceptable(hb.Timestamp / 1000) {
+		timestampSkewRejections.WithLabelValues(MsgTypeSignerHeartbeat).Inc()
+		return true
+	}
+	signer, err := verifySignature(signerHeartbeatDigest(hb.Address, hb.Software, hb.Timestamp), hb.Signature)
+	if err != nil || !strings.EqualFold(signer.Hex(), hb.Address) {
+		log.Printf("Rejecting heartbeat claiming to be from %s: bad signature", hb.Address)
+		return false
+	}
+	if !o.isTrusted(signer) {
+		return true
+	}
+	hb.Address = signer.Hex()
+	o.liveness.observe(hb, o.clock.Now())
+	o.versions.observeMessage(hb.Address, hb.ProtocolVersion, o.clock.Now())
+	return true
+}
+
+// livenessMonitor warns whenever fewer trusted signers are live than the
+// default threshold needs, and again once enough are back.
+func (o *OperatorNode) livenessMonitor() {
+	ticker := o.clock.Ticker(livenessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.checkLiveness()
+		}
+	}
+}
+
+func (o *OperatorNode) checkLiveness() {
+	report := o.SignerLiveness()
+	liveSigners.Set(float64(report.Live))
+
+	o.liveness.mu.Lock()
+	if o.clock.Since(o.liveness.since) < o.liveness.window {
+		o.liveness.mu.Unlock()
+		return
+	}
+	changed := o.liveness.atRisk != report.QuorumAtRisk
+	o.liveness.atRisk = report.QuorumAtRisk
+	o.liveness.mu.Unlock()
+
+	if report.QuorumAtRisk {
+		quorumAtRisk.Set(1)
+	} else {
+		quorumAtRisk.Set(0)
+	}
+	if !changed {
+		return
+	}
+	event := Event{Type: EventQuorumRestored, Live: report.Live, Threshold: report.Threshold, At: o.clock.Now()}
+	if report.QuorumAtRisk {
+		event.Type = EventQuorumAtRisk
+		log.Printf("⚠️ Quorum at risk: %d trusted signers live, %d needed", report.Live, report.Threshold)
+	} else {
+		log.Printf("✅ Quorum restored: %d trusted signers live, %d needed", report.Live, report.Threshold)
+	}
+	o.events.Emit(event)
+}
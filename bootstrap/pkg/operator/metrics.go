@@ -0,0 +1,281 @@
+package operator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var latencyObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
+var (
+	messageLatencySeconds = promauto.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  "l0proof",
+		Name:       "message_latency_seconds",
+		Help:       "Time from message build to each pipeline stage.",
+		Objectives: latencyObjectives,
+	}, []string{"stage"})
+
+	publishDeduplicatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "publish_deduplicated_total",
+		Help:      "Sign requests not re-published because the hash was already known.",
+	}, []string{"reason"})
+
+	signRequestsDeduplicated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "sign_requests_deduplicated_total",
+		Help:      "Gossiped sign requests not tracked because the hash needs no more work, by reason.",
+	}, []string{"reason"})
+
+	pendingRequestsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "pending_requests",
+		Help:      "Sign requests currently collecting signatures.",
+	})
+
+	foreignSignRequestsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "foreign_sign_requests_dropped_total",
+		Help:      "Sign requests ignored because another peer originated them.",
+	})
+
+	originatedSignRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "originated_sign_requests_total",
+		Help:      "Sign requests originated by nodes, by outcome.",
+	}, []string{"result"})
+
+	liveSigners = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "live_signers",
+		Help:      "Trusted signers heard from within the liveness window.",
+	})
+
+	quorumAtRisk = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "quorum_at_risk",
+		Help:      "1 while fewer trusted signers are live than the default threshold needs.",
+	})
+
+	publishPausedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "publish_paused_total",
+		Help:      "Sign requests not published because the quorum was at risk.",
+	})
+
+	priceBoundsRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "price_bounds_rejections_total",
+		Help:      "Prices not published for falling outside their job's bounds, by ticker.",
+	}, []string{"ticker"})
+
+	deadLettersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "dead_letters_total",
+		Help:      "Requests recorded as dead letters, by reason.",
+	}, []string{"reason"})
+
+	redrivenTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "redriven_total",
+		Help:      "Unconfirmed stored messages published again by a redrive.",
+	})
+
+	webhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "webhook_deliveries_total",
+		Help:      "Alert webhook deliveries, by result.",
+	}, []string{"result"})
+
+	timestampSkewRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "timestamp_skew_rejections_total",
+		Help:      "Messages rejected because their timestamp was too far from local time.",
+	}, []string{"type"})
+
+	pendingEvictionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "pending_evictions_total",
+		Help:      "Pending sign requests evicted because the pending map was full.",
+	})
+
+	keyRotationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "key_rotations_total",
+		Help:      "Signer key rotations by stage: announced, completed or rejected.",
+	}, []string{"stage"})
+
+	auditEntriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "audit_entries_total",
+		Help:      "Entries appended to the audit log, by operation.",
+	}, []string{"op"})
+
+	rateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "http_rate_limited_total",
+		Help:      "HTTP requests refused with 429, by route group and the bucket (ip or global) that ran dry.",
+	}, []string{"group", "scope"})
+
+	conflictsDetected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "conflicts_detected_total",
+		Help:      "Pairs of different hashes published for the same round.",
+	})
+
+	signResponsesRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "sign_responses_rejected_total",
+		Help:      "Sign responses ignored, by reason.",
+	}, []string{"reason"})
+
+	operatorEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "operator_events_total",
+		Help:      "Signature collection events emitted on the operator event bus.",
+	}, []string{"event"})
+
+	intakeQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "intake_queue_depth",
+		Help:      "Gossiped messages waiting to be handled.",
+	})
+
+	intakeDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "intake_dropped_total",
+		Help:      "Gossiped messages dropped because the intake queue was full, by policy.",
+	}, []string{"policy"})
+
+	syncRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "sync_requests_total",
+		Help:      "Catch-up sync requests served, by result.",
+	}, []string{"result"})
+
+	syncMessagesServed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "sync_messages_served_total",
+		Help:      "Confirmed messages sent in catch-up sync responses.",
+	})
+
+	replicationStandby = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "replication_standby",
+		Help:      "1 while the operator is a standby replicating from a primary.",
+	})
+
+	replicationEntriesApplied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "replication_entries_applied_total",
+		Help:      "Replication entries applied by a standby, by operation.",
+	}, []string{"op"})
+
+	replicationDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "replication_replicas_dropped_total",
+		Help:      "Replication streams cut off because the standby fell too far behind.",
+	})
+
+	latencyWritesDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "latency_writes_dropped_total",
+		Help:      "Latency records not stored because the latency writer fell behind or was closed.",
+	})
+
+	storedHashMismatches = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "stored_hash_mismatches_total",
+		Help:      "Messages read back whose stored fields no longer hash to their key.",
+	})
+
+	archivedMessagesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "archived_messages_total",
+		Help:      "Messages copied to the archive ahead of pruning.",
+	})
+
+	archiveReadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "archive_reads_total",
+		Help:      "Queries that fell back to the archive, by result.",
+	}, []string{"result"})
+
+	incompatibleMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "incompatible_messages_total",
+		Help:      "Messages refused because their protocol major differs from the operator's, by type.",
+	}, []string{"type"})
+
+	stakingEpoch = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "staking_epoch",
+		Help:      "Staking epoch whose signer set is in force.",
+	})
+
+	stakingSigners = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "staking_signers",
+		Help:      "Signers in the set of the current staking epoch.",
+	})
+
+	signerProtocolVersions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "signer_protocol_versions",
+		Help:      "Signer addresses by the protocol and software version last seen from them.",
+	}, []string{"protocol_version", "software"})
+
+	rewardSignatures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "reward_signatures_total",
+		Help:      "Signatures on confirmed messages credited towards rewards, by signer seat.",
+	}, []string{"signer"})
+
+	apiQuotaRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "api_quota_rejections_total",
+		Help:      "Public API requests refused because the API key used up a monthly quota.",
+	}, []string{"key", "quota"})
+
+	apiOpenConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "api_open_connections",
+		Help:      "Open connections to the HTTP API.",
+	})
+
+	slowClientDisconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "api_slow_client_disconnects_total",
+		Help:      "Streaming responses ended because the client stopped reading within the stream write timeout, by endpoint.",
+	}, []string{"endpoint"})
+
+	apiActiveStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Name:      "api_active_streams",
+		Help:      "Long-running API responses in progress, by kind: events, export or backup.",
+	}, []string{"kind"})
+
+	adminLoginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "admin_logins_total",
+		Help:      "Sign-In with Ethereum admin logins, by result: ok or refused.",
+	}, []string{"result"})
+
+	coalescedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "api_coalesced_requests_total",
+		Help:      "Public API requests answered from a database read shared with identical concurrent requests, by endpoint.",
+	}, []string{"endpoint"})
+
+	apiAccessDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "api_access_denied_total",
+		Help:      "API requests refused for lacking the role their route requires, by required role.",
+	}, []string{"role"})
+
+	apiIPDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Name:      "api_ip_denied_total",
+		Help:      "API requests refused by the IP allow and deny rules of their route group.",
+	}, []string{"group"})
+)
@@ -0,0 +1,67 @@
+package operator
+
+import (
+	"fmt"
+
+	"github.com/libp2p/go-libp2p"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const defaultListenAddr = "/ip4/0.0.0.0/tcp/4001"
+
+// NetworkConfig sets where the libp2p host listens and what it tells peers
+// to dial. ListenAddresses defaults to defaultListenAddr. AnnounceAddresses,
+// when set, replace the listen addresses in identify and peer records, for
+// operators behind NAT, a Docker port mapping or a Kubernetes service.
+type NetworkConfig struct {
+	ListenAddresses   []string `yaml:"listen_addresses"`
+	AnnounceAddresses []string `yaml:"announce_addresses"`
+}
+
+// Options converts the config into libp2p host options.
+func (c NetworkConfig) Options() ([]libp2p.Option, error) {
+	listen, err := c.listenAddrs()
+	if err != nil {
+		return nil, err
+	}
+	opts := []libp2p.Option{libp2p.ListenAddrs(listen...)}
+
+	announce, err := c.announceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	if len(announce) > 0 {
+		opts = append(opts, libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr {
+			return announce
+		}))
+	}
+	return opts, nil
+}
+
+func (c NetworkConfig) listenAddrs() ([]ma.Multiaddr, error) {
+	if len(c.ListenAddresses) == 0 {
+		return []ma.Multiaddr{ma.StringCast(defaultListenAddr)}, nil
+	}
+	return parseHostAddrs("listen", c.ListenAddresses)
+}
+
+func (c NetworkConfig) announceAddrs() ([]ma.Multiaddr, error) {
+	return parseHostAddrs("announce", c.AnnounceAddresses)
+}
+
+// parseHostAddrs parses multiaddrs of this host. They must not carry a /p2p
+// component; the host appends its own peer ID.
+func parseHostAddrs(kind string, list []string) ([]ma.Multiaddr, error) {
+	addrs := make([]ma.Multiaddr, 0, len(list))
+	for _, s := range list {
+		addr, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s address %q: %w", kind, s, err)
+		}
+		if _, err := addr.ValueForProtocol(ma.P_P2P); err == nil {
+			return nil, fmt.Errorf("%s address %q must not include /p2p", kind, s)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,90 @@
+package operator
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+
+	"bootstrap/pkg/store"
+)
+
+// SetOriginators accepts sign requests that nodes built from data they
+// collected themselves, as long as one of addrs signed them.
+func (o *OperatorNode) SetOriginators(addrs []string) {
+	o.acceptMux.Lock()
+	defer o.acceptMux.Unlock()
+
+	o.originators = append([]string(nil), addrs...)
+}
+
+func (o *OperatorNode) isOriginator(addr string) bool {
+	o.acceptMux.RLock()
+	defer o.acceptMux.RUnlock()
+
+	return containsAddress(o.originators, addr)
+}
+
+// verifyOrigin checks that req was signed by the originator it names and
+// that its hash covers its data, so that what is stored is what the
+// originator vouched for. It returns the metric label of the failure.
+func (o *OperatorNode) verifyOrigin(req *SignRequest) (string, error) {
+	if !o.isOriginator(req.Origin) {
+		return "unknown_originator", fmt.Errorf("%s is not an originator", req.Origin)
+	}
+	hash, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		return "malformed_hash", err
+	}
+	signer, err := verifySignature(accounts.TextHash(hash), req.OriginSignature)
+	if err != nil {
+		return "bad_signature", err
+	}
+	if !strings.EqualFold(signer.Hex(), req.Origin) {
+		return "bad_signature", fmt.Errorf("signed by %s", signer.Hex())
+	}
+	computed, err := computeHash(req.HashVersion, req.DataStructure, req.Data, req.Timestamp)
+	if err != nil {
+		return "hash_mismatch", err
+	}
+	if !strings.EqualFold(computed, req.Hash) {
+		return "hash_mismatch", fmt.Errorf("data hashes to %s", computed)
+	}
+	return "", nil
+}
+
+// handleOriginatedRequest stores a sign request a node originated and
+// tracks it like one of our own; signers received it on the same gossip.
+// It reports false when the request does not verify, which counts against
+// the peer that published it.
+func (o *OperatorNode) handleOriginatedRequest(ctx context.Context, req *SignRequest) bool {
+	if reason, err := o.verifyOrigin(req); err != nil {
+		originatedSignRequestsTotal.WithLabelValues(reason).Inc()
+		log.Printf("Dropping sign request %s originated by %s: %v [req=%s]", req.Hash, req.Origin, err, req.RequestID)
+		return reason == "unknown_originator"
+	}
+
+	if _, stored := o.db.GetMessage(ctx, req.Hash); !stored {
+		seq, err := o.nextSequence(ctx, req.DataStructureId)
+		if err != nil {
+			log.Printf("Error taking a sequence for %s: %v", req.Hash, err)
+			return true
+		}
+		req.Sequence = seq
+		req.Epoch = o.currentEpoch()
+		err = o.db.StoreData(ctx, req.Hash, req.Data, req.DataStructure, req.DataStructureMeta, req.Timestamp, req.DataStructureId, req.HashVersion, req.RequestID, req.Sequence, req.Epoch)
+		if err != nil && !errors.Is(err, store.ErrAlreadyStored) {
+			log.Printf("Error storing sign request %s originated by %s: %v", req.Hash, req.Origin, err)
+			return true
+		}
+	}
+
+	originatedSignRequestsTotal.WithLabelValues("accepted").Inc()
+	log.Printf("📥 Sign request %s originated by %s [req=%s]", req.Hash, req.Origin, req.RequestID)
+	o.handleSignRequest(ctx, req)
+	return true
+}
@@ -0,0 +1,215 @@
+package operator
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	// peerPersistInterval is how often the peer book is written to the
+	// database. It is also written on shutdown.
+	peerPersistInterval = time.Minute
+	// peerRecordMaxAge drops peers not seen for this long instead of
+	// dialing them again after a restart.
+	peerRecordMaxAge = 7 * 24 * time.Hour
+	// peerInvalidTolerance is how many invalid messages a peer may send
+	// before it needs at least as many valid ones to be redialed.
+	peerInvalidTolerance = 10
+)
+
+// reputable reports whether a peer is worth dialing: it has not sent more
+// invalid messages than valid ones, beyond a small tolerance.
+func reputable(record *store.PeerRecord) bool {
+	return record.InvalidMessages < peerInvalidTolerance || record.InvalidMessages <= record.ValidMessages
+}
+
+// peerRecord returns the reputation entry of id, creating it. Callers hold
+// knownPeersMux.
+func (o *OperatorNode) peerRecord(id peer.ID) *store.PeerRecord {
+	record, ok := o.reputation[id]
+	if !ok {
+		record = &store.PeerRecord{ID: id.String()}
+		o.reputation[id] = record
+	}
+	return record
+}
+
+func (o *OperatorNode) notePeerConnected(id peer.ID) {
+	now := o.clock.Now()
+
+	o.knownPeersMux.Lock()
+	defer o.knownPeersMux.Unlock()
+
+	o.knownPeers[id] = now
+	record := o.peerRecord(id)
+	record.LastSeen = now.UnixMilli()
+	record.FailedDials = 0
+}
+
+// notePeerMessage counts a gossiped message towards the reputation of the
+// peer that published it.
+func (o *OperatorNode) notePeerMessage(from peer.ID, valid bool) {
+	if from == "" || from == o.host.ID() {
+		return
+	}
+
+	o.knownPeersMux.Lock()
+	defer o.knownPeersMux.Unlock()
+
+	record := o.peerRecord(from)
+	if valid {
+		record.ValidMessages++
+		record.LastSeen = o.clock.Now().UnixMilli()
+	} else {
+		record.InvalidMessages++
+	}
+}
+
+func (o *OperatorNode) notePeerDialFailed(id peer.ID) {
+	o.knownPeersMux.Lock()
+	defer o.knownPeersMux.Unlock()
+
+	o.peerRecord(id).FailedDials++
+}
+
+// restorePeers loads the peer book saved by a previous run, puts the
+// addresses back into the peerstore and dials the reputable peers, so a
+// restart does not begin with an empty peerstore.
+func (o *OperatorNode) restorePeers() {
+	records, err := o.db.GetPeers(o.ctx)
+	if err != nil {
+		log.Printf("Error loading peers: %v", err)
+		return
+	}
+
+	cutoff := o.clock.Now().Add(-peerRecordMaxAge).UnixMilli()
+	var stale []string
+	var dial []peer.AddrInfo
+	o.knownPeersMux.Lock()
+	for i := range records {
+		record := records[i]
+		id, err := peer.Decode(record.ID)
+		if err != nil || id == o.host.ID() || record.LastSeen < cutoff {
+			stale = append(stale, record.ID)
+			continue
+		}
+
+		addrs := make([]ma.Multiaddr, 0, len(record.Addrs))
+		for _, s := range record.Addrs {
+			if addr, err := ma.NewMultiaddr(s); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+		o.host.Peerstore().AddAddrs(id, addrs, peerstore.RecentlyConnectedAddrTTL)
+		o.reputation[id] = &record
+
+		if reputable(&record) && len(addrs) > 0 {
+			dial = append(dial, peer.AddrInfo{ID: id, Addrs: addrs})
+		}
+	}
+	o.knownPeersMux.Unlock()
+
+	for _, id := range stale {
+		if err := o.db.DeletePeer(o.ctx, id); err != nil {
+			log.Printf("Error deleting peer %s: %v", id, err)
+		}
+	}
+	if len(records) > 0 {
+		log.Printf("✅ Restored %d peers, dialing %d", len(records)-len(stale), len(dial))
+	}
+	for _, info := range dial {
+		go o.dialPeer(info)
+	}
+}
+
+func (o *OperatorNode) dialPeer(info peer.AddrInfo) {
+	ctx, cancel := context.WithTimeout(o.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := o.host.Connect(ctx, info); err != nil {
+		if o.ctx.Err() == nil {
+			o.notePeerDialFailed(info.ID)
+			log.Printf("Failed to dial restored peer %s: %v", info.ID, err)
+		}
+	}
+}
+
+// reconnectCandidates lists peerstore peers worth redialing, best reputation
+// first.
+func (o *OperatorNode) reconnectCandidates() []peer.ID {
+	o.knownPeersMux.RLock()
+	defer o.knownPeersMux.RUnlock()
+
+	var ids []peer.ID
+	for _, id := range o.host.Peerstore().Peers() {
+		if id == o.host.ID() || o.host.Network().Connectedness(id) == network.Connected {
+			continue
+		}
+		if record, ok := o.reputation[id]; ok && !reputable(record) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	score := func(id peer.ID) int {
+		record, ok := o.reputation[id]
+		if !ok {
+			return 0
+		}
+		return record.ValidMessages - record.InvalidMessages - record.FailedDials
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return score(ids[i]) > score(ids[j])
+	})
+	return ids
+}
+
+// persistPeers writes the peer book with the current peerstore addresses.
+func (o *OperatorNode) persistPeers(ctx context.Context) {
+	o.knownPeersMux.Lock()
+	records := make([]store.PeerRecord, 0, len(o.reputation))
+	for id, record := range o.reputation {
+		if o.host.Network().Connectedness(id) == network.Connected {
+			record.LastSeen = o.clock.Now().UnixMilli()
+		}
+		snapshot := *record
+		if addrs := o.host.Peerstore().Addrs(id); len(addrs) > 0 {
+			snapshot.Addrs = make([]string, len(addrs))
+			for i, addr := range addrs {
+				snapshot.Addrs[i] = addr.String()
+			}
+		}
+		records = append(records, snapshot)
+	}
+	o.knownPeersMux.Unlock()
+
+	for _, record := range records {
+		if err := o.db.StorePeer(ctx, record); err != nil {
+			log.Printf("Error storing peer %s: %v", record.ID, err)
+			return
+		}
+	}
+}
+
+func (o *OperatorNode) peerPersister() {
+	ticker := o.clock.Ticker(peerPersistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.persistPeers(o.ctx)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package operator
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// ProtocolVersion is the major.minor version of the messages this operator
+// speaks. Minor versions only add optional fields; a new major changes what
+// is signed or how, and peers on another major are refused.
+const ProtocolVersion = "1.0"
+
+// legacyProtocolVersion is assumed for messages without a version, which
+// predate versioning and are compatible with 1.x.
+const legacyProtocolVersion = "1.0"
+
+// HelloProtocolID is the stream a peer opens on connect to announce its
+// versions. The operator answers with its own.
+const HelloProtocolID = protocol.ID("/l0proof/hello/1.0.0")
+
+const (
+	helloTimeout = 10 * time.Second
+	maxHelloSize = 4 << 10
+)
+
+// Hello announces a peer's protocol and software versions and, for a
+// signer, the addresses it signs with.
+type Hello struct {
+	ProtocolVersion string   `json:"protocol_version"`
+	Software        string   `json:"software,omitempty"`
+	Role            string   `json:"role,omitempty"`
+	Addresses       []string `json:"addresses,omitempty"`
+}
+
+// protocolMajor returns the major version of v, treating an empty version as
+// legacyProtocolVersion.
+func protocolMajor(v string) (int, bool) {
+	if v == "" {
+		v = legacyProtocolVersion
+	}
+	major, _, _ := strings.Cut(v, ".")
+	n, err := strconv.Atoi(major)
+	return n, err == nil
+}
+
+// protocolCompatible reports whether a peer speaking v can be understood.
+func protocolCompatible(v string) bool {
+	theirs, ok := protocolMajor(v)
+	ours, _ := protocolMajor(ProtocolVersion)
+	return ok && theirs == ours
+}
+
+// SignerVersion is one row of the signer version matrix. Rows for trusted
+// addresses never heard from have no versions.
+type SignerVersion struct {
+	Address         string `json:"address"`
+	PeerID          string `json:"peer_id,omitempty"`
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	Software        string `json:"software,omitempty"`
+	Compatible      bool   `json:"compatible"`
+	Trusted         bool   `json:"trusted"`
+	LastSeen        int64  `json:"last_seen,omitempty"`
+}
+
+// signerVersions tracks the versions signers announced or used, keyed by
+// lowercased signer address.
+type signerVersions struct {
+	mu      sync.Mutex
+	signers map[string]*SignerVersion
+}
+
+func newSignerVersions() *signerVersions {
+	return &signerVersions{signers: make(map[string]*SignerVersion)}
+}
+
+func (v *signerVersions) entry(address string) *SignerVersion {
+	key := strings.ToLower(address)
+	e, ok := v.signers[key]
+	if !ok {
+		e = &SignerVersion{Address: address}
+		v.signers[key] = e
+	}
+	return e
+}
+
+// observeHello records a handshake from p.
+func (v *signerVersions) observeHello(p peer.ID, hello Hello, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, address := range hello.Addresses {
+		e := v.entry(address)
+		e.PeerID = p.String()
+		e.ProtocolVersion = hello.ProtocolVersion
+		e.Software = hello.Software
+		e.LastSeen = now.Unix()
+	}
+	v.updateMetrics()
+}
+
+// observeMessage records the protocol version of a message signed by address.
+func (v *signerVersions) observeMessage(address, version string, now time.Time) {
+	if version == "" {
+		version = legacyProtocolVersion
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	e := v.entry(address)
+	if e.ProtocolVersion != version {
+		// The announced software no longer describes what is running.
+		e.Software = ""
+	}
+	e.ProtocolVersion = version
+	e.LastSeen = now.Unix()
+	v.updateMetrics()
+}
+
+// updateMetrics recounts signers per version. v.mu must be held.
+func (v *signerVersions) updateMetrics() {
+	signerProtocolVersions.Reset()
+	for _, e := range v.signers {
+		signerProtocolVersions.WithLabelValues(e.ProtocolVersion, e.Software).Inc()
+	}
+}
+
+// matrix lists every known signer and every trusted address, sorted by
+// address.
+func (v *signerVersions) matrix(trusted []string) []SignerVersion {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	isTrusted := make(map[string]bool, len(trusted))
+	rows := make(map[string]SignerVersion, len(v.signers)+len(trusted))
+	for _, address := range trusted {
+		key := strings.ToLower(address)
+		isTrusted[key] = true
+		rows[key] = SignerVersion{Address: address}
+	}
+	for key, e := range v.signers {
+		rows[key] = *e
+	}
+
+	matrix := make([]SignerVersion, 0, len(rows))
+	for key, row := range rows {
+		row.Trusted = isTrusted[key]
+		row.Compatible = row.ProtocolVersion != "" && protocolCompatible(row.ProtocolVersion)
+		matrix = append(matrix, row)
+	}
+	sort.Slice(matrix, func(i, j int) bool {
+		return strings.ToLower(matrix[i].Address) < strings.ToLower(matrix[j].Address)
+	})
+	return matrix
+}
+
+// SignerVersions returns the signer version matrix.
+func (o *OperatorNode) SignerVersions() []SignerVersion {
+	return o.versions.matrix(o.trustedSigners())
+}
+
+func (o *OperatorNode) handleHelloStream(s network.Stream) {
+	defer s.Close()
+	s.SetDeadline(o.clock.Now().Add(helloTimeout))
+	from := s.Conn().RemotePeer()
+
+	var hello Hello
+	if err := json.NewDecoder(io.LimitReader(s, maxHelloSize)).Decode(&hello); err != nil {
+		log.Printf("Malformed hello from %s: %v", from, err)
+		s.Reset()
+		return
+	}
+	reply := Hello{ProtocolVersion: ProtocolVersion, Software: buildVersion(), Role: "operator", Addresses: []string{o.address.Hex()}}
+	if err := json.NewEncoder(s).Encode(reply); err != nil {
+		log.Printf("Error answering hello from %s: %v", from, err)
+		s.Reset()
+		return
+	}
+
+	role := hello.Role
+	if role == "" {
+		role = "peer"
+	}
+	if !protocolCompatible(hello.ProtocolVersion) {
+		log.Printf("⚠️ %s %s speaks protocol %s, incompatible with %s; its messages will be refused", role, from, hello.ProtocolVersion, ProtocolVersion)
+	} else {
+		log.Printf("🤝 %s %s speaks protocol %s (%s)", role, from, hello.ProtocolVersion, hello.Software)
+	}
+	// A hello is not signed, so it only fills in rows of trusted signers,
+	// and signed responses overwrite what it claims.
+	var trusted []string
+	for _, address := range hello.Addresses {
+		if common.IsHexAddress(address) && o.isTrusted(common.HexToAddress(address)) {
+			trusted = append(trusted, address)
+		}
+	}
+	hello.Addresses = trusted
+	o.versions.observeHello(from, hello, o.clock.Now())
+}
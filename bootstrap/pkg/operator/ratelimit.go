@@ -0,0 +1,175 @@
+package operator
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitGroupPublic = "public"
+	rateLimitGroupAdmin  = "admin"
+
+	// Per-client buckets idle this long are dropped; a returning client
+	// starts with a full bucket, as it would have refilled by then anyway.
+	rateLimitClientIdle = 3 * time.Minute
+)
+
+// RateLimit is a token bucket: Rate requests per second on average with
+// bursts of up to Burst. A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// parseRateLimit reads a limit written as "rate" or "rate:burst". The burst
+// defaults to the rate rounded up.
+func parseRateLimit(s string) (RateLimit, error) {
+	ratePart, burstPart, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	r, err := strconv.ParseFloat(ratePart, 64)
+	if err != nil || r < 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate %q", ratePart)
+	}
+
+	limit := RateLimit{Rate: r, Burst: int(math.Ceil(r))}
+	if hasBurst {
+		burst, err := strconv.Atoi(burstPart)
+		if err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid burst %q", burstPart)
+		}
+		limit.Burst = burst
+	}
+	return limit, nil
+}
+
+// UnmarshalText lets config files use the "rate[:burst]" form.
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	limit, err := parseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+func (l RateLimit) limiter() *rate.Limiter {
+	burst := l.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// RateLimitConfig limits one route group, both across all clients and per
+// client IP. Proxies resolves the client IP for operators behind a reverse
+// proxy.
+type RateLimitConfig struct {
+	Global  RateLimit
+	PerIP   RateLimit
+	Proxies *TrustedProxies
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter enforces a RateLimitConfig for one route group. A nil
+// RateLimiter allows everything.
+type RateLimiter struct {
+	group  string
+	config RateLimitConfig
+	global *rate.Limiter
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// NewRateLimiter returns nil when cfg limits nothing.
+func NewRateLimiter(group string, cfg RateLimitConfig) *RateLimiter {
+	if !cfg.Global.enabled() && !cfg.PerIP.enabled() {
+		return nil
+	}
+
+	l := &RateLimiter{group: group, config: cfg, clients: make(map[string]*clientLimiter)}
+	if cfg.Global.enabled() {
+		l.global = cfg.Global.limiter()
+	}
+	return l
+}
+
+// allow takes a token for ip from the client and global buckets. When either
+// is empty it returns how long until a retry can succeed, and the scope that
+// refused.
+func (l *RateLimiter) allow(ip string, now time.Time) (bool, time.Duration, string) {
+	var client *rate.Reservation
+	if l.config.PerIP.enabled() {
+		client = l.clientLimiter(ip, now).ReserveN(now, 1)
+		if delay := client.DelayFrom(now); delay > 0 {
+			client.CancelAt(now)
+			return false, delay, "ip"
+		}
+	}
+
+	if l.global != nil {
+		global := l.global.ReserveN(now, 1)
+		if delay := global.DelayFrom(now); delay > 0 {
+			global.CancelAt(now)
+			if client != nil {
+				client.CancelAt(now)
+			}
+			return false, delay, "global"
+		}
+	}
+	return true, 0, ""
+}
+
+func (l *RateLimiter) clientLimiter(ip string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitClientIdle {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) > rateLimitClientIdle {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: l.config.PerIP.limiter()}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// middleware answers 429 with Retry-After once the group's buckets run dry.
+func (l *RateLimiter) middleware(h http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, delay, scope := l.allow(l.config.Proxies.ClientIP(r), time.Now())
+		if !ok {
+			rateLimitedTotal.WithLabelValues(l.group, scope).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,176 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// role is what a caller may do on the API. Roles are ordered: each may do
+// everything the roles below it may.
+type role int
+
+const (
+	// rolePublicRead is every caller, identified or not.
+	rolePublicRead role = iota
+	// roleConsumer is a caller identified by an API key or a SIWE session.
+	roleConsumer
+	// roleAdmin may use the admin endpoints.
+	roleAdmin
+)
+
+func (r role) String() string {
+	switch r {
+	case roleConsumer:
+		return "consumer"
+	case roleAdmin:
+		return "admin"
+	default:
+		return "public-read"
+	}
+}
+
+// parseRole reads a role name; empty means def.
+func parseRole(s string, def role) (role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return def, nil
+	case "public-read":
+		return rolePublicRead, nil
+	case "consumer":
+		return roleConsumer, nil
+	case "admin":
+		return roleAdmin, nil
+	}
+	return 0, fmt.Errorf("unknown role %q", s)
+}
+
+// principal is who a request was made by: an SIWE address, an API key name,
+// or anonymousUsage.
+type principal struct {
+	name string
+	role role
+}
+
+// principalContextKey carries the principal of an authorized request in
+// its context.
+type principalContextKey struct{}
+
+// auditOpAdminAction records a request to an admin endpoint. It is written
+// ahead of the handler, and aborted when the handler fails.
+const auditOpAdminAction = "admin_action"
+
+// SetRouteRoles raises the role routes require above their default, keyed
+// by the route pattern, e.g. "/data/{id}/export". It must be called before
+// Start.
+func (s *RPCServer) SetRouteRoles(roles map[string]role) {
+	s.routeRoles = roles
+}
+
+// SetAuditLog records admin actions in the audit log.
+func (s *RPCServer) SetAuditLog(audit *AuditLog) {
+	s.audit = audit
+}
+
+// adminsConfigured reports whether anyone can hold the admin role. Until
+// someone can, admin endpoints are disabled.
+func (s *RPCServer) adminsConfigured() bool {
+	return s.admins.hasRole(roleAdmin) || s.usage.hasRole(roleAdmin)
+}
+
+// authenticate establishes who made r from its bearer session or its API
+// key. A request with neither is anonymous; one with an invalid session or
+// key is refused.
+func (s *RPCServer) authenticate(r *http.Request) (principal, error) {
+	if _, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && s.admins != nil {
+		addr, sessionRole, err := s.admins.authenticate(r)
+		if err != nil {
+			return principal{}, err
+		}
+		return principal{name: addr.Hex(), role: sessionRole}, nil
+	}
+	key, ok := r.Context().Value(apiKeyContextKey{}).(meteredKey)
+	if !ok && s.usage != nil {
+		if key, ok = s.usage.identify(r); !ok {
+			return principal{}, errAdminUnauthorized
+		}
+	}
+	if !ok || key.name == anonymousUsage {
+		return principal{name: anonymousUsage, role: rolePublicRead}, nil
+	}
+	return principal{name: key.name, role: key.role}, nil
+}
+
+// requireRole refuses requests whose caller lacks the role the route needs:
+// min, or the role configured for the route's pattern when higher. Requests
+// to admin routes are written to the audit log.
+func (s *RPCServer) requireRole(min role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		required := max(min, s.routeRoles[r.Pattern])
+		if required == rolePublicRead {
+			h(w, r)
+			return
+		}
+		if required == roleAdmin && !s.adminsConfigured() {
+			apiAccessDenied.WithLabelValues(required.String()).Inc()
+			http.Error(w, "Admin endpoints are disabled: no admin address or admin API key is configured", http.StatusForbidden)
+			return
+		}
+
+		p, err := s.authenticate(r)
+		if err != nil || p.name == anonymousUsage {
+			apiAccessDenied.WithLabelValues(required.String()).Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="l0proof"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if p.role < required {
+			apiAccessDenied.WithLabelValues(required.String()).Inc()
+			http.Error(w, fmt.Sprintf("Forbidden: %s role required", required), http.StatusForbidden)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, p))
+		if required < roleAdmin {
+			h(w, r)
+			return
+		}
+		s.auditAdminAction(p, w, r, h)
+	}
+}
+
+// requireAdmin refuses requests without the admin role.
+func (s *RPCServer) requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return s.requireRole(roleAdmin, h)
+}
+
+// auditAdminAction runs an admin request, recording who made it ahead of
+// it and aborting the entry if it fails. Without an audit log the action is
+// only logged.
+func (s *RPCServer) auditAdminAction(p principal, w http.ResponseWriter, r *http.Request, h http.HandlerFunc) {
+	if s.audit == nil {
+		log.Printf("🛡️ Admin %s: %s %s", p.name, r.Method, r.URL.Path)
+		h(w, r)
+		return
+	}
+
+	seq, err := s.audit.Append(auditOpAdminAction, map[string]string{
+		"actor":  p.name,
+		"role":   p.role.String(),
+		"method": r.Method,
+		"path":   r.URL.Path,
+	})
+	if err != nil {
+		log.Printf("Error auditing admin action: %v", err)
+		http.Error(w, "Failed to audit request", http.StatusInternalServerError)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h(rec, r)
+	if rec.status >= http.StatusBadRequest {
+		s.audit.abort(seq, fmt.Errorf("status %d", rec.status))
+	}
+}
@@ -0,0 +1,76 @@
+package operator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminRoutesFailClosed(t *testing.T) {
+	called := false
+	s := &RPCServer{}
+	h := s.requireAdmin(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+	if rec.Code != http.StatusForbidden || called {
+		t.Fatalf("without admins: got %d (handler called %v), want 403", rec.Code, called)
+	}
+}
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	usage := NewUsageMeter(nil, UsageConfig{Keys: []APIKey{
+		{Name: "ops", Key: "admin-secret", Role: "admin"},
+		{Name: "acme", Key: "consumer-secret"},
+	}})
+	s := &RPCServer{usage: usage}
+	h := s.requireAdmin(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"unknown key", "nope", http.StatusUnauthorized},
+		{"consumer key", "consumer-secret", http.StatusForbidden},
+		{"admin key", "admin-secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/usage", nil)
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestRouteRolesOnlyRaise(t *testing.T) {
+	usage := NewUsageMeter(nil, UsageConfig{Keys: []APIKey{{Name: "acme", Key: "consumer-secret"}}})
+	s := &RPCServer{usage: usage, routeRoles: map[string]role{"/export": roleConsumer}}
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	mux.HandleFunc("/export", s.requireRole(rolePublicRead, ok))
+	mux.HandleFunc("/list", s.requireRole(rolePublicRead, ok))
+
+	for path, want := range map[string]int{"/export": http.StatusUnauthorized, "/list": http.StatusNoContent} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("anonymous %s: got %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set("X-API-Key", "consumer-secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("consumer /export: got %d, want 204", rec.Code)
+	}
+}
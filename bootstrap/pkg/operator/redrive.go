@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/benbjohnson/clock"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	defaultRedriveLimit = 100
+	maxRedriveLimit     = 1000
+)
+
+// RedriveRequest selects the stored messages of a data structure, with a
+// timestamp in [From, To], that never reached their threshold. A zero To
+// means no upper bound.
+type RedriveRequest struct {
+	DataStructureID int
+	From, To        int64
+	// Fresh publishes each message again under the current time, so under
+	// a new hash, rather than as it was. Signers refuse requests whose
+	// timestamp strayed too far from their clock, which a message left over
+	// from an outage soon does, so messages that would still carry such a
+	// timestamp are reported stale rather than published. Hashes that do
+	// not cover the timestamp are published as they were.
+	Fresh bool
+	Limit int
+}
+
+// RedrivenMessage is one message published again. Replaces names the
+// stored message a fresh request stands in for.
+type RedrivenMessage struct {
+	Hash      string `json:"hash"`
+	RequestID string `json:"request_id,omitempty"`
+	Replaces  string `json:"replaces,omitempty"`
+}
+
+// RedriveResult counts the unconfirmed messages found. Stale ones were left
+// alone, as signers would refuse their timestamp.
+type RedriveResult struct {
+	Unconfirmed int               `json:"unconfirmed"`
+	Republished []RedrivenMessage `json:"republished"`
+	Stale       int               `json:"stale"`
+	Failed      int               `json:"failed"`
+}
+
+// Redriver publishes stored messages that never reached their threshold
+// again, so that data stored during a signer outage is not left orphaned.
+type Redriver struct {
+	db        store.Database
+	publisher *PubSubService
+	clock     clock.Clock
+}
+
+func NewRedriver(db store.Database, publisher *PubSubService) *Redriver {
+	return &Redriver{db: db, publisher: publisher, clock: orWallClock(publisher.clock)}
+}
+
+// Redrive publishes up to req.Limit unconfirmed messages that are no longer
+// pending, oldest first. It stops at the first message a fenced or paused
+// operator refuses; other failures are counted and end up dead letters.
+func (r *Redriver) Redrive(ctx context.Context, req RedriveRequest) (RedriveResult, error) {
+	state := r.publisher.state
+	if state != nil && state.fenced() {
+		return RedriveResult{}, errStandbyReplay
+	}
+
+	var unconfirmed []store.Message
+	err := r.db.IterateMessages(ctx, req.DataStructureID, req.From, req.To, func(msg store.Message) bool {
+		if state != nil && (state.confirms(req.DataStructureID, msg.Signatures) || state.isPending(msg.Hash)) {
+			return true
+		}
+		unconfirmed = append(unconfirmed, msg)
+		return len(unconfirmed) < req.Limit
+	})
+	if err != nil {
+		return RedriveResult{}, err
+	}
+
+	result := RedriveResult{Unconfirmed: len(unconfirmed), Republished: []RedrivenMessage{}}
+	for _, msg := range unconfirmed {
+		sr, err := r.request(msg, req)
+		if err != nil {
+			log.Printf("Error redriving %s: %v", msg.Hash, err)
+			result.Failed++
+			continue
+		}
+		if state != nil && !state.timestampAcceptable(sr.Timestamp) {
+			result.Stale++
+			continue
+		}
+		err = r.publisher.PublishSignRequest(ctx, sr)
+		if errors.Is(err, errPublicationPaused) {
+			return result, err
+		}
+		if err != nil {
+			log.Printf("Error redriving %s: %v", msg.Hash, err)
+			result.Failed++
+			continue
+		}
+
+		redriven := RedrivenMessage{Hash: sr.Hash, RequestID: sr.RequestID}
+		if sr.Hash != msg.Hash {
+			redriven.Replaces = msg.Hash
+		}
+		result.Republished = append(result.Republished, redriven)
+		redrivenTotal.Inc()
+	}
+
+	if result.Stale > 0 {
+		log.Printf("⚠️ Not redriving %d messages of structure %d: their timestamp is past the allowed skew", result.Stale, req.DataStructureID)
+	}
+	log.Printf("♻️ Redrove %d of %d unconfirmed messages of structure %d", len(result.Republished), result.Unconfirmed, req.DataStructureID)
+	return result, nil
+}
+
+// request rebuilds the sign request of msg, under the current time when
+// req asks for fresh requests.
+func (r *Redriver) request(msg store.Message, req RedriveRequest) (*SignRequest, error) {
+	sr := signRequestOf(msg, req.DataStructureID)
+	if !req.Fresh {
+		return sr, nil
+	}
+
+	timestamp := r.clock.Now().Unix()
+	hash, err := computeHash(sr.HashVersion, sr.DataStructure, sr.Data, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if hash == msg.Hash {
+		return sr, nil
+	}
+	sr.Hash = hash
+	sr.Timestamp = timestamp
+	sr.RequestID = newRequestID()
+	sr.Sequence = 0
+	sr.Epoch = 0
+	return sr, nil
+}
+
+// signRequestOf rebuilds the sign request a stored message was published
+// with.
+func signRequestOf(msg store.Message, dataStructureID int) *SignRequest {
+	return &SignRequest{
+		Type:              MsgTypeSignRequest,
+		Hash:              msg.Hash,
+		Data:              msg.Data,
+		DataStructure:     msg.DataStructure,
+		DataStructureMeta: msg.DataStructureMeta,
+		DataStructureId:   dataStructureID,
+		Timestamp:         msg.Timestamp,
+		HashVersion:       msg.HashVersion,
+		RequestID:         msg.RequestID,
+		Sequence:          msg.Sequence,
+		Epoch:             msg.Epoch,
+	}
+}
@@ -0,0 +1,48 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+func TestRedriveReportsStaleTimestamps(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 1, nil)
+
+	// A message stored during an outage an hour ago, never confirmed.
+	old := testSignRequest(t, "100", mock.Now().Add(-time.Hour).Unix())
+	if err := op.db.StoreData(context.Background(), old.Hash, old.Data, old.DataStructure, old.DataStructureMeta, old.Timestamp, old.DataStructureId, old.HashVersion, old.RequestID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	redriver := NewRedriver(op.db, op.publisher())
+
+	result, err := redriver.Redrive(context.Background(), RedriveRequest{DataStructureID: 1, Limit: defaultRedriveLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stale != 1 || len(result.Republished) != 0 {
+		t.Fatalf("redrive as stored: got %+v, want the message reported stale", result)
+	}
+
+	s := &RPCServer{redriver: redriver}
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/admin/redrive/1", nil)
+	r.SetPathValue("id", "1")
+	s.handleRedrive(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("redrive: got %d: %s", rec.Code, rec.Body)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Stale != 0 || len(result.Republished) != 1 || result.Republished[0].Replaces != old.Hash {
+		t.Fatalf("default redrive: got %+v, want the message republished under a fresh timestamp", result)
+	}
+}
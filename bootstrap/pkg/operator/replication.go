@@ -0,0 +1,588 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"bootstrap/pkg/store"
+)
+
+// ReplicationProtocolID is the stream a standby operator reads the
+// primary's state changes from: stored messages, signatures and
+// confirmations, preceded by a backfill of what the standby lacks and a
+// snapshot of the pending requests. The standby writes ReplicationLease
+// grants back on the same stream.
+const ReplicationProtocolID = protocol.ID("/l0proof/replicate/2.0.0")
+
+const (
+	// replicationBuffer is how many entries may queue for a replica before
+	// it is cut off; it reconnects and backfills what it missed.
+	replicationBuffer = 1024
+	// replicationBackfillPage is how many messages are read per backfill
+	// query.
+	replicationBackfillPage = 500
+	// replicationWriteTimeout bounds a single entry write.
+	replicationWriteTimeout = 10 * time.Second
+	// maxReplicationHelloSize limits how much of a replica's hello is read.
+	maxReplicationHelloSize = 64 << 10
+
+	defaultFailoverAfter = 30 * time.Second
+
+	// replicationLease is how long a primary with standbys may publish on
+	// one grant, and replicationLeaseRenew how often a standby renews it.
+	replicationLease      = 10 * time.Second
+	replicationLeaseRenew = replicationLease / 3
+)
+
+// Replication entry operations.
+const (
+	replicateMessage   = "message"
+	replicateSignature = "signature"
+	replicateConfirmed = "confirmed"
+	// replicateSynced marks the end of the backfill and pending snapshot.
+	replicateSynced = "synced"
+)
+
+// ReplicationHello opens a replication stream. Since holds the last
+// sequence number the standby has per data structure; the primary
+// backfills everything after it.
+type ReplicationHello struct {
+	Since map[int]uint64 `json:"since"`
+}
+
+// ReplicationLease lets the primary publish for LeaseMs from its receipt.
+// A standby grants it while replicating and does not take over before the
+// last lease it granted has lapsed, so a primary cut off from its standby
+// stops publishing before the standby starts.
+type ReplicationLease struct {
+	LeaseMs int64 `json:"lease_ms"`
+}
+
+// ReplicationEntry is one state change sent to a standby. Request carries
+// a stored message; Pending says whether the primary is still collecting
+// signatures for it.
+type ReplicationEntry struct {
+	Op              string       `json:"op"`
+	Request         *SignRequest `json:"request,omitempty"`
+	Pending         bool         `json:"pending,omitempty"`
+	Hash            string       `json:"hash,omitempty"`
+	Signer          string       `json:"signer,omitempty"`
+	Signature       string       `json:"signature,omitempty"`
+	DataStructureID int          `json:"data_structure_id,omitempty"`
+	Timestamp       int64        `json:"timestamp,omitempty"`
+	// Confirmation accompanies a confirmed entry when the primary recorded
+	// the signer set the message was confirmed under.
+	Confirmation *store.Confirmation `json:"confirmation,omitempty"`
+}
+
+// ReplicationStatus is what /status reports about replication.
+type ReplicationStatus struct {
+	Role     string `json:"role"`
+	Primary  string `json:"primary,omitempty"`
+	InSync   bool   `json:"in_sync,omitempty"`
+	Replicas int    `json:"replicas"`
+	// Fenced is set on a primary with standbys whose lease has lapsed.
+	Fenced bool `json:"fenced,omitempty"`
+}
+
+// replicationHub fans state changes out to the connected replicas.
+type replicationHub struct {
+	mu       sync.Mutex
+	replicas map[chan ReplicationEntry]bool
+}
+
+func newReplicationHub() *replicationHub {
+	return &replicationHub{replicas: make(map[chan ReplicationEntry]bool)}
+}
+
+func (h *replicationHub) subscribe() chan ReplicationEntry {
+	ch := make(chan ReplicationEntry, replicationBuffer)
+	h.mu.Lock()
+	h.replicas[ch] = true
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *replicationHub) unsubscribe(ch chan ReplicationEntry) {
+	h.mu.Lock()
+	if h.replicas[ch] {
+		delete(h.replicas, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+// publish queues e for every replica. A replica that has fallen too far
+// behind is dropped; its channel is closed so its stream ends.
+func (h *replicationHub) publish(e ReplicationEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.replicas {
+		select {
+		case ch <- e:
+		default:
+			delete(h.replicas, ch)
+			close(ch)
+			replicationDroppedTotal.Inc()
+		}
+	}
+}
+
+func (h *replicationHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.replicas)
+}
+
+// replicatingDatabase publishes each successful mutation a standby needs
+// to the replication hub.
+type replicatingDatabase struct {
+	store.Database
+	hub *replicationHub
+}
+
+func newReplicatingDatabase(db store.Database, hub *replicationHub) store.Database {
+	return &replicatingDatabase{Database: db, hub: hub}
+}
+
+func (r *replicatingDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	if err := r.Database.StoreData(ctx, hash, data, dataStructure, dataStructureMeta, timestamp, dataStructureID, hashVersion, requestID, sequence, epoch); err != nil {
+		return err
+	}
+	r.hub.publish(ReplicationEntry{
+		Op: replicateMessage,
+		Request: &SignRequest{
+			Type:              MsgTypeSignRequest,
+			Hash:              hash,
+			Data:              data,
+			DataStructure:     dataStructure,
+			DataStructureMeta: dataStructureMeta,
+			DataStructureId:   dataStructureID,
+			Timestamp:         timestamp,
+			HashVersion:       hashVersion,
+			RequestID:         requestID,
+			Sequence:          sequence,
+			Epoch:             epoch,
+		},
+		Pending: true,
+	})
+	return nil
+}
+
+func (r *replicatingDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	if err := r.Database.StoreSignature(ctx, hash, signer, signature); err != nil {
+		return err
+	}
+	r.hub.publish(ReplicationEntry{Op: replicateSignature, Hash: hash, Signer: signer, Signature: signature})
+	return nil
+}
+
+func (r *replicatingDatabase) MarkConfirmed(ctx context.Context, dataStructureID int, hash string, timestamp int64, confirmation *store.Confirmation) error {
+	if err := r.Database.MarkConfirmed(ctx, dataStructureID, hash, timestamp, confirmation); err != nil {
+		return err
+	}
+	r.hub.publish(ReplicationEntry{Op: replicateConfirmed, Hash: hash, DataStructureID: dataStructureID, Timestamp: timestamp, Confirmation: confirmation})
+	return nil
+}
+
+// SetReplicas allows the given standby peers to replicate from this
+// operator. From then on it publishes only while a standby holds it a
+// lease.
+func (o *OperatorNode) SetReplicas(peers []peer.ID) {
+	o.replicationMux.Lock()
+	defer o.replicationMux.Unlock()
+
+	o.replicas = make(map[peer.ID]bool, len(peers))
+	for _, p := range peers {
+		o.replicas[p] = true
+	}
+}
+
+func (o *OperatorNode) isReplica(p peer.ID) bool {
+	o.replicationMux.RLock()
+	defer o.replicationMux.RUnlock()
+	return o.replicas[p]
+}
+
+// fenced reports whether the operator must not publish: it mirrors a
+// primary, or it is a primary with standbys and no lease from them.
+func (o *OperatorNode) fenced() bool {
+	o.replicationMux.RLock()
+	defer o.replicationMux.RUnlock()
+	return o.primary != nil || o.leaseLapsed()
+}
+
+// leaseLapsed reports whether a primary with standbys lacks a lease. The
+// caller holds replicationMux.
+func (o *OperatorNode) leaseLapsed() bool {
+	return len(o.replicas) > 0 && !o.clock.Now().Before(o.leaseUntil)
+}
+
+// extendLease records a lease granted by standby until until.
+func (o *OperatorNode) extendLease(standby peer.ID, until time.Time) {
+	o.replicationMux.Lock()
+	defer o.replicationMux.Unlock()
+	if o.leaseLapsed() {
+		log.Printf("🔏 Standby %s granted a publishing lease", standby)
+	}
+	if until.After(o.leaseUntil) {
+		o.leaseUntil = until
+	}
+}
+
+// readReplicationLeases extends the lease with each grant standby sends
+// until its stream ends.
+func (o *OperatorNode) readReplicationLeases(standby peer.ID, dec *json.Decoder) {
+	for {
+		var lease ReplicationLease
+		if err := dec.Decode(&lease); err != nil {
+			return
+		}
+		d := min(time.Duration(lease.LeaseMs)*time.Millisecond, replicationLease)
+		o.extendLease(standby, o.clock.Now().Add(d))
+	}
+}
+
+// grantReplicationLeases renews the primary's lease on s until ctx is done.
+// Each grant is recorded before it is sent, and counts for twice the lease
+// so that one delayed by up to a lease in transit is covered.
+func (o *OperatorNode) grantReplicationLeases(ctx context.Context, s network.Stream) {
+	enc := json.NewEncoder(s)
+	ticker := o.clock.Ticker(replicationLeaseRenew)
+	defer ticker.Stop()
+	for {
+		o.replicationMux.Lock()
+		o.leaseGranted = o.clock.Now().Add(2 * replicationLease)
+		o.replicationMux.Unlock()
+
+		s.SetWriteDeadline(o.clock.Now().Add(replicationWriteTimeout))
+		if err := enc.Encode(ReplicationLease{LeaseMs: replicationLease.Milliseconds()}); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// grantedLeaseLapsed reports whether the last lease granted to the primary
+// has lapsed, which a standby waits for before taking over.
+func (o *OperatorNode) grantedLeaseLapsed() bool {
+	o.replicationMux.RLock()
+	defer o.replicationMux.RUnlock()
+	return o.clock.Now().After(o.leaseGranted)
+}
+
+// ReplicationStatus reports the operator's replication role.
+func (o *OperatorNode) ReplicationStatus() ReplicationStatus {
+	o.replicationMux.RLock()
+	defer o.replicationMux.RUnlock()
+
+	status := ReplicationStatus{Role: "primary", Replicas: o.replication.count(), Fenced: o.leaseLapsed()}
+	if o.primary != nil {
+		status.Role = "standby"
+		status.Primary = o.primary.ID.String()
+		status.InSync = o.replicaInSync
+	}
+	return status
+}
+
+// handleReplicationStream serves one standby: the backfill it asks for,
+// the pending requests, then live changes until either side goes away.
+func (o *OperatorNode) handleReplicationStream(s network.Stream) {
+	remote := s.Conn().RemotePeer()
+	if !o.isReplica(remote) {
+		log.Printf("Refusing replication to unknown peer %s", remote)
+		s.Reset()
+		return
+	}
+
+	var hello ReplicationHello
+	s.SetReadDeadline(o.clock.Now().Add(replicationWriteTimeout))
+	dec := json.NewDecoder(io.LimitReader(s, maxReplicationHelloSize))
+	if err := dec.Decode(&hello); err != nil {
+		log.Printf("Malformed replication hello from %s: %v", remote, err)
+		s.Reset()
+		return
+	}
+	s.SetReadDeadline(time.Time{})
+	go o.readReplicationLeases(remote, json.NewDecoder(io.MultiReader(dec.Buffered(), s)))
+
+	// Subscribe before the backfill so nothing stored meanwhile is lost.
+	// Entries may then arrive twice, which applying tolerates.
+	ch := o.replication.subscribe()
+	defer o.replication.unsubscribe(ch)
+
+	enc := json.NewEncoder(s)
+	send := func(e ReplicationEntry) error {
+		s.SetWriteDeadline(o.clock.Now().Add(replicationWriteTimeout))
+		return enc.Encode(e)
+	}
+
+	log.Printf("🪞 Replicating to standby %s", remote)
+	if err := o.sendReplicationBackfill(hello, send); err != nil {
+		log.Printf("Replication backfill to %s failed: %v", remote, err)
+		s.Reset()
+		return
+	}
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			s.Close()
+			return
+		case e, ok := <-ch:
+			if !ok {
+				log.Printf("Standby %s fell behind; dropping its replication stream", remote)
+				s.Reset()
+				return
+			}
+			if err := send(e); err != nil {
+				log.Printf("Replication to %s ended: %v", remote, err)
+				s.Reset()
+				return
+			}
+		}
+	}
+}
+
+func (o *OperatorNode) sendReplicationBackfill(hello ReplicationHello, send func(ReplicationEntry) error) error {
+	ids, err := o.db.GetDataStructures(o.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list data structures: %w", err)
+	}
+
+	for _, id := range ids {
+		from := hello.Since[id] + 1
+		for {
+			messages, err := o.db.GetMessagesBySequence(o.ctx, id, from, 0, false, replicationBackfillPage)
+			if err != nil {
+				return fmt.Errorf("failed to read messages: %w", err)
+			}
+			for _, msg := range messages {
+				if err := sendReplicatedMessage(send, id, msg, false, o.db.IsConfirmed(o.ctx, id, msg.Hash), o.confirmationOf(o.ctx, msg.Hash)); err != nil {
+					return err
+				}
+			}
+			if len(messages) < replicationBackfillPage {
+				break
+			}
+			from = messages[len(messages)-1].Sequence + 1
+		}
+	}
+
+	o.pendingMux.RLock()
+	pending := make([]SignRequest, 0, len(o.pending))
+	for _, p := range o.pending {
+		pending = append(pending, p.data)
+	}
+	o.pendingMux.RUnlock()
+
+	for _, req := range pending {
+		msg, ok := o.db.GetMessage(o.ctx, req.Hash)
+		if !ok {
+			continue
+		}
+		if err := sendReplicatedMessage(send, req.DataStructureId, msg, true, o.db.IsConfirmed(o.ctx, req.DataStructureId, msg.Hash), o.confirmationOf(o.ctx, msg.Hash)); err != nil {
+			return err
+		}
+	}
+
+	return send(ReplicationEntry{Op: replicateSynced})
+}
+
+func sendReplicatedMessage(send func(ReplicationEntry) error, dataStructureID int, msg store.Message, pending, confirmed bool, confirmation *store.Confirmation) error {
+	req := signRequestOf(msg, dataStructureID)
+	if err := send(ReplicationEntry{Op: replicateMessage, Request: req, Pending: pending}); err != nil {
+		return err
+	}
+	for signer, signature := range msg.Signatures {
+		if err := send(ReplicationEntry{Op: replicateSignature, Hash: msg.Hash, Signer: signer, Signature: signature}); err != nil {
+			return err
+		}
+	}
+	if confirmed {
+		return send(ReplicationEntry{Op: replicateConfirmed, Hash: msg.Hash, DataStructureID: dataStructureID, Timestamp: msg.Timestamp, Confirmation: confirmation})
+	}
+	return nil
+}
+
+// SetReplicationPrimary makes the operator a standby of the operator at
+// addr, a multiaddr ending in /p2p/<peer ID>. A standby mirrors the
+// primary's messages, signatures and pending requests and publishes
+// nothing itself. When the primary has been unreachable for failoverAfter
+// it takes over with the replicated pending requests; zero disables
+// failover.
+func (o *OperatorNode) SetReplicationPrimary(addr string, failoverAfter time.Duration) error {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return fmt.Errorf("invalid replication primary %q: %w", addr, err)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return fmt.Errorf("invalid replication primary %q: %w", addr, err)
+	}
+
+	o.replicationMux.Lock()
+	o.primary = info
+	o.replicationMux.Unlock()
+	replicationStandby.Set(1)
+
+	go o.replicateFrom(*info, failoverAfter)
+	return nil
+}
+
+func (o *OperatorNode) replicateFrom(info peer.AddrInfo, failoverAfter time.Duration) {
+	downSince := o.clock.Now()
+	for o.ctx.Err() == nil {
+		err := o.replicateOnce(info, func() { downSince = time.Time{} })
+		if o.ctx.Err() != nil {
+			return
+		}
+		if downSince.IsZero() {
+			downSince = o.clock.Now()
+		}
+		o.setReplicaInSync(false)
+		log.Printf("⚠️ Replication from primary %s interrupted: %v", info.ID, err)
+
+		if failoverAfter > 0 && o.clock.Since(downSince) >= failoverAfter && o.grantedLeaseLapsed() {
+			o.promote(fmt.Sprintf("primary unreachable for %s", o.clock.Since(downSince).Round(time.Second)))
+			return
+		}
+		o.clock.Sleep(reconnectTimeout)
+	}
+}
+
+// replicateOnce follows one replication stream until it ends. connected is
+// called once the stream is open.
+func (o *OperatorNode) replicateOnce(info peer.AddrInfo, connected func()) error {
+	ctx, cancel := context.WithTimeout(o.ctx, reconnectTimeout)
+	defer cancel()
+
+	if err := o.host.Connect(ctx, info); err != nil {
+		return err
+	}
+	s, err := o.host.NewStream(ctx, info.ID, ReplicationProtocolID)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	hello := ReplicationHello{Since: make(map[int]uint64)}
+	ids, err := o.db.GetDataStructures(o.ctx)
+	if err != nil {
+		s.Reset()
+		return fmt.Errorf("failed to list data structures: %w", err)
+	}
+	for _, id := range ids {
+		if seq, err := o.db.LastSequence(o.ctx, id); err == nil && seq > 0 {
+			hello.Since[id] = seq
+		}
+	}
+	if err := json.NewEncoder(s).Encode(hello); err != nil {
+		s.Reset()
+		return err
+	}
+	leaseCtx, stopLeases := context.WithCancel(o.ctx)
+	defer stopLeases()
+	go o.grantReplicationLeases(leaseCtx, s)
+	connected()
+	log.Printf("🪞 Replicating from primary %s", info.ID)
+
+	dec := json.NewDecoder(s)
+	for {
+		var e ReplicationEntry
+		if err := dec.Decode(&e); err != nil {
+			s.Reset()
+			return err
+		}
+		if err := o.applyReplicationEntry(e); err != nil {
+			s.Reset()
+			return err
+		}
+	}
+}
+
+func (o *OperatorNode) applyReplicationEntry(e ReplicationEntry) error {
+	replicationEntriesApplied.WithLabelValues(e.Op).Inc()
+
+	switch e.Op {
+	case replicateMessage:
+		if e.Request == nil {
+			return fmt.Errorf("message entry without a request")
+		}
+		req := e.Request
+		err := o.db.StoreData(o.ctx, req.Hash, req.Data, req.DataStructure, req.DataStructureMeta, req.Timestamp, req.DataStructureId, req.HashVersion, req.RequestID, req.Sequence, req.Epoch)
+		if err != nil && !errors.Is(err, store.ErrAlreadyStored) {
+			return fmt.Errorf("failed to store replicated message: %w", err)
+		}
+		if e.Pending {
+			o.handleSignRequest(o.ctx, req)
+		}
+	case replicateSignature:
+		if err := o.db.StoreSignature(o.ctx, e.Hash, e.Signer, e.Signature); err != nil {
+			return fmt.Errorf("failed to store replicated signature: %w", err)
+		}
+		seat := o.seatOf(common.HexToAddress(e.Signer))
+		weight := o.weightOf(seat)
+		o.pendingMux.Lock()
+		// The signature counts with its weight, so that after a promotion
+		// weighted thresholds see what the primary had collected.
+		if p, ok := o.pending[e.Hash]; ok && !p.signers[seat] {
+			p.signers[seat] = true
+			p.weight += weight
+		}
+		o.pendingMux.Unlock()
+	case replicateConfirmed:
+		if err := o.db.MarkConfirmed(o.ctx, e.DataStructureID, e.Hash, e.Timestamp, e.Confirmation); err != nil {
+			return fmt.Errorf("failed to mark replicated message confirmed: %w", err)
+		}
+		o.pendingMux.Lock()
+		if p, ok := o.pending[e.Hash]; ok {
+			p.confirmed = true
+		}
+		o.pendingMux.Unlock()
+	case replicateSynced:
+		o.setReplicaInSync(true)
+		log.Println("✅ Standby is in sync with the primary")
+	default:
+		log.Printf("Ignoring unknown replication entry %q", e.Op)
+	}
+	return nil
+}
+
+func (o *OperatorNode) setReplicaInSync(inSync bool) {
+	o.replicationMux.Lock()
+	o.replicaInSync = inSync
+	o.replicationMux.Unlock()
+}
+
+// promote turns a standby into the primary. The pending requests it
+// replicated are rebroadcast from now on and its workers start publishing.
+func (o *OperatorNode) promote(reason string) {
+	o.replicationMux.Lock()
+	o.primary = nil
+	o.replicaInSync = false
+	o.replicationMux.Unlock()
+	replicationStandby.Set(0)
+
+	o.pendingMux.RLock()
+	pending := len(o.pending)
+	o.pendingMux.RUnlock()
+	log.Printf("🚨 Taking over as primary (%s) with %d pending requests", reason, pending)
+}
@@ -0,0 +1,98 @@
+package operator
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+func TestReplicatedSignatureCountsItsWeight(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 2, nil)
+	signer := cryptoeth.PubkeyToAddress(op.signers[0].PublicKey).Hex()
+	op.SetSignerWeights(map[string]uint64{signer: 5})
+
+	req := op.request(t, "100")
+	hash, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := cryptoeth.Sign(accounts.TextHash(hash), op.signers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := ReplicationEntry{Op: replicateSignature, Hash: req.Hash, Signer: signer, Signature: hexutil.Encode(signature)}
+	for i := 0; i < 2; i++ {
+		if err := op.applyReplicationEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	op.pendingMux.RLock()
+	p := op.pending[req.Hash]
+	signers, weight := len(p.signers), p.weight
+	op.pendingMux.RUnlock()
+	if signers != 1 || weight != 5 {
+		t.Fatalf("after replaying the signature twice: %d signers, weight %d; want 1 signer, weight 5", signers, weight)
+	}
+}
+
+func TestPrimaryPublishesOnlyUnderLease(t *testing.T) {
+	net := mocknet.New()
+	t.Cleanup(func() { net.Close() })
+	signers := newTestSigners(t, 2)
+	primaryClock := clock.NewMock()
+	primaryClock.Set(time.Now())
+	primary := startTestOperator(t, net, 0, primaryClock, signers, nil)
+	standby := startTestOperator(t, net, 1, clock.New(), signers, nil)
+	if err := net.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if primary.fenced() {
+		t.Fatal("primary without standbys is fenced")
+	}
+	primary.SetReplicas([]peer.ID{standby.host.ID()})
+	if !primary.fenced() {
+		t.Fatal("primary with a standby publishes before it holds a lease")
+	}
+
+	addr := fmt.Sprintf("%s/p2p/%s", testAddr(0), primary.host.ID())
+	if err := standby.SetReplicationPrimary(addr, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for primary.fenced() {
+		if time.Now().After(deadline) {
+			t.Fatal("standby never granted the primary a lease")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !standby.fenced() {
+		t.Fatal("standby is not fenced")
+	}
+	if standby.grantedLeaseLapsed() {
+		t.Fatal("standby considers the lease it just granted lapsed")
+	}
+
+	// Cut the standby off: the primary fences itself once the lease runs
+	// out, before the standby may take over.
+	net.UnlinkPeers(primary.host.ID(), standby.host.ID())
+	net.DisconnectPeers(primary.host.ID(), standby.host.ID())
+	primaryClock.Add(replicationLease + time.Second)
+	if !primary.fenced() {
+		t.Fatal("primary still publishes after its lease lapsed")
+	}
+	if status := primary.ReplicationStatus(); !status.Fenced {
+		t.Fatalf("status of the fenced primary: %+v", status)
+	}
+}
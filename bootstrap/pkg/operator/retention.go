@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bootstrap/pkg/archive"
+	"bootstrap/pkg/store"
+)
+
+const pruneInterval = 1 * time.Hour
+
+// applyRetentionPolicies copies retention settings from the structure config
+// into the registry so the pruner and other tools share one source of truth.
+func applyRetentionPolicies(ctx context.Context, db store.Database, structures map[string]DataStructure) error {
+	for name, structure := range structures {
+		retention := time.Duration(structure.RetentionDays) * 24 * time.Hour
+		if err := db.SetRetention(ctx, structure.ID, retention); err != nil {
+			return err
+		}
+		if retention > 0 {
+			log.Printf("Retention for %s: %d days", name, structure.RetentionDays)
+		}
+	}
+	return nil
+}
+
+// Pruner periodically removes messages that fall outside the retention
+// window of their data structure.
+type Pruner struct {
+	db       store.Database
+	interval time.Duration
+	// archive, when set, receives messages before they are pruned.
+	archive *archive.Archive
+}
+
+func NewPruner(db store.Database, interval time.Duration) *Pruner {
+	return &Pruner{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// SetArchive archives messages before they leave the database. A structure
+// whose messages could not be archived is not pruned.
+func (p *Pruner) SetArchive(a *archive.Archive) {
+	p.archive = a
+}
+
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.prune(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.prune(ctx)
+		}
+	}
+}
+
+func (p *Pruner) prune(ctx context.Context) {
+	ids, err := p.db.GetDataStructures(ctx)
+	if err != nil {
+		log.Printf("Pruner: failed to list data structures: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		retention, ok := p.db.GetRetention(ctx, id)
+		if !ok {
+			continue
+		}
+
+		if p.archive != nil {
+			until := now.Add(-retention + archiveLead(p.interval)).Unix()
+			if err := p.archiveBefore(ctx, id, until); err != nil {
+				log.Printf("Pruner: failed to archive structure %d, not pruning it: %v", id, err)
+				continue
+			}
+		}
+
+		removed, err := p.db.PruneMessages(ctx, id, now.Add(-retention).Unix())
+		if err != nil {
+			log.Printf("Pruner: failed to prune structure %d: %v", id, err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("🧹 Pruned %d messages of structure %d older than %v", removed, id, retention)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/crypto/sha3"
+)
+
+const defaultRewardPeriod = 24 * time.Hour
+
+type RewardsConfig struct {
+	// Pool is the amount, in the reward token's smallest unit, shared
+	// between signers every period. Empty reports participation only.
+	Pool string `yaml:"pool"`
+	// Period is how long participation is counted for before a
+	// distribution is made. With staking the periods are the staking
+	// epochs instead.
+	Period time.Duration `yaml:"period"`
+}
+
+func (c RewardsConfig) validate() error {
+	if c.Period < time.Second || c.Period%time.Second != 0 {
+		return fmt.Errorf("reward period must be a positive whole number of seconds")
+	}
+	if c.Pool == "" {
+		return nil
+	}
+	if pool, ok := new(big.Int).SetString(c.Pool, 10); !ok || pool.Sign() <= 0 {
+		return fmt.Errorf("invalid reward pool %q", c.Pool)
+	}
+	return nil
+}
+
+// SetRewards changes the reward pool and the length of the periods
+// participation is counted in.
+func (o *OperatorNode) SetRewards(pool *big.Int, period time.Duration) {
+	if period <= 0 {
+		return
+	}
+	o.pendingMux.Lock()
+	defer o.pendingMux.Unlock()
+
+	o.rewardPool = pool
+	o.rewardPeriod = period
+}
+
+// recordParticipation credits signers with a signature on a confirmed
+// message in the current reward period. Callers hold pendingMux.
+func (o *OperatorNode) recordParticipation(ctx context.Context, signers []string) {
+	period := epochAt(o.clock.Now(), o.rewardPeriod)
+	if err := o.db.AddParticipation(ctx, period, signers); err != nil {
+		log.Printf("Error recording participation in period %d: %v", period, err)
+		return
+	}
+	for _, signer := range signers {
+		rewardSignatures.WithLabelValues(signer).Inc()
+	}
+}
+
+// RewardClaim is one signer's share of a period's pool.
+type RewardClaim struct {
+	Index      uint64   `json:"index"`
+	Address    string   `json:"address"`
+	Signatures int      `json:"signatures"`
+	Share      float64  `json:"share"`
+	Amount     string   `json:"amount,omitempty"`
+	Proof      []string `json:"proof,omitempty"`
+}
+
+// RewardReport is the participation of a reward period and, with a pool
+// configured, the claimable amounts and their Merkle root.
+type RewardReport struct {
+	Period     uint64 `json:"period"`
+	StartsAt   int64  `json:"starts_at"`
+	EndsAt     int64  `json:"ends_at"`
+	Final      bool   `json:"final"`
+	Signatures int    `json:"signatures"`
+	Pool       string `json:"pool,omitempty"`
+	// Unallocated is what rounding amounts down leaves of the pool.
+	Unallocated string        `json:"unallocated,omitempty"`
+	MerkleRoot  string        `json:"merkle_root,omitempty"`
+	Claims      []RewardClaim `json:"claims"`
+}
+
+// RewardReport builds the report of a period. Claims are ordered by address
+// and each signer's amount is its share of the pool rounded down.
+func (o *OperatorNode) RewardReport(ctx context.Context, period uint64) (*RewardReport, error) {
+	o.pendingMux.Lock()
+	pool, length := o.rewardPool, o.rewardPeriod
+	o.pendingMux.Unlock()
+
+	counts, err := o.db.GetParticipation(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+
+	startsAt := int64(period) * int64(length/time.Second)
+	report := &RewardReport{
+		Period:   period,
+		StartsAt: startsAt,
+		EndsAt:   startsAt + int64(length/time.Second),
+		Final:    epochAt(o.clock.Now(), length) > period,
+		Claims:   make([]RewardClaim, 0, len(counts)),
+	}
+	for addr, n := range counts {
+		report.Claims = append(report.Claims, RewardClaim{Address: common.HexToAddress(addr).Hex(), Signatures: n})
+		report.Signatures += n
+	}
+	sort.Slice(report.Claims, func(i, j int) bool { return report.Claims[i].Address < report.Claims[j].Address })
+	for i := range report.Claims {
+		report.Claims[i].Index = uint64(i)
+		report.Claims[i].Share = float64(report.Claims[i].Signatures) / float64(report.Signatures)
+	}
+
+	if pool == nil || report.Signatures == 0 {
+		return report, nil
+	}
+	report.Pool = pool.String()
+	allocated := new(big.Int)
+	leaves := make([][]byte, len(report.Claims))
+	for i := range report.Claims {
+		claim := &report.Claims[i]
+		amount := new(big.Int).Mul(pool, big.NewInt(int64(claim.Signatures)))
+		amount.Quo(amount, big.NewInt(int64(report.Signatures)))
+		allocated.Add(allocated, amount)
+		claim.Amount = amount.String()
+		leaf, err := rewardLeaf(claim.Index, common.HexToAddress(claim.Address), amount)
+		if err != nil {
+			return nil, fmt.Errorf("claim of %s: %w", claim.Address, err)
+		}
+		leaves[i] = leaf
+	}
+	report.Unallocated = new(big.Int).Sub(pool, allocated).String()
+
+	levels := merkleLevels(leaves)
+	report.MerkleRoot = hexutil.Encode(levels[len(levels)-1][0])
+	for i := range report.Claims {
+		report.Claims[i].Proof = merkleProof(levels, i)
+	}
+	return report, nil
+}
+
+// RewardDistribution is the exported form of a report with a pool: the
+// input of a Merkle distributor contract, keyed by claimant.
+type RewardDistribution struct {
+	Period     uint64                       `json:"period"`
+	MerkleRoot string                       `json:"merkleRoot"`
+	TokenTotal string                       `json:"tokenTotal"`
+	Claims     map[string]DistributionClaim `json:"claims"`
+}
+
+type DistributionClaim struct {
+	Index  uint64   `json:"index"`
+	Amount string   `json:"amount"`
+	Proof  []string `json:"proof"`
+}
+
+// Distribution converts the report for export. It fails when no pool is
+// configured or nobody signed in the period.
+func (r *RewardReport) Distribution() (*RewardDistribution, error) {
+	if r.MerkleRoot == "" {
+		return nil, fmt.Errorf("period %d has no reward distribution", r.Period)
+	}
+	total := new(big.Int)
+	d := &RewardDistribution{
+		Period:     r.Period,
+		MerkleRoot: r.MerkleRoot,
+		Claims:     make(map[string]DistributionClaim, len(r.Claims)),
+	}
+	for _, claim := range r.Claims {
+		amount, _ := new(big.Int).SetString(claim.Amount, 10)
+		total.Add(total, amount)
+		proof := claim.Proof
+		if proof == nil {
+			proof = []string{}
+		}
+		d.Claims[claim.Address] = DistributionClaim{Index: claim.Index, Amount: claim.Amount, Proof: proof}
+	}
+	d.TokenTotal = total.String()
+	return d, nil
+}
+
+// rewardLeaf hashes a claim as keccak256(abi.encode(index, account, amount)).
+// abi.encode pads the account to a full word, so it is packed as a uint256.
+func rewardLeaf(index uint64, account common.Address, amount *big.Int) ([]byte, error) {
+	return SolidityKeccak256(
+		[]string{"uint256", "uint256", "uint256"},
+		[]interface{}{new(big.Int).SetUint64(index), new(big.Int).SetBytes(account[:]), amount},
+	)
+}
+
+// merkleLevels builds the tree bottom up. Pairs are hashed in sorted order,
+// as OpenZeppelin's MerkleProof expects, and an odd node out is carried up
+// unchanged.
+func merkleLevels(leaves [][]byte) [][][]byte {
+	levels := [][][]byte{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			a, b := level[i], level[i+1]
+			if bytes.Compare(a, b) > 0 {
+				a, b = b, a
+			}
+			hasher := sha3.NewLegacyKeccak256()
+			hasher.Write(a)
+			hasher.Write(b)
+			next = append(next, hasher.Sum(nil))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// merkleProof returns the siblings of leaf i from the bottom up.
+func merkleProof(levels [][][]byte, i int) []string {
+	var proof []string
+	for _, level := range levels[:len(levels)-1] {
+		if sibling := i ^ 1; sibling < len(level) {
+			proof = append(proof, hexutil.Encode(level[sibling]))
+		}
+		i /= 2
+	}
+	return proof
+}
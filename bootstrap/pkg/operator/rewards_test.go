@@ -0,0 +1,29 @@
+package operator
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestRewardLeafMatchesABIEncode(t *testing.T) {
+	account := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	leaf, err := rewardLeaf(3, account, big.NewInt(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// abi.encode puts each of index, account and amount in a full word.
+	encoded := make([]byte, 96)
+	encoded[31] = 3
+	copy(encoded[44:64], account[:])
+	big.NewInt(500).FillBytes(encoded[64:])
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(encoded)
+	if want := hasher.Sum(nil); !bytes.Equal(leaf, want) {
+		t.Fatalf("reward leaf %x, want %x", leaf, want)
+	}
+}
@@ -0,0 +1,1435 @@
+package operator
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/netutil"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// totalCountHeader carries the number of messages matching a list query
+// across all pages.
+const totalCountHeader = "X-Total-Count"
+
+type RPCServer struct {
+	operator  *OperatorNode
+	port      string
+	server    *http.Server
+	submitter *Submitter
+	submitKey string
+	sources   *collector.SourceRegistry
+	dead      *DeadLetters
+	redriver  *Redriver
+	admins    *AdminAuth
+	// routeRoles raise the role a route pattern requires.
+	routeRoles map[string]role
+	audit      *AuditLog
+	feed       *EventFeed
+	// structures are the loaded data structure definitions, keyed by name.
+	structures map[string]DataStructure
+	// coalesce shares reads between identical concurrent queries of hot
+	// endpoints.
+	coalesce coalescer
+	// streams tracks long-running responses so shutdown can drain them.
+	streams *streamTracker
+
+	limits        ServerLimits
+	publicLimiter *RateLimiter
+	adminLimiter  *RateLimiter
+	adminIPs      *IPFilter
+	usage         *UsageMeter
+}
+
+func NewRPCServer(operator *OperatorNode, port string) *RPCServer {
+	return &RPCServer{
+		operator: operator,
+		port:     port,
+		feed:     NewEventFeed(operator.events),
+		streams:  newStreamTracker(),
+	}
+}
+
+// EnableSubmit turns on POST /submit. Requests must carry the API key as a
+// bearer token.
+func (s *RPCServer) EnableSubmit(submitter *Submitter, apiKey string) {
+	s.submitter = submitter
+	s.submitKey = apiKey
+}
+
+// EnableDeadLetters serves the dead letters under /admin/deadletter.
+func (s *RPCServer) EnableDeadLetters(dead *DeadLetters) {
+	s.dead = dead
+}
+
+// EnableRedrive turns on POST /admin/redrive/{id}.
+func (s *RPCServer) EnableRedrive(redriver *Redriver) {
+	s.redriver = redriver
+}
+
+const (
+	defaultMaxConnections     = 1024
+	defaultMaxHeaderBytes     = 64 << 10
+	defaultMaxStreams         = 100
+	defaultReadHeaderTimeout  = 10 * time.Second
+	defaultReadTimeout        = 30 * time.Second
+	defaultWriteTimeout       = 45 * time.Second
+	defaultIdleTimeout        = 120 * time.Second
+	defaultStreamWriteTimeout = 15 * time.Second
+)
+
+// ServerLimits bound the connections of the API server. Zero fields keep
+// Go's defaults; MaxConnections zero accepts any number.
+type ServerLimits struct {
+	HTTP2                bool
+	MaxConnections       int
+	MaxHeaderBytes       int
+	MaxConcurrentStreams int
+	ReadHeaderTimeout    time.Duration
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	IdleTimeout          time.Duration
+	// StreamWriteTimeout bounds each write of a streaming response, which
+	// has no overall write timeout. A client that stops reading for this
+	// long is disconnected instead of pinning its handler. Zero never
+	// disconnects.
+	StreamWriteTimeout time.Duration
+}
+
+// SetServerLimits applies limits to the server. It must be called before
+// Start.
+func (s *RPCServer) SetServerLimits(limits ServerLimits) {
+	s.limits = limits
+}
+
+// EnableAdminAuth accepts SIWE sessions of allowlisted addresses, served
+// under /auth, with the role each address is allowlisted with.
+func (s *RPCServer) EnableAdminAuth(admins *AdminAuth) {
+	s.admins = admins
+}
+
+// SetRateLimits limits public read endpoints and admin endpoints
+// (submission and backups) separately. It must be called before Start.
+func (s *RPCServer) SetRateLimits(public, admin RateLimitConfig) {
+	s.publicLimiter = NewRateLimiter(rateLimitGroupPublic, public)
+	s.adminLimiter = NewRateLimiter(rateLimitGroupAdmin, admin)
+}
+
+// SetAdminIPFilter applies IP rules to the admin endpoints. It must be
+// called before Start.
+func (s *RPCServer) SetAdminIPFilter(filter *IPFilter) {
+	s.adminIPs = filter
+}
+
+// SetUsageMeter accounts public requests per API key. It must be called
+// before Start.
+func (s *RPCServer) SetUsageMeter(usage *UsageMeter) {
+	s.usage = usage
+}
+
+// SetStructures serves the definitions of the data structures under
+// /data/{id}/structure.
+func (s *RPCServer) SetStructures(structures map[string]DataStructure) {
+	s.structures = structures
+}
+
+func (s *RPCServer) SetSourceRegistry(sources *collector.SourceRegistry) {
+	s.sources = sources
+}
+
+func enableCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", totalCountHeader)
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// requestTimeout bounds a buffered API request. Handlers see it as the
+// deadline of the request context, which stops database scans.
+const requestTimeout = 30 * time.Second
+
+// timeoutMiddleware answers 503 once requestTimeout passes. The handler
+// writes into a buffer that is only sent if it finishes in time, so a late
+// handler can never write after the timeout response.
+func timeoutMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return http.TimeoutHandler(h, requestTimeout, "Request timed out").ServeHTTP
+}
+
+func logMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		h(w, r)
+		log.Printf("API Request: %s %s (took: %v) [req=%s]", r.Method, r.URL.Path, time.Since(start), requestID)
+	}
+}
+
+func (s *RPCServer) wrapHandler(h http.HandlerFunc) http.HandlerFunc {
+	return enableCORS(logMiddleware(s.usage.middleware(s.requireRole(rolePublicRead, s.publicLimiter.middleware(tracingMiddleware(timeoutMiddleware(h)))), false)))
+}
+
+// wrapStreamingHandler skips the request timeout and its buffering, for
+// handlers that stream until the client disconnects.
+func (s *RPCServer) wrapStreamingHandler(h http.HandlerFunc) http.HandlerFunc {
+	return enableCORS(logMiddleware(s.usage.middleware(s.requireRole(rolePublicRead, s.publicLimiter.middleware(tracingMiddleware(h))), true)))
+}
+
+func (s *RPCServer) wrapAdminHandler(h http.HandlerFunc) http.HandlerFunc {
+	return enableCORS(logMiddleware(s.adminIPs.middleware(s.adminLimiter.middleware(tracingMiddleware(timeoutMiddleware(h))))))
+}
+
+func (s *RPCServer) Start() {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/list", s.wrapHandler(s.handleList))
+	mux.HandleFunc("/data/", s.wrapHandler(s.handleDataStructure))
+	mux.HandleFunc("/data/{id}/export", s.wrapStreamingHandler(s.handleDataStructure))
+	mux.HandleFunc("/structures", s.wrapHandler(s.handleGetStructures))
+	mux.HandleFunc("/hash", s.wrapHandler(s.handleGetByHash))
+	mux.HandleFunc("/search", s.wrapHandler(s.handleSearch))
+	mux.HandleFunc("/sources", s.wrapHandler(s.handleSources))
+	mux.HandleFunc("/submit", s.wrapAdminHandler(s.handleSubmit))
+	mux.HandleFunc("/auth/nonce", s.wrapAdminHandler(s.handleAuthNonce))
+	mux.HandleFunc("/auth/login", s.wrapAdminHandler(s.handleAuthLogin))
+	mux.HandleFunc("/admin/usage", s.wrapAdminHandler(s.requireAdmin(s.handleUsage)))
+	mux.HandleFunc("/admin/deadletter", s.wrapAdminHandler(s.requireAdmin(s.handleDeadLetters)))
+	mux.HandleFunc("/admin/deadletter/{id}", s.wrapAdminHandler(s.requireAdmin(s.handleDeadLetter)))
+	mux.HandleFunc("/admin/deadletter/{id}/replay", s.wrapAdminHandler(s.requireAdmin(s.handleDeadLetterReplay)))
+	mux.HandleFunc("/admin/redrive/{id}", s.wrapAdminHandler(s.requireAdmin(s.handleRedrive)))
+	mux.HandleFunc("/config/signers", s.wrapHandler(s.handleSigners))
+	mux.HandleFunc("/config/signers/versions", s.wrapHandler(s.handleSignerVersions))
+	mux.HandleFunc("/config/signers/epochs/{epoch}", s.wrapHandler(s.handleSignerSet))
+	mux.HandleFunc("/signers/liveness", s.wrapHandler(s.handleSignerLiveness))
+	mux.HandleFunc("/verify", s.wrapHandler(s.handleVerify))
+	mux.HandleFunc("/stats/latency", s.wrapHandler(s.handleLatencyStats))
+	mux.HandleFunc("/rewards/{period}", s.wrapHandler(s.handleRewards))
+	mux.HandleFunc("/rewards/{period}/distribution", s.wrapHandler(s.handleRewards))
+	mux.HandleFunc("/status", s.wrapHandler(s.handleStatus))
+	mux.HandleFunc("/dashboard/", s.wrapHandler(s.handleDashboard))
+	mux.HandleFunc("/events", s.wrapStreamingHandler(s.handleEvents))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/admin/backup", enableCORS(logMiddleware(s.adminIPs.middleware(s.adminLimiter.middleware(tracingMiddleware(s.requireAdmin(s.handleBackup)))))))
+
+	mux.HandleFunc("/health", s.wrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "ok",
+			"time":   time.Now().Format(time.RFC3339),
+		})
+	}))
+
+	limits := s.limits
+	var handler http.Handler = mux
+	if limits.HTTP2 {
+		// Clients speak HTTP/2 in cleartext, by prior knowledge or upgrade;
+		// TLS is left to the proxy in front of the operator.
+		handler = h2c.NewHandler(mux, &http2.Server{
+			MaxConcurrentStreams: uint32(limits.MaxConcurrentStreams),
+			IdleTimeout:          limits.IdleTimeout,
+		})
+	}
+
+	s.server = &http.Server{
+		Addr:              ":" + s.port,
+		Handler:           handler,
+		ReadHeaderTimeout: limits.ReadHeaderTimeout,
+		ReadTimeout:       limits.ReadTimeout,
+		WriteTimeout:      limits.WriteTimeout,
+		IdleTimeout:       limits.IdleTimeout,
+		MaxHeaderBytes:    limits.MaxHeaderBytes,
+		ConnState:         trackConnState,
+	}
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		log.Fatalf("RPC server failed: %v", err)
+	}
+	if limits.MaxConnections > 0 {
+		// Connections past the cap wait in the accept backlog rather than
+		// costing a file descriptor each.
+		listener = netutil.LimitListener(listener, limits.MaxConnections)
+	}
+
+	log.Printf("Starting RPC server on port %s (http2: %t, max connections: %d)", s.port, limits.HTTP2, limits.MaxConnections)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("RPC server failed: %v", err)
+		}
+	}()
+}
+
+// streamDeadline gives the next write of a streaming response
+// StreamWriteTimeout to complete, lifting the server write timeout. It fails
+// when the writer cannot take deadlines, in which case the stream must not
+// start: it would neither outlive the write timeout nor drop slow clients.
+func (s *RPCServer) streamDeadline(rc *http.ResponseController) error {
+	deadline := time.Time{}
+	if s.limits.StreamWriteTimeout > 0 {
+		deadline = time.Now().Add(s.limits.StreamWriteTimeout)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set stream write deadline: %w", err)
+	}
+	return nil
+}
+
+// deadlineWriter renews the stream write deadline before every write, so a
+// response written in one long call, such as a backup, outlives the server
+// write timeout for as long as the client keeps reading.
+type deadlineWriter struct {
+	s  *RPCServer
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.s.streamDeadline(d.rc); err != nil {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
+
+// refuseStream answers a stream request whose writer cannot stream.
+func refuseStream(w http.ResponseWriter, endpoint string, err error) {
+	log.Printf("❌ Cannot stream %s: %v", endpoint, err)
+	http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+}
+
+// streamAborted logs why a stream ended on a failed write, counting clients
+// that stopped reading.
+func streamAborted(endpoint string, err error) {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		slowClientDisconnects.WithLabelValues(endpoint).Inc()
+	}
+}
+
+// trackConnState counts the open API connections.
+func trackConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		apiOpenConnections.Inc()
+	case http.StateHijacked, http.StateClosed:
+		apiOpenConnections.Dec()
+	}
+}
+
+// Shutdown first drains the streams, which http.Server.Shutdown cannot end
+// by itself, then waits for the remaining requests. Whatever is still open
+// at the deadline is closed.
+func (s *RPCServer) Shutdown(ctx context.Context) error {
+	log.Println("Shutting down RPC server...")
+	if open := s.streams.drain(ctx); len(open) > 0 {
+		log.Printf("⚠️ Streams still open at the shutdown deadline: %v", open)
+	}
+	s.feed.Close()
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.server.Close()
+	}
+	if flushErr := s.usage.Flush(ctx); flushErr != nil {
+		log.Printf("Error flushing API usage: %v", flushErr)
+	}
+	return err
+}
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the page and limit parameters of a list query. Pages
+// are 1-based, matching the Database contract; a missing or invalid page is
+// the first, and an out-of-range limit falls back to the default.
+func parsePagination(query url.Values) (int, int) {
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > maxPageLimit {
+		limit = defaultPageLimit
+	}
+	return page, limit
+}
+
+// parseZeroBasedPagination reads the page and limit parameters of
+// /data/{id}/list, whose pages have always counted from 0, and returns the
+// 1-based page of the Database contract.
+func parseZeroBasedPagination(query url.Values) (int, int) {
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 0 {
+		page = 0
+	}
+	_, limit := parsePagination(query)
+	return page + 1, limit
+}
+
+func (s *RPCServer) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, limit := parsePagination(r.URL.Query())
+	fields := parseFieldSelection(r.URL.Query())
+
+	dataStructureID, _ := strconv.Atoi(r.URL.Query().Get("dsid"))
+	confirmed, _ := strconv.ParseBool(r.URL.Query().Get("confirmed"))
+	if !s.canRead(r, dataStructureID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var messages []store.Message
+	var total int
+	var err error
+	if confirmed {
+		messages, err = s.operator.db.GetConfirmedMessages(r.Context(), dataStructureID, nil, nil, page, limit)
+		if err == nil {
+			total, err = s.operator.db.CountConfirmed(r.Context(), dataStructureID)
+		}
+	} else {
+		messages, err = s.operator.db.GetAllMessages(r.Context(), dataStructureID, page, limit)
+		if err == nil {
+			total, err = s.operator.db.CountMessages(r.Context(), dataStructureID)
+		}
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields.messages(messages))
+}
+
+func (s *RPCServer) handleDataStructure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/data/")
+	parts := strings.Split(path, "/")
+
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	dataStructureID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid data structure ID", http.StatusBadRequest)
+		return
+	}
+	if !s.canRead(r, dataStructureID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "list":
+		s.handleFilteredList(w, r, dataStructureID)
+	case "latest":
+		s.handleLatest(w, r, dataStructureID)
+	case "stats":
+		s.handleStats(w, r, dataStructureID)
+	case "export":
+		s.handleExport(w, r, dataStructureID)
+	case "gaps":
+		s.handleGaps(w, r, dataStructureID)
+	case "structure":
+		s.handleStructure(w, r, dataStructureID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// rangeSuffixes maps list query suffixes to the bound they set on an integer
+// field, e.g. price_gte=100.
+var rangeSuffixes = []string{"_gte", "_gt", "_lte", "_lt"}
+
+// parseRangeFilters collects the range parameters of a list query, merging
+// bounds on the same field. It returns the remaining parameters untouched.
+func parseRangeFilters(query url.Values) ([]store.RangeFilter, url.Values, error) {
+	byField := make(map[string]*store.RangeFilter)
+	var order []string
+	rest := make(url.Values)
+
+	for key, values := range query {
+		suffix := ""
+		for _, candidate := range rangeSuffixes {
+			if strings.HasSuffix(key, candidate) && len(key) > len(candidate) {
+				suffix = candidate
+				break
+			}
+		}
+		if suffix == "" || len(values) == 0 {
+			rest[key] = values
+			continue
+		}
+
+		bound, ok := new(big.Int).SetString(values[0], 10)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s must be an integer", key)
+		}
+
+		field := strings.TrimSuffix(key, suffix)
+		filter, exists := byField[field]
+		if !exists {
+			filter = &store.RangeFilter{Field: field}
+			byField[field] = filter
+			order = append(order, field)
+		}
+
+		switch suffix {
+		case "_gt":
+			bound.Add(bound, big.NewInt(1))
+			fallthrough
+		case "_gte":
+			if filter.Min == nil || bound.Cmp(filter.Min) > 0 {
+				filter.Min = bound
+			}
+		case "_lt":
+			bound.Sub(bound, big.NewInt(1))
+			fallthrough
+		case "_lte":
+			if filter.Max == nil || bound.Cmp(filter.Max) < 0 {
+				filter.Max = bound
+			}
+		}
+	}
+
+	sort.Strings(order)
+	ranges := make([]store.RangeFilter, 0, len(order))
+	for _, field := range order {
+		ranges = append(ranges, *byField[field])
+	}
+	return ranges, rest, nil
+}
+
+func (s *RPCServer) handleFilteredList(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	query := r.URL.Query()
+
+	ranges, query, err := parseRangeFilters(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	confirmed, _ := strconv.ParseBool(query.Get("confirmed"))
+	fields := parseFieldSelection(query)
+
+	// Get all query params (field=value pairs)
+	fieldFilters := make(map[string]string)
+	for field, values := range query {
+		if field == "page" || field == "limit" || field == "confirmed" || field == "fields" {
+			continue
+		}
+		if len(values) > 0 {
+			fieldFilters[field] = values[0]
+		}
+	}
+
+	page, limit := parseZeroBasedPagination(query)
+
+	var equals []store.FieldFilter
+	for f, v := range fieldFilters {
+		equals = append(equals, store.FieldFilter{Field: f, Value: v})
+	}
+
+	// Confirmed-only queries walk the newest messages and check every
+	// filter per message.
+	if confirmed {
+		messages, err := s.operator.db.GetConfirmedMessages(r.Context(), dataStructureID, ranges, equals, page, limit)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(ranges) == 0 && len(equals) == 0 {
+			total, err := s.operator.db.CountConfirmed(r.Context(), dataStructureID)
+			if err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(totalCountHeader, strconv.Itoa(total))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fields.messages(messages))
+		return
+	}
+
+	// Range queries scan the numeric index and check the other filters
+	// against each message, ordered by the first range field.
+	if len(ranges) > 0 {
+		messages, err := s.operator.db.GetMessagesByRange(r.Context(), dataStructureID, ranges, equals, page, limit)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fields.messages(messages))
+		return
+	}
+
+	// For simplicity, we'll just use the first field filter
+	var field, value string
+	for f, v := range fieldFilters {
+		field = f
+		value = v
+		break
+	}
+
+	messages, err := s.operator.db.GetMessagesByField(r.Context(), dataStructureID, field, value, page, limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	total, err := s.operator.db.CountMessages(r.Context(), dataStructureID, store.FieldFilter{Field: field, Value: value})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields.messages(messages))
+}
+
+// handleStats reports message counts and the newest confirmed message of a
+// data structure.
+func (s *RPCServer) handleStats(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	stats, err := s.operator.db.GetDataStructureStats(r.Context(), dataStructureID)
+	if err != nil && !errors.Is(err, store.ErrNoStats) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGaps reports the stored sequence range of a data structure and the
+// sequence numbers missing from it, so consumers know whether the history
+// they read is complete.
+func (s *RPCServer) handleGaps(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	info, err := s.operator.db.GetSequenceInfo(r.Context(), dataStructureID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// StructureInfo is the public definition of a data structure: the fields
+// of its messages and what their prices are quoted in by default.
+type StructureInfo struct {
+	Name           string                  `json:"name"`
+	Fields         []StructureField        `json:"fields"`
+	RequiredFields []string                `json:"required_fields,omitempty"`
+	HashVersion    int                     `json:"hash_version"`
+	Metadata       collector.QuoteMetadata `json:"metadata"`
+}
+
+type StructureField struct {
+	Name         string `json:"name"`
+	SolidityType string `json:"solidity_type"`
+}
+
+// handleStructure lists the definition loaded under the structure's ID, as
+// a list of at most one.
+func (s *RPCServer) handleStructure(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	infos := []StructureInfo{}
+	for name, structure := range s.structures {
+		if structure.ID != dataStructureID {
+			continue
+		}
+		info := StructureInfo{
+			Name:           name,
+			Fields:         make([]StructureField, len(structure.Fields)),
+			RequiredFields: structure.RequiredFields,
+			HashVersion:    structure.HashVersion,
+			Metadata:       structure.Metadata,
+		}
+		if info.HashVersion == 0 {
+			info.HashVersion = HashVersionJSON
+		}
+		for i, f := range structure.Fields {
+			info.Fields[i] = StructureField{Name: f.Name, SolidityType: f.SolidityType}
+		}
+		infos = append(infos, info)
+	}
+	if len(infos) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// exportFlushEvery is how many exported messages are buffered before the
+// response is flushed to the client.
+const exportFlushEvery = 100
+
+// handleExport streams every message in an optional [from, to] timestamp
+// window as newline-delimited JSON, oldest first, without loading the
+// history into memory.
+func (s *RPCServer) handleExport(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	query := r.URL.Query()
+	var from, to int64
+	for name, dst := range map[string]*int64{"from": &from, "to": &to} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			http.Error(w, fmt.Sprintf("Invalid %s timestamp", name), http.StatusBadRequest)
+			return
+		}
+		*dst = v
+	}
+
+	draining, done, ok := s.streams.track("export")
+	if !ok {
+		refuseDraining(w)
+		return
+	}
+	defer done()
+
+	// Exports outlive the server write timeout; they end when the client
+	// disconnects or stops reading, or the server shuts down.
+	rc := http.NewResponseController(w)
+	if err := s.streamDeadline(rc); err != nil {
+		refuseStream(w, "export", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+
+	count := 0
+	var writeErr error
+	var last int64
+	interrupted := false
+	err := s.operator.iterateMessages(r.Context(), dataStructureID, from, to, func(msg store.Message) bool {
+		select {
+		case <-draining:
+			interrupted = true
+			return false
+		default:
+		}
+		if writeErr = enc.Encode(msg); writeErr != nil {
+			return false
+		}
+		last = msg.Timestamp
+		count++
+		if count%exportFlushEvery == 0 {
+			if writeErr = rc.Flush(); writeErr != nil && !errors.Is(writeErr, http.ErrNotSupported) {
+				return false
+			}
+			if writeErr = s.streamDeadline(rc); writeErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		// Headers are already sent once a message has been written, so the
+		// stream just ends early.
+		if count == 0 {
+			http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		}
+		log.Printf("❌ Export of structure %d failed after %d messages: %v", dataStructureID, count, err)
+		return
+	}
+	if interrupted {
+		// The last line tells the client where to resume; messages sharing
+		// the last timestamp are exported again.
+		if err := s.streamDeadline(rc); err == nil {
+			enc.Encode(map[string]interface{}{"shutdown": true, "exported": count, "resume_from": last})
+		}
+		log.Printf("Export of structure %d interrupted by shutdown after %d messages", dataStructureID, count)
+		return
+	}
+	if writeErr != nil {
+		streamAborted("export", writeErr)
+		log.Printf("⚠️ Export of structure %d aborted after %d messages: %v", dataStructureID, count, writeErr)
+	}
+}
+
+func (s *RPCServer) handleLatest(w http.ResponseWriter, r *http.Request, dataStructureID int) {
+	query := r.URL.Query()
+	field := query.Get("field")
+	value := query.Get("value")
+	fields := parseFieldSelection(query)
+
+	if field == "" || value == "" {
+		field, value = "", ""
+	}
+
+	// A found message is returned as a pointer, nil when there is none.
+	key := fmt.Sprintf("latest:%d:%s=%s", dataStructureID, field, value)
+	result, err := s.coalesce.do(r.Context(), "latest", key, func(ctx context.Context) (interface{}, error) {
+		var msg store.Message
+		var found bool
+		var err error
+		if field != "" {
+			msg, found, err = s.operator.db.GetLatestByField(ctx, dataStructureID, field, value)
+		} else {
+			msg, found, err = s.operator.db.GetLatestConfirmed(ctx, dataStructureID)
+		}
+		if err != nil || !found {
+			return (*store.Message)(nil), err
+		}
+		return &msg, nil
+	})
+	msg, _ := result.(*store.Message)
+
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if msg == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields.message(*msg))
+}
+
+func (s *RPCServer) handleGetByHash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		http.Error(w, "Missing hash parameter", http.StatusBadRequest)
+		return
+	}
+
+	msg, exists := s.operator.findMessage(r.Context(), hash)
+	if !exists || !s.canRead(r, msg.DataStructureID) {
+		http.Error(w, "Hash not found", http.StatusNotFound)
+		return
+	}
+	if msg.Signatures == nil {
+		msg.Signatures = map[string]string{}
+	}
+
+	if latency, ok := s.operator.db.GetLatency(r.Context(), hash); ok {
+		msg.Latency = &latency
+	}
+	msg.Confirmation = s.operator.confirmationOf(r.Context(), hash)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// minHashPrefix is the shortest hash prefix /search accepts, so that a
+// search cannot walk the whole keyspace for a page of arbitrary messages.
+const minHashPrefix = 6
+
+// handleSearch resolves a truncated hash, as copied from logs or explorers,
+// to the messages whose hash starts with it. The response says whether more
+// messages than the limit matched.
+func (s *RPCServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query.Get("hash_prefix")), "0x"))
+	if len(prefix) < minHashPrefix {
+		http.Error(w, fmt.Sprintf("hash_prefix must have at least %d hex digits", minHashPrefix), http.StatusBadRequest)
+		return
+	}
+	if strings.Trim(prefix, "0123456789abcdef") != "" {
+		http.Error(w, "hash_prefix must be hexadecimal", http.StatusBadRequest)
+		return
+	}
+	_, limit := parsePagination(query)
+
+	messages, err := s.operator.db.SearchByHashPrefix(r.Context(), prefix, limit+1)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	truncated := len(messages) > limit
+	if truncated {
+		messages = messages[:limit]
+	}
+	readable := make([]store.Message, 0, len(messages))
+	for _, msg := range messages {
+		if s.canRead(r, msg.DataStructureID) {
+			readable = append(readable, msg)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hash_prefix": prefix,
+		"messages":    parseFieldSelection(query).messages(readable),
+		"truncated":   truncated,
+	})
+}
+
+func (s *RPCServer) handleGetStructures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := s.coalesce.do(r.Context(), "structures", "structures", func(ctx context.Context) (interface{}, error) {
+		return s.operator.db.GetDataStructures(ctx)
+	})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	// The shared result is filtered into a new slice per caller.
+	readable := []int{}
+	for _, id := range result.([]int) {
+		if s.canRead(r, id) {
+			readable = append(readable, id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readable)
+}
+
+func (s *RPCServer) handleLatencyStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.operator.latency.Percentiles())
+}
+
+// handleBackup streams a snapshot archive of the datastore. It bypasses the
+// timeout middleware and lifts the server write timeout since large stores
+// take longer than a regular request.
+func (s *RPCServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Backups cannot resume, so draining only waits for them.
+	_, done, ok := s.streams.track("backup")
+	if !ok {
+		refuseDraining(w)
+		return
+	}
+	defer done()
+
+	rc := http.NewResponseController(w)
+	if err := s.streamDeadline(rc); err != nil {
+		refuseStream(w, "backup", err)
+		return
+	}
+
+	filename := fmt.Sprintf("l0proof-snapshot-%s.gz", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := s.operator.db.Backup(r.Context(), &deadlineWriter{s: s, w: w, rc: rc}); err != nil {
+		streamAborted("backup", err)
+		log.Printf("Backup failed: %v", err)
+	}
+}
+
+// handleVerify re-verifies signatures against the trusted set. GET checks the
+// signatures stored for a hash against the signer set it was confirmed under,
+// or else that of its staking epoch; POST checks an arbitrary hash and
+// signatures supplied by the caller, against the set of "epoch" when given.
+func (s *RPCServer) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var hash string
+	var sigs []SignatureInput
+	var hashCheck *bool
+	var epoch uint64
+	var confirmation *store.Confirmation
+
+	switch r.Method {
+	case http.MethodGet:
+		hash = r.URL.Query().Get("hash")
+		if hash == "" {
+			http.Error(w, "Missing hash parameter", http.StatusBadRequest)
+			return
+		}
+		msg, exists := s.operator.findMessage(r.Context(), hash)
+		if !exists || !s.canRead(r, msg.DataStructureID) {
+			http.Error(w, "Hash not found", http.StatusNotFound)
+			return
+		}
+		matches := hashMatches(msg)
+		hashCheck = &matches
+		epoch = msg.Epoch
+		confirmation = s.operator.confirmationOf(r.Context(), hash)
+		for signer, signature := range msg.Signatures {
+			sigs = append(sigs, SignatureInput{ClaimedSigner: signer, Signature: signature})
+		}
+	case http.MethodPost:
+		var req struct {
+			Hash          string            `json:"hash"`
+			Signatures    map[string]string `json:"signatures"`
+			SignatureList []string          `json:"signature_list"`
+			Epoch         uint64            `json:"epoch"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Hash == "" {
+			http.Error(w, "Missing hash", http.StatusBadRequest)
+			return
+		}
+		hash = req.Hash
+		epoch = req.Epoch
+		for signer, signature := range req.Signatures {
+			sigs = append(sigs, SignatureInput{ClaimedSigner: signer, Signature: signature})
+		}
+		for _, signature := range req.SignatureList {
+			sigs = append(sigs, SignatureInput{Signature: signature})
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var report VerificationReport
+	var err error
+	switch {
+	case confirmation != nil:
+		report, err = s.operator.VerifySignaturesConfirmed(hash, sigs, *confirmation)
+	case epoch > 0:
+		report, err = s.operator.VerifySignaturesAt(r.Context(), hash, sigs, epoch)
+	default:
+		report, err = s.operator.VerifySignatures(hash, sigs)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report.HashMatches = hashCheck
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (s *RPCServer) handleSigners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"trusted_addresses":   s.operator.trustedSigners(),
+		"key_rotations":       s.operator.pendingRotations(),
+		"threshold":           s.operator.threshold(),
+		"threshold_overrides": s.operator.thresholdOverrides(),
+		"committees":          s.operator.committeeSeats(),
+		"weights":             s.operator.signerWeightsSnapshot(),
+		"weight_threshold":    s.operator.weightThreshold(),
+		"operator_address":    s.operator.address.Hex(),
+		"epoch":               s.operator.currentEpoch(),
+	})
+}
+
+// handleSignerSet serves the signer set recorded for a staking epoch, which
+// a proof from that epoch is checked against.
+func (s *RPCServer) handleSignerSet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	epoch, err := strconv.ParseUint(r.PathValue("epoch"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid epoch", http.StatusBadRequest)
+		return
+	}
+	set, found, err := s.operator.db.GetSignerSet(r.Context(), epoch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Epoch not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set)
+}
+
+// handleRewards serves the reward report of a period, or under
+// /distribution its Merkle distribution file.
+func (s *RPCServer) handleRewards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	period, err := strconv.ParseUint(r.PathValue("period"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid period", http.StatusBadRequest)
+		return
+	}
+	report, err := s.operator.RewardReport(r.Context(), period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.HasSuffix(r.URL.Path, "/distribution") {
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	distribution, err := report.Distribution()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("rewards-%d.json", period)))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(distribution)
+}
+
+// handleUsage reports API usage per key from one day through another, as
+// YYYY-MM-DD; both default to the current month.
+func (s *RPCServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.usage == nil {
+		http.Error(w, "Usage accounting disabled", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+	for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(param); v != "" {
+			day, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s date", param), http.StatusBadRequest)
+				return
+			}
+			*dst = day
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := s.usage.Report(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if name := r.URL.Query().Get("key"); name != "" {
+		filtered := keys[:0]
+		for _, k := range keys {
+			if k.Name == name {
+				filtered = append(filtered, k)
+			}
+		}
+		keys = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from.Format(time.DateOnly),
+		"to":   to.Format(time.DateOnly),
+		"keys": keys,
+	})
+}
+
+func (s *RPCServer) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.dead == nil {
+		http.Error(w, "Dead letters disabled", http.StatusNotFound)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > maxDeadLetterLimit {
+		limit = defaultDeadLetterLimit
+	}
+	letters, err := s.dead.List(r.Context(), r.URL.Query().Get("reason"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if letters == nil {
+		letters = []store.DeadLetter{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letters)
+}
+
+// handleDeadLetter discards a dead letter on DELETE.
+func (s *RPCServer) handleDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.dead == nil {
+		http.Error(w, "Dead letters disabled", http.StatusNotFound)
+		return
+	}
+
+	err := s.dead.Discard(r.Context(), r.PathValue("id"))
+	if errors.Is(err, errDeadLetterNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *RPCServer) handleDeadLetterReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.dead == nil {
+		http.Error(w, "Dead letters disabled", http.StatusNotFound)
+		return
+	}
+
+	sr, err := s.dead.Replay(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, errDeadLetterNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errStandbyReplay), errors.Is(err, errPublicationPaused):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SubmitResponse{Hash: sr.Hash, Timestamp: sr.Timestamp})
+}
+
+// handleRedrive publishes the unconfirmed messages of a data structure again,
+// those with a timestamp between "from" and "to" when given, under the
+// current time unless "fresh" is false.
+func (s *RPCServer) handleRedrive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.redriver == nil {
+		http.Error(w, "Redrive disabled", http.StatusNotFound)
+		return
+	}
+
+	dataStructureID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid data structure ID", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	req := RedriveRequest{DataStructureID: dataStructureID, Fresh: true}
+	if v := query.Get("fresh"); v != "" {
+		if req.Fresh, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid fresh flag", http.StatusBadRequest)
+			return
+		}
+	}
+	for param, dst := range map[string]*int64{"from": &req.From, "to": &req.To} {
+		if v := query.Get(param); v != "" {
+			if *dst, err = strconv.ParseInt(v, 10, 64); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s timestamp", param), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	req.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if req.Limit <= 0 || req.Limit > maxRedriveLimit {
+		req.Limit = defaultRedriveLimit
+	}
+
+	result, err := s.redriver.Redrive(r.Context(), req)
+	switch {
+	case errors.Is(err, errStandbyReplay), errors.Is(err, errPublicationPaused):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleSignerVersions serves the matrix of protocol and software versions
+// seen from each signer, for tracking a rollout.
+func (s *RPCServer) handleSignerVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"protocol_version": ProtocolVersion,
+		"software":         buildVersion(),
+		"signers":          s.operator.SignerVersions(),
+	})
+}
+
+func (s *RPCServer) handleSignerLiveness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.operator.SignerLiveness())
+}
+
+func (s *RPCServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.submitter == nil || s.submitKey == "" {
+		http.Error(w, "Submission disabled", http.StatusForbidden)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.submitKey)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SubmitRequest
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	signRequest, err := s.submitter.Submit(r.Context(), req)
+	switch {
+	case errors.Is(err, errUnknownStructure):
+		http.Error(w, fmt.Sprintf("Unknown structure_id: %s", req.StructureID), http.StatusBadRequest)
+		return
+	case errors.Is(err, errPublicationPaused):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, errSubmitPublish):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		writeValidationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hash":      signRequest.Hash,
+		"timestamp": signRequest.Timestamp,
+	})
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		json.NewEncoder(w).Encode(verr)
+		return
+	}
+	json.NewEncoder(w).Encode(&ValidationError{Problems: []string{err.Error()}})
+}
+
+func (s *RPCServer) handleSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	health := []collector.SourceHealth{}
+	if s.sources != nil {
+		health = s.sources.Health()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
@@ -0,0 +1,114 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"bootstrap/pkg/store"
+)
+
+func TestStreamDeadlineThroughMiddleware(t *testing.T) {
+	s := &RPCServer{limits: ServerLimits{StreamWriteTimeout: time.Second}}
+	result := make(chan error, 1)
+	srv := httptest.NewServer(s.wrapStreamingHandler(func(w http.ResponseWriter, r *http.Request) {
+		result <- s.streamDeadline(http.NewResponseController(w))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := <-result; err != nil {
+		t.Fatalf("stream deadline through the streaming middleware: %v", err)
+	}
+}
+
+func TestStreamDeadlineUnsupported(t *testing.T) {
+	s := &RPCServer{limits: ServerLimits{StreamWriteTimeout: time.Second}}
+	if err := s.streamDeadline(http.NewResponseController(httptest.NewRecorder())); err == nil {
+		t.Fatal("expected an error from a writer without deadlines")
+	}
+}
+
+// slowBackupDatabase writes its backup in chunks, pausing between them.
+type slowBackupDatabase struct {
+	store.Database
+	chunks int
+	pause  time.Duration
+}
+
+func (d *slowBackupDatabase) Backup(ctx context.Context, w io.Writer) error {
+	for i := 0; i < d.chunks; i++ {
+		time.Sleep(d.pause)
+		if _, err := w.Write([]byte("chunk\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestBackupOutlivesWriteTimeout(t *testing.T) {
+	op := newTestOperator(t, clock.New(), 1, func(db store.Database) store.Database {
+		return &slowBackupDatabase{Database: db, chunks: 4, pause: 100 * time.Millisecond}
+	})
+	s := &RPCServer{operator: op.OperatorNode, streams: newStreamTracker(), limits: ServerLimits{StreamWriteTimeout: time.Second}}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(s.handleBackup))
+	srv.Config.WriteTimeout = 150 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("backup cut off after %d bytes: %v", len(body), err)
+	}
+	if want := strings.Repeat("chunk\n", 4); string(body) != want {
+		t.Fatalf("backup body: got %q, want %q", body, want)
+	}
+}
+
+func TestFilteredListPagesFromZero(t *testing.T) {
+	op := newTestOperator(t, clock.New(), 1, nil)
+	now := op.clock.Now().Unix()
+	var newest string
+	for i, price := range []string{"100", "101", "102"} {
+		req := testSignRequest(t, price, now+int64(i))
+		if err := op.db.StoreData(context.Background(), req.Hash, req.Data, req.DataStructure, req.DataStructureMeta, req.Timestamp, req.DataStructureId, req.HashVersion, req.RequestID, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		newest = req.Hash
+	}
+	s := &RPCServer{operator: op.OperatorNode}
+
+	for page, want := range map[string]int{"0": 1, "2": 1, "3": 0} {
+		rec := httptest.NewRecorder()
+		s.handleDataStructure(rec, httptest.NewRequest(http.MethodGet, "/data/1/list?ticker=SBER&limit=1&page="+page, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %s: got %d: %s", page, rec.Code, rec.Body)
+		}
+		var messages []map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&messages); err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != want {
+			t.Fatalf("page %s: got %d messages, want %d", page, len(messages), want)
+		}
+		if page == "0" && messages[0]["hash"] != newest {
+			t.Fatalf("page 0 holds %v, want the newest message %s", messages[0]["hash"], newest)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+// Package operator runs the bootstrap operator: it collects data, gossips
+// sign requests to signers and serves the confirmed results over HTTP.
+package operator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+
+	"bootstrap/pkg/archive"
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// Run starts an operator from cfg and blocks until ctx is done, then shuts
+// it down. cfg should have passed Validate.
+func Run(ctx context.Context, cfg Config) (err error) {
+	trustedAddrs := cfg.AllTrustedAddresses()
+
+	privKey, err := getOrCreatePrivKey(cfg.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	backend, err := store.Open(cfg.DB)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+	db := newVerifyingDatabase(newTracingDatabase(backend))
+
+	// Until the operator exists nothing else owns the database; from then on
+	// its shutdown closes the database and the host.
+	var operator *OperatorNode
+	defer func() {
+		if err == nil {
+			return
+		}
+		if operator != nil {
+			operator.Shutdown()
+			return
+		}
+		log.Println("Cleaning up resources...")
+		if err := db.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+		cancel()
+	}()
+
+	var auditLog *AuditLog
+	if cfg.Audit.Path != "" {
+		auditLog, err = OpenAuditLog(cfg.Audit.Path, cfg.Audit.Sync)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		db = newAuditingDatabase(db, auditLog)
+		if err := auditLog.RecordTrustedSet(trustedAddrs); err != nil {
+			return fmt.Errorf("failed to record trusted set: %w", err)
+		}
+		seq, head := auditLog.Head()
+		log.Printf("✅ Audit log %s at entry %d (%s)", cfg.Audit.Path, seq, head)
+	}
+
+	if cfg.DB.RestoreSnapshot != "" {
+		if err := restoreSnapshot(ctx, db, cfg.DB.RestoreSnapshot); err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+	}
+
+	operator, err = NewOperatorNode(ctx, cancel, privKey, db, cfg.Topic, trustedAddrs, cfg.Network, cfg.Gossip, cfg.Intake)
+	if err != nil {
+		return fmt.Errorf("failed to create operator node: %w", err)
+	}
+
+	operator.SetMaxPending(cfg.Pending.MaxRequests)
+	operator.SetPendingTimings(cfg.Pending.Expiry, cfg.Pending.RebroadcastInterval, cfg.Pending.CleanupInterval)
+	operator.SetConflictWindow(cfg.Pending.ConflictWindow)
+
+	if operatorPeers, _ := cfg.TrustedOperatorPeers(); len(operatorPeers) > 0 {
+		operator.SetTrustedOperators(operatorPeers)
+	}
+	if cfg.Peers.AcceptForeignSignRequests {
+		log.Println("⚠️ Accepting sign requests from any peer")
+	}
+	operator.SetAcceptForeignRequests(cfg.Peers.AcceptForeignSignRequests)
+	if len(cfg.Peers.Originators) > 0 {
+		operator.SetOriginators(cfg.Peers.Originators)
+		log.Printf("📥 Accepting sign requests originated by %d nodes", len(cfg.Peers.Originators))
+	}
+	if replicas, _ := cfg.ReplicaPeers(); len(replicas) > 0 {
+		operator.SetReplicas(replicas)
+	}
+	if cfg.Replication.Primary != "" {
+		if err := operator.SetReplicationPrimary(cfg.Replication.Primary, cfg.Replication.FailoverAfter); err != nil {
+			return err
+		}
+		log.Printf("🪞 Running as a standby of %s", cfg.Replication.Primary)
+	}
+	// Everything below writes through the operator's database so that
+	// standbys see the changes.
+	db = operator.db
+	operator.SetMaxTimestampSkew(cfg.MaxTimestampSkew)
+	operator.SetKeyRotationOverlap(cfg.KeyRotationOverlap)
+	operator.SetLivenessWindow(cfg.SignerLivenessWindow)
+	operator.SetPauseWhenAtRisk(cfg.Alerts.PauseWhenAtRisk)
+	if urls := cfg.Alerts.WebhookURLs; len(urls) > 0 {
+		(&Webhooks{URLs: urls}).Subscribe(ctx, operator.events)
+		log.Printf("🚨 Sending quorum alerts to %d webhooks", len(urls))
+	}
+	if cfg.Staking.RPCURL != "" {
+		client, err := NewStakingClient(cfg.Staking)
+		if err != nil {
+			return err
+		}
+		staking := &Staking{
+			Client:       client,
+			Operator:     operator,
+			EpochLength:  cfg.Staking.EpochLength,
+			PollInterval: cfg.Staking.PollInterval,
+		}
+		if err := staking.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start staking: %w", err)
+		}
+	} else if len(cfg.SignerWeights) > 0 {
+		operator.SetSignerWeights(cfg.SignerWeights)
+		log.Printf("⚖️ Weighted voting enabled, %d weight required by default", operator.weightThreshold())
+	}
+	rewardPeriod := cfg.Rewards.Period
+	if cfg.Staking.RPCURL != "" {
+		rewardPeriod = cfg.Staking.EpochLength
+	}
+	var rewardPool *big.Int
+	if cfg.Rewards.Pool != "" {
+		rewardPool, _ = new(big.Int).SetString(cfg.Rewards.Pool, 10)
+		log.Printf("💰 Sharing %s reward units between signers every %s", rewardPool, rewardPeriod)
+	}
+	operator.SetRewards(rewardPool, rewardPeriod)
+
+	go checkClockDrift(cfg.NTPServer, cfg.MaxTimestampSkew)
+
+	rpcServer := NewRPCServer(operator, cfg.API.Port)
+	rpcServer.SetServerLimits(cfg.API.ServerLimits())
+	if len(cfg.API.Admins) > 0 || len(cfg.API.Consumers) > 0 {
+		admins, err := NewAdminAuth(cfg.API.Admins, cfg.API.Consumers, cfg.API.AdminDomain, cfg.API.AdminSessionTTL)
+		if err != nil {
+			return err
+		}
+		rpcServer.EnableAdminAuth(admins)
+		log.Printf("🔐 Accepting Sign-In with Ethereum from %d admin and %d consumer addresses", len(cfg.API.Admins), len(cfg.API.Consumers))
+	}
+	rpcServer.SetRouteRoles(cfg.API.Roles())
+	if auditLog != nil {
+		rpcServer.SetAuditLog(auditLog)
+	}
+	rpcServer.SetRateLimits(cfg.API.RateLimits())
+	rpcServer.SetAdminIPFilter(cfg.API.AdminIPFilter())
+	if usage := NewUsageMeter(db, cfg.API.Usage); usage != nil {
+		if err := usage.Start(ctx); err != nil {
+			return err
+		}
+		rpcServer.SetUsageMeter(usage)
+		log.Printf("🧾 Accounting API usage for %d keys", len(cfg.API.Usage.Keys))
+	}
+	if !rpcServer.adminsConfigured() {
+		log.Printf("⚠️ No admin address or admin API key configured; admin endpoints are disabled")
+	}
+	adminPublisher := &PubSubService{
+		topic:          operator.topic,
+		db:             db,
+		state:          operator,
+		latency:        operator.latency,
+		events:         operator.events,
+		clock:          operator.clock,
+		publishTimeout: 10 * time.Second,
+		maxRetries:     3,
+		retryDelay:     2 * time.Second,
+	}
+	deadLetters := NewDeadLetters(db, adminPublisher)
+	deadLetters.Subscribe(operator.events)
+	rpcServer.EnableDeadLetters(deadLetters)
+	rpcServer.EnableRedrive(NewRedriver(db, adminPublisher))
+
+	// Start data collection
+	collection := cfg.Collector
+	if collection.EnableMockSources {
+		log.Println("⚠️ Mock price sources are enabled; do not use in production")
+	}
+	sourceConfig, err := collector.LoadSourceConfig(collection.SourceConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load source config: %w", err)
+	}
+	jobs, err := collector.LoadJobConfigs(collection.JobsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load job config: %w", err)
+	}
+
+	var workers []*Worker
+	sourceRegistry := collector.NewSourceRegistry()
+	rpcServer.SetSourceRegistry(sourceRegistry)
+
+	structures, err := LoadDataStructures(collection.DataStructuresPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to load data structures: %v", err)
+	} else {
+		if err := addTenants(operator, cfg, structures); err != nil {
+			return err
+		}
+		rpcServer.SetStructures(structures)
+		if err := applyRetentionPolicies(ctx, db, structures); err != nil {
+			log.Printf("Warning: Failed to apply retention policies: %v", err)
+		}
+		for name, structure := range structures {
+			if structure.Threshold > 0 {
+				operator.SetThresholdOverride(structure.ID, structure.Threshold)
+			}
+			if len(structure.Signers) > 0 || len(structure.ExcludeSigners) > 0 {
+				operator.SetCommittee(structure.ID, structure.Signers, structure.ExcludeSigners)
+				if committee := structure.committeeOf(operator.trustedSigners()); len(committee) == 0 {
+					log.Printf("Warning: no trusted signer is eligible for structure %s", name)
+				}
+			}
+			if structure.WeightThreshold > 0 {
+				operator.SetWeightThresholdOverride(structure.ID, structure.WeightThreshold)
+			}
+			if structure.PendingExpirySeconds > 0 {
+				operator.SetPendingExpiryOverride(structure.ID, time.Duration(structure.PendingExpirySeconds)*time.Second)
+			}
+		}
+		ensureStats(ctx, db, operator.thresholdFor)
+
+		if collection.Disabled {
+			log.Println("🧮 Data collection disabled, signing submitted data only")
+		} else {
+			for _, ticker := range collection.Tickers {
+				structureID := "stock_quote"
+
+				sources, err := collector.CreatePriceSources(ticker, collection.Sources, collection.EnableMockSources, sourceConfig)
+				if err != nil {
+					return fmt.Errorf("failed to create price sources for %s: %w", ticker, err)
+				}
+				var streams []collector.StreamingPriceSource
+				for i, source := range sources {
+					if stream, ok := source.(collector.StreamingPriceSource); ok {
+						streams = append(streams, stream)
+					}
+					sources[i] = sourceRegistry.Wrap(ticker, source)
+				}
+
+				job := jobs[ticker]
+				aggregator := &collector.PriceAggregator{
+					Sources:      sources,
+					Timeout:      job.AggregationTimeout(),
+					MaxStaleness: collection.StalePriceMaxAge,
+				}
+
+				factory := NewMessageFactory(structureID, ticker, structures)
+				factory.Metadata = job.Metadata
+
+				schedule, err := job.ScheduleOr(collection.Schedule, collection.ScheduleTZ, collection.Interval)
+				if err != nil {
+					return fmt.Errorf("failed to configure schedule for %s: %w", ticker, err)
+				}
+
+				maxRetries, retryDelay := job.PublishRetries()
+				pubSubService := &PubSubService{
+					topic:          operator.topic,
+					db:             db,
+					state:          operator,
+					latency:        operator.latency,
+					events:         operator.events,
+					clock:          operator.clock,
+					publishTimeout: 10 * time.Second,
+					maxRetries:     maxRetries,
+					retryDelay:     retryDelay,
+				}
+
+				worker := &Worker{
+					Aggregator:     aggregator,
+					PubSub:         pubSubService,
+					MessageFactory: factory,
+					Ticker:         ticker,
+					StructureID:    structureID,
+					Schedule:       schedule,
+					Shutdown:       make(chan struct{}),
+					Bounds:         job.Bounds,
+					Clock:          operator.clock,
+				}
+
+				workers = append(workers, worker)
+
+				run := worker.Run
+				if collection.WorkerMode == "stream" {
+					if len(streams) == 0 {
+						log.Printf("Warning: no streaming sources for %s, falling back to polling", ticker)
+					} else {
+						run = (&StreamWorker{
+							Worker:            worker,
+							Streams:           streams,
+							Window:            collection.StreamWindow,
+							DeviationBps:      collection.DeviationBps,
+							HeartbeatInterval: job.Interval(collection.Interval),
+						}).Run
+					}
+				}
+
+				go func(run func(context.Context) error, t string) {
+					log.Printf("Starting data source worker for %s", t)
+					if err := run(ctx); err != nil {
+						log.Printf("Error running data source worker for %s: %v", t, err)
+					}
+				}(run, ticker)
+			}
+
+			baskets, err := loadBaskets(collection.BasketsPath)
+			if err != nil {
+				return fmt.Errorf("failed to load baskets: %w", err)
+			}
+
+			// startDerivedWorker runs a job priced from confirmed messages of
+			// other feeds rather than from price sources.
+			startDerivedWorker := func(kind, name, structureID string, source collector.PriceSource, configure func(*MessageFactory)) error {
+				job := jobs[name]
+				schedule, err := job.ScheduleOr(collection.Schedule, collection.ScheduleTZ, collection.Interval)
+				if err != nil {
+					return fmt.Errorf("failed to configure schedule for %s: %w", name, err)
+				}
+				maxRetries, retryDelay := job.PublishRetries()
+				factory := NewMessageFactory(structureID, name, structures)
+				factory.Metadata = job.Metadata
+				if configure != nil {
+					configure(factory)
+				}
+
+				worker := &Worker{
+					Aggregator: &collector.PriceAggregator{
+						Sources: []collector.PriceSource{sourceRegistry.Wrap(name, source)},
+						Timeout: job.AggregationTimeout(),
+					},
+					PubSub: &PubSubService{
+						topic:          operator.topic,
+						db:             db,
+						state:          operator,
+						latency:        operator.latency,
+						events:         operator.events,
+						clock:          operator.clock,
+						publishTimeout: 10 * time.Second,
+						maxRetries:     maxRetries,
+						retryDelay:     retryDelay,
+					},
+					MessageFactory: factory,
+					Ticker:         name,
+					StructureID:    structureID,
+					Schedule:       schedule,
+					Shutdown:       make(chan struct{}),
+					Bounds:         job.Bounds,
+					Clock:          operator.clock,
+				}
+				workers = append(workers, worker)
+
+				go func(w *Worker) {
+					log.Printf("Starting %s worker for %s", kind, w.Ticker)
+					if err := w.Run(ctx); err != nil {
+						log.Printf("Error running %s worker for %s: %v", kind, w.Ticker, err)
+					}
+				}(worker)
+				return nil
+			}
+
+			for name, basket := range baskets {
+				source, err := NewBasketPriceSource(name, basket, structures, db)
+				if err != nil {
+					return err
+				}
+				if err := startDerivedWorker("basket", name, "index_basket", source, nil); err != nil {
+					return err
+				}
+			}
+
+			conversions, err := loadConversions(collection.ConversionsPath)
+			if err != nil {
+				return fmt.Errorf("failed to load conversions: %w", err)
+			}
+
+			for name, conversion := range conversions {
+				source, err := NewConversionPriceSource(name, conversion, structures, db)
+				if err != nil {
+					return err
+				}
+				err = startDerivedWorker("conversion", name, "converted_quote", source, func(f *MessageFactory) {
+					f.Conversion = source
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			computedFeeds, err := loadComputedFeeds(collection.ComputedFeedsPath)
+			if err != nil {
+				return fmt.Errorf("failed to load computed feeds: %w", err)
+			}
+
+			for name, feed := range computedFeeds {
+				source, err := NewComputedPriceSource(name, feed, structures, db)
+				if err != nil {
+					return err
+				}
+				err = startDerivedWorker("computed feed", name, "computed_feed", source, func(f *MessageFactory) {
+					f.Computed = source
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			log.Println("✅ Data source workers started")
+		}
+
+		if anchorID := cfg.Audit.AnchorStructure; anchorID != "" && auditLog != nil {
+			structure, ok := structures[anchorID]
+			if !ok {
+				return fmt.Errorf("unknown audit anchor structure %q", anchorID)
+			}
+			anchor := &AuditAnchor{
+				Log: auditLog,
+				PubSub: &PubSubService{
+					topic:          operator.topic,
+					db:             db,
+					state:          operator,
+					latency:        operator.latency,
+					events:         operator.events,
+					clock:          operator.clock,
+					publishTimeout: 10 * time.Second,
+					maxRetries:     3,
+					retryDelay:     2 * time.Second,
+				},
+				StructureID: anchorID,
+				Structure:   structure,
+				Interval:    cfg.Audit.AnchorInterval,
+			}
+			go anchor.Run(ctx)
+			log.Printf("✅ Anchoring audit log head via %s", anchorID)
+		}
+
+		if heartbeatID := cfg.Heartbeat.Structure; heartbeatID != "" && cfg.Heartbeat.Interval > 0 {
+			if structure, ok := structures[heartbeatID]; ok {
+				heartbeat := &Heartbeat{
+					PubSub: &PubSubService{
+						topic:          operator.topic,
+						db:             db,
+						state:          operator,
+						latency:        operator.latency,
+						events:         operator.events,
+						clock:          operator.clock,
+						publishTimeout: 10 * time.Second,
+						maxRetries:     3,
+						retryDelay:     2 * time.Second,
+					},
+					StructureID: heartbeatID,
+					Structure:   structure,
+					Interval:    cfg.Heartbeat.Interval,
+					Peers:       operator.peerCount,
+					Clock:       operator.clock,
+				}
+				go heartbeat.Run(ctx)
+				log.Printf("✅ Publishing heartbeats via %s every %s", heartbeatID, cfg.Heartbeat.Interval)
+			} else {
+				log.Printf("Warning: heartbeat structure %q is not defined, not publishing heartbeats", heartbeatID)
+			}
+		}
+
+		producers, _ := cfg.ProducerPeers()
+		if cfg.API.SubmitAPIKey != "" || len(producers) > 0 {
+			submitter := NewSubmitter(structures, &PubSubService{
+				topic:          operator.topic,
+				db:             db,
+				state:          operator,
+				latency:        operator.latency,
+				events:         operator.events,
+				clock:          operator.clock,
+				publishTimeout: 10 * time.Second,
+				maxRetries:     3,
+				retryDelay:     2 * time.Second,
+			})
+			if apiKey := cfg.API.SubmitAPIKey; apiKey != "" {
+				rpcServer.EnableSubmit(submitter, apiKey)
+				log.Println("✅ Manual submission enabled")
+			}
+			if len(producers) > 0 {
+				operator.EnableSubmitStream(submitter, producers)
+				log.Printf("✅ Accepting submissions from %d producer peers", len(producers))
+			}
+		}
+	}
+
+	pruner := NewPruner(db, pruneInterval)
+	if cfg.Archive.Enabled() {
+		arch, err := archive.Open(cfg.Archive)
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		operator.SetArchive(arch)
+		pruner.SetArchive(arch)
+		log.Printf("📦 Archiving pruned messages to bucket %s", cfg.Archive.Bucket)
+	}
+	go pruner.Run(ctx)
+
+	go rpcServer.Start()
+	log.Println("✅ RPC server started")
+
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+
+	for _, worker := range workers {
+		log.Printf("Stopping worker for %s", worker.Ticker)
+		close(worker.Shutdown)
+	}
+
+	if err := rpcServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down RPC server: %v", err)
+	}
+
+	operator.Shutdown()
+	return nil
+}
+
+// getOrCreatePrivKey decodes a hex secp256k1 key, generating a fresh one
+// when none is configured.
+func getOrCreatePrivKey(hexKey string) (crypto.PrivKey, error) {
+	if hexKey == "" {
+		priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return priv, nil
+	}
+	pk, err := hex.DecodeString(hexKey)
+	if err != nil {
+		log.Println("Error decode PK")
+	}
+	return crypto.UnmarshalSecp256k1PrivateKey([]byte(pk))
+}
+
+// restoreSnapshot seeds an empty database from the snapshot at path. Once
+// the database holds records the snapshot is skipped, so leaving
+// restore_snapshot set does not re-apply it on every start.
+func restoreSnapshot(ctx context.Context, db store.Database, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	log.Printf("Restoring database from snapshot %s", path)
+	err = db.Restore(ctx, f)
+	if errors.Is(err, store.ErrStoreNotEmpty) {
+		log.Printf("⚠️ Database already holds records, not restoring snapshot %s", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("✅ Snapshot restored")
+	return nil
+}
@@ -0,0 +1,85 @@
+package operator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// compileSchema parses the optional JSON Schema attached to a structure.
+func (ds *DataStructure) compileSchema(name string) error {
+	if len(ds.Schema) == 0 {
+		return nil
+	}
+
+	compiled, err := jsonschema.CompileString(name+".schema.json", string(ds.Schema))
+	if err != nil {
+		return fmt.Errorf("invalid schema for %s: %w", name, err)
+	}
+	ds.compiled = compiled
+	return nil
+}
+
+// ValidateSchema checks field values against the structure's JSON Schema.
+// Structures without a schema accept any payload.
+func (ds DataStructure) ValidateSchema(fields map[string]interface{}) error {
+	if ds.compiled == nil {
+		return nil
+	}
+
+	// Round-trip through JSON so Go-typed builder output looks exactly like a
+	// decoded document to the validator.
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	err = ds.compiled.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return err
+	}
+
+	verr := &ValidationError{}
+	for _, e := range schemaErr.BasicOutput().Errors {
+		if e.KeywordLocation == "" {
+			continue
+		}
+		verr.add("%s: %s", instancePath(e.InstanceLocation), e.Error)
+	}
+	if len(verr.Problems) == 0 {
+		verr.add("%s", schemaErr.Message)
+	}
+	return verr
+}
+
+// ValidateRequest checks a built SignRequest against the structure schema.
+func (ds DataStructure) ValidateRequest(sr *SignRequest) error {
+	fields := make(map[string]interface{}, len(sr.DataStructureMeta))
+	for i, name := range sr.DataStructureMeta {
+		if i < len(sr.Data) {
+			fields[name] = sr.Data[i]
+		}
+	}
+	return ds.ValidateSchema(fields)
+}
+
+func instancePath(location string) string {
+	if location == "" {
+		return "payload"
+	}
+	return location
+}
@@ -0,0 +1,26 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+)
+
+// nextSequence hands out the sequence number of the next new request for a
+// data structure. The counter starts from the highest number in the
+// database, so numbers already published are not reused after a restart.
+// One that is handed out but never stored only shows up as a gap.
+func (o *OperatorNode) nextSequence(ctx context.Context, dataStructureID int) (uint64, error) {
+	o.sequencesMux.Lock()
+	defer o.sequencesMux.Unlock()
+
+	last, ok := o.sequences[dataStructureID]
+	if !ok {
+		var err error
+		last, err = o.db.LastSequence(ctx, dataStructureID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load last sequence: %w", err)
+		}
+	}
+	o.sequences[dataStructureID] = last + 1
+	return last + 1, nil
+}
@@ -0,0 +1,431 @@
+package operator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	siweNonceTTL           = 5 * time.Minute
+	defaultAdminSessionTTL = time.Hour
+	siweHeaderSuffix       = " wants you to sign in with your Ethereum account:"
+	// maxOutstandingNonces caps the nonces issued and not yet spent or
+	// expired, so unauthenticated nonce requests cannot grow the store
+	// without bound.
+	maxOutstandingNonces = 10000
+)
+
+var (
+	errAdminUnauthorized = errors.New("unauthorized")
+	errTooManyNonces     = errors.New("too many outstanding sign-in nonces")
+)
+
+// AdminAuth authenticates API requests with Sign-In with Ethereum
+// (EIP-4361): a client fetches a nonce, signs a SIWE message carrying it
+// with an allowlisted key and trades the signature for a session token,
+// sent as a bearer token afterwards. The session holds the role the
+// address is allowlisted with.
+type AdminAuth struct {
+	identities map[common.Address]role
+	// domain is the SIWE domain messages must name.
+	domain     string
+	sessionTTL time.Duration
+	clock      clock.Clock
+
+	mu       sync.Mutex
+	nonces   map[string]time.Time
+	sessions map[string]adminSession
+}
+
+type adminSession struct {
+	address common.Address
+	role    role
+	expires time.Time
+}
+
+// NewAdminAuth allowlists admins with the admin role and consumers with the
+// consumer role. domain is required: the Host header is the client's to
+// pick, so it cannot stand in for the domain messages are bound to.
+func NewAdminAuth(admins, consumers []string, domain string, sessionTTL time.Duration) (*AdminAuth, error) {
+	if domain == "" {
+		return nil, fmt.Errorf("a SIWE domain is required")
+	}
+	identities := make(map[common.Address]role, len(admins)+len(consumers))
+	for _, addr := range consumers {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid consumer address %q", addr)
+		}
+		identities[common.HexToAddress(addr)] = roleConsumer
+	}
+	for _, addr := range admins {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid admin address %q", addr)
+		}
+		identities[common.HexToAddress(addr)] = roleAdmin
+	}
+	if sessionTTL <= 0 {
+		sessionTTL = defaultAdminSessionTTL
+	}
+	return &AdminAuth{
+		identities: identities,
+		domain:     domain,
+		sessionTTL: sessionTTL,
+		clock:      clock.New(),
+		nonces:     make(map[string]time.Time),
+		sessions:   make(map[string]adminSession),
+	}, nil
+}
+
+// Nonce issues a single-use nonce for a SIWE message. It refuses while
+// maxOutstandingNonces are outstanding.
+func (a *AdminAuth) Nonce() (string, time.Time, error) {
+	nonce := randomToken(16)
+	now := a.clock.Now()
+	expires := now.Add(siweNonceTTL)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.purge(now)
+	if len(a.nonces) >= maxOutstandingNonces {
+		return "", time.Time{}, errTooManyNonces
+	}
+	a.nonces[nonce] = expires
+	return nonce, expires, nil
+}
+
+// AdminSession is what a successful login returns.
+type AdminSession struct {
+	Token     string    `json:"token"`
+	Address   string    `json:"address"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Login verifies a signed SIWE message and opens a session for its
+// address.
+func (a *AdminAuth) Login(message, signature string) (AdminSession, error) {
+	msg, err := parseSIWE(message)
+	if err != nil {
+		return AdminSession{}, err
+	}
+	if msg.Domain != a.domain {
+		return AdminSession{}, fmt.Errorf("message is for %s, not %s", msg.Domain, a.domain)
+	}
+
+	now := a.clock.Now()
+	if !msg.ExpirationTime.IsZero() && now.After(msg.ExpirationTime) {
+		return AdminSession{}, fmt.Errorf("message expired at %s", msg.ExpirationTime.Format(time.RFC3339))
+	}
+	if !msg.NotBefore.IsZero() && now.Before(msg.NotBefore) {
+		return AdminSession{}, fmt.Errorf("message is not valid before %s", msg.NotBefore.Format(time.RFC3339))
+	}
+
+	signer, err := recoverPersonalSign([]byte(message), signature)
+	if err != nil {
+		return AdminSession{}, err
+	}
+	if signer != msg.Address {
+		return AdminSession{}, fmt.Errorf("signed by %s, not %s", signer.Hex(), msg.Address.Hex())
+	}
+	signerRole, ok := a.identities[signer]
+	if !ok {
+		return AdminSession{}, fmt.Errorf("%s is not allowlisted", signer.Hex())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.purge(now)
+	// The nonce is spent only by a valid login, so a forged message cannot
+	// burn someone else's.
+	if _, ok := a.nonces[msg.Nonce]; !ok {
+		return AdminSession{}, fmt.Errorf("unknown or expired nonce")
+	}
+	delete(a.nonces, msg.Nonce)
+
+	session := adminSession{address: signer, role: signerRole, expires: now.Add(a.sessionTTL)}
+	if !msg.ExpirationTime.IsZero() && msg.ExpirationTime.Before(session.expires) {
+		session.expires = msg.ExpirationTime
+	}
+	token := randomToken(32)
+	a.sessions[token] = session
+	return AdminSession{Token: token, Address: signer.Hex(), Role: signerRole.String(), ExpiresAt: session.expires}, nil
+}
+
+// authenticate returns the address and role a request's bearer token
+// belongs to.
+func (a *AdminAuth) authenticate(r *http.Request) (common.Address, role, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return common.Address{}, 0, errAdminUnauthorized
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session, ok := a.sessions[token]
+	if !ok || a.clock.Now().After(session.expires) {
+		delete(a.sessions, token)
+		return common.Address{}, 0, errAdminUnauthorized
+	}
+	return session.address, session.role, nil
+}
+
+// hasRole reports whether any allowlisted address holds r. A nil AdminAuth
+// allowlists none.
+func (a *AdminAuth) hasRole(r role) bool {
+	if a == nil {
+		return false
+	}
+	for _, identity := range a.identities {
+		if identity == r {
+			return true
+		}
+	}
+	return false
+}
+
+// purge drops expired nonces and sessions. The caller holds a.mu.
+func (a *AdminAuth) purge(now time.Time) {
+	for nonce, expires := range a.nonces {
+		if now.After(expires) {
+			delete(a.nonces, nonce)
+		}
+	}
+	for token, session := range a.sessions {
+		if now.After(session.expires) {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+func (s *RPCServer) handleAuthNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.admins == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	nonce, expires, err := s.admins.Nonce()
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(siweNonceTTL.Seconds())))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nonce":      nonce,
+		"domain":     s.admins.domain,
+		"expires_at": expires,
+	})
+}
+
+type loginRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+func (s *RPCServer) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.admins == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	session, err := s.admins.Login(req.Message, req.Signature)
+	if err != nil {
+		adminLoginsTotal.WithLabelValues("refused").Inc()
+		http.Error(w, fmt.Sprintf("Login refused: %v", err), http.StatusUnauthorized)
+		return
+	}
+	adminLoginsTotal.WithLabelValues("ok").Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// siweMessage is the part of an EIP-4361 message the operator checks.
+type siweMessage struct {
+	Domain         string
+	Address        common.Address
+	URI            string
+	Version        string
+	ChainID        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+	NotBefore      time.Time
+}
+
+func parseSIWE(message string) (siweMessage, error) {
+	lines := strings.Split(message, "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return siweMessage{}, fmt.Errorf("not a SIWE message")
+	}
+	msg := siweMessage{Domain: strings.TrimSuffix(lines[0], siweHeaderSuffix)}
+	if !common.IsHexAddress(lines[1]) {
+		return siweMessage{}, fmt.Errorf("invalid address %q", lines[1])
+	}
+	msg.Address = common.HexToAddress(lines[1])
+
+	for _, line := range lines[2:] {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		var err error
+		switch key {
+		case "URI":
+			msg.URI = value
+		case "Version":
+			msg.Version = value
+		case "Chain ID":
+			msg.ChainID = value
+		case "Nonce":
+			msg.Nonce = value
+		case "Issued At":
+			msg.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			msg.ExpirationTime, err = time.Parse(time.RFC3339, value)
+		case "Not Before":
+			msg.NotBefore, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return siweMessage{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if msg.Version != "1" {
+		return siweMessage{}, fmt.Errorf("unsupported version %q", msg.Version)
+	}
+	if msg.Nonce == "" || msg.IssuedAt.IsZero() {
+		return siweMessage{}, fmt.Errorf("message needs a nonce and an issue time")
+	}
+	return msg, nil
+}
+
+// String renders the message in EIP-4361 form, without a statement.
+func (m siweMessage) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s\n%s\n\n", m.Domain, siweHeaderSuffix, m.Address.Hex())
+	fmt.Fprintf(&b, "URI: %s\nVersion: %s\nChain ID: %s\nNonce: %s\nIssued At: %s", m.URI, m.Version, m.ChainID, m.Nonce, m.IssuedAt.UTC().Format(time.RFC3339))
+	if !m.ExpirationTime.IsZero() {
+		fmt.Fprintf(&b, "\nExpiration Time: %s", m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// recoverPersonalSign returns the address that signed message with
+// personal_sign, accepting recovery IDs of 0/1 and wallet-style 27/28.
+func recoverPersonalSign(message []byte, signatureHex string) (common.Address, error) {
+	sig, err := hexutil.Decode(signatureHex)
+	if err != nil || len(sig) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature")
+	}
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	return verifySignature(accounts.TextHash(message), hexutil.Encode(sig))
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// AdminLoginCommand signs in to an operator's admin API with a private key
+// and prints the session token, for scripts that call admin endpoints.
+func AdminLoginCommand(args []string) error {
+	fs := flag.NewFlagSet("admin-login", flag.ContinueOnError)
+	apiURL := fs.String("url", "http://localhost:8080", "operator API URL")
+	keyHex := fs.String("key", "", "hex private key of an admin address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key, err := cryptoeth.HexToECDSA(strings.TrimPrefix(*keyHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	base, err := url.Parse(*apiURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	resp, err := http.Get(base.JoinPath("/auth/nonce").String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nonce request failed: %s", resp.Status)
+	}
+	var challenge struct {
+		Nonce     string    `json:"nonce"`
+		Domain    string    `json:"domain"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&challenge); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	message := siweMessage{
+		Domain:         challenge.Domain,
+		Address:        cryptoeth.PubkeyToAddress(key.PublicKey),
+		URI:            base.String(),
+		Version:        "1",
+		ChainID:        "1",
+		Nonce:          challenge.Nonce,
+		IssuedAt:       now,
+		ExpirationTime: now.Add(defaultAdminSessionTTL),
+	}.String()
+	sig, err := cryptoeth.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		return err
+	}
+	sig[64] += 27
+
+	body, _ := json.Marshal(loginRequest{Message: message, Signature: hexutil.Encode(sig)})
+	resp, err = http.Post(base.JoinPath("/auth/login").String(), "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: %s", resp.Status)
+	}
+	var session AdminSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return err
+	}
+	fmt.Println(session.Token)
+	return nil
+}
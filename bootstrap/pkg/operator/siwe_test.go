@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestAdminAuthRequiresDomain(t *testing.T) {
+	admin := "0x0000000000000000000000000000000000000001"
+	if _, err := NewAdminAuth([]string{admin}, nil, "", 0); err == nil {
+		t.Fatal("admin auth enabled without a SIWE domain")
+	}
+
+	cfg := APIConfig{Admins: []string{admin}}
+	if err := cfg.validate(); err == nil {
+		t.Fatal("config with admin addresses and no admin_domain validated")
+	}
+	cfg.AdminDomain = "operator.example.com"
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("config with admin_domain refused: %v", err)
+	}
+}
+
+func TestLoginChecksConfiguredDomain(t *testing.T) {
+	key, err := cryptoeth.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := cryptoeth.PubkeyToAddress(key.PublicKey)
+	auth, err := NewAdminAuth([]string{addr.Hex()}, nil, "operator.example.com", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(domain string) error {
+		nonce, _, err := auth.Nonce()
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := siweMessage{
+			Domain:   domain,
+			Address:  addr,
+			URI:      "https://" + domain,
+			Version:  "1",
+			ChainID:  "1",
+			Nonce:    nonce,
+			IssuedAt: time.Now(),
+		}.String()
+		sig, err := cryptoeth.Sign(accounts.TextHash([]byte(message)), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig[64] += 27
+		_, err = auth.Login(message, hexutil.Encode(sig))
+		return err
+	}
+
+	if err := login("evil.example.com"); err == nil {
+		t.Fatal("login for another domain accepted")
+	}
+	if err := login("operator.example.com"); err != nil {
+		t.Fatalf("login for the configured domain refused: %v", err)
+	}
+}
+
+func TestNonceStoreIsCapped(t *testing.T) {
+	auth, err := NewAdminAuth([]string{"0x0000000000000000000000000000000000000001"}, nil, "operator.example.com", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := clock.NewMock()
+	auth.clock = mock
+	for i := 0; i < maxOutstandingNonces; i++ {
+		if _, _, err := auth.Nonce(); err != nil {
+			t.Fatalf("nonce %d refused: %v", i, err)
+		}
+	}
+
+	s := &RPCServer{admins: auth}
+	rec := httptest.NewRecorder()
+	s.handleAuthNonce(rec, httptest.NewRequest(http.MethodGet, "/auth/nonce", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("nonce over the cap: got %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	mock.Add(siweNonceTTL + time.Second)
+	if _, _, err := auth.Nonce(); err != nil {
+		t.Fatalf("nonce after the outstanding ones expired refused: %v", err)
+	}
+}
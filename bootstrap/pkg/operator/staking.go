@@ -0,0 +1,342 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	defaultEpochLength         = time.Hour
+	defaultStakingPollInterval = time.Minute
+	defaultStakeUnit           = "1000000000000000000"
+	stakingCallTimeout         = 15 * time.Second
+)
+
+type StakingConfig struct {
+	// RPCURL is an Ethereum JSON-RPC endpoint. Setting it derives the
+	// trusted set and signer weights from Contract instead of
+	// trusted_addresses and signer_weights.
+	RPCURL string `yaml:"rpc_url"`
+	// Contract must implement
+	// getSigners() view returns (address[] signers, uint256[] stakes).
+	Contract string `yaml:"contract"`
+	// EpochLength is how often stake changes take effect.
+	EpochLength time.Duration `yaml:"epoch_length"`
+	// PollInterval is how often the contract is read for changes.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// StakeUnit is the stake, in the token's smallest unit, that weighs 1.
+	// Signers staking less are left out of the set.
+	StakeUnit string `yaml:"stake_unit"`
+}
+
+func (c StakingConfig) validate() error {
+	if c.RPCURL == "" {
+		return nil
+	}
+	if !common.IsHexAddress(c.Contract) {
+		return fmt.Errorf("invalid staking contract address: %q", c.Contract)
+	}
+	if c.EpochLength < time.Second || c.EpochLength%time.Second != 0 {
+		return fmt.Errorf("staking epoch length must be a positive whole number of seconds")
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("staking poll interval must be positive")
+	}
+	if unit, ok := new(big.Int).SetString(c.StakeUnit, 10); !ok || unit.Sign() <= 0 {
+		return fmt.Errorf("invalid stake unit %q", c.StakeUnit)
+	}
+	return nil
+}
+
+// stakingABI is the part of the staking contract the operator reads.
+const stakingABI = `[{"type":"function","name":"getSigners","stateMutability":"view","inputs":[],"outputs":[{"name":"signers","type":"address[]"},{"name":"stakes","type":"uint256[]"}]}]`
+
+// StakingClient reads signer stakes from the staking contract over JSON-RPC.
+type StakingClient struct {
+	url      string
+	contract common.Address
+	unit     *big.Int
+	abi      abi.ABI
+	client   *http.Client
+}
+
+func NewStakingClient(cfg StakingConfig) (*StakingClient, error) {
+	parsed, err := abi.JSON(strings.NewReader(stakingABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse staking ABI: %w", err)
+	}
+	unit, ok := new(big.Int).SetString(cfg.StakeUnit, 10)
+	if !ok || unit.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid stake unit %q", cfg.StakeUnit)
+	}
+	return &StakingClient{
+		url:      cfg.RPCURL,
+		contract: common.HexToAddress(cfg.Contract),
+		unit:     unit,
+		abi:      parsed,
+		client:   &http.Client{Timeout: stakingCallTimeout},
+	}, nil
+}
+
+// call performs a JSON-RPC call and decodes its result into out.
+func (c *StakingClient) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP %d", method, resp.StatusCode)
+	}
+
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("failed to decode %s reply: %w", method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s failed: %s (code %d)", method, reply.Error.Message, reply.Error.Code)
+	}
+	if err := json.Unmarshal(reply.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// SignerSet reads the signers and their weights at the latest block. The
+// returned set has no epoch yet.
+func (c *StakingClient) SignerSet(ctx context.Context) (store.SignerSet, error) {
+	var block hexutil.Uint64
+	if err := c.call(ctx, "eth_blockNumber", nil, &block); err != nil {
+		return store.SignerSet{}, err
+	}
+
+	data, err := c.abi.Pack("getSigners")
+	if err != nil {
+		return store.SignerSet{}, fmt.Errorf("failed to pack getSigners: %w", err)
+	}
+	var result hexutil.Bytes
+	callArgs := map[string]interface{}{"to": c.contract.Hex(), "data": hexutil.Bytes(data)}
+	if err := c.call(ctx, "eth_call", []interface{}{callArgs, block.String()}, &result); err != nil {
+		return store.SignerSet{}, err
+	}
+
+	out, err := c.abi.Unpack("getSigners", result)
+	if err != nil {
+		return store.SignerSet{}, fmt.Errorf("failed to unpack getSigners: %w", err)
+	}
+	signers, ok1 := out[0].([]common.Address)
+	stakes, ok2 := out[1].([]*big.Int)
+	if !ok1 || !ok2 || len(signers) != len(stakes) {
+		return store.SignerSet{}, fmt.Errorf("unexpected getSigners result")
+	}
+
+	set := store.SignerSet{Weights: make(map[string]uint64), Block: uint64(block)}
+	for i, signer := range signers {
+		weight := new(big.Int).Quo(stakes[i], c.unit)
+		if weight.Sign() <= 0 {
+			continue
+		}
+		if !weight.IsUint64() {
+			return store.SignerSet{}, fmt.Errorf("stake of %s overflows its weight; raise the stake unit", signer.Hex())
+		}
+		addr := signer.Hex()
+		if _, dup := set.Weights[addr]; !dup {
+			set.Signers = append(set.Signers, addr)
+		}
+		set.Weights[addr] += weight.Uint64()
+	}
+	sort.Strings(set.Signers)
+	return set, nil
+}
+
+// sameSigners reports whether a and b hold the same signers and weights.
+func sameSigners(a, b store.SignerSet) bool {
+	if !slices.Equal(a.Signers, b.Signers) || len(a.Weights) != len(b.Weights) {
+		return false
+	}
+	for addr, weight := range a.Weights {
+		if b.Weights[addr] != weight {
+			return false
+		}
+	}
+	return true
+}
+
+// epochAt returns the number of the epoch containing t. Epochs start on
+// multiples of length, so every operator agrees on their boundaries.
+func epochAt(t time.Time, length time.Duration) uint64 {
+	return uint64(t.Unix() / int64(length/time.Second))
+}
+
+// Staking keeps the operator's trusted set and weights in line with the
+// staking contract. Stake changes and exits read during an epoch only take
+// effect when the next one starts, and the set of every epoch is stored so
+// that proofs can be checked against the signers of their epoch.
+type Staking struct {
+	Client       *StakingClient
+	Operator     *OperatorNode
+	EpochLength  time.Duration
+	PollInterval time.Duration
+	// Clock defaults to the wall clock when nil.
+	Clock clock.Clock
+
+	current store.SignerSet
+}
+
+// Start applies the set of the current epoch, as stored before a restart or
+// else freshly read, and then follows the contract until ctx is done.
+func (s *Staking) Start(ctx context.Context) error {
+	now := orWallClock(s.Clock).Now()
+	epoch := epochAt(now, s.EpochLength)
+
+	set, found, err := s.Operator.db.GetSignerSet(ctx, epoch)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if set, err = s.Client.SignerSet(ctx); err != nil {
+			return fmt.Errorf("failed to read signer set: %w", err)
+		}
+		set.Epoch = epoch
+		set.StartsAt = now.Unix()
+		if err := s.Operator.db.StoreSignerSet(ctx, set); err != nil {
+			return err
+		}
+	}
+	if len(set.Signers) == 0 {
+		return fmt.Errorf("staking contract lists no signer with at least one stake unit")
+	}
+
+	s.apply(set)
+	go s.run(ctx)
+	return nil
+}
+
+func (s *Staking) run(ctx context.Context) {
+	clk := orWallClock(s.Clock)
+	poll := clk.Ticker(s.PollInterval)
+	defer poll.Stop()
+
+	next := func() time.Duration {
+		now := clk.Now()
+		return now.Truncate(s.EpochLength).Add(s.EpochLength).Sub(now)
+	}
+	boundary := clk.Timer(next())
+	defer boundary.Stop()
+
+	var latest *store.SignerSet
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			set, err := s.Client.SignerSet(ctx)
+			if err != nil {
+				log.Printf("Error reading staking contract: %v", err)
+				continue
+			}
+			if (latest == nil || !sameSigners(set, *latest)) && !sameSigners(set, s.current) {
+				log.Printf("📈 Stake change read at block %d, applies from epoch %d", set.Block, s.current.Epoch+1)
+			}
+			latest = &set
+		case <-boundary.C:
+			// Read once more so the new epoch starts from the stakes at its
+			// boundary, falling back to the last read.
+			set, err := s.Client.SignerSet(ctx)
+			if err != nil {
+				log.Printf("Error reading staking contract: %v", err)
+				if latest == nil {
+					set = s.current
+				} else {
+					set = *latest
+				}
+			}
+			latest = nil
+			s.advance(ctx, set)
+			boundary.Reset(next())
+		}
+	}
+}
+
+// advance starts the epoch now in with set.
+func (s *Staking) advance(ctx context.Context, set store.SignerSet) {
+	now := orWallClock(s.Clock).Now()
+	set.Epoch = epochAt(now, s.EpochLength)
+	set.StartsAt = now.Truncate(s.EpochLength).Unix()
+	if len(set.Signers) == 0 {
+		log.Printf("⚠️ Staking contract lists no signers; keeping the set of epoch %d", s.current.Epoch)
+		set.Signers, set.Weights, set.Block = s.current.Signers, s.current.Weights, s.current.Block
+	}
+	if err := s.Operator.db.StoreSignerSet(ctx, set); err != nil {
+		log.Printf("Error storing signer set of epoch %d: %v", set.Epoch, err)
+	}
+	s.apply(set)
+}
+
+func (s *Staking) apply(set store.SignerSet) {
+	s.current = set
+	s.Operator.applySignerSet(set)
+	stakingEpoch.Set(float64(set.Epoch))
+	stakingSigners.Set(float64(len(set.Signers)))
+	log.Printf("🗳️ Epoch %d: %d signers from block %d, %d weight required", set.Epoch, len(set.Signers), set.Block, s.Operator.weightThreshold())
+}
+
+// applySignerSet replaces the trusted set and weights with those of an
+// epoch.
+func (o *OperatorNode) applySignerSet(set store.SignerSet) {
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	o.trustedAddrs = append([]string(nil), set.Signers...)
+	o.weights = make(signerWeights, len(set.Weights))
+	for addr, weight := range set.Weights {
+		o.weights[strings.ToLower(addr)] = weight
+	}
+	o.epoch = set.Epoch
+}
+
+// currentEpoch returns the staking epoch new requests are made in, zero
+// without staking.
+func (o *OperatorNode) currentEpoch() uint64 {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	return o.epoch
+}
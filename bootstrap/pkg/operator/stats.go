@@ -0,0 +1,165 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"bootstrap/pkg/store"
+)
+
+// ensureStats rebuilds the stats of data structures stored before stats
+// were kept, so the stats API has a record for every structure.
+func ensureStats(ctx context.Context, db store.Database, thresholdFor func(int) int) {
+	ids, err := db.GetDataStructures(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list data structures for stats: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := db.GetDataStructureStats(ctx, id); !errors.Is(err, store.ErrNoStats) {
+			continue
+		}
+		stats, err := db.RebuildStats(ctx, id, thresholdFor(id))
+		if err != nil {
+			log.Printf("Warning: Failed to rebuild stats of structure %d: %v", id, err)
+			continue
+		}
+		log.Printf("📊 Built stats for structure %d: %d messages, %d confirmed", id, stats.MessageCount, stats.ConfirmedCount)
+	}
+}
+
+// RebuildStatsCommand recomputes stored stats from the indexes, for recovery
+// after a crash between writes or a change of threshold. The operator must
+// be stopped, as both backends lock their directory.
+func RebuildStatsCommand(args []string) error {
+	fs := flag.NewFlagSet("rebuild-stats", flag.ContinueOnError)
+	structuresPath := fs.String("structures", "config/data_structures.json", "path to the data structures file, for per-structure thresholds")
+	threshold := fs.Int("threshold", 0, "signatures required to count as confirmed (defaults to the operator's)")
+	dsid := fs.Int("dsid", -1, "data structure to rebuild (defaults to all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		return err
+	}
+
+	thresholds := make(map[int]int)
+	if *threshold <= 0 {
+		trustedAddrs := cfg.TrustedAddresses
+		if len(trustedAddrs) == 0 {
+			return fmt.Errorf("no trusted addresses set; pass -threshold")
+		}
+		// With signer weights, stats count a message as confirmed once it
+		// has as many signatures as could carry the weight threshold.
+		weights := make(signerWeights, len(cfg.SignerWeights))
+		for addr, weight := range cfg.SignerWeights {
+			weights[strings.ToLower(addr)] = weight
+		}
+		*threshold = len(trustedAddrs)/2 + 1
+		if len(weights) > 0 {
+			*threshold = weights.minSigners(trustedAddrs, weights.required(trustedAddrs, 0))
+		}
+
+		if structures, err := LoadDataStructures(*structuresPath); err == nil {
+			for _, structure := range structures {
+				eligible := structure.committeeOf(trustedAddrs)
+				switch {
+				case structure.Threshold > 0:
+					thresholds[structure.ID] = max(min(structure.Threshold, len(eligible)), 1)
+				case len(weights) > 0:
+					thresholds[structure.ID] = weights.minSigners(eligible, weights.required(eligible, structure.WeightThreshold))
+				case len(structure.Signers) > 0 || len(structure.ExcludeSigners) > 0:
+					thresholds[structure.ID] = len(eligible)/2 + 1
+				}
+			}
+		} else {
+			log.Printf("Warning: Failed to load data structures: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db, err := store.Open(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ids := []int{*dsid}
+	if *dsid < 0 {
+		if ids, err = db.GetDataStructures(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		required := *threshold
+		if t, ok := thresholds[id]; ok {
+			required = t
+		}
+
+		stats, err := db.RebuildStats(ctx, id, required)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("structure %d: %d messages, %d confirmed at threshold %d, last confirmed %s\n",
+			id, stats.MessageCount, stats.ConfirmedCount, required, stats.LastConfirmedHash)
+	}
+	return nil
+}
+
+// ReindexCommand backfills the field time and numeric indexes for messages
+// stored before they existed, which field and range queries otherwise miss.
+// The operator must be stopped, as both backends lock their directory.
+func ReindexCommand(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	dsid := fs.Int("dsid", -1, "data structure to reindex (defaults to all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := LoadConfig(nil)
+	if err != nil {
+		return err
+	}
+	db, err := store.Open(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ids := []int{*dsid}
+	if *dsid < 0 {
+		if ids, err = db.GetDataStructures(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		indexed, err := db.RebuildIndexes(ctx, id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("structure %d: %d messages indexed\n", id, indexed)
+	}
+	return nil
+}
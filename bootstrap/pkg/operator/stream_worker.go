@@ -0,0 +1,201 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"bootstrap/pkg/collector"
+)
+
+// StreamWorker is the push-driven counterpart of Worker. It averages every
+// streamed price over a sliding window and publishes as soon as the average
+// moves more than DeviationBps from the last published value, or when
+// HeartbeatInterval passes without a publish.
+//
+// Publishing happens off the stream loop, one request at a time, so a slow
+// publish never stalls reading the streams. Prices that come due while a
+// publish is in flight are evaluated afresh once it finishes.
+type StreamWorker struct {
+	*Worker
+	Streams           []collector.StreamingPriceSource
+	Window            time.Duration
+	DeviationBps      float64
+	HeartbeatInterval time.Duration
+
+	samples       map[string][]collector.PriceUpdate
+	lastPublished float64
+	lastPublishAt time.Time
+
+	// publishing is set while a publish is in flight; due and dueHeartbeat
+	// record that another came due meanwhile.
+	publishing   bool
+	due          bool
+	dueHeartbeat bool
+	publishes    chan collector.PriceBreakdown
+	published    chan streamPublishResult
+}
+
+// streamPublishResult reports a finished publish back to the stream loop.
+type streamPublishResult struct {
+	average float64
+	at      time.Time
+	err     error
+}
+
+func (w *StreamWorker) Run(ctx context.Context) error {
+	builder, err := w.MessageFactory.GetBuilder()
+	if err != nil {
+		return fmt.Errorf("failed to get message builder: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan collector.PriceUpdate, 256)
+	for _, stream := range w.Streams {
+		go stream.Stream(ctx, updates)
+	}
+
+	w.samples = make(map[string][]collector.PriceUpdate)
+
+	// Only one publish is ever in flight, so neither channel blocks.
+	w.publishes = make(chan collector.PriceBreakdown, 1)
+	w.published = make(chan streamPublishResult, 1)
+	publisherDone := make(chan struct{})
+	go func() {
+		defer close(publisherDone)
+		w.publishLoop(ctx, builder)
+	}()
+	// On shutdown a publish in flight is finished before ctx is cancelled.
+	defer func() {
+		close(w.publishes)
+		<-publisherDone
+	}()
+
+	heartbeat := orWallClock(w.Clock).Ticker(w.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Shutdown:
+			return nil
+		case update := <-updates:
+			w.samples[update.Source] = append(w.samples[update.Source], update)
+			w.evaluate(false)
+		case <-heartbeat.C:
+			w.evaluate(true)
+		case result := <-w.published:
+			w.publishing = false
+			if result.err == nil {
+				w.lastPublished = result.average
+				w.lastPublishAt = result.at
+			}
+			if w.due {
+				heartbeat := w.dueHeartbeat
+				w.due, w.dueHeartbeat = false, false
+				w.evaluate(heartbeat)
+			}
+		}
+	}
+}
+
+// evaluate hands the window average to the publisher when it has moved far
+// enough, or on a heartbeat once the last publish is older than the
+// heartbeat interval.
+func (w *StreamWorker) evaluate(heartbeat bool) {
+	breakdown, ok := w.windowAverage(orWallClock(w.Clock).Now())
+	if !ok {
+		return
+	}
+	if !w.publishDue(breakdown.Average, heartbeat) {
+		return
+	}
+	if w.publishing {
+		w.due = true
+		w.dueHeartbeat = w.dueHeartbeat || heartbeat
+		return
+	}
+	w.publishing = true
+	w.publishes <- breakdown
+}
+
+func (w *StreamWorker) publishDue(avg float64, heartbeat bool) bool {
+	stale := orWallClock(w.Clock).Since(w.lastPublishAt) >= w.HeartbeatInterval
+	return w.deviationBps(avg) >= w.DeviationBps || (heartbeat && stale)
+}
+
+func (w *StreamWorker) deviationBps(avg float64) float64 {
+	if w.lastPublished == 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(avg-w.lastPublished) / w.lastPublished * 10000
+}
+
+// publishLoop publishes what the stream loop hands it until publishes is
+// closed.
+func (w *StreamWorker) publishLoop(ctx context.Context, builder MessageBuilder) {
+	for breakdown := range w.publishes {
+		w.published <- streamPublishResult{
+			average: breakdown.Average,
+			at:      orWallClock(w.Clock).Now(),
+			err:     w.publish(ctx, builder, breakdown),
+		}
+	}
+}
+
+func (w *StreamWorker) publish(ctx context.Context, builder MessageBuilder, breakdown collector.PriceBreakdown) error {
+	ctx, span := tracer.Start(ctx, "stream_worker.publish", trace.WithAttributes(
+		attribute.String("ticker", w.Ticker),
+		attribute.Float64("average", breakdown.Average),
+	))
+	defer span.End()
+
+	if err := w.publishPrice(ctx, builder, breakdown); err != nil {
+		recordSpanError(span, err)
+		log.Printf("Error publishing streamed price for %s: %v", w.Ticker, err)
+		return err
+	}
+	return nil
+}
+
+// windowAverage drops samples older than the window and averages the
+// per-source means, so a chatty stream does not outweigh a quiet one.
+func (w *StreamWorker) windowAverage(now time.Time) (collector.PriceBreakdown, bool) {
+	cutoff := now.Add(-w.Window)
+
+	var prices []float64
+	var quotes []collector.SourceQuote
+	for source, samples := range w.samples {
+		first := 0
+		for first < len(samples) && samples[first].At.Before(cutoff) {
+			first++
+		}
+		samples = samples[first:]
+		w.samples[source] = samples
+
+		if len(samples) == 0 {
+			continue
+		}
+
+		var sum float64
+		for _, s := range samples {
+			sum += s.Price
+		}
+		mean := sum / float64(len(samples))
+		prices = append(prices, mean)
+		quotes = append(quotes, collector.SourceQuote{Source: source, Price: mean})
+	}
+
+	if len(prices) == 0 {
+		return collector.PriceBreakdown{}, false
+	}
+	return collector.NewPriceBreakdown(prices, quotes), true
+}
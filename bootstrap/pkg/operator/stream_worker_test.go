@@ -0,0 +1,119 @@
+package operator
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/store"
+)
+
+// blockingStoreDatabase holds every message stored until release is closed.
+type blockingStoreDatabase struct {
+	store.Database
+	release chan struct{}
+	stored  atomic.Int32
+}
+
+func (d *blockingStoreDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, meta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence uint64, epoch uint64) error {
+	d.stored.Add(1)
+	<-d.release
+	return d.Database.StoreData(ctx, hash, data, dataStructure, meta, timestamp, dataStructureID, hashVersion, requestID, sequence, epoch)
+}
+
+func waitStored(t *testing.T, db *blockingStoreDatabase, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for db.stored.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for publish %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testStream pushes prices, then closes sent.
+type testStream struct {
+	prices []float64
+	at     time.Time
+	sent   chan struct{}
+}
+
+func (s *testStream) FetchPrice(context.Context) (float64, error) { return s.prices[0], nil }
+func (s *testStream) Name() string                                { return "test" }
+
+func (s *testStream) Stream(ctx context.Context, updates chan<- collector.PriceUpdate) {
+	for _, price := range s.prices {
+		select {
+		case updates <- collector.PriceUpdate{Source: s.Name(), Price: price, At: s.at}:
+		case <-ctx.Done():
+			return
+		}
+	}
+	close(s.sent)
+	<-ctx.Done()
+}
+
+func TestStreamWorkerReadsWhilePublishing(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	db := &blockingStoreDatabase{release: make(chan struct{})}
+	op := newTestOperator(t, mock, 3, func(inner store.Database) store.Database {
+		db.Database = inner
+		return db
+	})
+
+	structures, err := LoadDataStructures("../../config/data_structures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// More updates than the stream buffer holds, all far off the first.
+	stream := &testStream{prices: []float64{100}, at: mock.Now(), sent: make(chan struct{})}
+	for i := 0; i < 300; i++ {
+		stream.prices = append(stream.prices, 200)
+	}
+	w := &StreamWorker{
+		Worker: &Worker{
+			PubSub:         op.publisher(),
+			MessageFactory: NewMessageFactory("stock_quote", "SBER", structures),
+			Ticker:         "SBER",
+			StructureID:    "stock_quote",
+			Shutdown:       make(chan struct{}),
+			Clock:          mock,
+		},
+		Streams:           []collector.StreamingPriceSource{stream},
+		Window:            time.Hour,
+		DeviationBps:      5000,
+		HeartbeatInterval: time.Hour,
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Run(context.Background()) }()
+
+	waitStored(t, db, 1)
+	select {
+	case <-stream.sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream reading stalled behind a publish")
+	}
+	if n := db.stored.Load(); n != 1 {
+		t.Fatalf("%d publishes in flight, want 1", n)
+	}
+
+	// What came due meanwhile goes out as one publish of the latest average.
+	close(db.release)
+	waitStored(t, db, 2)
+	close(w.Shutdown)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := db.stored.Load(); n != 2 {
+		t.Fatalf("published %d times, want 2", n)
+	}
+	if w.lastPublished <= 150 {
+		t.Fatalf("last published %v, want the average of the later prices", w.lastPublished)
+	}
+}
@@ -0,0 +1,165 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"bootstrap/pkg/store"
+)
+
+// SubmitProtocolID is the stream an allowlisted producer opens to have
+// data it collected signed, as an alternative to POST /submit. The producer
+// writes one SubmitRequest per stream and reads back a SubmitResponse.
+const SubmitProtocolID = protocol.ID("/l0proof/submit/1.0.0")
+
+const (
+	// submitStreamTimeout bounds a whole submit exchange, publishing
+	// included.
+	submitStreamTimeout = 30 * time.Second
+	// maxSubmitRequestSize limits how much of one submit request is read.
+	maxSubmitRequestSize = 64 << 10
+)
+
+var (
+	errUnknownStructure = errors.New("unknown structure_id")
+	errSubmitPublish    = errors.New("failed to publish")
+)
+
+// SubmitRequest is data produced outside the operator for a structure.
+// Fields are checked against the structure like those collected by a
+// Worker; a missing timestamp is the time of submission.
+type SubmitRequest struct {
+	StructureID string                 `json:"structure_id"`
+	Fields      map[string]interface{} `json:"fields"`
+}
+
+// SubmitResponse names the sign request a submission was published as.
+type SubmitResponse struct {
+	Hash      string `json:"hash,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Submitter turns submitted data into sign requests and publishes them.
+type Submitter struct {
+	structures map[string]DataStructure
+	publisher  *PubSubService
+}
+
+func NewSubmitter(structures map[string]DataStructure, publisher *PubSubService) *Submitter {
+	return &Submitter{structures: structures, publisher: publisher}
+}
+
+// Submit validates req and publishes it. Errors wrap errUnknownStructure,
+// a *ValidationError, errPublicationPaused or errSubmitPublish.
+func (s *Submitter) Submit(ctx context.Context, req SubmitRequest) (*SignRequest, error) {
+	structure, ok := s.structures[req.StructureID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownStructure, req.StructureID)
+	}
+
+	signRequest, err := buildSubmission(structure, req, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if s.publisher.latency != nil {
+		s.publisher.latency.MarkBuilt(signRequest.Hash, time.Now())
+	}
+
+	if err := s.publisher.PublishSignRequest(ctx, signRequest); err != nil {
+		if errors.Is(err, errPublicationPaused) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errSubmitPublish, err)
+	}
+	return signRequest, nil
+}
+
+// buildSubmission checks req against structure and builds its sign request,
+// hashed like the messages a Worker builds from the same values. The
+// request is timestamped with its timestamp field, or now when it has none.
+func buildSubmission(structure DataStructure, req SubmitRequest, now int64) (*SignRequest, error) {
+	values := make(map[string]interface{}, len(req.Fields)+1)
+	for name, value := range req.Fields {
+		values[name] = value
+	}
+	if _, ok := values["timestamp"]; !ok {
+		values["timestamp"] = now
+	}
+
+	fields, err := structure.NormalizeFields(values)
+	if err != nil {
+		return nil, err
+	}
+	// The schema describes values as they are hashed.
+	if err := structure.ValidateSchema(fields); err != nil {
+		return nil, err
+	}
+
+	timestamp := now
+	if raw, ok := fields["timestamp"]; ok {
+		n, err := store.ParseInteger(raw)
+		if err != nil || !n.IsInt64() || n.Sign() < 0 {
+			return nil, &ValidationError{Problems: []string{`field "timestamp": expected a Unix timestamp`}}
+		}
+		timestamp = n.Int64()
+	}
+	return buildSignRequest(req.StructureID, structure, fields, timestamp)
+}
+
+// EnableSubmitStream serves SubmitProtocolID to the given producers.
+func (o *OperatorNode) EnableSubmitStream(submitter *Submitter, producers []peer.ID) {
+	allowed := make(map[peer.ID]bool, len(producers))
+	for _, p := range producers {
+		allowed[p] = true
+	}
+
+	o.host.SetStreamHandler(SubmitProtocolID, func(s network.Stream) {
+		remote := s.Conn().RemotePeer()
+		if !allowed[remote] {
+			log.Printf("Refusing submission from unknown peer %s", remote)
+			s.Reset()
+			return
+		}
+		o.handleSubmitStream(s, submitter)
+	})
+}
+
+func (o *OperatorNode) handleSubmitStream(s network.Stream, submitter *Submitter) {
+	defer s.Close()
+	s.SetDeadline(o.clock.Now().Add(submitStreamTimeout))
+	remote := s.Conn().RemotePeer()
+
+	var req SubmitRequest
+	dec := json.NewDecoder(io.LimitReader(s, maxSubmitRequestSize))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		writeSubmitResponse(s, SubmitResponse{Error: "malformed submit request"})
+		return
+	}
+
+	signRequest, err := submitter.Submit(o.ctx, req)
+	if err != nil {
+		log.Printf("Rejected submission for %s from %s: %v", req.StructureID, remote, err)
+		writeSubmitResponse(s, SubmitResponse{Error: err.Error()})
+		return
+	}
+	log.Printf("📥 Submission %s for %s from %s", signRequest.Hash, req.StructureID, remote)
+	writeSubmitResponse(s, SubmitResponse{Hash: signRequest.Hash, Timestamp: signRequest.Timestamp})
+}
+
+func writeSubmitResponse(s network.Stream, resp SubmitResponse) {
+	if err := json.NewEncoder(s).Encode(resp); err != nil {
+		log.Printf("Error writing submit response to %s: %v", s.Conn().RemotePeer(), err)
+		s.Reset()
+	}
+}
@@ -0,0 +1,84 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"bootstrap/pkg/collector"
+)
+
+func TestSubmitHashesLikeBuilder(t *testing.T) {
+	structures, err := LoadDataStructures("../../config/data_structures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	structure := structures["stock_quote"]
+
+	for _, price := range []float64{0.000001, 1, 312.45} {
+		builder := &StockQuoteMessageBuilder{
+			Ticker:           "SBER",
+			StructureID:      "stock_quote",
+			Structure:        structure,
+			DestinationChain: 1,
+			Metadata:         collector.QuoteMetadata{Currency: "RUB", Venue: "MOEX"},
+		}
+		built, err := builder.BuildMessage(collector.PriceBreakdown{Average: price, Spread: price / 100, SourceCount: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := structure.ValidateRequest(built); err != nil {
+			t.Fatalf("built message fails the schema: %v", err)
+		}
+
+		// Submit the same values the way a client would: JSON numbers
+		// where they fit, decoded as the handler decodes them.
+		fields := map[string]interface{}{}
+		for i, name := range built.DataStructureMeta {
+			value := built.Data[i]
+			if s, ok := value.(string); ok && structure.Fields[i].SolidityType == "uint256" && len(s) < 16 {
+				value = json.RawMessage(s)
+			}
+			fields[name] = value
+		}
+		body, err := json.Marshal(map[string]interface{}{"structure_id": "stock_quote", "fields": fields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req SubmitRequest
+		dec := json.NewDecoder(strings.NewReader(string(body)))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		submitted, err := buildSubmission(structure, req, built.Timestamp+60)
+		if err != nil {
+			t.Fatalf("price %g: %v", price, err)
+		}
+		if submitted.Hash != built.Hash {
+			t.Fatalf("price %g: submitted hash %s, built hash %s\nsubmitted %s\nbuilt     %s",
+				price, submitted.Hash, built.Hash, fmt.Sprint(submitted.Data...), fmt.Sprint(built.Data...))
+		}
+		if submitted.Timestamp != built.Timestamp {
+			t.Fatalf("submitted timestamp %d, want the timestamp field %d", submitted.Timestamp, built.Timestamp)
+		}
+	}
+}
+
+func TestNormalizeIntegersAsDecimalStrings(t *testing.T) {
+	for _, raw := range []interface{}{json.Number("42"), "42", 42, int64(42), float64(42)} {
+		value, err := normalizeSolidityValue("uint256", raw)
+		if err != nil {
+			t.Fatalf("%T %v: %v", raw, raw, err)
+		}
+		if value != "42" {
+			t.Fatalf("%T %v normalized to %#v, want \"42\"", raw, raw, value)
+		}
+	}
+	value, err := normalizeSolidityValue("int256", json.Number("-7"))
+	if err != nil || value != "-7" {
+		t.Fatalf("int256 -7 normalized to %#v, %v", value, err)
+	}
+}
@@ -0,0 +1,98 @@
+package operator
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"bootstrap/pkg/store"
+)
+
+// SyncProtocolID is the request/response protocol a signer or mirror uses
+// to fetch confirmed messages it missed, such as while it was restarting.
+// Gossip only carries what is published while a peer is listening.
+const SyncProtocolID = protocol.ID("/l0proof/sync/1.0.0")
+
+const (
+	// maxSyncMessages caps the messages in one sync response; callers page
+	// through longer ranges by asking again from the last sequence + 1.
+	maxSyncMessages = 500
+	// syncStreamTimeout bounds a whole sync exchange.
+	syncStreamTimeout = 30 * time.Second
+	// maxSyncRequestSize limits how much of a sync request is read.
+	maxSyncRequestSize = 4 << 10
+)
+
+// SyncRequest asks for the confirmed messages of a data structure with a
+// sequence number in [From, To]. A zero To means up to the latest, and a
+// Limit outside 1..maxSyncMessages means maxSyncMessages.
+type SyncRequest struct {
+	DataStructureID int    `json:"data_structure_id"`
+	From            uint64 `json:"from"`
+	To              uint64 `json:"to,omitempty"`
+	Limit           int    `json:"limit,omitempty"`
+}
+
+// SyncResponse carries the messages found, lowest sequence first, and the
+// highest sequence number the operator has stored for the structure, so
+// the caller knows whether to ask again.
+type SyncResponse struct {
+	Messages []store.Message `json:"messages"`
+	Last     uint64          `json:"last"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func (o *OperatorNode) handleSyncStream(s network.Stream) {
+	defer s.Close()
+	s.SetDeadline(o.clock.Now().Add(syncStreamTimeout))
+
+	var req SyncRequest
+	if err := json.NewDecoder(io.LimitReader(s, maxSyncRequestSize)).Decode(&req); err != nil {
+		syncRequestsTotal.WithLabelValues("bad_request").Inc()
+		writeSyncResponse(s, SyncResponse{Error: "malformed sync request"})
+		return
+	}
+
+	resp := o.syncMessages(req)
+	if resp.Error != "" {
+		syncRequestsTotal.WithLabelValues("error").Inc()
+	} else {
+		syncRequestsTotal.WithLabelValues("ok").Inc()
+		syncMessagesServed.Add(float64(len(resp.Messages)))
+	}
+	log.Printf("🔄 Sync for structure %d from %s: sequence %d-%d, %d messages", req.DataStructureID, s.Conn().RemotePeer(), req.From, req.To, len(resp.Messages))
+	writeSyncResponse(s, resp)
+}
+
+func (o *OperatorNode) syncMessages(req SyncRequest) SyncResponse {
+	limit := req.Limit
+	if limit < 1 || limit > maxSyncMessages {
+		limit = maxSyncMessages
+	}
+
+	last, err := o.db.LastSequence(o.ctx, req.DataStructureID)
+	if err != nil {
+		log.Printf("Error reading last sequence for sync: %v", err)
+		return SyncResponse{Error: "database error"}
+	}
+	messages, err := o.db.GetMessagesBySequence(o.ctx, req.DataStructureID, req.From, req.To, true, limit)
+	if err != nil {
+		log.Printf("Error reading messages for sync: %v", err)
+		return SyncResponse{Error: "database error"}
+	}
+	if messages == nil {
+		messages = []store.Message{}
+	}
+	return SyncResponse{Messages: messages, Last: last}
+}
+
+func writeSyncResponse(s network.Stream, resp SyncResponse) {
+	if err := json.NewEncoder(s).Encode(resp); err != nil {
+		log.Printf("Error writing sync response to %s: %v", s.Conn().RemotePeer(), err)
+		s.Reset()
+	}
+}
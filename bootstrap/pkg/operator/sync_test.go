@@ -0,0 +1,44 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestWeightedConfirmationDecidesWhatIsServed(t *testing.T) {
+	mock := clock.NewMock()
+	mock.Set(time.Now())
+	op := newTestOperator(t, mock, 3, nil)
+	op.SetMaxTimestampSkew(time.Hour)
+	weights := map[string]uint64{}
+	for i, weight := range []uint64{1, 1, 10} {
+		weights[cryptoeth.PubkeyToAddress(op.signers[i].PublicKey).Hex()] = weight
+	}
+	op.SetSignerWeights(weights)
+
+	req := op.request(t, "100")
+	if err := op.publisher().PublishSignRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	// One signer meets the signature count the heavy signer would, but
+	// not the weight.
+	op.respond(t, req, 0)
+	if threshold := op.thresholdFor(1); threshold > 1 {
+		t.Fatalf("threshold %d, want the one signer the weights need", threshold)
+	}
+	if resp := op.syncMessages(SyncRequest{DataStructureID: 1, From: 1}); len(resp.Messages) != 0 {
+		t.Fatalf("synced %d messages short of the signed weight", len(resp.Messages))
+	}
+	if _, found, _ := op.db.GetLatestConfirmed(context.Background(), 1); found {
+		t.Fatal("message short of the signed weight served as confirmed")
+	}
+
+	op.respond(t, req, 2)
+	if resp := op.syncMessages(SyncRequest{DataStructureID: 1, From: 1}); len(resp.Messages) != 1 {
+		t.Fatalf("synced %d messages once confirmed, want 1", len(resp.Messages))
+	}
+}
@@ -0,0 +1,254 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// tenantNamePattern keeps tenant names usable as a topic and structure
+// namespace.
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Tenant is an oracle customer served in isolation by the same operator.
+// Its sign requests travel on a topic of its own, <tenant>.<topic>, and its
+// data structures are signed by its own trusted set only. Structures are
+// named by numeric ID in its data structures file, IDs being unique across
+// the deployment, and are addressed as <tenant>.<id>.
+type Tenant struct {
+	Name             string   `yaml:"name"`
+	TrustedAddresses []string `yaml:"trusted_addresses"`
+	// Threshold is the default threshold of the tenant's structures; zero
+	// is a majority of its trusted set.
+	Threshold          int    `yaml:"threshold"`
+	DataStructuresPath string `yaml:"data_structures_path"`
+}
+
+func (t Tenant) validate() error {
+	if !tenantNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid tenant name %q", t.Name)
+	}
+	if len(t.TrustedAddresses) == 0 {
+		return fmt.Errorf("tenant %s has no trusted addresses", t.Name)
+	}
+	for _, addr := range t.TrustedAddresses {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("tenant %s lists invalid trusted address %q", t.Name, addr)
+		}
+	}
+	if t.Threshold < 0 || t.Threshold > len(t.TrustedAddresses) {
+		return fmt.Errorf("tenant %s threshold must be between 0 and its %d trusted addresses", t.Name, len(t.TrustedAddresses))
+	}
+	if t.DataStructuresPath == "" {
+		return fmt.Errorf("tenant %s has no data structures path", t.Name)
+	}
+	return nil
+}
+
+// TopicName namespaces feed, the operator's own topic, for the tenant.
+func (t Tenant) TopicName(feed string) string {
+	return t.Name + "." + feed
+}
+
+// loadStructures reads the tenant's data structures, keyed by their
+// namespaced names.
+func (t Tenant) loadStructures() (map[string]DataStructure, error) {
+	loaded, err := LoadDataStructures(t.DataStructuresPath)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+	}
+	structures := make(map[string]DataStructure, len(loaded))
+	for name, structure := range loaded {
+		if id, err := strconv.Atoi(name); err != nil || id <= 0 {
+			return nil, fmt.Errorf("tenant %s: structure %q must be named by a positive numeric ID", t.Name, name)
+		}
+		structures[t.Name+"."+name] = structure
+	}
+	return structures, nil
+}
+
+// scopeStructure confines ds to the signers of trusted: a committee of its
+// own may only name signers from trusted, and without one it is trusted
+// itself. threshold is inherited unless ds overrides it.
+func scopeStructure(name string, ds DataStructure, trusted []string, threshold int) (DataStructure, error) {
+	if len(ds.Signers) == 0 {
+		ds.Signers = trusted
+	} else {
+		var signers []string
+		for _, addr := range ds.Signers {
+			if containsAddress(trusted, addr) {
+				signers = append(signers, addr)
+			}
+		}
+		// An empty committee would admit everyone.
+		if len(signers) == 0 {
+			return ds, fmt.Errorf("structure %s lists none of the signers it may use", name)
+		}
+		ds.Signers = signers
+	}
+	if ds.Threshold == 0 {
+		ds.Threshold = threshold
+	}
+	return ds, nil
+}
+
+// addTenants joins the topics of the configured tenants and adds their
+// structures to structures. Every structure, the operator's own included, is
+// scoped to the trusted set of its owner so that no signer counts towards
+// another tenant's data.
+func addTenants(o *OperatorNode, cfg Config, structures map[string]DataStructure) error {
+	if len(cfg.Tenants) == 0 {
+		return nil
+	}
+
+	const operatorOwner = "the operator"
+	owners := make(map[int]string)
+	for name, ds := range structures {
+		scoped, err := scopeStructure(name, ds, cfg.TrustedAddresses, 0)
+		if err != nil {
+			return err
+		}
+		structures[name] = scoped
+		owners[ds.ID] = operatorOwner
+	}
+
+	for _, tenant := range cfg.Tenants {
+		tenantStructures, err := tenant.loadStructures()
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for name, ds := range tenantStructures {
+			id := ds.ID
+			if owner, taken := owners[id]; taken {
+				return fmt.Errorf("structure %s reuses ID %d of %s", name, id, owner)
+			}
+			scoped, err := scopeStructure(name, ds, tenant.TrustedAddresses, tenant.Threshold)
+			if err != nil {
+				return err
+			}
+			owners[id] = tenant.Name
+			structures[name] = scoped
+			ids = append(ids, id)
+		}
+		if err := o.JoinTenant(tenant.Name, tenant.TopicName(cfg.Topic), ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantTopic is the gossip topic of one tenant.
+type tenantTopic struct {
+	name  string
+	topic *pubsub.Topic
+	sub   *pubsub.Subscription
+}
+
+// JoinTenant joins the topic of a tenant and routes the sign requests of
+// its structures over it.
+func (o *OperatorNode) JoinTenant(name, topicName string, structureIDs []int) error {
+	topic, err := o.pubsub.Join(topicName)
+	if err != nil {
+		return fmt.Errorf("failed to join topic %s: %w", topicName, err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topicName, err)
+	}
+	t := &tenantTopic{name: name, topic: topic, sub: sub}
+
+	o.tenantsMux.Lock()
+	o.tenantTopics[name] = t
+	for _, id := range structureIDs {
+		o.structureTenants[id] = name
+	}
+	o.tenantsMux.Unlock()
+
+	go o.listenTenant(t)
+	log.Printf("🏢 Serving tenant %s on topic %s", name, topicName)
+	return nil
+}
+
+// listenTenant feeds the messages of a tenant topic into the same intake as
+// the operator's own topic.
+func (o *OperatorNode) listenTenant(t *tenantTopic) {
+	for {
+		msg, err := t.sub.Next(o.ctx)
+		if err == nil {
+			o.enqueueIncoming(msg.GetFrom(), msg.Data)
+			continue
+		}
+		if o.ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error reading topic of tenant %s: %v. Resubscribing...", t.name, err)
+		t.sub.Cancel()
+		for {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-o.clock.After(reconnectTimeout):
+			}
+			if t.sub, err = t.topic.Subscribe(); err == nil {
+				break
+			}
+			log.Printf("Error resubscribing to topic of tenant %s: %v", t.name, err)
+		}
+	}
+}
+
+// tenantOf returns the tenant owning a structure, "" for the operator's own.
+func (o *OperatorNode) tenantOf(dataStructureID int) string {
+	o.tenantsMux.RLock()
+	defer o.tenantsMux.RUnlock()
+
+	return o.structureTenants[dataStructureID]
+}
+
+// topicFor returns the topic sign requests of a structure travel on.
+func (o *OperatorNode) topicFor(dataStructureID int) *pubsub.Topic {
+	o.tenantsMux.RLock()
+	defer o.tenantsMux.RUnlock()
+
+	if t, ok := o.tenantTopics[o.structureTenants[dataStructureID]]; ok {
+		return t.topic
+	}
+	return o.topic
+}
+
+// closeTenantTopics ends the tenant subscriptions.
+func (o *OperatorNode) closeTenantTopics() {
+	o.tenantsMux.RLock()
+	defer o.tenantsMux.RUnlock()
+
+	for _, t := range o.tenantTopics {
+		t.sub.Cancel()
+	}
+}
+
+type apiKeyContextKey struct{}
+
+// withAPIKey records the API key a request was accounted under.
+func withAPIKey(ctx context.Context, key meteredKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// canRead reports whether r may see the messages of a structure. Keys of a
+// tenant see that tenant's structures only, keys of no tenant see all, and
+// requests without a key see the operator's own structures.
+func (s *RPCServer) canRead(r *http.Request, dataStructureID int) bool {
+	owner := s.operator.tenantOf(dataStructureID)
+	key, ok := r.Context().Value(apiKeyContextKey{}).(meteredKey)
+	if !ok || key.name == anonymousUsage {
+		return owner == ""
+	}
+	return key.tenant == "" || strings.EqualFold(key.tenant, owner)
+}
@@ -0,0 +1,397 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"bootstrap/pkg/store"
+)
+
+const tracingServiceName = "l0proof-bootstrap"
+
+var tracer = otel.Tracer("l0proof/bootstrap")
+
+// SetupTracing installs the global tracer provider. Spans are exported over
+// OTLP/HTTP only when OTEL_EXPORTER_OTLP_ENDPOINT (or the traces-specific
+// variant) is set; otherwise tracing stays a no-op. The returned function
+// flushes and stops the exporter.
+func SetupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = tracingServiceName
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// injectTraceContext serializes the span context of ctx into a carrier that
+// travels inside pubsub messages.
+func injectTraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// extractTraceContext restores a span context previously written by
+// injectTraceContext.
+func extractTraceContext(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, for streaming
+// handlers that flush and set write deadlines.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func tracingMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("HTTP %s %s", r.Method, r.URL.Path),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.target", r.URL.RequestURI()),
+			),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	}
+}
+
+// tracingDatabase wraps a Database and records a span for every call.
+type tracingDatabase struct {
+	store.Database
+}
+
+func newTracingDatabase(db store.Database) store.Database {
+	return &tracingDatabase{Database: db}
+}
+
+func (t *tracingDatabase) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := tracer.Start(ctx, "db."+name, trace.WithAttributes(attrs...))
+	return span
+}
+
+func (t *tracingDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	span := t.startSpan(ctx, "StoreData", attribute.String("hash", hash), attribute.Int("dsid", dataStructureID), attribute.String("request_id", requestID))
+	defer span.End()
+
+	err := t.Database.StoreData(ctx, hash, data, dataStructure, dataStructureMeta, timestamp, dataStructureID, hashVersion, requestID, sequence, epoch)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (t *tracingDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	span := t.startSpan(ctx, "StoreSignature", attribute.String("hash", hash), attribute.String("signer", signer))
+	defer span.End()
+
+	err := t.Database.StoreSignature(ctx, hash, signer, signature)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (t *tracingDatabase) GetData(ctx context.Context, hash string) ([]interface{}, []string, []string, int64, bool) {
+	span := t.startSpan(ctx, "GetData", attribute.String("hash", hash))
+	defer span.End()
+
+	data, structure, meta, timestamp, ok := t.Database.GetData(ctx, hash)
+	span.SetAttributes(attribute.Bool("found", ok))
+	return data, structure, meta, timestamp, ok
+}
+
+func (t *tracingDatabase) GetSignatures(ctx context.Context, hash string) (map[string]string, bool) {
+	span := t.startSpan(ctx, "GetSignatures", attribute.String("hash", hash))
+	defer span.End()
+
+	sigs, ok := t.Database.GetSignatures(ctx, hash)
+	span.SetAttributes(attribute.Int("count", len(sigs)))
+	return sigs, ok
+}
+
+func (t *tracingDatabase) GetMessage(ctx context.Context, hash string) (store.Message, bool) {
+	span := t.startSpan(ctx, "GetMessage", attribute.String("hash", hash))
+	defer span.End()
+
+	msg, ok := t.Database.GetMessage(ctx, hash)
+	span.SetAttributes(attribute.Bool("found", ok))
+	return msg, ok
+}
+
+func (t *tracingDatabase) GetAllMessages(ctx context.Context, dataStructureID int, page, limit int) ([]store.Message, error) {
+	span := t.startSpan(ctx, "GetAllMessages", attribute.Int("dsid", dataStructureID), attribute.Int("page", page), attribute.Int("limit", limit))
+	defer span.End()
+
+	messages, err := t.Database.GetAllMessages(ctx, dataStructureID, page, limit)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return messages, err
+}
+
+func (t *tracingDatabase) GetLatestMessage(ctx context.Context, dataStructureID int) (store.Message, bool, error) {
+	span := t.startSpan(ctx, "GetLatestMessage", attribute.Int("dsid", dataStructureID))
+	defer span.End()
+
+	msg, confirmed, err := t.Database.GetLatestMessage(ctx, dataStructureID)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return msg, confirmed, err
+}
+
+func (t *tracingDatabase) IterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(store.Message) bool) error {
+	span := t.startSpan(ctx, "IterateMessages", attribute.Int("dsid", dataStructureID), attribute.Int64("from", from), attribute.Int64("to", to))
+	defer span.End()
+
+	count := 0
+	err := t.Database.IterateMessages(ctx, dataStructureID, from, to, func(msg store.Message) bool {
+		count++
+		return fn(msg)
+	})
+	span.SetAttributes(attribute.Int("count", count))
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (t *tracingDatabase) GetLatestConfirmed(ctx context.Context, dataStructureID int) (store.Message, bool, error) {
+	span := t.startSpan(ctx, "GetLatestConfirmed", attribute.Int("dsid", dataStructureID))
+	defer span.End()
+
+	msg, found, err := t.Database.GetLatestConfirmed(ctx, dataStructureID)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	span.SetAttributes(attribute.Bool("found", found))
+	return msg, found, err
+}
+
+func (t *tracingDatabase) GetMessagesByField(ctx context.Context, dataStructureID int, field, value string, page, limit int) ([]store.Message, error) {
+	span := t.startSpan(ctx, "GetMessagesByField", attribute.Int("dsid", dataStructureID), attribute.String("field", field))
+	defer span.End()
+
+	messages, err := t.Database.GetMessagesByField(ctx, dataStructureID, field, value, page, limit)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return messages, err
+}
+
+func (t *tracingDatabase) GetLatestByField(ctx context.Context, dataStructureID int, field, value string) (store.Message, bool, error) {
+	span := t.startSpan(ctx, "GetLatestByField", attribute.Int("dsid", dataStructureID), attribute.String("field", field))
+	defer span.End()
+
+	msg, found, err := t.Database.GetLatestByField(ctx, dataStructureID, field, value)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return msg, found, err
+}
+
+func (t *tracingDatabase) GetMessagesByRange(ctx context.Context, dataStructureID int, ranges []store.RangeFilter, equals []store.FieldFilter, page, limit int) ([]store.Message, error) {
+	span := t.startSpan(ctx, "GetMessagesByRange", attribute.Int("dsid", dataStructureID), attribute.Int("ranges", len(ranges)), attribute.Int("filters", len(equals)))
+	defer span.End()
+
+	messages, err := t.Database.GetMessagesByRange(ctx, dataStructureID, ranges, equals, page, limit)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return messages, err
+}
+
+func (t *tracingDatabase) GetConfirmedMessages(ctx context.Context, dataStructureID int, ranges []store.RangeFilter, equals []store.FieldFilter, page, limit int) ([]store.Message, error) {
+	span := t.startSpan(ctx, "GetConfirmedMessages", attribute.Int("dsid", dataStructureID), attribute.Int("page", page), attribute.Int("limit", limit))
+	defer span.End()
+
+	messages, err := t.Database.GetConfirmedMessages(ctx, dataStructureID, ranges, equals, page, limit)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return messages, err
+}
+
+func (t *tracingDatabase) RebuildIndexes(ctx context.Context, dataStructureID int) (int, error) {
+	span := t.startSpan(ctx, "RebuildIndexes", attribute.Int("dsid", dataStructureID))
+	defer span.End()
+
+	indexed, err := t.Database.RebuildIndexes(ctx, dataStructureID)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return indexed, err
+}
+
+func (t *tracingDatabase) GetDataStructures(ctx context.Context) ([]int, error) {
+	span := t.startSpan(ctx, "GetDataStructures")
+	defer span.End()
+
+	ids, err := t.Database.GetDataStructures(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return ids, err
+}
+
+func (t *tracingDatabase) GetDataStructureStats(ctx context.Context, id int) (store.DataStructureStats, error) {
+	span := t.startSpan(ctx, "GetDataStructureStats", attribute.Int("dsid", id))
+	defer span.End()
+
+	stats, err := t.Database.GetDataStructureStats(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrNoStats) {
+		recordSpanError(span, err)
+	}
+	return stats, err
+}
+
+func (t *tracingDatabase) MarkConfirmed(ctx context.Context, dataStructureID int, hash string, timestamp int64, confirmation *store.Confirmation) error {
+	span := t.startSpan(ctx, "MarkConfirmed", attribute.Int("dsid", dataStructureID), attribute.String("hash", hash))
+	defer span.End()
+
+	err := t.Database.MarkConfirmed(ctx, dataStructureID, hash, timestamp, confirmation)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (t *tracingDatabase) RebuildStats(ctx context.Context, dataStructureID, threshold int) (store.DataStructureStats, error) {
+	span := t.startSpan(ctx, "RebuildStats", attribute.Int("dsid", dataStructureID), attribute.Int("threshold", threshold))
+	defer span.End()
+
+	stats, err := t.Database.RebuildStats(ctx, dataStructureID, threshold)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return stats, err
+}
+
+func (t *tracingDatabase) CountMessages(ctx context.Context, dataStructureID int, filters ...store.FieldFilter) (int, error) {
+	span := t.startSpan(ctx, "CountMessages", attribute.Int("dsid", dataStructureID), attribute.Int("filters", len(filters)))
+	defer span.End()
+
+	count, err := t.Database.CountMessages(ctx, dataStructureID, filters...)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return count, err
+}
+
+func (t *tracingDatabase) CountConfirmed(ctx context.Context, dataStructureID int) (int, error) {
+	span := t.startSpan(ctx, "CountConfirmed", attribute.Int("dsid", dataStructureID))
+	defer span.End()
+
+	count, err := t.Database.CountConfirmed(ctx, dataStructureID)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return count, err
+}
+
+func (t *tracingDatabase) HasData(ctx context.Context, hash string) bool {
+	span := t.startSpan(ctx, "HasData", attribute.String("hash", hash))
+	defer span.End()
+
+	exists := t.Database.HasData(ctx, hash)
+	span.SetAttributes(attribute.Bool("found", exists))
+	return exists
+}
+
+func (t *tracingDatabase) SearchByHashPrefix(ctx context.Context, prefix string, limit int) ([]store.Message, error) {
+	span := t.startSpan(ctx, "SearchByHashPrefix", attribute.String("prefix", prefix), attribute.Int("limit", limit))
+	defer span.End()
+
+	messages, err := t.Database.SearchByHashPrefix(ctx, prefix, limit)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return messages, err
+}
+
+func (t *tracingDatabase) StoreLatency(ctx context.Context, hash string, latency store.MessageLatency) error {
+	span := t.startSpan(ctx, "StoreLatency", attribute.String("hash", hash))
+	defer span.End()
+
+	err := t.Database.StoreLatency(ctx, hash, latency)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (t *tracingDatabase) GetLatency(ctx context.Context, hash string) (store.MessageLatency, bool) {
+	span := t.startSpan(ctx, "GetLatency", attribute.String("hash", hash))
+	defer span.End()
+
+	latency, ok := t.Database.GetLatency(ctx, hash)
+	span.SetAttributes(attribute.Bool("found", ok))
+	return latency, ok
+}
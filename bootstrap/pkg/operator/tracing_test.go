@@ -0,0 +1,78 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStreamingHandlerCanFlush(t *testing.T) {
+	s := &RPCServer{}
+	flushed := make(chan error, 1)
+	srv := httptest.NewServer(s.wrapStreamingHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		flushed <- http.NewResponseController(w).Flush()
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := <-flushed; err != nil {
+		t.Fatalf("flush through the streaming middleware failed: %v", err)
+	}
+}
+
+func TestSignatureSpansContinueSignerTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	op := newTestOperator(t, clock.New(), 3, newTracingDatabase)
+	req := op.request(t, "100")
+
+	// The signer's span, as it travels back in the sign response.
+	signerCtx, signer := tp.Tracer("signer").Start(context.Background(), "sign")
+	signer.End()
+	resp := op.signResponse(t, req, 0)
+	resp.TraceContext = injectTraceContext(signerCtx)
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op.HandleMessage(op.host.ID(), data)
+	if signers, _ := op.pendingSigners(req.Hash); signers != 1 {
+		t.Fatalf("signature not recorded: %d signers", signers)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == signer.SpanContext().TraceID() {
+			spans[span.Name()] = span
+		}
+	}
+	parentOf := func(child, parent string, want trace.SpanContext) {
+		span, ok := spans[child]
+		if !ok {
+			t.Fatalf("no %s span in the signer's trace", child)
+		}
+		if span.Parent().SpanID() != want.SpanID() {
+			t.Fatalf("%s span is not a child of %s", child, parent)
+		}
+	}
+	parentOf("pubsub.receive "+MsgTypeSignResponse, "the signer's span", signer.SpanContext())
+	parentOf("verify_signature", "pubsub.receive", spans["pubsub.receive "+MsgTypeSignResponse].SpanContext())
+	parentOf("db.StoreSignature", "verify_signature", spans["verify_signature"].SpanContext())
+}
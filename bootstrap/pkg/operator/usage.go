@@ -0,0 +1,392 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"bootstrap/pkg/store"
+)
+
+const (
+	// anonymousUsage is the name requests without an API key are accounted
+	// under.
+	anonymousUsage = "anonymous"
+
+	usageFlushInterval = time.Minute
+	usageDayLayout     = "20060102"
+)
+
+// APIKey identifies a consumer of the public API. Tier names an entry of
+// UsageConfig.Tiers; keys without one have no quota. Keys of a tenant only
+// read the structures of that tenant.
+type APIKey struct {
+	Name   string `yaml:"name"`
+	Key    string `yaml:"key"`
+	Tier   string `yaml:"tier"`
+	Tenant string `yaml:"tenant"`
+	// Role is "consumer" (the default) or "admin"; admin keys may use the
+	// admin endpoints.
+	Role string `yaml:"role"`
+}
+
+// UsageTier caps what a key may use per calendar month (UTC). Zero leaves a
+// dimension unlimited.
+type UsageTier struct {
+	Requests      int64 `yaml:"requests" json:"requests,omitempty"`
+	Bytes         int64 `yaml:"bytes" json:"bytes,omitempty"`
+	StreamMinutes int64 `yaml:"stream_minutes" json:"stream_minutes,omitempty"`
+}
+
+// UsageConfig turns on usage accounting of the public routes per API key.
+// Keys are sent as X-API-Key, or as the api_key query parameter by clients
+// such as EventSource that cannot set headers.
+type UsageConfig struct {
+	Keys  []APIKey             `yaml:"keys"`
+	Tiers map[string]UsageTier `yaml:"tiers"`
+	// RequireKey refuses public requests without a key; otherwise they are
+	// accounted as "anonymous".
+	RequireKey bool `yaml:"require_key"`
+}
+
+func (c UsageConfig) validate() error {
+	names := make(map[string]bool, len(c.Keys))
+	secrets := make(map[string]bool, len(c.Keys))
+	for _, key := range c.Keys {
+		if key.Name == "" || key.Name == anonymousUsage {
+			return fmt.Errorf("invalid API key name %q", key.Name)
+		}
+		if key.Key == "" {
+			return fmt.Errorf("API key %s has no key", key.Name)
+		}
+		if names[key.Name] || secrets[key.Key] {
+			return fmt.Errorf("API key %s is configured twice", key.Name)
+		}
+		if _, ok := c.Tiers[key.Tier]; key.Tier != "" && !ok {
+			return fmt.Errorf("API key %s has unknown tier %q", key.Name, key.Tier)
+		}
+		if r, err := parseRole(key.Role, roleConsumer); err != nil || r == rolePublicRead {
+			return fmt.Errorf("API key %s has invalid role %q", key.Name, key.Role)
+		}
+		names[key.Name], secrets[key.Key] = true, true
+	}
+	for name, tier := range c.Tiers {
+		if tier.Requests < 0 || tier.Bytes < 0 || tier.StreamMinutes < 0 {
+			return fmt.Errorf("usage tier %s has a negative quota", name)
+		}
+	}
+	if c.RequireKey && len(c.Keys) == 0 {
+		return fmt.Errorf("require_key needs at least one API key")
+	}
+	return nil
+}
+
+type meteredKey struct {
+	name   string
+	tier   string
+	tenant string
+	role   role
+}
+
+// UsageMeter accounts requests, response bytes and streaming time per API
+// key, enforces tier quotas and persists daily totals. A nil UsageMeter
+// accounts nothing.
+type UsageMeter struct {
+	db         store.Database
+	keys       map[[32]byte]meteredKey
+	tiers      map[string]UsageTier
+	requireKey bool
+
+	mu sync.Mutex
+	// month and monthly hold the month-to-date totals quotas are checked
+	// against; unflushed holds what has not reached db yet, by day.
+	month     string
+	monthly   map[string]store.Usage
+	unflushed map[string]map[string]store.Usage
+}
+
+// NewUsageMeter returns nil when no API keys are configured.
+func NewUsageMeter(db store.Database, cfg UsageConfig) *UsageMeter {
+	if len(cfg.Keys) == 0 {
+		return nil
+	}
+	m := &UsageMeter{
+		db:         db,
+		keys:       make(map[[32]byte]meteredKey, len(cfg.Keys)),
+		tiers:      cfg.Tiers,
+		requireKey: cfg.RequireKey,
+		monthly:    make(map[string]store.Usage),
+		unflushed:  make(map[string]map[string]store.Usage),
+	}
+	// Keys are looked up by digest so that lookups take no time dependent
+	// on the secret.
+	for _, key := range cfg.Keys {
+		keyRole, _ := parseRole(key.Role, roleConsumer)
+		m.keys[sha256.Sum256([]byte(key.Key))] = meteredKey{name: key.Name, tier: key.Tier, tenant: key.Tenant, role: keyRole}
+	}
+	return m
+}
+
+// hasRole reports whether any key holds r. A nil UsageMeter has no keys.
+func (m *UsageMeter) hasRole(r role) bool {
+	if m == nil {
+		return false
+	}
+	for _, key := range m.keys {
+		if key.role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Start loads the month-to-date usage quotas are checked against, then
+// flushes usage to db every minute until ctx is done.
+func (m *UsageMeter) Start(ctx context.Context) error {
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	days, err := m.db.GetUsage(ctx, first.Format(usageDayLayout), now.Format(usageDayLayout))
+	if err != nil {
+		return fmt.Errorf("failed to load usage: %w", err)
+	}
+
+	m.mu.Lock()
+	m.month = now.Format("200601")
+	for _, usage := range days {
+		for name, u := range usage {
+			m.monthly[name] = m.monthly[name].Add(u)
+		}
+	}
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(usageFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Flush(context.Background()); err != nil {
+					log.Printf("Error flushing API usage: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Flush writes usage accounted since the last flush to db. Usage that fails
+// to write is kept for the next flush.
+func (m *UsageMeter) Flush(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	pending := m.unflushed
+	m.unflushed = make(map[string]map[string]store.Usage)
+	m.mu.Unlock()
+
+	for day, usage := range pending {
+		if err := m.db.AddUsage(ctx, day, usage); err != nil {
+			m.mu.Lock()
+			for name, u := range usage {
+				m.addUnflushed(day, name, u)
+			}
+			m.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// addUnflushed must be called with mu held.
+func (m *UsageMeter) addUnflushed(day, name string, u store.Usage) {
+	if m.unflushed[day] == nil {
+		m.unflushed[day] = make(map[string]store.Usage)
+	}
+	m.unflushed[day][name] = m.unflushed[day][name].Add(u)
+}
+
+func (m *UsageMeter) record(name string, u store.Usage, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if month := now.Format("200601"); month != m.month {
+		m.month = month
+		m.monthly = make(map[string]store.Usage)
+	}
+	m.monthly[name] = m.monthly[name].Add(u)
+	m.addUnflushed(now.Format(usageDayLayout), name, u)
+}
+
+// identify returns the key a request is accounted under. ok is false for an
+// unknown key, or a missing one when keys are required.
+func (m *UsageMeter) identify(r *http.Request) (key meteredKey, ok bool) {
+	secret := r.Header.Get("X-API-Key")
+	if secret == "" {
+		secret = r.URL.Query().Get("api_key")
+	}
+	if secret == "" {
+		return meteredKey{name: anonymousUsage}, !m.requireKey
+	}
+	key, ok = m.keys[sha256.Sum256([]byte(secret))]
+	return key, ok
+}
+
+// exceeded returns the quota key has used up this month, or "".
+func (m *UsageMeter) exceeded(key meteredKey, streaming bool, now time.Time) string {
+	tier, ok := m.tiers[key.tier]
+	if !ok {
+		return ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used := m.monthly[key.name]
+	if now.Format("200601") != m.month {
+		used = store.Usage{}
+	}
+	switch {
+	case tier.Requests > 0 && used.Requests >= tier.Requests:
+		return "requests"
+	case tier.Bytes > 0 && used.Bytes >= tier.Bytes:
+		return "bytes"
+	case streaming && tier.StreamMinutes > 0 && used.StreamSeconds >= tier.StreamMinutes*60:
+		return "stream_minutes"
+	}
+	return ""
+}
+
+// middleware accounts each request to its key and answers 429 until the
+// next month once a quota is used up. Streaming requests are also accounted
+// the time they stay connected.
+func (m *UsageMeter) middleware(h http.HandlerFunc, streaming bool) http.HandlerFunc {
+	if m == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := m.identify(r)
+		if !ok {
+			http.Error(w, "Invalid or missing API key", http.StatusUnauthorized)
+			return
+		}
+		now := time.Now().UTC()
+		if quota := m.exceeded(key, streaming, now); quota != "" {
+			apiQuotaRejections.WithLabelValues(key.name, quota).Inc()
+			next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			w.Header().Set("Retry-After", strconv.Itoa(int(next.Sub(now).Seconds())+1))
+			http.Error(w, fmt.Sprintf("Monthly %s quota exceeded", quota), http.StatusTooManyRequests)
+			return
+		}
+
+		counter := &byteCounter{ResponseWriter: w}
+		h(counter, r.WithContext(withAPIKey(r.Context(), key)))
+
+		u := store.Usage{Requests: 1, Bytes: counter.bytes}
+		if streaming {
+			u.StreamSeconds = int64(time.Since(now).Round(time.Second).Seconds())
+		}
+		m.record(key.name, u, time.Now().UTC())
+	}
+}
+
+// byteCounter counts the response body bytes written through it.
+type byteCounter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func (c *byteCounter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *byteCounter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// KeyUsage is one key's usage over a report's range.
+type KeyUsage struct {
+	Name          string       `json:"name"`
+	Tier          string       `json:"tier,omitempty"`
+	Requests      int64        `json:"requests"`
+	Bytes         int64        `json:"bytes"`
+	StreamMinutes float64      `json:"stream_minutes"`
+	Quota         *UsageTier   `json:"quota,omitempty"`
+	Days          []DailyUsage `json:"days"`
+}
+
+type DailyUsage struct {
+	Day           string  `json:"day"`
+	Requests      int64   `json:"requests"`
+	Bytes         int64   `json:"bytes"`
+	StreamMinutes float64 `json:"stream_minutes"`
+}
+
+// Report returns the usage of every key from one day through another,
+// flushing first so that it is current.
+func (m *UsageMeter) Report(ctx context.Context, from, to time.Time) ([]KeyUsage, error) {
+	if err := m.Flush(ctx); err != nil {
+		return nil, err
+	}
+	days, err := m.db.GetUsage(ctx, from.Format(usageDayLayout), to.Format(usageDayLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := make(map[string]string, len(m.keys))
+	for _, key := range m.keys {
+		tiers[key.name] = key.tier
+	}
+	byName := make(map[string]*KeyUsage)
+	totals := make(map[string]store.Usage)
+	for day, usage := range days {
+		date, err := time.Parse(usageDayLayout, day)
+		if err != nil {
+			continue
+		}
+		for name, u := range usage {
+			k, ok := byName[name]
+			if !ok {
+				k = &KeyUsage{Name: name, Tier: tiers[name], Days: []DailyUsage{}}
+				if tier, ok := m.tiers[k.Tier]; ok {
+					k.Quota = &tier
+				}
+				byName[name] = k
+			}
+			totals[name] = totals[name].Add(u)
+			k.Days = append(k.Days, DailyUsage{
+				Day:           date.Format(time.DateOnly),
+				Requests:      u.Requests,
+				Bytes:         u.Bytes,
+				StreamMinutes: float64(u.StreamSeconds) / 60,
+			})
+		}
+	}
+
+	report := make([]KeyUsage, 0, len(byName))
+	for name, k := range byName {
+		k.Requests, k.Bytes = totals[name].Requests, totals[name].Bytes
+		k.StreamMinutes = float64(totals[name].StreamSeconds) / 60
+		sort.Slice(k.Days, func(i, j int) bool { return k.Days[i].Day < k.Days[j].Day })
+		report = append(report, *k)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	return report, nil
+}
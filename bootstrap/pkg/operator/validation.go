@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"bootstrap/pkg/store"
+)
+
+// ValidationError lists every problem found in a payload so callers can fix
+// them in one go.
+type ValidationError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// NormalizeFields checks submitted values against the structure's field
+// types and required fields and returns them converted to the
+// representation used for hashing, integers as decimal strings. Unknown
+// fields are rejected.
+func (ds DataStructure) NormalizeFields(values map[string]interface{}) (map[string]interface{}, error) {
+	verr := &ValidationError{}
+	normalized := make(map[string]interface{}, len(values))
+
+	known := make(map[string]bool, len(ds.Fields))
+	for _, f := range ds.Fields {
+		known[f.Name] = true
+
+		raw, ok := values[f.Name]
+		if !ok || raw == nil {
+			continue
+		}
+
+		value, err := normalizeSolidityValue(f.SolidityType, raw)
+		if err != nil {
+			verr.add("field %q: %v", f.Name, err)
+			continue
+		}
+		normalized[f.Name] = value
+	}
+
+	for name := range values {
+		if !known[name] {
+			verr.add("field %q is not part of the structure", name)
+		}
+	}
+
+	for _, name := range ds.RequiredFields {
+		if _, ok := values[name]; !ok {
+			verr.add("field %q is required", name)
+		}
+	}
+
+	if len(verr.Problems) > 0 {
+		return nil, verr
+	}
+	return normalized, nil
+}
+
+func normalizeSolidityValue(solidityType string, raw interface{}) (interface{}, error) {
+	switch {
+	case solidityType == "string":
+		v, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		return v, nil
+
+	case solidityType == "bool":
+		v, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool")
+		}
+		return v, nil
+
+	case solidityType == "address":
+		v, ok := raw.(string)
+		if !ok || !common.IsHexAddress(v) {
+			return nil, fmt.Errorf("expected hex address")
+		}
+		return common.HexToAddress(v).Hex(), nil
+
+	case solidityType == "bytes32":
+		v, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected 0x-prefixed hex string")
+		}
+		b, err := hexutil.Decode(v)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("expected 32 bytes of hex")
+		}
+		return v, nil
+
+	case strings.HasPrefix(solidityType, "uint"), strings.HasPrefix(solidityType, "int"):
+		n, err := store.ParseInteger(raw)
+		if err != nil {
+			return nil, err
+		}
+		bits := 256
+		signed := strings.HasPrefix(solidityType, "int")
+		if suffix := strings.TrimLeft(solidityType, "uint"); suffix != "" {
+			if _, err := fmt.Sscanf(suffix, "%d", &bits); err != nil || bits <= 0 || bits > 256 || bits%8 != 0 {
+				return nil, fmt.Errorf("unsupported type %s", solidityType)
+			}
+		}
+		if !signed && n.Sign() < 0 {
+			return nil, fmt.Errorf("expected non-negative integer")
+		}
+		if (signed && !fitsSigned(n, bits)) || (!signed && n.BitLen() > bits) {
+			return nil, fmt.Errorf("value overflows %s", solidityType)
+		}
+		return n.String(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", solidityType)
+	}
+}
@@ -0,0 +1,126 @@
+package operator
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+// vectorsSampleKey signs test vectors when no key is supplied. It is the
+// well-known Hardhat account #0 key and must never hold funds.
+const vectorsSampleKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+type TestVector struct {
+	Structure   string        `json:"structure"`
+	HashVersion int           `json:"hash_version"`
+	Types       []string      `json:"types"`
+	Fields      []string      `json:"fields"`
+	Values      []interface{} `json:"values"`
+	Timestamp   int64         `json:"timestamp"`
+	Packed      string        `json:"packed"`
+	Hash        string        `json:"hash"`
+	Digest      string        `json:"digest"`
+	Signer      string        `json:"signer"`
+	// Signature is in the form signer nodes publish it (v in {0, 1});
+	// SignatureV27 has v shifted to {27, 28} as ecrecover expects.
+	Signature    string `json:"signature"`
+	SignatureV27 string `json:"signature_v27"`
+}
+
+// VectorsCommand implements the `vectors` subcommand: it hashes sample values for
+// a structure and prints every intermediate step as JSON so other
+// implementations can check they produce identical digests.
+func VectorsCommand(args []string) error {
+	fs := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	structuresPath := fs.String("structures", "config/data_structures.json", "path to the data structures file")
+	structureID := fs.String("structure", "stock_quote", "structure to build the vector for")
+	valuesJSON := fs.String("values", "", "JSON object with field values")
+	timestamp := fs.Int64("timestamp", 1700000000, "message timestamp")
+	version := fs.Int("hash-version", 0, "hash version (defaults to the structure's)")
+	keyHex := fs.String("key", vectorsSampleKey, "hex private key for the sample signature")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	structures, err := LoadDataStructures(*structuresPath)
+	if err != nil {
+		return err
+	}
+	structure, ok := structures[*structureID]
+	if !ok {
+		return fmt.Errorf("unknown structure: %s", *structureID)
+	}
+
+	values := make(map[string]interface{})
+	if *valuesJSON != "" {
+		dec := json.NewDecoder(strings.NewReader(*valuesJSON))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return fmt.Errorf("invalid values: %w", err)
+		}
+	}
+	if _, ok := values["timestamp"]; !ok {
+		values["timestamp"] = *timestamp
+	}
+
+	fields, err := structure.NormalizeFields(values)
+	if err != nil {
+		return err
+	}
+
+	if *version != 0 {
+		structure.HashVersion = *version
+	}
+	sr, err := buildSignRequest(*structureID, structure, fields, *timestamp)
+	if err != nil {
+		return err
+	}
+
+	packed, err := packMessage(sr.HashVersion, sr.DataStructure, sr.Data, sr.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	hashBytes, err := hex.DecodeString(sr.Hash)
+	if err != nil {
+		return err
+	}
+	digest := accounts.TextHash(hashBytes)
+
+	key, err := cryptoeth.HexToECDSA(strings.TrimPrefix(*keyHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	signature, err := cryptoeth.Sign(digest, key)
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	signatureV27 := append([]byte(nil), signature...)
+	signatureV27[64] += 27
+
+	vector := TestVector{
+		Structure:    *structureID,
+		HashVersion:  sr.HashVersion,
+		Types:        sr.DataStructure,
+		Fields:       sr.DataStructureMeta,
+		Values:       sr.Data,
+		Timestamp:    sr.Timestamp,
+		Packed:       hexutil.Encode(packed),
+		Hash:         "0x" + sr.Hash,
+		Digest:       hexutil.Encode(digest),
+		Signer:       cryptoeth.PubkeyToAddress(key.PublicKey).Hex(),
+		Signature:    hexutil.Encode(signature),
+		SignatureV27: hexutil.Encode(signatureV27),
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vector)
+}
@@ -0,0 +1,182 @@
+package operator
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+
+	"bootstrap/pkg/store"
+)
+
+type SignatureCheck struct {
+	ClaimedSigner string `json:"claimed_signer,omitempty"`
+	Signature     string `json:"signature"`
+	Recovered     string `json:"recovered,omitempty"`
+	Trusted       bool   `json:"trusted"`
+	Weight        uint64 `json:"weight,omitempty"`
+	Valid         bool   `json:"valid"`
+	Error         string `json:"error,omitempty"`
+}
+
+type VerificationReport struct {
+	Hash        string `json:"hash"`
+	Digest      string `json:"digest"`
+	HashMatches *bool  `json:"hash_matches,omitempty"`
+	Threshold   int    `json:"threshold"`
+	ValidCount  int    `json:"valid_count"`
+	// WeightThreshold and ValidWeight are set when signer weights are
+	// configured; the threshold is then met only if both are.
+	WeightThreshold uint64 `json:"weight_threshold,omitempty"`
+	ValidWeight     uint64 `json:"valid_weight,omitempty"`
+	ThresholdMet    bool   `json:"threshold_met"`
+	// Epoch is the staking epoch whose signer set was used, zero for the
+	// current set. Confirmation is the recorded set used instead, if any.
+	Epoch        uint64              `json:"epoch,omitempty"`
+	Confirmation *store.Confirmation `json:"confirmation,omitempty"`
+	Signatures   []SignatureCheck    `json:"signatures"`
+}
+
+// SignatureInput is a signature to verify together with the address that
+// supposedly produced it. ClaimedSigner may be empty when unknown.
+type SignatureInput struct {
+	ClaimedSigner string
+	Signature     string
+}
+
+// signerView is the signer set signatures are verified against: the
+// operator's current one, or the stored set of a staking epoch.
+type signerView interface {
+	isTrusted(addr common.Address) bool
+	seatOf(addr common.Address) string
+	weighted() bool
+	weightOf(seat string) uint64
+	threshold() int
+	weightThreshold() uint64
+}
+
+// epochSigners is the signerView of a stored staking epoch.
+type epochSigners struct {
+	set     store.SignerSet
+	weights signerWeights
+}
+
+func newEpochSigners(set store.SignerSet) epochSigners {
+	weights := make(signerWeights, len(set.Weights))
+	for addr, weight := range set.Weights {
+		weights[strings.ToLower(addr)] = weight
+	}
+	return epochSigners{set: set, weights: weights}
+}
+
+func (e epochSigners) isTrusted(addr common.Address) bool {
+	return containsAddress(e.set.Signers, addr.Hex())
+}
+
+func (e epochSigners) seatOf(addr common.Address) string { return addr.Hex() }
+func (e epochSigners) weighted() bool                    { return len(e.weights) > 0 }
+func (e epochSigners) weightOf(seat string) uint64       { return e.weights.of(seat) }
+
+func (e epochSigners) threshold() int {
+	if !e.weighted() {
+		return len(e.set.Signers)/2 + 1
+	}
+	return e.weights.minSigners(e.set.Signers, e.weightThreshold())
+}
+
+func (e epochSigners) weightThreshold() uint64 {
+	return e.weights.required(e.set.Signers, 0)
+}
+
+// VerifySignatures re-runs ecrecover for every signature over the hash and
+// checks the recovered signers against the trusted set.
+func (o *OperatorNode) VerifySignatures(hash string, sigs []SignatureInput) (VerificationReport, error) {
+	return verifySignatures(o, hash, sigs)
+}
+
+// VerifySignaturesConfirmed checks signatures against the signer set and
+// thresholds a message was confirmed under, which stay valid after the
+// trusted set changed.
+func (o *OperatorNode) VerifySignaturesConfirmed(hash string, sigs []SignatureInput, c store.Confirmation) (VerificationReport, error) {
+	report, err := verifySignatures(newConfirmedSigners(c), hash, sigs)
+	report.Epoch = c.Epoch
+	report.Confirmation = &c
+	return report, err
+}
+
+// VerifySignaturesAt checks signatures against the signer set of a staking
+// epoch, as a proof from that epoch must be.
+func (o *OperatorNode) VerifySignaturesAt(ctx context.Context, hash string, sigs []SignatureInput, epoch uint64) (VerificationReport, error) {
+	set, found, err := o.db.GetSignerSet(ctx, epoch)
+	if err != nil {
+		return VerificationReport{}, err
+	}
+	if !found {
+		return VerificationReport{}, fmt.Errorf("no signer set recorded for epoch %d", epoch)
+	}
+	report, err := verifySignatures(newEpochSigners(set), hash, sigs)
+	report.Epoch = epoch
+	return report, err
+}
+
+func verifySignatures(o signerView, hash string, sigs []SignatureInput) (VerificationReport, error) {
+	hashBytes, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil {
+		return VerificationReport{}, fmt.Errorf("invalid hash: %w", err)
+	}
+
+	digest := accounts.TextHash(hashBytes)
+	report := VerificationReport{
+		Hash:      hash,
+		Digest:    "0x" + hex.EncodeToString(digest),
+		Threshold: o.threshold(),
+	}
+	weighted := o.weighted()
+	if weighted {
+		report.WeightThreshold = o.weightThreshold()
+	}
+
+	seen := make(map[string]bool)
+	for _, sig := range sigs {
+		claimed := sig.ClaimedSigner
+		check := SignatureCheck{
+			ClaimedSigner: claimed,
+			Signature:     sig.Signature,
+		}
+
+		recovered, err := verifySignature(digest, sig.Signature)
+		if err != nil {
+			check.Error = err.Error()
+			report.Signatures = append(report.Signatures, check)
+			continue
+		}
+
+		check.Recovered = recovered.Hex()
+		check.Trusted = o.isTrusted(recovered)
+
+		switch {
+		case claimed != "" && !strings.EqualFold(claimed, recovered.Hex()):
+			check.Error = "recovered address does not match claimed signer"
+		case !check.Trusted:
+			check.Error = "signer is not in the trusted set"
+		case seen[o.seatOf(recovered)]:
+			check.Error = "duplicate signer"
+		default:
+			check.Valid = true
+			seen[o.seatOf(recovered)] = true
+			report.ValidCount++
+			if weighted {
+				check.Weight = o.weightOf(o.seatOf(recovered))
+				report.ValidWeight += check.Weight
+			}
+		}
+
+		report.Signatures = append(report.Signatures, check)
+	}
+
+	report.ThresholdMet = report.ValidCount >= report.Threshold && report.ValidWeight >= report.WeightThreshold
+	return report, nil
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// signerWeights maps lowercased seats to their voting weight. Seats it does
+// not list weigh 1, so an empty map is one address, one vote.
+type signerWeights map[string]uint64
+
+func (w signerWeights) of(seat string) uint64 {
+	if weight, ok := w[strings.ToLower(seat)]; ok {
+		return weight
+	}
+	return 1
+}
+
+func (w signerWeights) total(seats []string) uint64 {
+	var total uint64
+	for _, seat := range seats {
+		total += w.of(seat)
+	}
+	return total
+}
+
+// required returns the weight needed to confirm a message signed by seats:
+// override if set, bounded by their total weight, otherwise a strict
+// majority of it.
+func (w signerWeights) required(seats []string, override uint64) uint64 {
+	total := w.total(seats)
+	if override > 0 {
+		return max(min(override, total), 1)
+	}
+	return total/2 + 1
+}
+
+// minSigners returns the fewest seats whose weight reaches required. A
+// message with fewer signatures cannot be confirmed, which lets code that
+// only counts signatures apply a weighted threshold as a lower bound.
+func (w signerWeights) minSigners(seats []string, required uint64) int {
+	weights := make([]uint64, len(seats))
+	for i, seat := range seats {
+		weights[i] = w.of(seat)
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i] > weights[j] })
+
+	var sum uint64
+	for i, weight := range weights {
+		sum += weight
+		if sum >= required {
+			return i + 1
+		}
+	}
+	return max(len(seats), 1)
+}
+
+// SetSignerWeights gives trusted addresses a voting weight, e.g. derived
+// from their stake. Once any weight is set, messages need a weighted
+// majority of their eligible signers on top of the signature count. Weights
+// of rotated keys carry over to their successors.
+func (o *OperatorNode) SetSignerWeights(weights map[string]uint64) {
+	o.trustedMux.Lock()
+	defer o.trustedMux.Unlock()
+
+	o.weights = make(signerWeights, len(weights))
+	for addr, weight := range weights {
+		o.weights[strings.ToLower(resolveSuccessor(addr, o.successors))] = weight
+	}
+}
+
+// SetWeightThresholdOverride makes messages of a data structure require a
+// custom signed weight, bounded by the weight of its eligible signers.
+func (o *OperatorNode) SetWeightThresholdOverride(dataStructureID int, weight uint64) {
+	o.thresholdsMux.Lock()
+	o.weightThresholds[dataStructureID] = weight
+	o.thresholdsMux.Unlock()
+}
+
+// weighted reports whether signer weights are configured.
+func (o *OperatorNode) weighted() bool {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	return len(o.weights) > 0
+}
+
+// weightOf returns the voting weight of the signer holding seat.
+func (o *OperatorNode) weightOf(seat string) uint64 {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	return o.weights.of(seat)
+}
+
+// weightThresholdFor returns the signed weight required to confirm a
+// message of the given data structure.
+func (o *OperatorNode) weightThresholdFor(dataStructureID int) uint64 {
+	eligible := o.eligibleSigners(dataStructureID)
+
+	o.thresholdsMux.RLock()
+	override := o.weightThresholds[dataStructureID]
+	o.thresholdsMux.RUnlock()
+
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	return o.weights.required(eligible, override)
+}
+
+// signedWeight sums the weight of the distinct eligible seats among signers,
+// which are the addresses signatures are stored under.
+func (o *OperatorNode) signedWeight(dataStructureID int, signers []string) (int, uint64) {
+	seats := make(map[string]bool, len(signers))
+	var weight uint64
+	for _, signer := range signers {
+		if !common.IsHexAddress(signer) {
+			continue
+		}
+		addr := common.HexToAddress(signer)
+		if !o.isTrusted(addr) {
+			continue
+		}
+		seat := o.seatOf(addr)
+		if seats[seat] || !o.isEligible(dataStructureID, seat) {
+			continue
+		}
+		seats[seat] = true
+		weight += o.weightOf(seat)
+	}
+	return len(seats), weight
+}
+
+// confirms reports whether signatures, keyed by signer address as stored,
+// meet both thresholds of the data structure.
+func (o *OperatorNode) confirms(dataStructureID int, signatures map[string]string) bool {
+	if !o.weighted() {
+		return len(signatures) >= o.thresholdFor(dataStructureID)
+	}
+	signers := make([]string, 0, len(signatures))
+	for signer := range signatures {
+		signers = append(signers, signer)
+	}
+	count, weight := o.signedWeight(dataStructureID, signers)
+	return count >= o.thresholdFor(dataStructureID) && weight >= o.weightThresholdFor(dataStructureID)
+}
+
+// signerWeightsSnapshot returns the configured weight of every trusted seat.
+func (o *OperatorNode) signerWeightsSnapshot() map[string]uint64 {
+	o.trustedMux.RLock()
+	defer o.trustedMux.RUnlock()
+
+	if len(o.weights) == 0 {
+		return nil
+	}
+	weights := make(map[string]uint64, len(o.trustedAddrs))
+	for _, addr := range o.trustedAddrs {
+		weights[addr] = o.weights.of(addr)
+	}
+	return weights
+}
@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// WhoamiCommand implements the `whoami` subcommand: it prints the identities
+// derived from the configured key, so TRUSTED_OPERATOR_PEERS on other
+// operators and BOOTSTRAP_NODE on signers can be filled in without starting
+// the operator.
+func WhoamiCommand(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
+	}
+	if cfg.PrivateKey == "" {
+		log.Println("⚠️ PRIVATE_KEY is not set; a fresh key is generated on every start, so these values will not be stable")
+	}
+	privKey, err := getOrCreatePrivKey(cfg.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return fmt.Errorf("failed to derive peer ID: %w", err)
+	}
+	address, err := ethAddressFromKey(privKey)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := advertisedAddrs(cfg.Network, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("peer_id:     %s\n", id)
+	fmt.Printf("eth_address: %s\n", address.Hex())
+	fmt.Println("multiaddrs:")
+	for _, addr := range addrs {
+		fmt.Printf("  %s\n", addr)
+	}
+	return nil
+}
+
+// advertisedAddrs lists the addresses peers are told to dial: the announce
+// addresses when configured, otherwise the listen addresses.
+func advertisedAddrs(network NetworkConfig, id peer.ID) ([]ma.Multiaddr, error) {
+	announce, err := network.announceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := ma.NewComponent("p2p", id.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(announce) > 0 {
+		addrs := make([]ma.Multiaddr, 0, len(announce))
+		for _, addr := range announce {
+			addrs = append(addrs, addr.Encapsulate(suffix))
+		}
+		return addrs, nil
+	}
+
+	listen, err := network.listenAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var addrs []ma.Multiaddr
+	for _, addr := range listen {
+		dialable, err := dialableAddrs(addr, id)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, dialable...)
+	}
+	return addrs, nil
+}
+
+// dialableAddrs expands a wildcard listen address to one address per local
+// interface, each with the /p2p suffix peers need to dial it.
+func dialableAddrs(listen ma.Multiaddr, id peer.ID) ([]ma.Multiaddr, error) {
+	ifaceAddrs, err := manet.InterfaceMultiaddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	resolved, err := manet.ResolveUnspecifiedAddress(listen, ifaceAddrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve listen address: %w", err)
+	}
+
+	suffix, err := ma.NewComponent("p2p", id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]ma.Multiaddr, 0, len(resolved))
+	for _, addr := range resolved {
+		if manet.IsIPLoopback(addr) {
+			continue
+		}
+		addrs = append(addrs, addr.Encapsulate(suffix))
+	}
+	return addrs, nil
+}
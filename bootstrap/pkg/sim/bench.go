@@ -0,0 +1,219 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/operator"
+	"bootstrap/pkg/store"
+)
+
+// BenchConfig describes one load test of the signing pipeline.
+type BenchConfig struct {
+	Signers    int
+	Rate       float64
+	Duration   time.Duration
+	Drain      time.Duration
+	Ticker     string
+	Structure  string
+	Structures map[string]operator.DataStructure
+}
+
+// BenchReport summarizes a load test. Rates are per second of wall time
+// from the first publish until the drain finished.
+type BenchReport struct {
+	Signers         int                         `json:"signers"`
+	TargetRate      float64                     `json:"target_rate"`
+	ElapsedMs       int64                       `json:"elapsed_ms"`
+	Published       int                         `json:"published"`
+	PublishErrors   int                         `json:"publish_errors"`
+	Confirmed       int                         `json:"confirmed"`
+	PublishedPerSec float64                     `json:"published_per_sec"`
+	ConfirmedPerSec float64                     `json:"confirmed_per_sec"`
+	ConfirmLatency  operator.LatencyPercentiles `json:"confirm_latency"`
+	DBWrites        BenchDBWrites               `json:"db_writes"`
+}
+
+// BenchDBWrites counts operator database writes by kind.
+type BenchDBWrites struct {
+	Messages   int64   `json:"messages"`
+	Signatures int64   `json:"signatures"`
+	Latencies  int64   `json:"latencies"`
+	PerSec     float64 `json:"per_sec"`
+}
+
+// countingDatabase counts writes made through it.
+type countingDatabase struct {
+	store.Database
+	messages   atomic.Int64
+	signatures atomic.Int64
+	latencies  atomic.Int64
+}
+
+func (c *countingDatabase) StoreData(ctx context.Context, messageID string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	c.messages.Add(1)
+	return c.Database.StoreData(ctx, messageID, data, dataStructure, dataStructureMeta, timestamp, dataStructureID, hashVersion, requestID, sequence, epoch)
+}
+
+func (c *countingDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	c.signatures.Add(1)
+	return c.Database.StoreSignature(ctx, hash, signer, signature)
+}
+
+func (c *countingDatabase) StoreLatency(ctx context.Context, hash string, latency store.MessageLatency) error {
+	c.latencies.Add(1)
+	return c.Database.StoreLatency(ctx, hash, latency)
+}
+
+// RunBench publishes synthetic sign requests at config.Rate for
+// config.Duration against a simulated network, then waits up to
+// config.Drain for outstanding requests to be confirmed.
+func RunBench(ctx context.Context, config BenchConfig) (BenchReport, error) {
+	if config.Rate <= 0 {
+		return BenchReport{}, fmt.Errorf("rate must be positive")
+	}
+
+	counter := &countingDatabase{}
+	sim, err := New(ctx, Config{
+		Signers:    config.Signers,
+		Ticker:     config.Ticker,
+		Structure:  config.Structure,
+		Structures: config.Structures,
+		WrapDB: func(db store.Database) store.Database {
+			counter.Database = db
+			return counter
+		},
+	})
+	if err != nil {
+		return BenchReport{}, err
+	}
+	defer sim.Close()
+
+	report := BenchReport{Signers: config.Signers, TargetRate: config.Rate}
+	publisher := sim.publisher()
+	var hashes []string
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / config.Rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	stop := start.Add(config.Duration)
+	for i := 0; time.Now().Before(stop); i++ {
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-ticker.C:
+		}
+
+		// Every request reports on a ticker of its own, so no two share a
+		// hash or are taken for conflicting rounds of one ticker.
+		builder, err := operator.NewMessageFactory(config.Structure, fmt.Sprintf("%s%d", config.Ticker, i), config.Structures).GetBuilder()
+		if err != nil {
+			return report, err
+		}
+		sr, err := builder.BuildMessage(collector.PriceBreakdown{Average: 100, SourceCount: 1})
+		if err != nil {
+			return report, fmt.Errorf("failed to build request: %w", err)
+		}
+		sim.operator.Latency().MarkBuilt(sr.Hash, time.Now())
+		if err := publisher.PublishSignRequest(ctx, sr); err != nil {
+			report.PublishErrors++
+			log.Printf("bench: publish failed: %v", err)
+			continue
+		}
+		hashes = append(hashes, sr.Hash)
+	}
+	report.Published = len(hashes)
+
+	latencies := benchDrain(ctx, sim.db, hashes, config.Drain)
+	elapsed := time.Since(start)
+
+	report.ElapsedMs = elapsed.Milliseconds()
+	report.Confirmed = len(latencies)
+	report.PublishedPerSec = float64(report.Published) / elapsed.Seconds()
+	report.ConfirmedPerSec = float64(report.Confirmed) / elapsed.Seconds()
+	report.ConfirmLatency = operator.NewLatencyPercentiles(latencies)
+	report.DBWrites = BenchDBWrites{
+		Messages:   counter.messages.Load(),
+		Signatures: counter.signatures.Load(),
+		Latencies:  counter.latencies.Load(),
+	}
+	total := report.DBWrites.Messages + report.DBWrites.Signatures + report.DBWrites.Latencies
+	report.DBWrites.PerSec = float64(total) / elapsed.Seconds()
+
+	return report, nil
+}
+
+// benchDrain waits until every hash has reached its threshold or the drain
+// timeout passes, and returns the build-to-threshold latency in milliseconds
+// of each confirmed hash.
+func benchDrain(ctx context.Context, db store.Database, hashes []string, timeout time.Duration) []float64 {
+	outstanding := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		outstanding[hash] = true
+	}
+
+	var latencies []float64
+	deadline := time.Now().Add(timeout)
+	for len(outstanding) > 0 {
+		for hash := range outstanding {
+			if lat, ok := db.GetLatency(ctx, hash); ok && lat.ThresholdAt != 0 {
+				latencies = append(latencies, float64(lat.ThresholdAt-lat.BuiltAt))
+				delete(outstanding, hash)
+			}
+		}
+		if len(outstanding) == 0 || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return latencies
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return latencies
+}
+
+// BenchCommand implements the `bench` subcommand and prints a JSON report.
+func BenchCommand(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	structuresPath := fs.String("structures", "config/data_structures.json", "path to the data structures file")
+	structure := fs.String("structure", "stock_quote", "structure to publish")
+	ticker := fs.String("ticker", "SBER", "ticker to publish")
+	signers := fs.Int("signers", 4, "number of signer nodes")
+	rate := fs.Float64("rate", 50, "sign requests published per second")
+	duration := fs.Duration("duration", 10*time.Second, "how long to publish")
+	drain := fs.Duration("drain", 10*time.Second, "time allowed for outstanding requests to confirm")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	structures, err := operator.LoadDataStructures(*structuresPath)
+	if err != nil {
+		return err
+	}
+
+	report, err := RunBench(context.Background(), BenchConfig{
+		Signers:    *signers,
+		Rate:       *rate,
+		Duration:   *duration,
+		Drain:      *drain,
+		Ticker:     *ticker,
+		Structure:  *structure,
+		Structures: structures,
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
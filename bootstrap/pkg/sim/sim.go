@@ -0,0 +1,622 @@
+// Package sim runs an operator and signer nodes in one process, over
+// libp2p's in-memory transport, to exercise the sign and confirm flow.
+package sim
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"os"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"bootstrap/pkg/collector"
+	"bootstrap/pkg/operator"
+	"bootstrap/pkg/store"
+)
+
+const topic = "l0proof-sim"
+
+// Config describes one simulated network.
+type Config struct {
+	Signers       int
+	Rounds        int
+	Ticker        string
+	Structure     string
+	Structures    map[string]operator.DataStructure
+	RoundTimeout  time.Duration
+	RoundInterval time.Duration
+	Faults        Faults
+	// WrapDB, when set, wraps the operator's database, e.g. to count writes.
+	WrapDB func(store.Database) store.Database
+}
+
+// Faults injects failures into a simulation to exercise retry,
+// resubscribe and pending-expiry paths. The zero value runs a healthy
+// network.
+type Faults struct {
+	// DropRate is the probability that a signer ignores an incoming
+	// sign_request or loses its outgoing sign_response.
+	DropRate float64
+	// Partitioned signers are cut off from every other peer for
+	// PartitionFor at the start of the run, then healed.
+	Partitioned  int
+	PartitionFor time.Duration
+	// SignDelay plus up to SignJitter is waited before each signature is
+	// published.
+	SignDelay  time.Duration
+	SignJitter time.Duration
+	// CrashRound crashes the operator CrashAfter into that round (1-based)
+	// and restarts it RestartAfter later, losing its pending set. Zero
+	// disables the crash.
+	CrashRound   int
+	CrashAfter   time.Duration
+	RestartAfter time.Duration
+}
+
+// Round is the outcome of one published price.
+type Round struct {
+	Round      int    `json:"round"`
+	Hash       string `json:"hash"`
+	RequestID  string `json:"request_id"`
+	Signatures int    `json:"signatures"`
+	Threshold  int    `json:"threshold"`
+	Confirmed  bool   `json:"confirmed"`
+	LatencyMs  int64  `json:"latency_ms"`
+}
+
+// Report summarizes a simulation run.
+type Report struct {
+	Signers   int     `json:"signers"`
+	Rounds    []Round `json:"rounds"`
+	Confirmed int     `json:"confirmed"`
+	Failed    int     `json:"failed"`
+}
+
+// Simulation wires an operator and signer nodes together over libp2p's
+// in-memory transport, so the full sign/confirm flow runs in one process
+// without Docker or real networking.
+type Simulation struct {
+	config       Config
+	net          mocknet.Mocknet
+	operator     *operator.OperatorNode
+	stop         context.CancelFunc
+	operatorHost host.Host
+	operatorKey  crypto.PrivKey
+	trusted      []string
+	signers      []*signer
+	db           store.Database
+	dbPath       string
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// signer mimics a signer node: it signs every sign_request it sees and
+// gossips the response back.
+type signer struct {
+	host    host.Host
+	topic   *pubsub.Topic
+	sub     *pubsub.Subscription
+	key     *ecdsa.PrivateKey
+	address string
+	faults  Faults
+}
+
+func newKey() (crypto.PrivKey, *ecdsa.PrivateKey, error) {
+	priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	raw, err := priv.Raw()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get raw private key: %w", err)
+	}
+	ecdsaKey, err := cryptoeth.ToECDSA(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert to ECDSA key: %w", err)
+	}
+	return priv, ecdsaKey, nil
+}
+
+// addr gives each simulated peer a distinct fake address; mocknet never
+// dials it but requires one per peer.
+func addr(i int) ma.Multiaddr {
+	return ma.StringCast(fmt.Sprintf("/ip4/10.0.%d.%d/tcp/4001", i/256, i%256))
+}
+
+// New starts the operator and signers and waits until every signer has
+// joined the operator's gossip topic.
+func New(ctx context.Context, config Config) (*Simulation, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sim := &Simulation{config: config, net: mocknet.New(), ctx: ctx, cancel: cancel}
+
+	for i := 0; i < config.Signers; i++ {
+		priv, ecdsaKey, err := newKey()
+		if err != nil {
+			sim.Close()
+			return nil, err
+		}
+		h, err := sim.net.AddPeer(priv, addr(i+1))
+		if err != nil {
+			sim.Close()
+			return nil, fmt.Errorf("failed to add signer peer: %w", err)
+		}
+		address := cryptoeth.PubkeyToAddress(ecdsaKey.PublicKey).Hex()
+		sim.signers = append(sim.signers, &signer{host: h, key: ecdsaKey, address: address, faults: config.Faults})
+		sim.trusted = append(sim.trusted, address)
+	}
+
+	var err error
+	sim.operatorKey, _, err = newKey()
+	if err != nil {
+		sim.Close()
+		return nil, err
+	}
+	sim.operatorHost, err = sim.net.AddPeer(sim.operatorKey, addr(0))
+	if err != nil {
+		sim.Close()
+		return nil, fmt.Errorf("failed to add operator peer: %w", err)
+	}
+
+	if err := sim.net.LinkAll(); err != nil {
+		sim.Close()
+		return nil, fmt.Errorf("failed to link peers: %w", err)
+	}
+	if err := sim.net.ConnectAllButSelf(); err != nil {
+		sim.Close()
+		return nil, fmt.Errorf("failed to connect peers: %w", err)
+	}
+
+	sim.dbPath, err = os.MkdirTemp("", "l0proof-sim-*")
+	if err != nil {
+		sim.Close()
+		return nil, fmt.Errorf("failed to create database dir: %w", err)
+	}
+	if err := sim.startOperator(); err != nil {
+		sim.Close()
+		return nil, err
+	}
+
+	for _, s := range sim.signers {
+		if err := s.start(ctx); err != nil {
+			sim.Close()
+			return nil, err
+		}
+	}
+
+	if err := sim.waitForMesh(ctx, 10*time.Second); err != nil {
+		sim.Close()
+		return nil, err
+	}
+
+	return sim, nil
+}
+
+// startOperator opens the database and runs an operator on the operator
+// host. It is called again after a simulated crash.
+func (sim *Simulation) startOperator() error {
+	ldb, err := store.NewLevelDBDatabase(sim.dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	sim.db = ldb
+	if sim.config.WrapDB != nil {
+		sim.db = sim.config.WrapDB(ldb)
+	}
+
+	ctx, cancel := context.WithCancel(sim.ctx)
+	sim.operator, err = operator.NewOperatorNodeWithHost(ctx, cancel, sim.operatorHost, sim.operatorKey, sim.db, topic, sim.trusted, operator.GossipConfig{}, operator.IntakeConfig{}, clock.New())
+	if err != nil {
+		cancel()
+		return err
+	}
+	sim.stop = cancel
+	return nil
+}
+
+// crashOperator stops the operator without the graceful path: its pending
+// set is lost and its connections drop, but the host stays registered so it
+// can be restarted on the same peer ID.
+func (sim *Simulation) crashOperator() {
+	log.Println("💥 sim: crashing operator")
+	sim.stop()
+	for _, s := range sim.signers {
+		sim.net.DisconnectPeers(sim.operatorHost.ID(), s.host.ID())
+	}
+	if err := sim.db.Close(); err != nil {
+		log.Printf("sim: error closing database: %v", err)
+	}
+	sim.operator = nil
+}
+
+// restartOperator brings a crashed operator back and reconnects it to every
+// reachable signer.
+func (sim *Simulation) restartOperator(ctx context.Context) error {
+	if err := sim.startOperator(); err != nil {
+		return fmt.Errorf("failed to restart operator: %w", err)
+	}
+	for _, s := range sim.signers {
+		if _, err := sim.net.ConnectPeers(sim.operatorHost.ID(), s.host.ID()); err != nil {
+			log.Printf("sim: operator could not reach %s: %v", s.address, err)
+		}
+	}
+	log.Println("🔁 sim: operator restarted")
+	return sim.waitForMesh(ctx, 10*time.Second)
+}
+
+// partition cuts signers off from every other peer for d, then heals the
+// links in the background.
+func (sim *Simulation) partition(signers []*signer, d time.Duration) error {
+	peers := sim.net.Peers()
+	for _, s := range signers {
+		for _, p := range peers {
+			if p == s.host.ID() {
+				continue
+			}
+			sim.net.DisconnectPeers(s.host.ID(), p)
+			if err := sim.net.UnlinkPeers(s.host.ID(), p); err != nil {
+				return fmt.Errorf("failed to partition %s: %w", s.address, err)
+			}
+		}
+		log.Printf("✂️ sim: partitioned signer %s for %s", s.address, d)
+	}
+
+	go func() {
+		select {
+		case <-sim.ctx.Done():
+			return
+		case <-time.After(d):
+		}
+		for _, s := range signers {
+			for _, p := range peers {
+				if p == s.host.ID() {
+					continue
+				}
+				if _, err := sim.net.LinkPeers(s.host.ID(), p); err != nil {
+					log.Printf("sim: failed to relink %s: %v", s.address, err)
+					continue
+				}
+				sim.net.ConnectPeers(s.host.ID(), p)
+			}
+			log.Printf("🩹 sim: healed signer %s", s.address)
+		}
+	}()
+	return nil
+}
+
+func (s *signer) start(ctx context.Context) error {
+	ps, err := pubsub.NewGossipSub(ctx, s.host)
+	if err != nil {
+		return fmt.Errorf("failed to create pubsub: %w", err)
+	}
+	s.topic, err = ps.Join(topic)
+	if err != nil {
+		return fmt.Errorf("failed to join topic: %w", err)
+	}
+	s.sub, err = s.topic.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	go s.listen(ctx)
+	return nil
+}
+
+func (s *signer) listen(ctx context.Context) {
+	for {
+		msg, err := s.sub.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		var req operator.SignRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil || req.Type != operator.MsgTypeSignRequest {
+			continue
+		}
+		if s.drop() {
+			continue
+		}
+		go s.sign(ctx, &req)
+	}
+}
+
+func (s *signer) drop() bool {
+	return s.faults.DropRate > 0 && mathrand.Float64() < s.faults.DropRate
+}
+
+func (s *signer) sign(ctx context.Context, req *operator.SignRequest) {
+	delay := s.faults.SignDelay
+	if s.faults.SignJitter > 0 {
+		delay += time.Duration(mathrand.Int63n(int64(s.faults.SignJitter)))
+	}
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	hash, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		return
+	}
+
+	signature, err := cryptoeth.Sign(accounts.TextHash(hash), s.key)
+	if err != nil {
+		log.Printf("sim signer %s failed to sign: %v", s.address, err)
+		return
+	}
+
+	resp, err := json.Marshal(operator.SignResponse{
+		Type:      operator.MsgTypeSignResponse,
+		Hash:      req.Hash,
+		Signature: hexutil.Encode(signature),
+		PeerID:    s.address,
+		Timestamp: time.Now().Unix(),
+		RequestID: req.RequestID,
+
+		ProtocolVersion: operator.ProtocolVersion,
+	})
+	if err != nil {
+		return
+	}
+	if s.drop() {
+		return
+	}
+
+	if err := s.topic.Publish(ctx, resp); err != nil && ctx.Err() == nil {
+		log.Printf("sim signer %s failed to publish: %v", s.address, err)
+	}
+}
+
+// meshReady reports whether the operator and every signer see each other on
+// the topic. Partitioned signers are not expected to.
+func (sim *Simulation) meshReady() bool {
+	operatorPeers := make(map[peer.ID]bool)
+	for _, p := range sim.operator.TopicPeers() {
+		operatorPeers[p] = true
+	}
+
+	for _, s := range sim.signers {
+		if len(sim.net.LinksBetweenPeers(sim.operatorHost.ID(), s.host.ID())) == 0 {
+			continue
+		}
+		if !operatorPeers[s.host.ID()] {
+			return false
+		}
+		seesOperator := false
+		for _, p := range s.topic.ListPeers() {
+			if p == sim.operatorHost.ID() {
+				seesOperator = true
+				break
+			}
+		}
+		if !seesOperator {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForMesh blocks until the operator and signers see each other on the
+// topic, so the first round is not lost to gossip warm-up.
+func (sim *Simulation) waitForMesh(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if sim.meshReady() {
+			// Give gossipsub one heartbeat to graft the mesh as well.
+			time.Sleep(pubsub.GossipSubHeartbeatInterval)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("signers did not join the topic within %s", timeout)
+}
+
+// Run publishes config.Rounds prices from a mock source and waits for each
+// to reach its threshold.
+func (sim *Simulation) Run(ctx context.Context) (Report, error) {
+	factory := operator.NewMessageFactory(sim.config.Structure, sim.config.Ticker, sim.config.Structures)
+	builder, err := factory.GetBuilder()
+	if err != nil {
+		return Report{}, err
+	}
+
+	aggregator := &collector.PriceAggregator{
+		Sources: []collector.PriceSource{collector.NewMockPriceSource(100, 0.01)},
+		Timeout: 5 * time.Second,
+	}
+
+	faults := sim.config.Faults
+	if faults.Partitioned > 0 {
+		n := faults.Partitioned
+		if n > len(sim.signers) {
+			n = len(sim.signers)
+		}
+		if err := sim.partition(sim.signers[:n], faults.PartitionFor); err != nil {
+			return Report{}, err
+		}
+	}
+
+	report := Report{Signers: len(sim.signers)}
+	for round := 1; round <= sim.config.Rounds; round++ {
+		result, err := sim.runRound(ctx, round, aggregator, builder)
+		if err != nil {
+			return report, err
+		}
+		report.Rounds = append(report.Rounds, result)
+		if result.Confirmed {
+			report.Confirmed++
+		} else {
+			report.Failed++
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(sim.config.RoundInterval):
+		}
+	}
+
+	return report, nil
+}
+
+// publisher returns a PubSubService bound to the current operator, which
+// changes when the operator is restarted.
+func (sim *Simulation) publisher() *operator.PubSubService {
+	return operator.NewPubSubService(sim.operator, sim.db, 5*time.Second, 3, 500*time.Millisecond)
+}
+
+func (sim *Simulation) runRound(ctx context.Context, round int, aggregator *collector.PriceAggregator, builder operator.MessageBuilder) (Round, error) {
+	breakdown, err := aggregator.GetPriceBreakdown(ctx)
+	if err != nil {
+		return Round{}, fmt.Errorf("round %d: %w", round, err)
+	}
+	sr, err := builder.BuildMessage(breakdown)
+	if err != nil {
+		return Round{}, fmt.Errorf("round %d: %w", round, err)
+	}
+
+	start := time.Now()
+	if err := sim.publisher().PublishSignRequest(ctx, sr); err != nil {
+		return Round{}, fmt.Errorf("round %d: %w", round, err)
+	}
+
+	faults := sim.config.Faults
+	if faults.CrashRound == round {
+		time.Sleep(faults.CrashAfter)
+		sim.crashOperator()
+		time.Sleep(faults.RestartAfter)
+		if err := sim.restartOperator(ctx); err != nil {
+			return Round{}, fmt.Errorf("round %d: %w", round, err)
+		}
+		if err := sim.publisher().PublishSignRequest(ctx, sr); err != nil {
+			return Round{}, fmt.Errorf("round %d: %w", round, err)
+		}
+	}
+
+	result := Round{
+		Round:     round,
+		Hash:      sr.Hash,
+		RequestID: sr.RequestID,
+		Threshold: sim.operator.Threshold(sr.DataStructureId),
+	}
+
+	deadline := time.Now().Add(sim.config.RoundTimeout)
+	for time.Now().Before(deadline) {
+		sigs, _ := sim.db.GetSignatures(ctx, sr.Hash)
+		result.Signatures = len(sigs)
+		if result.Signatures >= result.Threshold {
+			result.Confirmed = true
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	return result, nil
+}
+
+// Close stops every node and removes the temporary database.
+func (sim *Simulation) Close() {
+	sim.cancel()
+	if sim.operator != nil {
+		sim.operator.Shutdown()
+	}
+	for _, s := range sim.signers {
+		if s.sub != nil {
+			s.sub.Cancel()
+		}
+	}
+	sim.net.Close()
+	if sim.dbPath != "" {
+		os.RemoveAll(sim.dbPath)
+	}
+}
+
+// Command implements the `sim` subcommand. It prints a JSON report and fails
+// when any round misses its threshold, so it can gate CI.
+func Command(args []string) error {
+	fs := flag.NewFlagSet("sim", flag.ContinueOnError)
+	structuresPath := fs.String("structures", "config/data_structures.json", "path to the data structures file")
+	structure := fs.String("structure", "stock_quote", "structure to publish")
+	ticker := fs.String("ticker", "SBER", "ticker to publish")
+	signers := fs.Int("signers", 4, "number of signer nodes")
+	rounds := fs.Int("rounds", 3, "number of prices to publish")
+	roundTimeout := fs.Duration("round-timeout", 10*time.Second, "time allowed for a round to reach threshold")
+	roundInterval := fs.Duration("round-interval", time.Second, "pause between rounds")
+	var faults Faults
+	fs.Float64Var(&faults.DropRate, "drop-rate", 0, "probability a signer drops a request or response")
+	fs.IntVar(&faults.Partitioned, "partition", 0, "number of signers partitioned at the start")
+	fs.DurationVar(&faults.PartitionFor, "partition-for", 5*time.Second, "how long partitioned signers stay cut off")
+	fs.DurationVar(&faults.SignDelay, "sign-delay", 0, "delay before each signature is published")
+	fs.DurationVar(&faults.SignJitter, "sign-jitter", 0, "random extra signature delay")
+	fs.IntVar(&faults.CrashRound, "crash-round", 0, "round in which the operator crashes (0 disables)")
+	fs.DurationVar(&faults.CrashAfter, "crash-after", 0, "time into the crash round before the operator crashes")
+	fs.DurationVar(&faults.RestartAfter, "restart-after", time.Second, "downtime before the operator restarts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	structures, err := operator.LoadDataStructures(*structuresPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	sim, err := New(ctx, Config{
+		Signers:       *signers,
+		Rounds:        *rounds,
+		Ticker:        *ticker,
+		Structure:     *structure,
+		Structures:    structures,
+		RoundTimeout:  *roundTimeout,
+		RoundInterval: *roundInterval,
+		Faults:        faults,
+	})
+	if err != nil {
+		return err
+	}
+	defer sim.Close()
+
+	report, err := sim.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rounds did not reach threshold", report.Failed, len(report.Rounds))
+	}
+	return nil
+}
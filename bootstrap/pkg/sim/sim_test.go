@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bootstrap/pkg/operator"
+)
+
+func testConfig(t *testing.T, signers, rounds int) Config {
+	t.Helper()
+	structures, err := operator.LoadDataStructures("../../config/data_structures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{
+		Signers:      signers,
+		Rounds:       rounds,
+		Ticker:       "SBER",
+		Structure:    "stock_quote",
+		Structures:   structures,
+		RoundTimeout: 10 * time.Second,
+		// Rounds of one ticker within a second conflict.
+		RoundInterval: time.Second,
+	}
+}
+
+func runSimulation(t *testing.T, config Config) Report {
+	t.Helper()
+	ctx := context.Background()
+	sim, err := New(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+
+	report, err := sim.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestRoundsReachThreshold(t *testing.T) {
+	report := runSimulation(t, testConfig(t, 4, 2))
+	if report.Confirmed != 2 || report.Failed != 0 {
+		t.Fatalf("got %d confirmed and %d failed rounds, want 2 confirmed: %+v", report.Confirmed, report.Failed, report.Rounds)
+	}
+	for _, round := range report.Rounds {
+		if round.Threshold < 3 || round.Signatures < round.Threshold {
+			t.Fatalf("round %d: %d of %d signatures", round.Round, round.Signatures, round.Threshold)
+		}
+	}
+}
+
+func TestConfirmsAfterOperatorCrash(t *testing.T) {
+	config := testConfig(t, 3, 1)
+	config.Faults = Faults{CrashRound: 1, RestartAfter: 100 * time.Millisecond}
+
+	report := runSimulation(t, config)
+	if report.Confirmed != 1 {
+		t.Fatalf("round after the operator restarted not confirmed: %+v", report.Rounds)
+	}
+}
+
+func TestBenchConfirmsWhatItPublishes(t *testing.T) {
+	config := testConfig(t, 3, 0)
+	report, err := RunBench(context.Background(), BenchConfig{
+		Signers:    config.Signers,
+		Rate:       20,
+		Duration:   500 * time.Millisecond,
+		Drain:      10 * time.Second,
+		Ticker:     config.Ticker,
+		Structure:  config.Structure,
+		Structures: config.Structures,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Published == 0 || report.Confirmed != report.Published {
+		t.Fatalf("confirmed %d of %d published requests", report.Confirmed, report.Published)
+	}
+	if report.DBWrites.Messages != int64(report.Published) {
+		t.Fatalf("stored %d messages for %d published requests", report.DBWrites.Messages, report.Published)
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func openTestStore(t *testing.T, backend string) Database {
+	t.Helper()
+	db, err := Open(Config{Backend: backend, Path: filepath.Join(t.TempDir(), backend)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRestoreOnlyIntoEmptyStore(t *testing.T) {
+	for _, backend := range []string{"leveldb", "badger"} {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			source := openTestStore(t, backend)
+			if err := source.SetRetention(ctx, 1, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			var snapshot bytes.Buffer
+			if err := source.Backup(ctx, &snapshot); err != nil {
+				t.Fatal(err)
+			}
+
+			target := openTestStore(t, backend)
+			if err := target.Restore(ctx, bytes.NewReader(snapshot.Bytes())); err != nil {
+				t.Fatalf("restore into an empty store: %v", err)
+			}
+			if retention, ok := target.GetRetention(ctx, 1); !ok || retention != time.Hour {
+				t.Fatalf("restored retention: got %s, %v; want 1h", retention, ok)
+			}
+
+			if err := target.SetRetention(ctx, 1, 2*time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			err := target.Restore(ctx, bytes.NewReader(snapshot.Bytes()))
+			if !errors.Is(err, ErrStoreNotEmpty) {
+				t.Fatalf("restore into a populated store: got %v, want ErrStoreNotEmpty", err)
+			}
+			if retention, _ := target.GetRetention(ctx, 1); retention != 2*time.Hour {
+				t.Fatalf("refused restore overwrote a record: retention %s", retention)
+			}
+		})
+	}
+}
+
+func TestBadgerRestoreKeepsExpiry(t *testing.T) {
+	ctx := context.Background()
+	source := openTestStore(t, "badger").(*BadgerDatabase)
+	err := source.db.Update(func(txn *badger.Txn) error {
+		if err := txn.SetEntry(badger.NewEntry([]byte("ttl"), []byte("v")).WithTTL(time.Hour)); err != nil {
+			return err
+		}
+		return txn.Set([]byte("forever"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot bytes.Buffer
+	if err := source.Backup(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	target := openTestStore(t, "badger").(*BadgerDatabase)
+	if err := target.Restore(ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	expiry := func(key string) uint64 {
+		var expiresAt uint64
+		err := target.db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(key))
+			if err != nil {
+				return err
+			}
+			expiresAt = item.ExpiresAt()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		return expiresAt
+	}
+	if expiresAt := expiry("ttl"); expiresAt == 0 || expiresAt > uint64(time.Now().Add(time.Hour).Unix()) {
+		t.Fatalf("restored TTL entry expires at %d, want within the hour", expiresAt)
+	}
+	if expiresAt := expiry("forever"); expiresAt != 0 {
+		t.Fatalf("restored entry without TTL expires at %d", expiresAt)
+	}
+}
@@ -0,0 +1,1824 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const (
+	// defaultBadgerValueThreshold keeps index entries and signature maps in
+	// the LSM tree while message bodies go to the value log, so compactions
+	// no longer rewrite every payload.
+	defaultBadgerValueThreshold = 256
+	defaultBadgerGCInterval     = 10 * time.Minute
+	badgerGCDiscardRatio        = 0.5
+)
+
+// BadgerConfig tunes the Badger backend. The zero value uses the defaults.
+type BadgerConfig struct {
+	ValueThreshold int64         `yaml:"value_threshold"`
+	GCInterval     time.Duration `yaml:"gc_interval"`
+}
+
+func (c BadgerConfig) withDefaults() BadgerConfig {
+	if c.ValueThreshold <= 0 {
+		c.ValueThreshold = defaultBadgerValueThreshold
+	}
+	if c.GCInterval <= 0 {
+		c.GCInterval = defaultBadgerGCInterval
+	}
+	return c
+}
+
+// BadgerDatabase stores the same keys as LevelDBDatabase in Badger. Messages
+// of a data structure with a retention policy are written with a native TTL,
+// so they expire without waiting for the pruner.
+type BadgerDatabase struct {
+	db *badger.DB
+	// mu serializes writers; most of them read before writing and would
+	// otherwise fail with badger.ErrConflict under concurrent signatures.
+	mu   sync.Mutex
+	path string
+	stop chan struct{}
+	done chan struct{}
+	// sigBatch group commits signatures; nil writes each one directly.
+	sigBatch *signatureBatcher
+	sigCache *signatureCache
+}
+
+func NewBadgerDatabase(path string, cfg BadgerConfig) (*BadgerDatabase, error) {
+	cfg = cfg.withDefaults()
+
+	opts := badger.DefaultOptions(path).
+		WithValueThreshold(cfg.ValueThreshold).
+		WithLoggingLevel(badger.WARNING)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Badger: %w", err)
+	}
+
+	bdb := &BadgerDatabase{
+		db:   db,
+		path: path,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go bdb.runValueLogGC(cfg.GCInterval)
+
+	return bdb, nil
+}
+
+// runValueLogGC reclaims value log space left behind by expired and pruned
+// messages.
+func (bdb *BadgerDatabase) runValueLogGC(interval time.Duration) {
+	defer close(bdb.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bdb.stop:
+			return
+		case <-ticker.C:
+			for {
+				err := bdb.db.RunValueLogGC(badgerGCDiscardRatio)
+				if err == nil {
+					continue
+				}
+				if !errors.Is(err, badger.ErrNoRewrite) {
+					log.Printf("Badger value log GC failed: %v", err)
+				}
+				break
+			}
+		}
+	}
+}
+
+func (bdb *BadgerDatabase) Close() error {
+	if bdb.sigBatch != nil {
+		bdb.sigBatch.close()
+	}
+	close(bdb.stop)
+	<-bdb.done
+	return bdb.db.Close()
+}
+
+func badgerGet(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+// badgerExpiry returns the unix time at which a message stored under a
+// retention policy expires, or zero to keep it forever.
+func badgerExpiry(txn *badger.Txn, dataStructureID int, timestamp int64) (uint64, error) {
+	data, err := badgerGet(txn, []byte(fmt.Sprintf("%s%d", retentionPrefix, dataStructureID)))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get retention: %w", err)
+	}
+
+	seconds, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || seconds <= 0 {
+		return 0, nil
+	}
+	return uint64(timestamp + seconds), nil
+}
+
+// messageExpiry returns the expiry of a stored message so its signatures and
+// latency record go away with it.
+func messageExpiry(txn *badger.Txn, hash string) uint64 {
+	item, err := txn.Get([]byte(dataPrefix + hash))
+	if err != nil {
+		return 0
+	}
+	return item.ExpiresAt()
+}
+
+func setExpiring(txn *badger.Txn, key, value []byte, expiresAt uint64) error {
+	entry := badger.NewEntry(key, value)
+	entry.ExpiresAt = expiresAt
+	return txn.SetEntry(entry)
+}
+
+func (bdb *BadgerDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	msg := Message{
+		Hash:              hash,
+		Data:              data,
+		DataStructure:     dataStructure,
+		DataStructureMeta: dataStructureMeta,
+		Timestamp:         timestamp,
+		HashVersion:       hashVersion,
+		RequestID:         requestID,
+		Sequence:          sequence,
+		Epoch:             epoch,
+		DataStructureID:   dataStructureID,
+	}
+
+	msgData, err := encodeMessage(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(dataPrefix + hash)); err == nil {
+			return ErrAlreadyStored
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return fmt.Errorf("failed to check message: %w", err)
+		}
+
+		dsKey := []byte(dataStructPrefix + fmt.Sprintf("%d", dataStructureID))
+		if _, err := txn.Get(dsKey); errors.Is(err, badger.ErrKeyNotFound) {
+			dsData, err := json.Marshal(dataStructure)
+			if err != nil {
+				return fmt.Errorf("failed to marshal data structure: %w", err)
+			}
+			if err := txn.Set(dsKey, dsData); err != nil {
+				return fmt.Errorf("failed to store data structure: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to get data structure: %w", err)
+		}
+
+		expiresAt, err := badgerExpiry(txn, dataStructureID, timestamp)
+		if err != nil {
+			return err
+		}
+
+		if err := setExpiring(txn, []byte(dataPrefix+hash), msgData, expiresAt); err != nil {
+			return fmt.Errorf("failed to store message by hash: %w", err)
+		}
+
+		for _, key := range messageIndexKeys(dataStructureID, msg) {
+			if err := setExpiring(txn, key, nil, expiresAt); err != nil {
+				return fmt.Errorf("failed to create index: %w", err)
+			}
+		}
+
+		if sequence > 0 {
+			head, err := badgerSequenceHead(txn, dataStructureID)
+			if err != nil {
+				return err
+			}
+			if sequence > head {
+				if err := txn.Set(sequenceHeadKey(dataStructureID), []byte(strconv.FormatUint(sequence, 10))); err != nil {
+					return fmt.Errorf("failed to store sequence head: %w", err)
+				}
+			}
+		}
+
+		stats, err := readBadgerStats(txn, dataStructureID)
+		if err != nil && !errors.Is(err, ErrNoStats) {
+			return err
+		}
+		stats.recordMessage(timestamp)
+		return writeBadgerStats(txn, stats)
+	})
+}
+
+// readBadgerStats loads the stats record of a data structure. On ErrNoStats
+// the returned record is empty but usable.
+func readBadgerStats(txn *badger.Txn, dataStructureID int) (DataStructureStats, error) {
+	stats := DataStructureStats{ID: dataStructureID}
+
+	data, err := badgerGet(txn, statsKey(dataStructureID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return stats, ErrNoStats
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return stats, fmt.Errorf("failed to unmarshal stats: %w", err)
+	}
+	return stats, nil
+}
+
+func writeBadgerStats(txn *badger.Txn, stats DataStructureStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	if err := txn.Set(statsKey(stats.ID), data); err != nil {
+		return fmt.Errorf("failed to store stats: %w", err)
+	}
+	return nil
+}
+
+// MarkConfirmed records that a message reached its signature threshold,
+// along with its confirmation, which may be nil when unknown. Repeated calls
+// for the same message are ignored. The marker expires with the message.
+func (bdb *BadgerDatabase) MarkConfirmed(ctx context.Context, dataStructureID int, hash string, timestamp int64, confirmation *Confirmation) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		key := confirmedKey(dataStructureID, hash)
+		if _, err := txn.Get(key); err == nil {
+			return nil
+		}
+
+		stats, err := readBadgerStats(txn, dataStructureID)
+		if err != nil && !errors.Is(err, ErrNoStats) {
+			return err
+		}
+		stats.recordConfirmed(hash, timestamp)
+
+		expiresAt := messageExpiry(txn, hash)
+		if err := setExpiring(txn, key, []byte(strconv.FormatInt(timestamp, 10)), expiresAt); err != nil {
+			return fmt.Errorf("failed to mark message confirmed: %w", err)
+		}
+		if confirmation != nil {
+			record, set, setID, err := encodeConfirmation(*confirmation)
+			if err != nil {
+				return err
+			}
+			// Shared by other messages, so the set never expires.
+			if err := txn.Set([]byte(signerSetPrefix+setID), set); err != nil {
+				return fmt.Errorf("failed to store signer set: %w", err)
+			}
+			if err := setExpiring(txn, []byte(confirmationPrefix+hash), record, expiresAt); err != nil {
+				return fmt.Errorf("failed to store confirmation: %w", err)
+			}
+		}
+		return writeBadgerStats(txn, stats)
+	})
+}
+
+func (bdb *BadgerDatabase) GetConfirmation(ctx context.Context, hash string) (Confirmation, bool, error) {
+	var c Confirmation
+	found := false
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		record, err := badgerGet(txn, []byte(confirmationPrefix+hash))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		var r confirmationRecord
+		if err := json.Unmarshal(record, &r); err != nil {
+			return fmt.Errorf("failed to unmarshal confirmation: %w", err)
+		}
+		set, err := badgerGet(txn, []byte(signerSetPrefix+r.SignerSetID))
+		if err != nil {
+			return fmt.Errorf("failed to read signer set %s: %w", r.SignerSetID, err)
+		}
+		if c, err = decodeConfirmation(record, set); err != nil {
+			return err
+		}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return Confirmation{}, false, err
+	}
+	return c, found, nil
+}
+
+func (bdb *BadgerDatabase) IsConfirmed(ctx context.Context, dataStructureID int, hash string) bool {
+	confirmed := false
+	bdb.db.View(func(txn *badger.Txn) error {
+		confirmed = confirmedIn(txn, dataStructureID, hash)
+		return nil
+	})
+	return confirmed
+}
+
+func (bdb *BadgerDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	if bdb.sigBatch != nil {
+		return bdb.sigBatch.store(ctx, hash, signer, signature)
+	}
+	return bdb.writeSignatures(signatureSet{hash: {signer: signature}})
+}
+
+// writeSignatures merges sigs into the stored signature maps in a single
+// transaction.
+func (bdb *BadgerDatabase) writeSignatures(sigs signatureSet) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	err := bdb.db.Update(func(txn *badger.Txn) error {
+		for hash, added := range sigs {
+			sigKey := []byte(signaturePrefix + hash)
+			stored := make(map[string]string)
+
+			sigData, err := badgerGet(txn, sigKey)
+			if err == nil {
+				if err := json.Unmarshal(sigData, &stored); err != nil {
+					return fmt.Errorf("failed to unmarshal signatures: %w", err)
+				}
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return fmt.Errorf("failed to get signatures: %w", err)
+			}
+
+			for signer, signature := range added {
+				stored[signer] = signature
+			}
+
+			sigData, err = json.Marshal(stored)
+			if err != nil {
+				return fmt.Errorf("failed to marshal signatures: %w", err)
+			}
+
+			if err := setExpiring(txn, sigKey, sigData, messageExpiry(txn, hash)); err != nil {
+				return fmt.Errorf("failed to store signatures: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The commit version is not known here; the next read caches the new
+	// maps under it.
+	for hash := range sigs {
+		bdb.sigCache.remove(hash)
+	}
+	return nil
+}
+
+// readMessage loads a message and attaches its signatures.
+func (bdb *BadgerDatabase) readMessage(txn *badger.Txn, hash string) (Message, bool) {
+	data, err := badgerGet(txn, []byte(dataPrefix+hash))
+	if err != nil {
+		return Message{}, false
+	}
+
+	var msg Message
+	if err := decodeMessage(data, &msg); err != nil {
+		return Message{}, false
+	}
+
+	if sigs, exists := bdb.readSignatures(txn, hash); exists {
+		msg.Signatures = sigs
+	}
+	return msg, true
+}
+
+// confirmedIn reports whether txn sees a message marked confirmed.
+func confirmedIn(txn *badger.Txn, dataStructureID int, hash string) bool {
+	_, err := txn.Get(confirmedKey(dataStructureID, hash))
+	return err == nil
+}
+
+// readSignatures reads the signatures as txn sees them. A cached map is only
+// used when it is the version txn sees, which spares decoding it.
+func (bdb *BadgerDatabase) readSignatures(txn *badger.Txn, hash string) (map[string]string, bool) {
+	item, err := txn.Get([]byte(signaturePrefix + hash))
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return make(map[string]string), false
+		}
+		return nil, false
+	}
+	if sigs, version, ok := bdb.sigCache.get(hash); ok && version == item.Version() {
+		return sigs, true
+	}
+
+	sigData, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, false
+	}
+	var sigs map[string]string
+	if err := json.Unmarshal(sigData, &sigs); err != nil {
+		return nil, false
+	}
+	bdb.sigCache.set(hash, sigs, item.Version())
+	return sigs, true
+}
+
+func (bdb *BadgerDatabase) GetData(ctx context.Context, hash string) ([]interface{}, []string, []string, int64, bool) {
+	msg, ok := bdb.GetMessage(ctx, hash)
+	if !ok {
+		return nil, nil, nil, 0, false
+	}
+	return msg.Data, msg.DataStructure, msg.DataStructureMeta, msg.Timestamp, true
+}
+
+func (bdb *BadgerDatabase) GetMessage(ctx context.Context, hash string) (Message, bool) {
+	var msg Message
+	var found bool
+	bdb.db.View(func(txn *badger.Txn) error {
+		msg, found = bdb.readMessage(txn, hash)
+		return nil
+	})
+	return msg, found
+}
+
+func (bdb *BadgerDatabase) GetSignatures(ctx context.Context, hash string) (map[string]string, bool) {
+	var sigs map[string]string
+	var exists bool
+	bdb.db.View(func(txn *badger.Txn) error {
+		sigs, exists = bdb.readSignatures(txn, hash)
+		return nil
+	})
+	return sigs, exists
+}
+
+// keyIterator iterates the keys under prefix without fetching values.
+func keyIterator(txn *badger.Txn, prefix []byte, reverse bool) *badger.Iterator {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+	opts.Reverse = reverse
+
+	it := txn.NewIterator(opts)
+	if reverse {
+		it.Seek(append(append([]byte(nil), prefix...), 0xFF))
+	} else {
+		it.Seek(prefix)
+	}
+	return it
+}
+
+// GetAllMessages returns the messages of a data structure newest first.
+func (bdb *BadgerDatabase) GetAllMessages(ctx context.Context, dataStructureID int, page, limit int) ([]Message, error) {
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		it, prefix := timestampIterator(txn, dataStructureID, true)
+		defer it.Close()
+
+		var hashes []string
+		for ; validTimestampKey(it, prefix) && !cursor.full(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+
+			if _, err := txn.Get([]byte(dataPrefix + hash)); err != nil || !cursor.accept() {
+				continue
+			}
+			hashes = append(hashes, hash)
+		}
+
+		var err error
+		messages, err = loadMessages(ctx, hashes, func(hash string) (Message, bool) {
+			return bdb.readMessage(txn, hash)
+		})
+		return err
+	})
+
+	return messages, err
+}
+
+func (bdb *BadgerDatabase) GetLatestMessage(ctx context.Context, dataStructureID int) (Message, bool, error) {
+	var msg Message
+	var confirmed bool
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(fmt.Sprintf("%s%d:", indexPrefix, dataStructureID))
+		it := keyIterator(txn, prefix, true)
+		defer it.Close()
+
+		if !it.ValidForPrefix(prefix) {
+			return badger.ErrKeyNotFound
+		}
+
+		parts := strings.Split(string(it.Item().Key()), ":")
+		if len(parts) < 4 {
+			return fmt.Errorf("invalid index key format")
+		}
+
+		data, err := badgerGet(txn, []byte(dataPrefix+parts[3]))
+		if err != nil {
+			return err
+		}
+		if err := decodeMessage(data, &msg); err != nil {
+			return err
+		}
+
+		if sigs, exists := bdb.readSignatures(txn, msg.Hash); exists {
+			msg.Signatures = sigs
+			confirmed = true
+		}
+		return nil
+	})
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	return msg, confirmed, nil
+}
+
+// IterateMessages calls fn for each message with a timestamp in [from, to],
+// oldest first, until fn returns false. A zero to means no upper bound. The
+// whole walk reads one consistent view.
+func (bdb *BadgerDatabase) IterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(Message) bool) error {
+	return bdb.db.View(func(txn *badger.Txn) error {
+		it, prefix := timestampIterator(txn, dataStructureID, false)
+		defer it.Close()
+
+		if from > 0 {
+			it.Seek(timestampIndexStart(dataStructureID, from))
+		}
+
+		for ; validTimestampKey(it, prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			timestamp, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok || timestamp < from {
+				continue
+			}
+			if to > 0 && timestamp > to {
+				break
+			}
+
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok {
+				continue
+			}
+			if !fn(msg) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// GetLatestConfirmed returns the newest message marked confirmed.
+func (bdb *BadgerDatabase) GetLatestConfirmed(ctx context.Context, dataStructureID int) (Message, bool, error) {
+	messages, err := bdb.GetConfirmedMessages(ctx, dataStructureID, nil, nil, 1, 1)
+	if err != nil || len(messages) == 0 {
+		return Message{}, false, err
+	}
+	return messages[0], true, nil
+}
+
+// GetMessagesByField returns messages whose field equals value, newest first,
+// walking the field time index backwards.
+func (bdb *BadgerDatabase) GetMessagesByField(ctx context.Context, dataStructureID int, field, value string, page, limit int) ([]Message, error) {
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := fieldTimeIndexPrefix(dataStructureID, FieldFilter{Field: field, Value: value})
+		it := keyIterator(txn, prefix, true)
+		defer it.Close()
+
+		var hashes []string
+		for ; it.ValidForPrefix(prefix) && !cursor.full(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			key := string(it.Item().Key())
+			hash := key[strings.LastIndex(key, ":")+1:]
+
+			if _, err := txn.Get([]byte(dataPrefix + hash)); err != nil || !cursor.accept() {
+				continue
+			}
+			hashes = append(hashes, hash)
+		}
+
+		var err error
+		messages, err = loadMessages(ctx, hashes, func(hash string) (Message, bool) {
+			return bdb.readMessage(txn, hash)
+		})
+		return err
+	})
+
+	return messages, err
+}
+
+// GetLatestByField returns the newest message marked confirmed whose field
+// equals value.
+func (bdb *BadgerDatabase) GetLatestByField(ctx context.Context, dataStructureID int, field, value string) (Message, bool, error) {
+	var latest Message
+	found := false
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(fmt.Sprintf("%s%d:%s:%v:", indexPrefix, dataStructureID, field, value))
+		it := keyIterator(txn, prefix, false)
+		defer it.Close()
+
+		for ; it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hash := string(it.Item().Key()[len(prefix):])
+			if !confirmedIn(txn, dataStructureID, hash) {
+				continue
+			}
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok {
+				continue
+			}
+			if !found || msg.Timestamp > latest.Timestamp {
+				latest = msg
+				found = true
+			}
+		}
+		return nil
+	})
+
+	return latest, found, err
+}
+
+// GetMessagesByRange returns messages within every range filter and equal to
+// every field filter, in ascending order of the first range's field. Only the
+// first range is served from the index; the rest are checked per message.
+func (bdb *BadgerDatabase) GetMessagesByRange(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("at least one range filter is required")
+	}
+
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := numericFieldPrefix(dataStructureID, ranges[0].Field)
+		start, limitKey := ranges[0].bounds(dataStructureID)
+
+		it := keyIterator(txn, prefix, false)
+		defer it.Close()
+
+		for it.Seek(start); it.ValidForPrefix(prefix) && !cursor.full(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			key := it.Item().Key()
+			if bytes.Compare(key, limitKey) >= 0 {
+				break
+			}
+			hash, ok := numericKeyHash(key, prefix)
+			if !ok {
+				continue
+			}
+
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok || !matchesQuery(msg, ranges[1:], equals) || !cursor.accept() {
+				continue
+			}
+
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+
+	return messages, err
+}
+
+// GetConfirmedMessages returns messages marked confirmed, newest first,
+// optionally narrowed by range and field filters.
+func (bdb *BadgerDatabase) GetConfirmedMessages(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error) {
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		it, prefix := timestampIterator(txn, dataStructureID, true)
+		defer it.Close()
+
+		for ; validTimestampKey(it, prefix) && !cursor.full(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+
+			if !confirmedIn(txn, dataStructureID, hash) {
+				continue
+			}
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok || !matchesQuery(msg, ranges, equals) || !cursor.accept() {
+				continue
+			}
+
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+
+	return messages, err
+}
+
+// RebuildIndexes writes the field time and numeric index keys of every stored
+// message of a data structure and returns how many messages it indexed.
+func (bdb *BadgerDatabase) RebuildIndexes(ctx context.Context, dataStructureID int) (int, error) {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	batch := bdb.db.NewWriteBatch()
+	defer batch.Cancel()
+	indexed := 0
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		it, prefix := timestampIterator(txn, dataStructureID, false)
+		defer it.Close()
+
+		for ; validTimestampKey(it, prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok {
+				continue
+			}
+
+			msg.Hash = hash
+			for _, key := range secondaryIndexKeys(dataStructureID, msg) {
+				entry := badger.NewEntry(key, nil)
+				entry.ExpiresAt = it.Item().ExpiresAt()
+				if err := batch.SetEntry(entry); err != nil {
+					return err
+				}
+			}
+			indexed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	if err := batch.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write indexes: %w", err)
+	}
+	return indexed, nil
+}
+
+func (bdb *BadgerDatabase) GetDataStructures(ctx context.Context) ([]int, error) {
+	var ids []int
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(dataStructPrefix)
+		it := keyIterator(txn, prefix, false)
+		defer it.Close()
+
+		for ; it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			id, err := strconv.Atoi(strings.TrimPrefix(string(it.Item().Key()), dataStructPrefix))
+			if err != nil {
+				continue
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids, err
+}
+
+// timestampIterator iterates the timestamp index of a data structure. Use
+// validTimestampKey to stop before its field indexes.
+func timestampIterator(txn *badger.Txn, dataStructureID int, reverse bool) (*badger.Iterator, []byte) {
+	prefix := []byte(fmt.Sprintf("%s%d:", indexPrefix, dataStructureID))
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+	opts.Reverse = reverse
+
+	it := txn.NewIterator(opts)
+	if reverse {
+		it.Seek(append(append([]byte(nil), prefix...), ':'))
+	} else {
+		it.Seek(append(append([]byte(nil), prefix...), '0'))
+	}
+	return it, prefix
+}
+
+func validTimestampKey(it *badger.Iterator, prefix []byte) bool {
+	if !it.ValidForPrefix(prefix) {
+		return false
+	}
+	key := it.Item().Key()
+	return len(key) > len(prefix) && key[len(prefix)] >= '0' && key[len(prefix)] <= '9'
+}
+
+func (bdb *BadgerDatabase) GetDataStructureStats(ctx context.Context, id int) (DataStructureStats, error) {
+	var stats DataStructureStats
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		var err error
+		stats, err = readBadgerStats(txn, id)
+		return err
+	})
+
+	return stats, err
+}
+
+// RebuildStats recomputes the stats of a data structure from its index,
+// counting messages with at least threshold signatures as confirmed.
+func (bdb *BadgerDatabase) RebuildStats(ctx context.Context, dataStructureID, threshold int) (DataStructureStats, error) {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	return bdb.rebuildStats(ctx, dataStructureID, func(txn *badger.Txn, hash string) bool {
+		sigs, exists := bdb.readSignatures(txn, hash)
+		return exists && len(sigs) >= threshold
+	})
+}
+
+// rebuildStats rewrites the stats record and confirmation markers from the
+// index. confirmed decides which messages count as confirmed. The caller
+// holds mu.
+func (bdb *BadgerDatabase) rebuildStats(ctx context.Context, dataStructureID int, confirmed func(txn *badger.Txn, hash string) bool) (DataStructureStats, error) {
+	stats := DataStructureStats{ID: dataStructureID}
+	batch := bdb.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		// Markers of confirmed messages, expiring along with the message.
+		markerEntries := make(map[string]*badger.Entry)
+
+		it, prefix := timestampIterator(txn, dataStructureID, false)
+		for ; validTimestampKey(it, prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				it.Close()
+				return err
+			}
+			timestamp, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+			stats.recordMessage(timestamp)
+			if confirmed(txn, hash) {
+				stats.recordConfirmed(hash, timestamp)
+				entry := badger.NewEntry(confirmedKey(dataStructureID, hash), []byte(strconv.FormatInt(timestamp, 10)))
+				entry.ExpiresAt = it.Item().ExpiresAt()
+				markerEntries[hash] = entry
+			}
+		}
+		it.Close()
+
+		prefix = confirmedPrefix(dataStructureID)
+		markers := keyIterator(txn, prefix, false)
+		defer markers.Close()
+		for ; markers.ValidForPrefix(prefix); markers.Next() {
+			if _, ok := markerEntries[string(markers.Item().Key()[len(prefix):])]; ok {
+				continue
+			}
+			if err := batch.Delete(markers.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range markerEntries {
+			if err := batch.SetEntry(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return stats, fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	if err := batch.Set(statsKey(dataStructureID), data); err != nil {
+		return stats, fmt.Errorf("failed to store stats: %w", err)
+	}
+	if err := batch.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to store stats: %w", err)
+	}
+	return stats, nil
+}
+
+// CountMessages counts the messages of a data structure, or with filters
+// those matching every field filter, from the indexes alone.
+func (bdb *BadgerDatabase) CountMessages(ctx context.Context, dataStructureID int, filters ...FieldFilter) (int, error) {
+	count := 0
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		if len(filters) > 0 {
+			var err error
+			count, err = countFieldMatches(dataStructureID, filters, func(prefix []byte, visit func(string)) error {
+				it := keyIterator(txn, prefix, false)
+				defer it.Close()
+
+				for ; it.ValidForPrefix(prefix); it.Next() {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					visit(string(it.Item().Key()[len(prefix):]))
+				}
+				return nil
+			})
+			return err
+		}
+
+		it, prefix := timestampIterator(txn, dataStructureID, false)
+		defer it.Close()
+		for ; validTimestampKey(it, prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// CountConfirmed counts the messages of a data structure marked confirmed.
+func (bdb *BadgerDatabase) CountConfirmed(ctx context.Context, dataStructureID int) (int, error) {
+	count := 0
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		it, prefix := timestampIterator(txn, dataStructureID, false)
+		defer it.Close()
+
+		for ; validTimestampKey(it, prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, hash, ok := parseTimestampKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+			if confirmedIn(txn, dataStructureID, hash) {
+				count++
+			}
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+func (bdb *BadgerDatabase) HasData(ctx context.Context, hash string) bool {
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(dataPrefix + hash))
+		return err
+	})
+	return err == nil
+}
+
+func (bdb *BadgerDatabase) SearchByHashPrefix(ctx context.Context, prefix string, limit int) ([]Message, error) {
+	var messages []Message
+	if limit < 1 {
+		return messages, nil
+	}
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		keyPrefix := []byte(dataPrefix + prefix)
+		it := keyIterator(txn, keyPrefix, false)
+		defer it.Close()
+
+		var hashes []string
+		for ; it.ValidForPrefix(keyPrefix) && len(hashes) < limit; it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hashes = append(hashes, string(it.Item().Key()[len(dataPrefix):]))
+		}
+
+		var err error
+		messages, err = loadMessages(ctx, hashes, func(hash string) (Message, bool) {
+			return bdb.readMessage(txn, hash)
+		})
+		return err
+	})
+
+	return messages, err
+}
+
+func (bdb *BadgerDatabase) StoreLatency(ctx context.Context, hash string, latency MessageLatency) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	data, err := json.Marshal(latency)
+	if err != nil {
+		return fmt.Errorf("failed to marshal latency: %w", err)
+	}
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := setExpiring(txn, []byte(latencyPrefix+hash), data, messageExpiry(txn, hash)); err != nil {
+			return fmt.Errorf("failed to store latency: %w", err)
+		}
+		return nil
+	})
+}
+
+func (bdb *BadgerDatabase) GetLatency(ctx context.Context, hash string) (MessageLatency, bool) {
+	var latency MessageLatency
+	var found bool
+
+	bdb.db.View(func(txn *badger.Txn) error {
+		data, err := badgerGet(txn, []byte(latencyPrefix+hash))
+		if err != nil {
+			return nil
+		}
+		found = json.Unmarshal(data, &latency) == nil
+		return nil
+	})
+
+	if !found {
+		return MessageLatency{}, false
+	}
+	return latency, true
+}
+
+// Backup streams the store from a single read transaction, which Badger
+// serves from a consistent snapshot. Records keep their expiry.
+func (bdb *BadgerDatabase) Backup(ctx context.Context, w io.Writer) error {
+	sw, err := newSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+
+	err = bdb.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read snapshot record: %w", err)
+			}
+			if err := sw.Write(item.Key(), value, item.ExpiresAt()); err != nil {
+				return fmt.Errorf("failed to write snapshot record: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Close()
+}
+
+// Restore loads every record of a snapshot archive into the store, which
+// must be empty. Records keep the expiry they were archived with; those
+// that have expired since are skipped.
+func (bdb *BadgerDatabase) Restore(ctx context.Context, r io.Reader) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	empty := true
+	bdb.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		it.Rewind()
+		empty = !it.Valid()
+		return nil
+	})
+	if !empty {
+		return ErrStoreNotEmpty
+	}
+
+	sr, err := newSnapshotReader(r)
+	if err != nil {
+		return err
+	}
+
+	batch := bdb.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot record: %w", err)
+		}
+		if expiresAt != 0 && expiresAt <= uint64(time.Now().Unix()) {
+			continue
+		}
+
+		entry := badger.NewEntry(key, value)
+		entry.ExpiresAt = expiresAt
+		if err := batch.SetEntry(entry); err != nil {
+			return fmt.Errorf("failed to write restore batch: %w", err)
+		}
+	}
+
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("failed to write restore batch: %w", err)
+	}
+
+	bdb.sigCache.purge()
+	return nil
+}
+
+// SetRetention records the retention policy of a data structure. It applies
+// as a TTL to messages stored from now on; messages already stored keep their
+// expiry, and the pruner removes those that fall outside a shorter window.
+func (bdb *BadgerDatabase) SetRetention(ctx context.Context, dataStructureID int, retention time.Duration) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	key := []byte(fmt.Sprintf("%s%d", retentionPrefix, dataStructureID))
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if retention <= 0 {
+			if err := txn.Delete(key); err != nil {
+				return fmt.Errorf("failed to clear retention: %w", err)
+			}
+			return nil
+		}
+
+		if err := txn.Set(key, []byte(strconv.FormatInt(int64(retention/time.Second), 10))); err != nil {
+			return fmt.Errorf("failed to store retention: %w", err)
+		}
+		return nil
+	})
+}
+
+func (bdb *BadgerDatabase) GetRetention(ctx context.Context, dataStructureID int) (time.Duration, bool) {
+	var seconds int64
+
+	bdb.db.View(func(txn *badger.Txn) error {
+		data, err := badgerGet(txn, []byte(fmt.Sprintf("%s%d", retentionPrefix, dataStructureID)))
+		if err != nil {
+			return nil
+		}
+		seconds, _ = strconv.ParseInt(string(data), 10, 64)
+		return nil
+	})
+
+	if seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func (bdb *BadgerDatabase) StoreKeyRotation(ctx context.Context, rotation KeyRotation) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	data, err := json.Marshal(rotation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key rotation: %w", err)
+	}
+
+	key := []byte(rotationPrefix + strings.ToLower(rotation.OldAddress) + ":" + strings.ToLower(rotation.NewAddress))
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(key, data); err != nil {
+			return fmt.Errorf("failed to store key rotation: %w", err)
+		}
+		return nil
+	})
+}
+
+func (bdb *BadgerDatabase) StoreSignerSet(ctx context.Context, set SignerSet) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signer set: %w", err)
+	}
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(signerSetKey(set.Epoch), data); err != nil {
+			return fmt.Errorf("failed to store signer set: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddParticipation counts one signature on a confirmed message for each of
+// signers in a reward period.
+func (bdb *BadgerDatabase) AddParticipation(ctx context.Context, period uint64, signers []string) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	added := make(map[string]int, len(signers))
+	for _, signer := range signers {
+		added[strings.ToLower(signer)]++
+	}
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		for signer, n := range added {
+			key := participationKey(period, signer)
+			count := 0
+			data, err := badgerGet(txn, key)
+			if err == nil {
+				if count, err = strconv.Atoi(string(data)); err != nil {
+					return fmt.Errorf("failed to parse participation of %s: %w", signer, err)
+				}
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return fmt.Errorf("failed to read participation: %w", err)
+			}
+			if err := txn.Set(key, []byte(strconv.Itoa(count+n))); err != nil {
+				return fmt.Errorf("failed to store participation: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetParticipation returns the signature counts of a reward period, keyed by
+// lowercased signer.
+func (bdb *BadgerDatabase) GetParticipation(ctx context.Context, period uint64) (map[string]int, error) {
+	prefix := participationPrefix(period)
+	counts := make(map[string]int)
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read participation: %w", err)
+			}
+			count, err := strconv.Atoi(string(value))
+			if err != nil {
+				return fmt.Errorf("failed to parse participation: %w", err)
+			}
+			counts[string(it.Item().Key()[len(prefix):])] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// AddUsage adds to the usage of each API key in usage on day.
+func (bdb *BadgerDatabase) AddUsage(ctx context.Context, day string, usage map[string]Usage) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		for apiKey, u := range usage {
+			key := usageKey(day, apiKey)
+			var total Usage
+			data, err := badgerGet(txn, key)
+			if err == nil {
+				if err := json.Unmarshal(data, &total); err != nil {
+					return fmt.Errorf("failed to decode usage of %s: %w", apiKey, err)
+				}
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return fmt.Errorf("failed to read usage: %w", err)
+			}
+			if data, err = json.Marshal(total.Add(u)); err != nil {
+				return fmt.Errorf("failed to encode usage: %w", err)
+			}
+			if err := txn.Set(key, data); err != nil {
+				return fmt.Errorf("failed to store usage: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetUsage returns the usage of the days from through to, both YYYYMMDD and
+// inclusive, keyed by day and then API key.
+func (bdb *BadgerDatabase) GetUsage(ctx context.Context, from, to string) (map[string]map[string]Usage, error) {
+	usage := make(map[string]map[string]Usage)
+	start, limit := []byte(usagePrefix+from), []byte(usagePrefix+to+";")
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(usagePrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.Valid() && bytes.Compare(it.Item().Key(), limit) < 0; it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			day, apiKey, ok := parseUsageKey(it.Item().Key())
+			if !ok {
+				continue
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read usage: %w", err)
+			}
+			var u Usage
+			if err := json.Unmarshal(value, &u); err != nil {
+				return fmt.Errorf("failed to decode usage: %w", err)
+			}
+			if usage[day] == nil {
+				usage[day] = make(map[string]Usage)
+			}
+			usage[day][apiKey] = u
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (bdb *BadgerDatabase) GetSignerSet(ctx context.Context, epoch uint64) (SignerSet, bool, error) {
+	var set SignerSet
+	found := false
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(signerSetKey(epoch))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read signer set: %w", err)
+		}
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return fmt.Errorf("failed to read signer set: %w", err)
+		}
+		if err := json.Unmarshal(value, &set); err != nil {
+			return fmt.Errorf("failed to unmarshal signer set: %w", err)
+		}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return SignerSet{}, false, err
+	}
+	return set, found, nil
+}
+
+// GetKeyRotations returns every recorded rotation, oldest announcement
+// first.
+func (bdb *BadgerDatabase) GetKeyRotations(ctx context.Context) ([]KeyRotation, error) {
+	var rotations []KeyRotation
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(rotationPrefix)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read key rotation: %w", err)
+			}
+
+			var rotation KeyRotation
+			if err := json.Unmarshal(value, &rotation); err != nil {
+				return fmt.Errorf("failed to unmarshal key rotation: %w", err)
+			}
+			rotations = append(rotations, rotation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rotations, func(i, j int) bool {
+		return rotations[i].AnnouncedAt < rotations[j].AnnouncedAt
+	})
+	return rotations, nil
+}
+
+func (bdb *BadgerDatabase) StorePeer(ctx context.Context, record PeerRecord) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal peer record: %w", err)
+	}
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte(peerPrefix+record.ID), data); err != nil {
+			return fmt.Errorf("failed to store peer record: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetPeers returns every recorded peer, most recently seen first.
+func (bdb *BadgerDatabase) GetPeers(ctx context.Context) ([]PeerRecord, error) {
+	var peers []PeerRecord
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(peerPrefix)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read peer record: %w", err)
+			}
+
+			var record PeerRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("failed to unmarshal peer record: %w", err)
+			}
+			peers = append(peers, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortPeers(peers)
+	return peers, nil
+}
+
+func (bdb *BadgerDatabase) DeletePeer(ctx context.Context, id string) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete([]byte(peerPrefix + id)); err != nil {
+			return fmt.Errorf("failed to delete peer record: %w", err)
+		}
+		return nil
+	})
+}
+
+func (bdb *BadgerDatabase) StoreDeadLetter(ctx context.Context, letter DeadLetter) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(deadLetterKey(letter.ID), data); err != nil {
+			return fmt.Errorf("failed to store dead letter: %w", err)
+		}
+		return nil
+	})
+}
+
+func (bdb *BadgerDatabase) GetDeadLetter(ctx context.Context, id string) (DeadLetter, bool, error) {
+	var letter DeadLetter
+	found := false
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(deadLetterKey(id))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		if err := json.Unmarshal(value, &letter); err != nil {
+			return fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return DeadLetter{}, false, err
+	}
+	return letter, found, nil
+}
+
+// GetDeadLetters returns up to limit dead letters recorded for reason, or
+// for any reason when it is empty, oldest first.
+func (bdb *BadgerDatabase) GetDeadLetters(ctx context.Context, reason string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(deadLetterPrefix)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && len(letters) < limit; it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read dead letter: %w", err)
+			}
+
+			var letter DeadLetter
+			if err := json.Unmarshal(value, &letter); err != nil {
+				return fmt.Errorf("failed to unmarshal dead letter: %w", err)
+			}
+			if keepDeadLetter(letter, reason) {
+				letters = append(letters, letter)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+func (bdb *BadgerDatabase) DeleteDeadLetter(ctx context.Context, id string) error {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete(deadLetterKey(id)); err != nil {
+			return fmt.Errorf("failed to delete dead letter: %w", err)
+		}
+		return nil
+	})
+}
+
+// LastSequence returns the highest sequence number ever stored for a data
+// structure, or 0 when none was. Neither pruning nor expiry lowers it.
+func (bdb *BadgerDatabase) LastSequence(ctx context.Context, dataStructureID int) (uint64, error) {
+	var seq uint64
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		var err error
+		seq, err = badgerSequenceHead(txn, dataStructureID)
+		return err
+	})
+	return seq, err
+}
+
+func badgerSequenceHead(txn *badger.Txn, dataStructureID int) (uint64, error) {
+	data, err := badgerGet(txn, sequenceHeadKey(dataStructureID))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sequence head: %w", err)
+	}
+	return strconv.ParseUint(string(data), 10, 64)
+}
+
+// GetSequenceInfo walks the sequence index of a data structure to find the
+// stored range and the gaps in it. Index entries expire with their
+// messages, so expired history shows up as a later First, not as a gap.
+func (bdb *BadgerDatabase) GetSequenceInfo(ctx context.Context, dataStructureID int) (SequenceInfo, error) {
+	info := SequenceInfo{DataStructureID: dataStructureID, Gaps: []SequenceGap{}}
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := sequencePrefixFor(dataStructureID)
+		it := keyIterator(txn, prefix, false)
+		defer it.Close()
+
+		for ; it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			seq, _, err := parseSequenceKey(it.Item().Key())
+			if err != nil {
+				continue
+			}
+			info.observe(seq)
+		}
+		return nil
+	})
+	if err != nil {
+		return SequenceInfo{}, err
+	}
+	return info, nil
+}
+
+// GetMessagesBySequence returns up to limit messages of a data structure
+// with a sequence number in [from, to], lowest first, with confirmedOnly
+// only those marked confirmed. A zero to means no upper bound.
+func (bdb *BadgerDatabase) GetMessagesBySequence(ctx context.Context, dataStructureID int, from, to uint64, confirmedOnly bool, limit int) ([]Message, error) {
+	var messages []Message
+	if limit < 1 {
+		return messages, nil
+	}
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := sequencePrefixFor(dataStructureID)
+		it := keyIterator(txn, prefix, false)
+		defer it.Close()
+
+		for it.Seek(sequenceKey(dataStructureID, from, "")); it.ValidForPrefix(prefix) && len(messages) < limit; it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			seq, hash, err := parseSequenceKey(it.Item().Key())
+			if err != nil {
+				continue
+			}
+			if to > 0 && seq > to {
+				break
+			}
+
+			if confirmedOnly && !confirmedIn(txn, dataStructureID, hash) {
+				continue
+			}
+			msg, ok := bdb.readMessage(txn, hash)
+			if !ok {
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// PruneMessages deletes messages of a data structure with a timestamp older
+// than before. Messages stored under a retention policy usually expire on
+// their own first; this catches older data and shortened policies, and
+// brings the stats in line with what expired.
+func (bdb *BadgerDatabase) PruneMessages(ctx context.Context, dataStructureID int, before int64) (int, error) {
+	bdb.mu.Lock()
+	defer bdb.mu.Unlock()
+
+	prefix := []byte(fmt.Sprintf("%s%d:", indexPrefix, dataStructureID))
+	expired := make(map[string]bool)
+	var keys [][]byte
+
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		it := keyIterator(txn, prefix, false)
+		for ; it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				it.Close()
+				return err
+			}
+			parts := strings.Split(string(it.Item().Key()), ":")
+			if len(parts) != 4 {
+				continue
+			}
+			timestamp, err := strconv.ParseInt(parts[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			if timestamp < before {
+				expired[parts[3]] = true
+			}
+		}
+		it.Close()
+
+		if len(expired) == 0 {
+			return nil
+		}
+
+		// Field, field time, numeric and sequence index keys end with the
+		// message hash as well, so a second pass catches them without
+		// rebuilding each key from the stored data.
+		for _, indexed := range [][]byte{prefix, []byte(fmt.Sprintf("%s%d:", fieldTimePrefix, dataStructureID)), []byte(fmt.Sprintf("%s%d:", numericIndexPrefix, dataStructureID)), sequencePrefixFor(dataStructureID)} {
+			it = keyIterator(txn, indexed, false)
+			for ; it.ValidForPrefix(indexed); it.Next() {
+				if err := ctx.Err(); err != nil {
+					it.Close()
+					return err
+				}
+				key := it.Item().KeyCopy(nil)
+				if expired[string(key[strings.LastIndex(string(key), ":")+1:])] {
+					keys = append(keys, key)
+				}
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	if len(expired) > 0 {
+		for hash := range expired {
+			keys = append(keys,
+				[]byte(dataPrefix+hash),
+				[]byte(signaturePrefix+hash),
+				[]byte(latencyPrefix+hash),
+				[]byte(confirmationPrefix+hash),
+				confirmedKey(dataStructureID, hash),
+			)
+		}
+
+		batch := bdb.db.NewWriteBatch()
+		defer batch.Cancel()
+		for _, key := range keys {
+			if err := batch.Delete(key); err != nil {
+				return 0, fmt.Errorf("failed to prune messages: %w", err)
+			}
+		}
+		if err := batch.Flush(); err != nil {
+			return 0, fmt.Errorf("failed to prune messages: %w", err)
+		}
+		for hash := range expired {
+			bdb.sigCache.remove(hash)
+		}
+	}
+
+	// Messages also leave through their TTL without passing here, so the
+	// stats are recounted from what is left rather than adjusted. Markers
+	// decide what counts as confirmed, keeping the original thresholds.
+	err = bdb.db.View(func(txn *badger.Txn) error {
+		_, err := readBadgerStats(txn, dataStructureID)
+		return err
+	})
+	if errors.Is(err, ErrNoStats) {
+		return len(expired), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = bdb.rebuildStats(ctx, dataStructureID, func(txn *badger.Txn, hash string) bool {
+		return confirmedIn(txn, dataStructureID, hash)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(expired), nil
+}
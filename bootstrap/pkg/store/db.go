@@ -0,0 +1,2063 @@
+// Package store persists signed messages and their indexes in LevelDB or
+// Badger.
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"golang.org/x/sync/errgroup"
+)
+
+// Paged methods share one contract: page is 1-based, a page below 1 is the
+// first page, and limit is the page size, with a limit below 1 returning an
+// empty page. Each method documents its order, which is stable across pages.
+// Every method but Close takes a context; scans stop once it is done and
+// return its error.
+type Database interface {
+	StoreData(ctx context.Context, messageID string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error
+	StoreSignature(ctx context.Context, hash, signer, signature string) error
+	GetData(ctx context.Context, hash string) ([]interface{}, []string, []string, int64, bool)
+	GetSignatures(ctx context.Context, hash string) (map[string]string, bool)
+	GetMessage(ctx context.Context, hash string) (Message, bool)
+	GetAllMessages(ctx context.Context, dataStructureID int, page, limit int) ([]Message, error)
+	GetLatestMessage(ctx context.Context, dataStructureID int) (Message, bool, error)
+	GetLatestConfirmed(ctx context.Context, dataStructureID int) (Message, bool, error)
+	GetMessagesByField(ctx context.Context, dataStructureID int, field, value string, page, limit int) ([]Message, error)
+	GetLatestByField(ctx context.Context, dataStructureID int, field, value string) (Message, bool, error)
+	GetMessagesByRange(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error)
+	GetConfirmedMessages(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error)
+	IterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(Message) bool) error
+	RebuildIndexes(ctx context.Context, dataStructureID int) (int, error)
+	GetDataStructures(ctx context.Context) ([]int, error)
+	GetDataStructureStats(ctx context.Context, id int) (DataStructureStats, error)
+	MarkConfirmed(ctx context.Context, dataStructureID int, hash string, timestamp int64, confirmation *Confirmation) error
+	GetConfirmation(ctx context.Context, hash string) (Confirmation, bool, error)
+	// IsConfirmed reports whether MarkConfirmed was called for a message,
+	// with or without a recorded confirmation.
+	IsConfirmed(ctx context.Context, dataStructureID int, hash string) bool
+	RebuildStats(ctx context.Context, dataStructureID, threshold int) (DataStructureStats, error)
+	CountMessages(ctx context.Context, dataStructureID int, filters ...FieldFilter) (int, error)
+	CountConfirmed(ctx context.Context, dataStructureID int) (int, error)
+	HasData(ctx context.Context, hash string) bool
+	// SearchByHashPrefix returns up to limit messages whose hash starts
+	// with prefix, in hash order.
+	SearchByHashPrefix(ctx context.Context, prefix string, limit int) ([]Message, error)
+	StoreLatency(ctx context.Context, hash string, latency MessageLatency) error
+	GetLatency(ctx context.Context, hash string) (MessageLatency, bool)
+	SetRetention(ctx context.Context, dataStructureID int, retention time.Duration) error
+	GetRetention(ctx context.Context, dataStructureID int) (time.Duration, bool)
+	PruneMessages(ctx context.Context, dataStructureID int, before int64) (int, error)
+	StoreKeyRotation(ctx context.Context, rotation KeyRotation) error
+	GetKeyRotations(ctx context.Context) ([]KeyRotation, error)
+	StoreSignerSet(ctx context.Context, set SignerSet) error
+	GetSignerSet(ctx context.Context, epoch uint64) (SignerSet, bool, error)
+	AddParticipation(ctx context.Context, period uint64, signers []string) error
+	GetParticipation(ctx context.Context, period uint64) (map[string]int, error)
+	AddUsage(ctx context.Context, day string, usage map[string]Usage) error
+	GetUsage(ctx context.Context, from, to string) (map[string]map[string]Usage, error)
+	StorePeer(ctx context.Context, record PeerRecord) error
+	GetPeers(ctx context.Context) ([]PeerRecord, error)
+	DeletePeer(ctx context.Context, id string) error
+	StoreDeadLetter(ctx context.Context, letter DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (DeadLetter, bool, error)
+	GetDeadLetters(ctx context.Context, reason string, limit int) ([]DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+	LastSequence(ctx context.Context, dataStructureID int) (uint64, error)
+	GetSequenceInfo(ctx context.Context, dataStructureID int) (SequenceInfo, error)
+	GetMessagesBySequence(ctx context.Context, dataStructureID int, from, to uint64, confirmedOnly bool, limit int) ([]Message, error)
+	Backup(ctx context.Context, w io.Writer) error
+	Restore(ctx context.Context, r io.Reader) error
+	Close() error
+}
+
+type Message struct {
+	Hash              string            `json:"hash"`
+	Data              []interface{}     `json:"data"`
+	DataStructure     []string          `json:"data_structure"`
+	DataStructureMeta []string          `json:"data_structure_meta"`
+	Signatures        map[string]string `json:"signatures"`
+	Timestamp         int64             `json:"timestamp"`
+	HashVersion       int               `json:"hash_version,omitempty"`
+	RequestID         string            `json:"request_id,omitempty"`
+	Sequence          uint64            `json:"sequence,omitempty"`
+	// Epoch names the signer set the message was requested under; zero
+	// before staking epochs were used.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// DataStructureID reads as 0 for messages stored before it was
+	// recorded.
+	DataStructureID int             `json:"data_structure_id,omitempty"`
+	Latency         *MessageLatency `json:"latency,omitempty"`
+	// Confirmation is attached on read where the signer set a message was
+	// confirmed under matters; it is stored separately.
+	Confirmation *Confirmation `json:"confirmation,omitempty"`
+	// Verified is set on read once the hash has been recomputed from the
+	// stored fields; it is never stored.
+	Verified *bool `json:"verified,omitempty"`
+}
+
+// DataStructureStats is kept up to date as messages are stored, confirmed and
+// pruned. Confirmation uses the threshold in force when a message reached it.
+type DataStructureStats struct {
+	ID                int    `json:"id"`
+	MessageCount      int    `json:"message_count"`
+	ConfirmedCount    int    `json:"confirmed_count"`
+	LastMessageTime   int64  `json:"last_message_time"`
+	LastConfirmedTime int64  `json:"last_confirmed_time"`
+	LastConfirmedHash string `json:"last_confirmed_hash"`
+}
+
+// ErrNoStats is returned for a data structure whose stats were never
+// recorded, such as one stored before stats were kept. RebuildStats fixes it.
+var ErrNoStats = errors.New("no stats recorded")
+
+// ErrAlreadyStored is returned by StoreData for a hash that is already
+// stored. Nothing is written, so a retried store cannot leave a second set
+// of index entries or a second sequence number behind.
+var ErrAlreadyStored = errors.New("message already stored")
+
+func (s *DataStructureStats) recordMessage(timestamp int64) {
+	s.MessageCount++
+	if timestamp > s.LastMessageTime {
+		s.LastMessageTime = timestamp
+	}
+}
+
+func (s *DataStructureStats) recordConfirmed(hash string, timestamp int64) {
+	s.ConfirmedCount++
+	if timestamp >= s.LastConfirmedTime {
+		s.LastConfirmedTime = timestamp
+		s.LastConfirmedHash = hash
+	}
+}
+
+// pageCursor applies the pagination contract while walking matches in
+// order.
+type pageCursor struct {
+	skip      int
+	remaining int
+}
+
+func newPageCursor(page, limit int) *pageCursor {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return &pageCursor{skip: (page - 1) * limit, remaining: limit}
+}
+
+// accept consumes one match and reports whether it belongs on the page.
+func (c *pageCursor) accept() bool {
+	if c.skip > 0 {
+		c.skip--
+		return false
+	}
+	if c.remaining == 0 {
+		return false
+	}
+	c.remaining--
+	return true
+}
+
+// full reports whether the page needs no more matches.
+func (c *pageCursor) full() bool {
+	return c.remaining == 0
+}
+
+// loadConcurrency bounds the message reads a list query runs at once.
+const loadConcurrency = 8
+
+// loadMessages reads the messages of a page concurrently, keeping the order
+// of hashes and leaving out those read reports missing.
+func loadMessages(ctx context.Context, hashes []string, read func(hash string) (Message, bool)) ([]Message, error) {
+	messages := make([]Message, len(hashes))
+	found := make([]bool, len(hashes))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(loadConcurrency)
+	for i, hash := range hashes {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			messages[i], found[i] = read(hash)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	loaded := messages[:0]
+	for i, msg := range messages {
+		if found[i] {
+			loaded = append(loaded, msg)
+		}
+	}
+	if len(loaded) == 0 {
+		return nil, nil
+	}
+	return loaded, nil
+}
+
+// FieldFilter matches messages whose indexed field equals Value.
+type FieldFilter struct {
+	Field string
+	Value string
+}
+
+type LevelDBDatabase struct {
+	db   *leveldb.DB
+	mu   sync.RWMutex
+	path string
+	// sigBatch group commits signatures; nil writes each one directly.
+	sigBatch *signatureBatcher
+	sigCache *signatureCache
+}
+
+func NewLevelDBDatabase(path string) (*LevelDBDatabase, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LevelDB: %w", err)
+	}
+
+	return &LevelDBDatabase{
+		db:   db,
+		path: path,
+	}, nil
+}
+
+const (
+	dataPrefix       = "data:"
+	signaturePrefix  = "sig:"
+	trustedPrefix    = "trusted:"
+	dataStructPrefix = "ds:"
+	indexPrefix      = "index:"
+	latencyPrefix    = "lat:"
+	retentionPrefix  = "retention:"
+	rotationPrefix   = "rotation:"
+	epochPrefix      = "epoch:"
+	rewardPrefix     = "reward:"
+	usagePrefix      = "usage:"
+	// A confirmation refers to its signer set by content hash, so the many
+	// messages confirmed under one set share a single copy. Like every key
+	// naming a hash, confirmations sort after data:, which inspection needs.
+	confirmationPrefix = "proof:"
+	signerSetPrefix    = "signerset:"
+	peerPrefix         = "peer:"
+	statsPrefix        = "stats:"
+	// Field values are indexed a second time, ordered by timestamp, so a
+	// page of matches is a single key scan.
+	fieldTimePrefix = "fts:"
+)
+
+// statsKey holds the stats record of a data structure. Confirmed messages
+// are marked under confirmedPrefix so pruning knows which counts to adjust.
+func statsKey(dataStructureID int) []byte {
+	return []byte(fmt.Sprintf("%s%d", statsPrefix, dataStructureID))
+}
+
+func confirmedPrefix(dataStructureID int) []byte {
+	return []byte(fmt.Sprintf("%s%d:confirmed:", statsPrefix, dataStructureID))
+}
+
+func confirmedKey(dataStructureID int, hash string) []byte {
+	return append(confirmedPrefix(dataStructureID), hash...)
+}
+
+// timestampIndexRange covers the timestamp index of a data structure and none
+// of its field indexes, whose keys continue with a field name instead of
+// digits.
+func timestampIndexRange(dataStructureID int) *util.Range {
+	prefix := fmt.Sprintf("%s%d:", indexPrefix, dataStructureID)
+	return &util.Range{Start: []byte(prefix + "0"), Limit: []byte(prefix + ":")}
+}
+
+func fieldIndexPrefix(dataStructureID int, filter FieldFilter) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s:%v:", indexPrefix, dataStructureID, filter.Field, filter.Value))
+}
+
+func fieldTimeIndexPrefix(dataStructureID int, filter FieldFilter) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s:%v:", fieldTimePrefix, dataStructureID, filter.Field, filter.Value))
+}
+
+// fieldTimeIndexKey orders the messages matching a field value by timestamp,
+// zero-padded so that keys sort numerically:
+//
+//	fts:<dsid>:<field>:<value>:<timestamp>:<hash>
+func fieldTimeIndexKey(dataStructureID int, field string, value interface{}, timestamp int64, hash string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s:%v:%020d:%s", fieldTimePrefix, dataStructureID, field, value, timestamp, hash))
+}
+
+// secondaryIndexKeys builds the index keys of a message that were added after
+// messages were first stored, which RebuildIndexes backfills.
+func secondaryIndexKeys(dataStructureID int, msg Message) [][]byte {
+	var keys [][]byte
+	for field, value := range indexedFields(msg) {
+		keys = append(keys, fieldTimeIndexKey(dataStructureID, field, value, msg.Timestamp, msg.Hash))
+	}
+	return append(keys, numericIndexKeys(dataStructureID, msg.DataStructure, msg.DataStructureMeta, msg.Data, msg.Hash)...)
+}
+
+// indexedFields maps the field names of a message to their values. The
+// request ID is indexed like a field so a feed round can be looked up
+// through the regular field query.
+func indexedFields(msg Message) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for i, field := range msg.DataStructureMeta {
+		if i < len(msg.Data) {
+			fields[field] = msg.Data[i]
+		}
+	}
+	if msg.RequestID != "" {
+		fields["request_id"] = msg.RequestID
+	}
+	return fields
+}
+
+// messageIndexKeys builds the timestamp, field, field time, numeric and
+// sequence index keys of a message.
+func messageIndexKeys(dataStructureID int, msg Message) [][]byte {
+	keys := [][]byte{[]byte(fmt.Sprintf("%s%d:%d:%s", indexPrefix, dataStructureID, msg.Timestamp, msg.Hash))}
+	for field, value := range indexedFields(msg) {
+		keys = append(keys, []byte(fmt.Sprintf("%s%d:%s:%v:%s", indexPrefix, dataStructureID, field, value, msg.Hash)))
+	}
+
+	keys = append(keys, secondaryIndexKeys(dataStructureID, msg)...)
+	if msg.Sequence > 0 {
+		keys = append(keys, sequenceKey(dataStructureID, msg.Sequence, msg.Hash))
+	}
+	return keys
+}
+
+// parseTimestampKey splits a timestamp index key into its timestamp and
+// message hash.
+func parseTimestampKey(key []byte) (int64, string, bool) {
+	parts := strings.Split(string(key), ":")
+	if len(parts) != 4 {
+		return 0, "", false
+	}
+	timestamp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return timestamp, parts[3], true
+}
+
+// countFieldMatches counts the messages matching every filter. scan visits
+// the hash of each field index key under a prefix.
+func countFieldMatches(dataStructureID int, filters []FieldFilter, scan func(prefix []byte, visit func(hash string)) error) (int, error) {
+	var matched map[string]bool
+	for _, filter := range filters {
+		prefix := fieldIndexPrefix(dataStructureID, filter)
+		next := make(map[string]bool)
+		err := scan(prefix, func(hash string) {
+			if matched == nil || matched[hash] {
+				next[hash] = true
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
+		matched = next
+		if len(matched) == 0 {
+			break
+		}
+	}
+	return len(matched), nil
+}
+
+func (ldb *LevelDBDatabase) Close() error {
+	if ldb.sigBatch != nil {
+		ldb.sigBatch.close()
+	}
+	return ldb.db.Close()
+}
+
+func (ldb *LevelDBDatabase) StoreData(ctx context.Context, hash string, data []interface{}, dataStructure []string, dataStructureMeta []string, timestamp int64, dataStructureID int, hashVersion int, requestID string, sequence, epoch uint64) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	msg := Message{
+		Hash:              hash,
+		Data:              data,
+		DataStructure:     dataStructure,
+		DataStructureMeta: dataStructureMeta,
+		Timestamp:         timestamp,
+		HashVersion:       hashVersion,
+		RequestID:         requestID,
+		Sequence:          sequence,
+		Epoch:             epoch,
+		DataStructureID:   dataStructureID,
+	}
+
+	existed, err := ldb.db.Has([]byte(dataPrefix+hash), nil)
+	if err != nil {
+		return fmt.Errorf("failed to check message: %w", err)
+	}
+	if existed {
+		return ErrAlreadyStored
+	}
+
+	dsKey := []byte(dataStructPrefix + fmt.Sprintf("%d", dataStructureID))
+	if exists, _ := ldb.db.Has(dsKey, nil); !exists {
+		dsData, err := json.Marshal(dataStructure)
+		if err != nil {
+			return fmt.Errorf("failed to marshal data structure: %w", err)
+		}
+		if err := ldb.db.Put(dsKey, dsData, nil); err != nil {
+			return fmt.Errorf("failed to store data structure: %w", err)
+		}
+	}
+
+	msgData, err := encodeMessage(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	// Store by hash with data structure ID reference
+	if err := ldb.db.Put([]byte(dataPrefix+hash), msgData, nil); err != nil {
+		return fmt.Errorf("failed to store message by hash: %w", err)
+	}
+
+	for _, key := range messageIndexKeys(dataStructureID, msg) {
+		if err := ldb.db.Put(key, []byte{}, nil); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	if sequence > 0 {
+		head, err := ldb.lastSequence(dataStructureID)
+		if err != nil {
+			return err
+		}
+		if sequence > head {
+			if err := ldb.db.Put(sequenceHeadKey(dataStructureID), []byte(strconv.FormatUint(sequence, 10)), nil); err != nil {
+				return fmt.Errorf("failed to store sequence head: %w", err)
+			}
+		}
+	}
+
+	stats, err := ldb.readStats(dataStructureID)
+	if err != nil && !errors.Is(err, ErrNoStats) {
+		return err
+	}
+	stats.recordMessage(timestamp)
+	return ldb.writeStats(stats)
+}
+
+// readStats loads the stats record of a data structure. On ErrNoStats the
+// returned record is empty but usable. The caller holds mu.
+func (ldb *LevelDBDatabase) readStats(dataStructureID int) (DataStructureStats, error) {
+	stats := DataStructureStats{ID: dataStructureID}
+
+	data, err := ldb.db.Get(statsKey(dataStructureID), nil)
+	if err == leveldb.ErrNotFound {
+		return stats, ErrNoStats
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return stats, fmt.Errorf("failed to unmarshal stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (ldb *LevelDBDatabase) writeStats(stats DataStructureStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	if err := ldb.db.Put(statsKey(stats.ID), data, nil); err != nil {
+		return fmt.Errorf("failed to store stats: %w", err)
+	}
+	return nil
+}
+
+// MarkConfirmed records that a message reached its signature threshold,
+// along with its confirmation, which may be nil when unknown, e.g. for
+// messages replicated from an operator that did not record it. Repeated
+// calls for the same message are ignored.
+func (ldb *LevelDBDatabase) MarkConfirmed(ctx context.Context, dataStructureID int, hash string, timestamp int64, confirmation *Confirmation) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	key := confirmedKey(dataStructureID, hash)
+	if exists, _ := ldb.db.Has(key, nil); exists {
+		return nil
+	}
+
+	stats, err := ldb.readStats(dataStructureID)
+	if err != nil && !errors.Is(err, ErrNoStats) {
+		return err
+	}
+	stats.recordConfirmed(hash, timestamp)
+
+	batch := new(leveldb.Batch)
+	batch.Put(key, []byte(strconv.FormatInt(timestamp, 10)))
+	if confirmation != nil {
+		record, set, setID, err := encodeConfirmation(*confirmation)
+		if err != nil {
+			return err
+		}
+		batch.Put([]byte(signerSetPrefix+setID), set)
+		batch.Put([]byte(confirmationPrefix+hash), record)
+	}
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to mark message confirmed: %w", err)
+	}
+	return ldb.writeStats(stats)
+}
+
+func (ldb *LevelDBDatabase) GetConfirmation(ctx context.Context, hash string) (Confirmation, bool, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	record, err := ldb.db.Get([]byte(confirmationPrefix+hash), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return Confirmation{}, false, nil
+	}
+	if err != nil {
+		return Confirmation{}, false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	var r confirmationRecord
+	if err := json.Unmarshal(record, &r); err != nil {
+		return Confirmation{}, false, fmt.Errorf("failed to unmarshal confirmation: %w", err)
+	}
+	set, err := ldb.db.Get([]byte(signerSetPrefix+r.SignerSetID), nil)
+	if err != nil {
+		return Confirmation{}, false, fmt.Errorf("failed to read signer set %s: %w", r.SignerSetID, err)
+	}
+	c, err := decodeConfirmation(record, set)
+	return c, err == nil, err
+}
+
+func (ldb *LevelDBDatabase) IsConfirmed(ctx context.Context, dataStructureID int, hash string) bool {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	return ldb.liveView().confirmed(dataStructureID, hash)
+}
+
+func (ldb *LevelDBDatabase) StoreSignature(ctx context.Context, hash, signer, signature string) error {
+	if ldb.sigBatch != nil {
+		return ldb.sigBatch.store(ctx, hash, signer, signature)
+	}
+	return ldb.writeSignatures(signatureSet{hash: {signer: signature}})
+}
+
+// writeSignatures merges sigs into the stored signature maps with a single
+// batch write.
+func (ldb *LevelDBDatabase) writeSignatures(sigs signatureSet) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	merged := make(signatureSet, len(sigs))
+	for hash, added := range sigs {
+		sigKey := []byte(signaturePrefix + hash)
+		var stored map[string]string
+
+		if sigData, err := ldb.db.Get(sigKey, nil); err == nil {
+			if err := json.Unmarshal(sigData, &stored); err != nil {
+				return fmt.Errorf("failed to unmarshal signatures: %w", err)
+			}
+		} else if err != leveldb.ErrNotFound {
+			return fmt.Errorf("failed to get signatures: %w", err)
+		} else {
+			stored = make(map[string]string)
+		}
+
+		for signer, signature := range added {
+			stored[signer] = signature
+		}
+
+		sigData, err := json.Marshal(stored)
+		if err != nil {
+			return fmt.Errorf("failed to marshal signatures: %w", err)
+		}
+		batch.Put(sigKey, sigData)
+		merged[hash] = stored
+	}
+
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to store signatures: %w", err)
+	}
+
+	for hash, stored := range merged {
+		ldb.sigCache.put(hash, stored)
+	}
+	return nil
+}
+
+func (ldb *LevelDBDatabase) GetData(ctx context.Context, hash string) ([]interface{}, []string, []string, int64, bool) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, nil, nil, 0, false
+	}
+	defer view.Release()
+
+	data, err := view.Get([]byte(dataPrefix+hash), nil)
+	if err != nil {
+		return nil, nil, nil, 0, false
+	}
+
+	var msg Message
+	if err := decodeMessage(data, &msg); err != nil {
+		return nil, nil, nil, 0, false
+	}
+
+	return msg.Data, msg.DataStructure, msg.DataStructureMeta, msg.Timestamp, true
+}
+
+// GetMessage returns the stored message with its signatures attached.
+func (ldb *LevelDBDatabase) GetMessage(ctx context.Context, hash string) (Message, bool) {
+	view, err := ldb.view()
+	if err != nil {
+		return Message{}, false
+	}
+	defer view.Release()
+
+	return view.message(hash)
+}
+
+func (ldb *LevelDBDatabase) GetSignatures(ctx context.Context, hash string) (map[string]string, bool) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, false
+	}
+	defer view.Release()
+
+	return view.signatures(hash)
+}
+
+// GetAllMessages returns the messages of a data structure newest first.
+func (ldb *LevelDBDatabase) GetAllMessages(ctx context.Context, dataStructureID int, page, limit int) ([]Message, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	var hashes []string
+	cursor := newPageCursor(page, limit)
+
+	iter := view.NewIterator(timestampIndexRange(dataStructureID), nil)
+	defer iter.Release()
+
+	for ok := iter.Last(); ok && !cursor.full(); ok = iter.Prev() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, hash, valid := parseTimestampKey(iter.Key())
+		if !valid {
+			continue
+		}
+
+		if exists, err := view.Has([]byte(dataPrefix+hash), nil); err != nil || !exists {
+			continue
+		}
+
+		if !cursor.accept() {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	return loadMessages(ctx, hashes, view.message)
+}
+
+func (ldb *LevelDBDatabase) GetLatestMessage(ctx context.Context, dataStructureID int) (Message, bool, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return Message{}, false, err
+	}
+	defer view.Release()
+
+	var prefix []byte
+	prefix = []byte(fmt.Sprintf("%s%d:", indexPrefix, dataStructureID))
+
+	iter := view.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	if !iter.Last() {
+		return Message{}, false, leveldb.ErrNotFound
+	}
+
+	key := string(iter.Key())
+	var hash string
+
+	parts := strings.Split(key, ":")
+	if len(parts) < 4 {
+		return Message{}, false, fmt.Errorf("invalid index key format")
+	}
+	hash = parts[3]
+
+	data, err := view.Get([]byte(dataPrefix+hash), nil)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	var msg Message
+	if err := decodeMessage(data, &msg); err != nil {
+		return Message{}, false, err
+	}
+
+	sigs, exists := view.signatures(msg.Hash)
+	if exists {
+		msg.Signatures = sigs
+		return msg, true, nil
+	}
+
+	return msg, false, nil
+}
+
+// timestampIndexStart positions a timestamp index scan at from. Timestamp
+// keys sort numerically while they have the same number of digits, which
+// holds for unix seconds until the year 2286.
+func timestampIndexStart(dataStructureID int, from int64) []byte {
+	return []byte(fmt.Sprintf("%s%d:%d", indexPrefix, dataStructureID, from))
+}
+
+// IterateMessages calls fn for each message with a timestamp in [from, to],
+// oldest first, until fn returns false. A zero to means no upper bound. It
+// reads from a snapshot without holding the lock, so a slow consumer does
+// not stall writers.
+func (ldb *LevelDBDatabase) IterateMessages(ctx context.Context, dataStructureID int, from, to int64, fn func(Message) bool) error {
+	view, err := ldb.view()
+	if err != nil {
+		return err
+	}
+	defer view.Release()
+
+	rng := timestampIndexRange(dataStructureID)
+	if from > 0 {
+		rng.Start = timestampIndexStart(dataStructureID, from)
+	}
+	iter := view.NewIterator(rng, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		timestamp, hash, ok := parseTimestampKey(iter.Key())
+		if !ok || timestamp < from {
+			continue
+		}
+		if to > 0 && timestamp > to {
+			break
+		}
+
+		msg, ok := view.message(hash)
+		if !ok {
+			continue
+		}
+
+		if !fn(msg) {
+			break
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to scan index: %w", err)
+	}
+	return nil
+}
+
+// GetLatestConfirmed returns the newest message marked confirmed.
+func (ldb *LevelDBDatabase) GetLatestConfirmed(ctx context.Context, dataStructureID int) (Message, bool, error) {
+	messages, err := ldb.GetConfirmedMessages(ctx, dataStructureID, nil, nil, 1, 1)
+	if err != nil || len(messages) == 0 {
+		return Message{}, false, err
+	}
+	return messages[0], true, nil
+}
+
+// GetMessagesByField returns messages whose field equals value, newest first,
+// walking the field time index backwards.
+func (ldb *LevelDBDatabase) GetMessagesByField(ctx context.Context, dataStructureID int, field, value string, page, limit int) ([]Message, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	var hashes []string
+	cursor := newPageCursor(page, limit)
+
+	iter := view.NewIterator(util.BytesPrefix(fieldTimeIndexPrefix(dataStructureID, FieldFilter{Field: field, Value: value})), nil)
+	defer iter.Release()
+
+	for ok := iter.Last(); ok && !cursor.full(); ok = iter.Prev() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		key := string(iter.Key())
+		hash := key[strings.LastIndex(key, ":")+1:]
+
+		if exists, err := view.Has([]byte(dataPrefix+hash), nil); err != nil || !exists {
+			continue
+		}
+		if !cursor.accept() {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan field index: %w", err)
+	}
+
+	return loadMessages(ctx, hashes, view.message)
+}
+
+// GetLatestByField returns the newest message marked confirmed whose field
+// equals value.
+func (ldb *LevelDBDatabase) GetLatestByField(ctx context.Context, dataStructureID int, field, value string) (Message, bool, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return Message{}, false, err
+	}
+	defer view.Release()
+
+	prefix := []byte(fmt.Sprintf("%s%d:%s:%v:", indexPrefix, dataStructureID, field, value))
+	iter := view.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	var latest Message
+	found := false
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return Message{}, false, err
+		}
+		key := string(iter.Key())
+		messageID := key[len(prefix):]
+
+		data, err := view.Get([]byte(dataPrefix+messageID), nil)
+		if err != nil {
+			continue
+		}
+
+		var msg Message
+		if err := decodeMessage(data, &msg); err != nil {
+			continue
+		}
+
+		if !view.confirmed(dataStructureID, msg.Hash) {
+			continue
+		}
+		if !found || msg.Timestamp > latest.Timestamp {
+			if sigs, exists := view.signatures(msg.Hash); exists {
+				msg.Signatures = sigs
+			}
+			latest = msg
+			found = true
+		}
+	}
+
+	return latest, found, nil
+}
+
+// GetMessagesByRange returns messages within every range filter and equal to
+// every field filter, in ascending order of the first range's field. Only the
+// first range is served from the index; the rest are checked per message.
+func (ldb *LevelDBDatabase) GetMessagesByRange(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("at least one range filter is required")
+	}
+
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	prefix := numericFieldPrefix(dataStructureID, ranges[0].Field)
+	start, limitKey := ranges[0].bounds(dataStructureID)
+	iter := view.NewIterator(&util.Range{Start: start, Limit: limitKey}, nil)
+	defer iter.Release()
+
+	for !cursor.full() && iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash, ok := numericKeyHash(iter.Key(), prefix)
+		if !ok {
+			continue
+		}
+
+		data, err := view.Get([]byte(dataPrefix+hash), nil)
+		if err != nil {
+			continue
+		}
+
+		var msg Message
+		if err := decodeMessage(data, &msg); err != nil {
+			continue
+		}
+		if !matchesQuery(msg, ranges[1:], equals) || !cursor.accept() {
+			continue
+		}
+
+		if sigs, exists := view.signatures(hash); exists {
+			msg.Signatures = sigs
+		}
+
+		messages = append(messages, msg)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan numeric index: %w", err)
+	}
+
+	return messages, nil
+}
+
+// GetConfirmedMessages returns messages marked confirmed, newest first,
+// optionally narrowed by range and field filters.
+func (ldb *LevelDBDatabase) GetConfirmedMessages(ctx context.Context, dataStructureID int, ranges []RangeFilter, equals []FieldFilter, page, limit int) ([]Message, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	var messages []Message
+	cursor := newPageCursor(page, limit)
+
+	iter := view.NewIterator(timestampIndexRange(dataStructureID), nil)
+	defer iter.Release()
+
+	for ok := iter.Last(); ok && !cursor.full(); ok = iter.Prev() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, hash, valid := parseTimestampKey(iter.Key())
+		if !valid {
+			continue
+		}
+
+		if !view.confirmed(dataStructureID, hash) {
+			continue
+		}
+
+		msg, ok := view.message(hash)
+		if !ok || !matchesQuery(msg, ranges, equals) || !cursor.accept() {
+			continue
+		}
+
+		messages = append(messages, msg)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	return messages, nil
+}
+
+// RebuildIndexes writes the field time and numeric index keys of every stored
+// message of a data structure and returns how many messages it indexed.
+func (ldb *LevelDBDatabase) RebuildIndexes(ctx context.Context, dataStructureID int) (int, error) {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	indexed := 0
+
+	iter := ldb.db.NewIterator(timestampIndexRange(dataStructureID), nil)
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			iter.Release()
+			return 0, err
+		}
+		_, hash, ok := parseTimestampKey(iter.Key())
+		if !ok {
+			continue
+		}
+
+		data, err := ldb.db.Get([]byte(dataPrefix+hash), nil)
+		if err != nil {
+			continue
+		}
+		var msg Message
+		if err := decodeMessage(data, &msg); err != nil {
+			continue
+		}
+		msg.Hash = hash
+
+		for _, key := range secondaryIndexKeys(dataStructureID, msg) {
+			batch.Put(key, []byte{})
+		}
+		indexed++
+
+		if batch.Len() >= restoreBatchSize {
+			if err := ldb.db.Write(batch, nil); err != nil {
+				iter.Release()
+				return 0, fmt.Errorf("failed to write indexes: %w", err)
+			}
+			batch.Reset()
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return 0, fmt.Errorf("failed to write indexes: %w", err)
+	}
+	return indexed, nil
+}
+
+func (ldb *LevelDBDatabase) GetDataStructures(ctx context.Context) ([]int, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	var ids []int
+	iter := ldb.db.NewIterator(util.BytesPrefix([]byte(dataStructPrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		key := string(iter.Key())
+		idStr := strings.TrimPrefix(key, dataStructPrefix)
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (ldb *LevelDBDatabase) GetDataStructureStats(ctx context.Context, id int) (DataStructureStats, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	return ldb.readStats(id)
+}
+
+// RebuildStats recomputes the stats of a data structure from its index,
+// counting messages with at least threshold signatures as confirmed.
+func (ldb *LevelDBDatabase) RebuildStats(ctx context.Context, dataStructureID, threshold int) (DataStructureStats, error) {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	stats := DataStructureStats{ID: dataStructureID}
+	batch := new(leveldb.Batch)
+
+	iter := ldb.db.NewIterator(util.BytesPrefix(confirmedPrefix(dataStructureID)), nil)
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			iter.Release()
+			return stats, err
+		}
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return stats, fmt.Errorf("failed to scan confirmed messages: %w", err)
+	}
+
+	live := ldb.liveView()
+	iter = ldb.db.NewIterator(timestampIndexRange(dataStructureID), nil)
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			iter.Release()
+			return stats, err
+		}
+		timestamp, hash, ok := parseTimestampKey(iter.Key())
+		if !ok {
+			continue
+		}
+		stats.recordMessage(timestamp)
+		if sigs, exists := live.signatures(hash); exists && len(sigs) >= threshold {
+			stats.recordConfirmed(hash, timestamp)
+			batch.Put(confirmedKey(dataStructureID, hash), []byte(strconv.FormatInt(timestamp, 10)))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return stats, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return stats, fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	batch.Put(statsKey(dataStructureID), data)
+
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return stats, fmt.Errorf("failed to store stats: %w", err)
+	}
+	return stats, nil
+}
+
+// CountMessages counts the messages of a data structure, or with filters
+// those matching every field filter, from the indexes alone.
+func (ldb *LevelDBDatabase) CountMessages(ctx context.Context, dataStructureID int, filters ...FieldFilter) (int, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	if len(filters) > 0 {
+		return countFieldMatches(dataStructureID, filters, func(prefix []byte, visit func(string)) error {
+			iter := ldb.db.NewIterator(util.BytesPrefix(prefix), nil)
+			defer iter.Release()
+
+			for iter.Next() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				visit(string(iter.Key()[len(prefix):]))
+			}
+			return iter.Error()
+		})
+	}
+
+	iter := ldb.db.NewIterator(timestampIndexRange(dataStructureID), nil)
+	defer iter.Release()
+
+	count := 0
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+	return count, nil
+}
+
+// CountConfirmed counts the messages of a data structure marked confirmed.
+func (ldb *LevelDBDatabase) CountConfirmed(ctx context.Context, dataStructureID int) (int, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return 0, err
+	}
+	defer view.Release()
+
+	iter := view.NewIterator(timestampIndexRange(dataStructureID), nil)
+	defer iter.Release()
+
+	count := 0
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		_, hash, ok := parseTimestampKey(iter.Key())
+		if !ok {
+			continue
+		}
+		if view.confirmed(dataStructureID, hash) {
+			count++
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+	return count, nil
+}
+
+func (ldb *LevelDBDatabase) HasData(ctx context.Context, hash string) bool {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	exists, _ := ldb.db.Has([]byte(dataPrefix+hash), nil)
+	return exists
+}
+
+func (ldb *LevelDBDatabase) SearchByHashPrefix(ctx context.Context, prefix string, limit int) ([]Message, error) {
+	var messages []Message
+	if limit < 1 {
+		return messages, nil
+	}
+
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	keyPrefix := []byte(dataPrefix + prefix)
+	iter := view.NewIterator(util.BytesPrefix(keyPrefix), nil)
+	defer iter.Release()
+
+	var hashes []string
+	for len(hashes) < limit && iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, string(iter.Key()[len(dataPrefix):]))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan messages: %w", err)
+	}
+
+	return loadMessages(ctx, hashes, view.message)
+}
+
+func (ldb *LevelDBDatabase) StoreLatency(ctx context.Context, hash string, latency MessageLatency) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	data, err := json.Marshal(latency)
+	if err != nil {
+		return fmt.Errorf("failed to marshal latency: %w", err)
+	}
+
+	if err := ldb.db.Put([]byte(latencyPrefix+hash), data, nil); err != nil {
+		return fmt.Errorf("failed to store latency: %w", err)
+	}
+
+	return nil
+}
+
+func (ldb *LevelDBDatabase) GetLatency(ctx context.Context, hash string) (MessageLatency, bool) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	data, err := ldb.db.Get([]byte(latencyPrefix+hash), nil)
+	if err != nil {
+		return MessageLatency{}, false
+	}
+
+	var latency MessageLatency
+	if err := json.Unmarshal(data, &latency); err != nil {
+		return MessageLatency{}, false
+	}
+
+	return latency, true
+}
+
+// Backup streams a point-in-time snapshot of the whole store. Writers are not
+// blocked while the archive is produced.
+func (ldb *LevelDBDatabase) Backup(ctx context.Context, w io.Writer) error {
+	snap, err := ldb.db.GetSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	defer snap.Release()
+
+	sw, err := newSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+
+	iter := snap.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sw.Write(iter.Key(), iter.Value(), 0); err != nil {
+			return fmt.Errorf("failed to write snapshot record: %w", err)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate snapshot: %w", err)
+	}
+
+	return sw.Close()
+}
+
+// Restore loads every record of a snapshot archive into the store, which
+// must be empty. LevelDB keeps no expiry, so records that carry one are
+// restored for the pruner to remove.
+func (ldb *LevelDBDatabase) Restore(ctx context.Context, r io.Reader) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	iter := ldb.db.NewIterator(nil, nil)
+	empty := !iter.First()
+	iter.Release()
+	if !empty {
+		return ErrStoreNotEmpty
+	}
+
+	sr, err := newSnapshotReader(r)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, _, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot record: %w", err)
+		}
+
+		batch.Put(key, value)
+		if batch.Len() >= restoreBatchSize {
+			if err := ldb.db.Write(batch, nil); err != nil {
+				return fmt.Errorf("failed to write restore batch: %w", err)
+			}
+			batch.Reset()
+		}
+	}
+
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write restore batch: %w", err)
+	}
+
+	ldb.sigCache.purge()
+	return nil
+}
+
+// SetRetention records the retention policy of a data structure in the
+// registry. A zero duration keeps messages forever.
+func (ldb *LevelDBDatabase) SetRetention(ctx context.Context, dataStructureID int, retention time.Duration) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	key := []byte(fmt.Sprintf("%s%d", retentionPrefix, dataStructureID))
+	if retention <= 0 {
+		if err := ldb.db.Delete(key, nil); err != nil {
+			return fmt.Errorf("failed to clear retention: %w", err)
+		}
+		return nil
+	}
+
+	if err := ldb.db.Put(key, []byte(strconv.FormatInt(int64(retention/time.Second), 10)), nil); err != nil {
+		return fmt.Errorf("failed to store retention: %w", err)
+	}
+	return nil
+}
+
+func (ldb *LevelDBDatabase) GetRetention(ctx context.Context, dataStructureID int) (time.Duration, bool) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	data, err := ldb.db.Get([]byte(fmt.Sprintf("%s%d", retentionPrefix, dataStructureID)), nil)
+	if err != nil {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+func (ldb *LevelDBDatabase) StoreKeyRotation(ctx context.Context, rotation KeyRotation) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	data, err := json.Marshal(rotation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key rotation: %w", err)
+	}
+
+	key := []byte(rotationPrefix + strings.ToLower(rotation.OldAddress) + ":" + strings.ToLower(rotation.NewAddress))
+	if err := ldb.db.Put(key, data, nil); err != nil {
+		return fmt.Errorf("failed to store key rotation: %w", err)
+	}
+	return nil
+}
+
+func (ldb *LevelDBDatabase) StoreSignerSet(ctx context.Context, set SignerSet) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signer set: %w", err)
+	}
+	if err := ldb.db.Put(signerSetKey(set.Epoch), data, nil); err != nil {
+		return fmt.Errorf("failed to store signer set: %w", err)
+	}
+	return nil
+}
+
+// AddParticipation counts one signature on a confirmed message for each of
+// signers in a reward period.
+func (ldb *LevelDBDatabase) AddParticipation(ctx context.Context, period uint64, signers []string) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	added := make(map[string]int, len(signers))
+	for _, signer := range signers {
+		added[strings.ToLower(signer)]++
+	}
+	for signer, n := range added {
+		key := participationKey(period, signer)
+		count := 0
+		if data, err := ldb.db.Get(key, nil); err == nil {
+			if count, err = strconv.Atoi(string(data)); err != nil {
+				return fmt.Errorf("failed to parse participation of %s: %w", signer, err)
+			}
+		} else if !errors.Is(err, leveldb.ErrNotFound) {
+			return fmt.Errorf("failed to read participation: %w", err)
+		}
+		batch.Put(key, []byte(strconv.Itoa(count+n)))
+	}
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to store participation: %w", err)
+	}
+	return nil
+}
+
+// GetParticipation returns the signature counts of a reward period, keyed by
+// lowercased signer.
+func (ldb *LevelDBDatabase) GetParticipation(ctx context.Context, period uint64) (map[string]int, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	prefix := participationPrefix(period)
+	counts := make(map[string]int)
+	iter := ldb.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		count, err := strconv.Atoi(string(iter.Value()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse participation: %w", err)
+		}
+		counts[string(iter.Key()[len(prefix):])] = count
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate participation: %w", err)
+	}
+	return counts, nil
+}
+
+// AddUsage adds to the usage of each API key in usage on day.
+func (ldb *LevelDBDatabase) AddUsage(ctx context.Context, day string, usage map[string]Usage) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	for apiKey, u := range usage {
+		key := usageKey(day, apiKey)
+		var total Usage
+		if data, err := ldb.db.Get(key, nil); err == nil {
+			if err := json.Unmarshal(data, &total); err != nil {
+				return fmt.Errorf("failed to decode usage of %s: %w", apiKey, err)
+			}
+		} else if !errors.Is(err, leveldb.ErrNotFound) {
+			return fmt.Errorf("failed to read usage: %w", err)
+		}
+		data, err := json.Marshal(total.Add(u))
+		if err != nil {
+			return fmt.Errorf("failed to encode usage: %w", err)
+		}
+		batch.Put(key, data)
+	}
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to store usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage returns the usage of the days from through to, both YYYYMMDD and
+// inclusive, keyed by day and then API key.
+func (ldb *LevelDBDatabase) GetUsage(ctx context.Context, from, to string) (map[string]map[string]Usage, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	usage := make(map[string]map[string]Usage)
+	iter := ldb.db.NewIterator(&util.Range{Start: []byte(usagePrefix + from), Limit: []byte(usagePrefix + to + ";")}, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		day, apiKey, ok := parseUsageKey(iter.Key())
+		if !ok {
+			continue
+		}
+		var u Usage
+		if err := json.Unmarshal(iter.Value(), &u); err != nil {
+			return nil, fmt.Errorf("failed to decode usage: %w", err)
+		}
+		if usage[day] == nil {
+			usage[day] = make(map[string]Usage)
+		}
+		usage[day][apiKey] = u
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (ldb *LevelDBDatabase) GetSignerSet(ctx context.Context, epoch uint64) (SignerSet, bool, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	data, err := ldb.db.Get(signerSetKey(epoch), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return SignerSet{}, false, nil
+	}
+	if err != nil {
+		return SignerSet{}, false, fmt.Errorf("failed to read signer set: %w", err)
+	}
+	var set SignerSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return SignerSet{}, false, fmt.Errorf("failed to unmarshal signer set: %w", err)
+	}
+	return set, true, nil
+}
+
+// GetKeyRotations returns every recorded rotation, oldest announcement
+// first.
+func (ldb *LevelDBDatabase) GetKeyRotations(ctx context.Context) ([]KeyRotation, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	var rotations []KeyRotation
+	iter := ldb.db.NewIterator(util.BytesPrefix([]byte(rotationPrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var rotation KeyRotation
+		if err := json.Unmarshal(iter.Value(), &rotation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal key rotation: %w", err)
+		}
+		rotations = append(rotations, rotation)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate key rotations: %w", err)
+	}
+
+	sort.Slice(rotations, func(i, j int) bool {
+		return rotations[i].AnnouncedAt < rotations[j].AnnouncedAt
+	})
+	return rotations, nil
+}
+
+func (ldb *LevelDBDatabase) StorePeer(ctx context.Context, record PeerRecord) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal peer record: %w", err)
+	}
+
+	if err := ldb.db.Put([]byte(peerPrefix+record.ID), data, nil); err != nil {
+		return fmt.Errorf("failed to store peer record: %w", err)
+	}
+	return nil
+}
+
+// GetPeers returns every recorded peer, most recently seen first.
+func (ldb *LevelDBDatabase) GetPeers(ctx context.Context) ([]PeerRecord, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	var peers []PeerRecord
+	iter := ldb.db.NewIterator(util.BytesPrefix([]byte(peerPrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record PeerRecord
+		if err := json.Unmarshal(iter.Value(), &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal peer record: %w", err)
+		}
+		peers = append(peers, record)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate peer records: %w", err)
+	}
+
+	sortPeers(peers)
+	return peers, nil
+}
+
+func (ldb *LevelDBDatabase) DeletePeer(ctx context.Context, id string) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	if err := ldb.db.Delete([]byte(peerPrefix+id), nil); err != nil {
+		return fmt.Errorf("failed to delete peer record: %w", err)
+	}
+	return nil
+}
+
+func (ldb *LevelDBDatabase) StoreDeadLetter(ctx context.Context, letter DeadLetter) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	if err := ldb.db.Put(deadLetterKey(letter.ID), data, nil); err != nil {
+		return fmt.Errorf("failed to store dead letter: %w", err)
+	}
+	return nil
+}
+
+func (ldb *LevelDBDatabase) GetDeadLetter(ctx context.Context, id string) (DeadLetter, bool, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	data, err := ldb.db.Get(deadLetterKey(id), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return DeadLetter{}, false, nil
+	}
+	if err != nil {
+		return DeadLetter{}, false, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	var letter DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return DeadLetter{}, false, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return letter, true, nil
+}
+
+// GetDeadLetters returns up to limit dead letters recorded for reason, or
+// for any reason when it is empty, oldest first.
+func (ldb *LevelDBDatabase) GetDeadLetters(ctx context.Context, reason string, limit int) ([]DeadLetter, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	var letters []DeadLetter
+	iter := ldb.db.NewIterator(util.BytesPrefix([]byte(deadLetterPrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() && len(letters) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(iter.Value(), &letter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		if keepDeadLetter(letter, reason) {
+			letters = append(letters, letter)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+func (ldb *LevelDBDatabase) DeleteDeadLetter(ctx context.Context, id string) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	if err := ldb.db.Delete(deadLetterKey(id), nil); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// LastSequence returns the highest sequence number ever stored for a data
+// structure, or 0 when none was. Pruning does not lower it.
+func (ldb *LevelDBDatabase) LastSequence(ctx context.Context, dataStructureID int) (uint64, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	return ldb.lastSequence(dataStructureID)
+}
+
+// lastSequence reads the sequence head. The caller holds mu.
+func (ldb *LevelDBDatabase) lastSequence(dataStructureID int) (uint64, error) {
+	data, err := ldb.db.Get(sequenceHeadKey(dataStructureID), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sequence head: %w", err)
+	}
+	return strconv.ParseUint(string(data), 10, 64)
+}
+
+// GetSequenceInfo walks the sequence index of a data structure to find the
+// stored range and the gaps in it.
+func (ldb *LevelDBDatabase) GetSequenceInfo(ctx context.Context, dataStructureID int) (SequenceInfo, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	info := SequenceInfo{DataStructureID: dataStructureID, Gaps: []SequenceGap{}}
+	iter := ldb.db.NewIterator(util.BytesPrefix(sequencePrefixFor(dataStructureID)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return SequenceInfo{}, err
+		}
+		seq, _, err := parseSequenceKey(iter.Key())
+		if err != nil {
+			continue
+		}
+		info.observe(seq)
+	}
+	if err := iter.Error(); err != nil {
+		return SequenceInfo{}, fmt.Errorf("failed to scan sequence index: %w", err)
+	}
+	return info, nil
+}
+
+// GetMessagesBySequence returns up to limit messages of a data structure
+// with a sequence number in [from, to], lowest first, with confirmedOnly
+// only those marked confirmed. A zero to means no upper bound.
+func (ldb *LevelDBDatabase) GetMessagesBySequence(ctx context.Context, dataStructureID int, from, to uint64, confirmedOnly bool, limit int) ([]Message, error) {
+	view, err := ldb.view()
+	if err != nil {
+		return nil, err
+	}
+	defer view.Release()
+
+	var messages []Message
+	if limit < 1 {
+		return messages, nil
+	}
+
+	prefix := sequencePrefixFor(dataStructureID)
+	iter := view.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for ok := iter.Seek(sequenceKey(dataStructureID, from, "")); ok && len(messages) < limit; ok = iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		seq, hash, err := parseSequenceKey(iter.Key())
+		if err != nil {
+			continue
+		}
+		if to > 0 && seq > to {
+			break
+		}
+
+		if confirmedOnly && !view.confirmed(dataStructureID, hash) {
+			continue
+		}
+		msg, ok := view.message(hash)
+		if !ok {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan sequence index: %w", err)
+	}
+	return messages, nil
+}
+
+// PruneMessages deletes messages of a data structure with a timestamp older
+// than before, together with their signatures, latency records and indexes.
+func (ldb *LevelDBDatabase) PruneMessages(ctx context.Context, dataStructureID int, before int64) (int, error) {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	prefix := []byte(fmt.Sprintf("%s%d:", indexPrefix, dataStructureID))
+
+	expired := make(map[string]bool)
+	iter := ldb.db.NewIterator(util.BytesPrefix(prefix), nil)
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			iter.Release()
+			return 0, err
+		}
+		parts := strings.Split(string(iter.Key()), ":")
+		if len(parts) != 4 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		if timestamp < before {
+			expired[parts[3]] = true
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("failed to scan index: %w", err)
+	}
+
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	batch := new(leveldb.Batch)
+
+	// Field, field time, numeric and sequence index keys end with the message
+	// hash as well, so a second pass catches them without having to rebuild
+	// each key from the stored data.
+	for _, indexed := range [][]byte{prefix, []byte(fmt.Sprintf("%s%d:", fieldTimePrefix, dataStructureID)), []byte(fmt.Sprintf("%s%d:", numericIndexPrefix, dataStructureID)), sequencePrefixFor(dataStructureID)} {
+		iter = ldb.db.NewIterator(util.BytesPrefix(indexed), nil)
+		for iter.Next() {
+			if err := ctx.Err(); err != nil {
+				iter.Release()
+				return 0, err
+			}
+			key := string(iter.Key())
+			hash := key[strings.LastIndex(key, ":")+1:]
+			if expired[hash] {
+				batch.Delete([]byte(key))
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return 0, fmt.Errorf("failed to scan index: %w", err)
+		}
+	}
+
+	stats, err := ldb.readStats(dataStructureID)
+	if err != nil && !errors.Is(err, ErrNoStats) {
+		return 0, err
+	}
+
+	for hash := range expired {
+		batch.Delete([]byte(dataPrefix + hash))
+		batch.Delete([]byte(signaturePrefix + hash))
+		batch.Delete([]byte(latencyPrefix + hash))
+		batch.Delete([]byte(confirmationPrefix + hash))
+
+		if exists, _ := ldb.db.Has(confirmedKey(dataStructureID, hash), nil); exists {
+			batch.Delete(confirmedKey(dataStructureID, hash))
+			stats.ConfirmedCount--
+		}
+	}
+
+	// Only old messages are pruned, so the newest message survives unless
+	// everything went, and a pruned last confirmed message means no
+	// confirmed message is left.
+	stats.MessageCount = max(stats.MessageCount-len(expired), 0)
+	stats.ConfirmedCount = max(stats.ConfirmedCount, 0)
+	if stats.MessageCount == 0 {
+		stats.LastMessageTime = 0
+	}
+	if expired[stats.LastConfirmedHash] {
+		stats.LastConfirmedTime = 0
+		stats.LastConfirmedHash = ""
+	}
+	if err == nil {
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal stats: %w", err)
+		}
+		batch.Put(statsKey(dataStructureID), data)
+	}
+
+	if err := ldb.db.Write(batch, nil); err != nil {
+		return 0, fmt.Errorf("failed to prune messages: %w", err)
+	}
+
+	for hash := range expired {
+		ldb.sigCache.remove(hash)
+	}
+	return len(expired), nil
+}
+
+// MessageLatency holds the pipeline timestamps of a single message in unix
+// milliseconds. Zero means the stage has not been reached.
+type MessageLatency struct {
+	BuiltAt          int64 `json:"built_at"`
+	PublishedAt      int64 `json:"published_at,omitempty"`
+	FirstSignatureAt int64 `json:"first_signature_at,omitempty"`
+	ThresholdAt      int64 `json:"threshold_at,omitempty"`
+}
+
+// KeyRotation moves a signer seat from OldAddress to NewAddress. The signer
+// announces it signed with the old key; during the overlap window either key
+// counts for the seat, after which only the new key is trusted.
+type KeyRotation struct {
+	Type       string `json:"type"`
+	OldAddress string `json:"old_address"`
+	NewAddress string `json:"new_address"`
+	Timestamp  int64  `json:"timestamp"`
+	Signature  string `json:"signature"`
+
+	// Set by the operator when it records the rotation.
+	AnnouncedAt  int64 `json:"announced_at,omitempty"`
+	OverlapUntil int64 `json:"overlap_until,omitempty"`
+	Completed    bool  `json:"completed,omitempty"`
+}
+
+// SignerSet is the trusted set of one staking epoch and the weights derived
+// from its stakes, kept so that proofs can be checked against the signers
+// of their epoch long after it ended.
+type SignerSet struct {
+	Epoch    uint64            `json:"epoch"`
+	StartsAt int64             `json:"starts_at"`
+	Signers  []string          `json:"signers"`
+	Weights  map[string]uint64 `json:"weights,omitempty"`
+	// Block is the chain height the stakes were read at.
+	Block uint64 `json:"block,omitempty"`
+}
+
+func signerSetKey(epoch uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", epochPrefix, epoch))
+}
+
+// Usage is what one API key consumed in a day.
+type Usage struct {
+	Requests      int64 `json:"requests"`
+	Bytes         int64 `json:"bytes"`
+	StreamSeconds int64 `json:"stream_seconds"`
+}
+
+// Add returns the sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		Requests:      u.Requests + o.Requests,
+		Bytes:         u.Bytes + o.Bytes,
+		StreamSeconds: u.StreamSeconds + o.StreamSeconds,
+	}
+}
+
+// usageKey files the usage of an API key under its day, written as
+// YYYYMMDD so that days sort in order.
+func usageKey(day, key string) []byte {
+	return []byte(usagePrefix + day + ":" + key)
+}
+
+// parseUsageKey splits a usage key into its day and API key.
+func parseUsageKey(key []byte) (day, apiKey string, ok bool) {
+	return strings.Cut(strings.TrimPrefix(string(key), usagePrefix), ":")
+}
+
+// participationPrefix covers the signature counts of a reward period;
+// participationKey adds the lowercased signer.
+func participationPrefix(period uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d:", rewardPrefix, period))
+}
+
+func participationKey(period uint64, signer string) []byte {
+	return append(participationPrefix(period), strings.ToLower(signer)...)
+}
+
+// Confirmation is the signer set and thresholds a message was confirmed
+// under, kept so that its proof can be checked after the set changed.
+type Confirmation struct {
+	// SignerSetID identifies the signer set; messages confirmed under the
+	// same set share it.
+	SignerSetID string            `json:"signer_set_id,omitempty"`
+	Signers     []string          `json:"signers"`
+	Weights     map[string]uint64 `json:"weights,omitempty"`
+	// Aliases maps keys rotating into a seat to the signer they sign for.
+	Aliases         map[string]string `json:"aliases,omitempty"`
+	Threshold       int               `json:"threshold"`
+	WeightThreshold uint64            `json:"weight_threshold,omitempty"`
+	Epoch           uint64            `json:"epoch,omitempty"`
+	ConfirmedAt     int64             `json:"confirmed_at"`
+}
+
+// confirmationRecord is how a Confirmation is stored, its signers in a
+// separate signerSetRecord.
+type confirmationRecord struct {
+	SignerSetID     string `json:"signer_set_id"`
+	Threshold       int    `json:"threshold"`
+	WeightThreshold uint64 `json:"weight_threshold,omitempty"`
+	Epoch           uint64 `json:"epoch,omitempty"`
+	ConfirmedAt     int64  `json:"confirmed_at"`
+}
+
+type signerSetRecord struct {
+	Signers []string          `json:"signers"`
+	Weights map[string]uint64 `json:"weights,omitempty"`
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// encodeConfirmation splits c into its record and signer set, keyed by the
+// set's content hash.
+func encodeConfirmation(c Confirmation) (record, set []byte, setID string, err error) {
+	set, err = json.Marshal(signerSetRecord{Signers: c.Signers, Weights: c.Weights, Aliases: c.Aliases})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to marshal signer set: %w", err)
+	}
+	sum := sha256.Sum256(set)
+	setID = hex.EncodeToString(sum[:])
+	record, err = json.Marshal(confirmationRecord{
+		SignerSetID:     setID,
+		Threshold:       c.Threshold,
+		WeightThreshold: c.WeightThreshold,
+		Epoch:           c.Epoch,
+		ConfirmedAt:     c.ConfirmedAt,
+	})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to marshal confirmation: %w", err)
+	}
+	return record, set, setID, nil
+}
+
+func decodeConfirmation(record, set []byte) (Confirmation, error) {
+	var r confirmationRecord
+	if err := json.Unmarshal(record, &r); err != nil {
+		return Confirmation{}, fmt.Errorf("failed to unmarshal confirmation: %w", err)
+	}
+	var s signerSetRecord
+	if err := json.Unmarshal(set, &s); err != nil {
+		return Confirmation{}, fmt.Errorf("failed to unmarshal signer set: %w", err)
+	}
+	return Confirmation{
+		SignerSetID:     r.SignerSetID,
+		Signers:         s.Signers,
+		Weights:         s.Weights,
+		Aliases:         s.Aliases,
+		Threshold:       r.Threshold,
+		WeightThreshold: r.WeightThreshold,
+		Epoch:           r.Epoch,
+		ConfirmedAt:     r.ConfirmedAt,
+	}, nil
+}
+
+// PeerRecord is what the operator remembers about a peer across restarts:
+// where to dial it and how it has behaved. Times are unix milliseconds.
+type PeerRecord struct {
+	ID       string   `json:"id"`
+	Addrs    []string `json:"addrs"`
+	LastSeen int64    `json:"last_seen"`
+
+	ValidMessages   int `json:"valid_messages"`
+	InvalidMessages int `json:"invalid_messages"`
+	FailedDials     int `json:"failed_dials"`
+}
+
+func sortPeers(peers []PeerRecord) {
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].LastSeen > peers[j].LastSeen
+	})
+}
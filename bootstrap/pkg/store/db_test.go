@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// storeTestMessages stores a message of structure 1 for ticker at each
+// timestamp, in the order given.
+func storeTestMessages(t *testing.T, db Database, ticker string, timestamps ...int64) {
+	t.Helper()
+	for _, ts := range timestamps {
+		hash := fmt.Sprintf("%s%060x", ticker, ts)
+		err := db.StoreData(context.Background(), hash, []interface{}{ticker, fmt.Sprint(ts)}, []string{"string", "uint256"}, []string{"ticker", "price"}, ts, 1, 1, "", 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func messageTimestamps(messages []Message) []int64 {
+	var timestamps []int64
+	for _, msg := range messages {
+		timestamps = append(timestamps, msg.Timestamp)
+	}
+	return timestamps
+}
+
+func TestGetMessagesByFieldPagesNewestFirst(t *testing.T) {
+	for _, backend := range []string{"leveldb", "badger"} {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			db := openTestStore(t, backend)
+			storeTestMessages(t, db, "SBER", 1700000300, 1700000100, 1700000500, 1700000200, 1700000400)
+			storeTestMessages(t, db, "GAZP", 1700000600, 1700000000)
+
+			for _, tc := range []struct {
+				page, limit int
+				want        []int64
+			}{
+				{1, 2, []int64{1700000500, 1700000400}},
+				{2, 2, []int64{1700000300, 1700000200}},
+				{3, 2, []int64{1700000100}},
+				{4, 2, nil},
+				{0, 2, []int64{1700000500, 1700000400}},
+				{1, 0, nil},
+			} {
+				messages, err := db.GetMessagesByField(ctx, 1, "ticker", "SBER", tc.page, tc.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := messageTimestamps(messages); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+					t.Errorf("page %d limit %d: got %v, want %v", tc.page, tc.limit, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldTimeIndexFollowsPruneAndRebuild(t *testing.T) {
+	ctx := context.Background()
+	db := openTestStore(t, "leveldb").(*LevelDBDatabase)
+	storeTestMessages(t, db, "SBER", 1700000100, 1700000200, 1700000300)
+
+	countKeys := func() int {
+		iter := db.db.NewIterator(util.BytesPrefix(fieldTimeIndexPrefix(1, FieldFilter{Field: "ticker", Value: "SBER"})), nil)
+		defer iter.Release()
+		n := 0
+		for iter.Next() {
+			n++
+		}
+		return n
+	}
+
+	if _, err := db.PruneMessages(ctx, 1, 1700000200); err != nil {
+		t.Fatal(err)
+	}
+	if n := countKeys(); n != 2 {
+		t.Fatalf("field time keys after pruning one message: got %d, want 2", n)
+	}
+
+	// Messages stored before the index existed are found once rebuilt.
+	iter := db.db.NewIterator(util.BytesPrefix([]byte(fieldTimePrefix)), nil)
+	for iter.Next() {
+		if err := db.db.Delete(iter.Key(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	iter.Release()
+	if messages, _ := db.GetMessagesByField(ctx, 1, "ticker", "SBER", 1, 10); len(messages) != 0 {
+		t.Fatalf("unindexed messages found: %v", messageTimestamps(messages))
+	}
+	if indexed, err := db.RebuildIndexes(ctx, 1); err != nil || indexed != 2 {
+		t.Fatalf("rebuild: indexed %d, %v; want 2", indexed, err)
+	}
+	messages, err := db.GetMessagesByField(ctx, 1, "ticker", "SBER", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messageTimestamps(messages); fmt.Sprint(got) != fmt.Sprint([]int64{1700000300, 1700000200}) {
+		t.Fatalf("after rebuild: got %v", got)
+	}
+}
+
+func TestConfirmedQueriesFollowConfirmationMarkers(t *testing.T) {
+	for _, backend := range []string{"leveldb", "badger"} {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			db := openTestStore(t, backend)
+
+			// Every message holds signatures; only the marked ones are
+			// confirmed, however many that is.
+			hashes := make(map[int64]string)
+			for i, ts := range []int64{1700000100, 1700000200, 1700000300} {
+				hash := fmt.Sprintf("%064x", ts)
+				hashes[ts] = hash
+				err := db.StoreData(ctx, hash, []interface{}{"SBER", fmt.Sprint(ts)}, []string{"string", "uint256"}, []string{"ticker", "price"}, ts, 1, 1, "", uint64(i+1), 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, signer := range []string{"0xa", "0xb", "0xc"} {
+					if err := db.StoreSignature(ctx, hash, signer, "0x01"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			for _, ts := range []int64{1700000100, 1700000200} {
+				if err := db.MarkConfirmed(ctx, 1, hashes[ts], ts, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			messages, err := db.GetConfirmedMessages(ctx, 1, nil, nil, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messageTimestamps(messages); fmt.Sprint(got) != fmt.Sprint([]int64{1700000200, 1700000100}) {
+				t.Errorf("confirmed messages: got %v", got)
+			}
+			if count, err := db.CountConfirmed(ctx, 1); err != nil || count != 2 {
+				t.Errorf("confirmed count: got %d, %v; want 2", count, err)
+			}
+			if msg, ok, err := db.GetLatestConfirmed(ctx, 1); err != nil || !ok || msg.Timestamp != 1700000200 {
+				t.Errorf("latest confirmed: got %d (found %v), %v", msg.Timestamp, ok, err)
+			}
+			if msg, ok, err := db.GetLatestByField(ctx, 1, "ticker", "SBER"); err != nil || !ok || msg.Timestamp != 1700000200 || len(msg.Signatures) != 3 {
+				t.Errorf("latest SBER: got %d with %d signatures (found %v), %v", msg.Timestamp, len(msg.Signatures), ok, err)
+			}
+
+			confirmed, err := db.GetMessagesBySequence(ctx, 1, 1, 0, true, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messageTimestamps(confirmed); fmt.Sprint(got) != fmt.Sprint([]int64{1700000100, 1700000200}) {
+				t.Errorf("confirmed by sequence: got %v", got)
+			}
+			all, err := db.GetMessagesBySequence(ctx, 1, 1, 0, false, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 3 {
+				t.Errorf("all by sequence: got %v", messageTimestamps(all))
+			}
+
+			if !db.IsConfirmed(ctx, 1, hashes[1700000100]) || db.IsConfirmed(ctx, 1, hashes[1700000300]) {
+				t.Error("IsConfirmed disagrees with the markers")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// deadLetterPrefix keys dead letters by ID, which starts with the time they
+// were recorded, so they iterate oldest first.
+const deadLetterPrefix = "deadletter:"
+
+// DeadLetter is a sign request that was lost rather than signed: publishing
+// it failed on every retry, it failed validation, or it left the pending set
+// short of its threshold. Request holds it as it was to be published, so it
+// can be replayed.
+type DeadLetter struct {
+	ID              string          `json:"id"`
+	Reason          string          `json:"reason"`
+	Error           string          `json:"error,omitempty"`
+	Hash            string          `json:"hash"`
+	RequestID       string          `json:"request_id,omitempty"`
+	DataStructureID int             `json:"data_structure_id"`
+	Signatures      int             `json:"signatures,omitempty"`
+	Request         json.RawMessage `json:"request"`
+	// RecordedAt is in unix milliseconds.
+	RecordedAt int64 `json:"recorded_at"`
+}
+
+// DeadLetterID names the dead letter of hash recorded at recordedAt.
+func DeadLetterID(recordedAt int64, hash string) string {
+	return fmt.Sprintf("%013d-%s", recordedAt, hash)
+}
+
+func deadLetterKey(id string) []byte {
+	return []byte(deadLetterPrefix + id)
+}
+
+// keepDeadLetter reports whether letter is listed under reason; an empty
+// reason lists every dead letter.
+func keepDeadLetter(letter DeadLetter, reason string) bool {
+	return reason == "" || letter.Reason == reason
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Stored messages carry a leading format byte so the encoding can evolve
+// without rewriting the store. Records written before the byte was
+// introduced are plain JSON objects and therefore start with '{'.
+const (
+	messageFormatMsgpackV1  byte = 0x01
+	messageFormatLegacyJSON byte = '{'
+)
+
+func encodeMessage(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(messageFormatMsgpackV1)
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeMessage(data []byte, msg *Message) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty message record")
+	}
+
+	switch data[0] {
+	case messageFormatMsgpackV1:
+		dec := msgpack.NewDecoder(bytes.NewReader(data[1:]))
+		dec.SetCustomStructTag("json")
+		return dec.Decode(msg)
+	case messageFormatLegacyJSON:
+		return json.Unmarshal(data, msg)
+	default:
+		return fmt.Errorf("unknown message format %#x", data[0])
+	}
+}
@@ -0,0 +1,425 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// knownPrefixes lists every key prefix the LevelDB backend writes.
+var knownPrefixes = []string{
+	dataPrefix, signaturePrefix, trustedPrefix, dataStructPrefix, indexPrefix,
+	latencyPrefix, retentionPrefix, rotationPrefix, epochPrefix, peerPrefix, statsPrefix,
+	confirmationPrefix, signerSetPrefix, rewardPrefix, usagePrefix,
+	numericIndexPrefix, sequenceHeadPrefix, sequencePrefix, deadLetterPrefix,
+	fieldTimePrefix,
+}
+
+// StructureInspection describes one data structure found in the store.
+type StructureInspection struct {
+	ID     int
+	Fields []string
+	// Messages counts timestamp index entries of stored messages.
+	Messages int
+	// StatsMessages is the stored stats count, or -1 without a record.
+	StatsMessages int
+}
+
+// Inspection is what InspectLevelDB found in a LevelDB directory. Every list
+// holds keys or hashes as stored.
+type Inspection struct {
+	Keys       int
+	Messages   int
+	Structures []StructureInspection
+	// UnreadableMessages hold data that does not decode.
+	UnreadableMessages []string
+	// UnindexedMessages are in no timestamp index.
+	UnindexedMessages []string
+	// OrphanedIndexes are index, field time, numeric, sequence, confirmed
+	// marker and confirmation keys of messages that are not stored.
+	OrphanedIndexes []string
+	// SignaturesWithoutData are hashes with signatures but no message.
+	SignaturesWithoutData []string
+	// OrphanedLatencies are latency records of messages that are not stored.
+	OrphanedLatencies []string
+	// MalformedKeys have an unknown prefix or do not parse.
+	MalformedKeys []string
+
+	// owners maps a stored hash to a data structure that one of its index
+	// keys names, so repair can reindex messages missing from the timestamp
+	// index.
+	owners map[string]int
+}
+
+// Healthy reports whether nothing inconsistent was found.
+func (in *Inspection) Healthy() bool {
+	if len(in.UnreadableMessages)+len(in.UnindexedMessages)+len(in.OrphanedIndexes)+
+		len(in.SignaturesWithoutData)+len(in.OrphanedLatencies)+len(in.MalformedKeys) > 0 {
+		return false
+	}
+	for _, s := range in.Structures {
+		if s.StatsMessages >= 0 && s.StatsMessages != s.Messages {
+			return false
+		}
+	}
+	return true
+}
+
+// InspectLevelDB opens the LevelDB directory at path read-only and checks
+// every key. The operator must be stopped, as it locks the directory.
+func InspectLevelDB(ctx context.Context, path string) (*Inspection, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LevelDB: %w", err)
+	}
+	defer db.Close()
+
+	return inspectLevelDB(ctx, db)
+}
+
+func inspectLevelDB(ctx context.Context, db ldbReader) (*Inspection, error) {
+	in := &Inspection{owners: make(map[string]int)}
+	// stored maps every stored hash to whether a timestamp index has it.
+	// data: sorts before every key that refers to a hash, so the map is
+	// complete by the time those keys are checked.
+	stored := make(map[string]bool)
+	structures := make(map[int]*StructureInspection)
+	structure := func(id int) *StructureInspection {
+		s, ok := structures[id]
+		if !ok {
+			s = &StructureInspection{ID: id, StatsMessages: -1}
+			structures[id] = s
+		}
+		return s
+	}
+
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		in.Keys++
+		key := string(iter.Key())
+
+		prefix := ""
+		for _, p := range knownPrefixes {
+			if strings.HasPrefix(key, p) {
+				prefix = p
+				break
+			}
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if prefix == "" || rest == "" {
+			in.MalformedKeys = append(in.MalformedKeys, key)
+			continue
+		}
+
+		switch prefix {
+		case dataPrefix:
+			in.Messages++
+			stored[rest] = false
+			var msg Message
+			if err := decodeMessage(iter.Value(), &msg); err != nil {
+				in.UnreadableMessages = append(in.UnreadableMessages, rest)
+			}
+
+		case signaturePrefix:
+			if _, ok := stored[rest]; !ok {
+				in.SignaturesWithoutData = append(in.SignaturesWithoutData, rest)
+			}
+
+		case latencyPrefix:
+			if _, ok := stored[rest]; !ok {
+				in.OrphanedLatencies = append(in.OrphanedLatencies, rest)
+			}
+
+		case confirmationPrefix:
+			if _, ok := stored[rest]; !ok {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+			}
+
+		case dataStructPrefix:
+			id, err := strconv.Atoi(rest)
+			if err != nil {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			s := structure(id)
+			if err := json.Unmarshal(iter.Value(), &s.Fields); err != nil {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+			}
+
+		case indexPrefix:
+			id, hash, timestamped, ok := parseIndexKey(rest)
+			if !ok {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if _, exists := stored[hash]; !exists {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+				continue
+			}
+			in.owners[hash] = id
+			if timestamped {
+				stored[hash] = true
+				structure(id).Messages++
+			}
+
+		case fieldTimePrefix:
+			id, hash, ok := parseFieldTimeKey(rest)
+			if !ok {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if _, exists := stored[hash]; !exists {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+				continue
+			}
+			in.owners[hash] = id
+
+		case numericIndexPrefix:
+			id, hash, ok := parseNumericKey(rest)
+			if !ok {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if _, exists := stored[hash]; !exists {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+				continue
+			}
+			in.owners[hash] = id
+
+		case sequencePrefix:
+			_, hash, err := parseSequenceKey(iter.Key())
+			id, idErr := strconv.Atoi(strings.SplitN(rest, ":", 2)[0])
+			if err != nil || idErr != nil {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if _, exists := stored[hash]; !exists {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+				continue
+			}
+			in.owners[hash] = id
+
+		case statsPrefix:
+			parts := strings.SplitN(rest, ":", 3)
+			id, err := strconv.Atoi(parts[0])
+			if err != nil {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if len(parts) == 1 {
+				var stats DataStructureStats
+				if err := json.Unmarshal(iter.Value(), &stats); err != nil {
+					in.MalformedKeys = append(in.MalformedKeys, key)
+					continue
+				}
+				structure(id).StatsMessages = stats.MessageCount
+				continue
+			}
+			if len(parts) != 3 || parts[1] != "confirmed" || parts[2] == "" {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+				continue
+			}
+			if _, exists := stored[parts[2]]; !exists {
+				in.OrphanedIndexes = append(in.OrphanedIndexes, key)
+			}
+
+		case sequenceHeadPrefix, retentionPrefix:
+			if _, err := strconv.Atoi(rest); err != nil {
+				in.MalformedKeys = append(in.MalformedKeys, key)
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scan database: %w", err)
+	}
+
+	for hash, indexed := range stored {
+		if !indexed {
+			in.UnindexedMessages = append(in.UnindexedMessages, hash)
+		}
+	}
+	sort.Strings(in.UnindexedMessages)
+
+	for _, s := range structures {
+		in.Structures = append(in.Structures, *s)
+	}
+	sort.Slice(in.Structures, func(i, j int) bool { return in.Structures[i].ID < in.Structures[j].ID })
+	return in, nil
+}
+
+// parseIndexKey splits an index key after its prefix into the data structure
+// ID and message hash, and tells a timestamp index key from a field one.
+func parseIndexKey(rest string) (int, string, bool, bool) {
+	parts := strings.Split(rest, ":")
+	if len(parts) < 3 || parts[len(parts)-1] == "" {
+		return 0, "", false, false
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false, false
+	}
+	hash := parts[len(parts)-1]
+
+	// Field names never start with a digit; see timestampIndexRange.
+	if parts[1] != "" && parts[1][0] >= '0' && parts[1][0] <= '9' {
+		if len(parts) != 3 {
+			return 0, "", false, false
+		}
+		if _, err := strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, "", false, false
+		}
+		return id, hash, true, true
+	}
+	if len(parts) < 4 {
+		return 0, "", false, false
+	}
+	return id, hash, false, true
+}
+
+// parseFieldTimeKey splits a field time index key after its prefix into the
+// data structure ID and message hash.
+func parseFieldTimeKey(rest string) (int, string, bool) {
+	parts := strings.Split(rest, ":")
+	if len(parts) < 5 || parts[len(parts)-1] == "" {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false
+	}
+	if _, err := strconv.ParseInt(parts[len(parts)-2], 10, 64); err != nil {
+		return 0, "", false
+	}
+	return id, parts[len(parts)-1], true
+}
+
+// parseNumericKey splits a numeric index key after its prefix into the data
+// structure ID and message hash.
+func parseNumericKey(rest string) (int, string, bool) {
+	idPart, tail, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		return 0, "", false
+	}
+	field, _, ok := strings.Cut(tail, ":")
+	if !ok || field == "" {
+		return 0, "", false
+	}
+	key := []byte(numericIndexPrefix + rest)
+	prefix := numericFieldPrefix(id, field)
+	if len(key) <= len(prefix)+numericKeyWidth || key[len(prefix)+numericKeyWidth] != ':' {
+		return 0, "", false
+	}
+	hash, ok := numericKeyHash(key, prefix)
+	return id, hash, ok
+}
+
+// Repair is what RepairLevelDB changed.
+type Repair struct {
+	// Deleted counts orphaned index, signature and latency keys removed.
+	Deleted int
+	// Reindexed counts messages whose index keys were rewritten.
+	Reindexed int
+	// Unrecoverable are unindexed messages no key ties to a data structure.
+	Unrecoverable []string
+}
+
+// RepairLevelDB deletes the orphaned keys InspectLevelDB reports and rewrites
+// the index keys of every message whose data structure is known. Unreadable
+// messages and malformed keys are left for a person to look at. Stats are
+// not touched; they depend on the confirmation threshold, so rebuild them
+// afterwards. The operator must be stopped.
+func RepairLevelDB(ctx context.Context, path string) (*Inspection, Repair, error) {
+	var repair Repair
+
+	db, err := leveldb.OpenFile(path, &opt.Options{ErrorIfMissing: true})
+	if err != nil {
+		return nil, repair, fmt.Errorf("failed to open LevelDB: %w", err)
+	}
+	defer db.Close()
+
+	in, err := inspectLevelDB(ctx, db)
+	if err != nil {
+		return nil, repair, err
+	}
+
+	batch := new(leveldb.Batch)
+	flush := func() error {
+		if batch.Len() < restoreBatchSize {
+			return nil
+		}
+		if err := db.Write(batch, nil); err != nil {
+			return fmt.Errorf("failed to write repair: %w", err)
+		}
+		batch.Reset()
+		return nil
+	}
+
+	for _, key := range in.OrphanedIndexes {
+		batch.Delete([]byte(key))
+		repair.Deleted++
+		if err := flush(); err != nil {
+			return in, repair, err
+		}
+	}
+	for _, hash := range in.SignaturesWithoutData {
+		batch.Delete([]byte(signaturePrefix + hash))
+		repair.Deleted++
+		if err := flush(); err != nil {
+			return in, repair, err
+		}
+	}
+	for _, hash := range in.OrphanedLatencies {
+		batch.Delete([]byte(latencyPrefix + hash))
+		repair.Deleted++
+		if err := flush(); err != nil {
+			return in, repair, err
+		}
+	}
+
+	for _, hash := range in.UnindexedMessages {
+		if _, ok := in.owners[hash]; !ok {
+			repair.Unrecoverable = append(repair.Unrecoverable, hash)
+		}
+	}
+
+	for hash, id := range in.owners {
+		if err := ctx.Err(); err != nil {
+			return in, repair, err
+		}
+		data, err := db.Get([]byte(dataPrefix+hash), nil)
+		if err != nil {
+			return in, repair, fmt.Errorf("failed to get message %s: %w", hash, err)
+		}
+		var msg Message
+		if err := decodeMessage(data, &msg); err != nil {
+			continue
+		}
+		msg.Hash = hash
+		for _, key := range messageIndexKeys(id, msg) {
+			batch.Put(key, []byte{})
+		}
+		repair.Reindexed++
+		if err := flush(); err != nil {
+			return in, repair, err
+		}
+	}
+
+	if err := db.Write(batch, nil); err != nil {
+		return in, repair, fmt.Errorf("failed to write repair: %w", err)
+	}
+	return in, repair, nil
+}
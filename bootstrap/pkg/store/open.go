@@ -0,0 +1,50 @@
+package store
+
+import (
+	"fmt"
+	"log"
+)
+
+// Config selects and tunes the storage backend.
+type Config struct {
+	// Backend is "leveldb" or "badger".
+	Backend string `yaml:"backend"`
+	// Path defaults to data/<backend>.
+	Path            string       `yaml:"path"`
+	Badger          BadgerConfig `yaml:"badger"`
+	RestoreSnapshot string       `yaml:"restore_snapshot"`
+	// SignatureBatch group commits signatures arriving close together.
+	SignatureBatch SignatureBatchConfig `yaml:"signature_batch"`
+	// SignatureCacheSize is how many hashes' signatures are kept in memory.
+	SignatureCacheSize int `yaml:"signature_cache_size"`
+}
+
+// Open opens the configured backend.
+func Open(cfg Config) (Database, error) {
+	dbPath := cfg.Path
+	if dbPath == "" {
+		dbPath = "data/" + cfg.Backend
+	}
+
+	log.Printf("Opening %s database at %s", cfg.Backend, dbPath)
+	switch cfg.Backend {
+	case "leveldb":
+		ldb, err := NewLevelDBDatabase(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		ldb.sigBatch = newSignatureBatcher(cfg.SignatureBatch, ldb.writeSignatures)
+		ldb.sigCache = newSignatureCache(cfg.SignatureCacheSize)
+		return ldb, nil
+	case "badger":
+		bdb, err := NewBadgerDatabase(dbPath, cfg.Badger)
+		if err != nil {
+			return nil, err
+		}
+		bdb.sigBatch = newSignatureBatcher(cfg.SignatureBatch, bdb.writeSignatures)
+		bdb.sigCache = newSignatureCache(cfg.SignatureCacheSize)
+		return bdb, nil
+	default:
+		return nil, fmt.Errorf("unknown db backend %q, expected leveldb or badger", cfg.Backend)
+	}
+}
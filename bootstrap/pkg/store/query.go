@@ -0,0 +1,196 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Integer fields are also indexed under numericIndexPrefix with a fixed-width
+// encoding that sorts by value, so range queries are a single key scan:
+//
+//	num:<dsid>:<field>:<33 byte value>:<hash>
+//
+// The value is a sign byte (0 negative, 1 otherwise) followed by 32 bytes
+// big-endian: the magnitude for non-negative numbers and 2^256 minus the
+// magnitude for negative ones.
+const (
+	numericIndexPrefix = "num:"
+	numericKeyWidth    = 33
+)
+
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// RangeFilter bounds an integer field, both ends inclusive. A nil bound is
+// open.
+type RangeFilter struct {
+	Field string
+	Min   *big.Int
+	Max   *big.Int
+}
+
+func isNumericType(solidityType string) bool {
+	return strings.HasPrefix(solidityType, "uint") || strings.HasPrefix(solidityType, "int")
+}
+
+// encodeNumeric returns the sortable index encoding of n. Values outside the
+// int256/uint256 range clamp to the ends of the encoding.
+func encodeNumeric(n *big.Int) []byte {
+	out := make([]byte, numericKeyWidth)
+
+	if n.Sign() >= 0 {
+		out[0] = 1
+		if n.BitLen() > 256 {
+			copy(out[1:], bytes.Repeat([]byte{0xFF}, 32))
+			return out
+		}
+		n.FillBytes(out[1:])
+		return out
+	}
+
+	m := new(big.Int).Sub(twoTo256, new(big.Int).Abs(n))
+	if m.Sign() > 0 {
+		m.FillBytes(out[1:])
+	}
+	return out
+}
+
+func numericFieldPrefix(dataStructureID int, field string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s:", numericIndexPrefix, dataStructureID, field))
+}
+
+// numericIndexKeys builds the numeric index keys of a message, one per
+// integer field holding a parseable value.
+func numericIndexKeys(dataStructureID int, types, fields []string, data []interface{}, hash string) [][]byte {
+	var keys [][]byte
+	for i, field := range fields {
+		if i >= len(types) || i >= len(data) || !isNumericType(types[i]) {
+			continue
+		}
+		n, err := ParseInteger(data[i])
+		if err != nil {
+			continue
+		}
+
+		key := numericFieldPrefix(dataStructureID, field)
+		key = append(key, encodeNumeric(n)...)
+		key = append(key, ':')
+		keys = append(keys, append(key, hash...))
+	}
+	return keys
+}
+
+// numericKeyHash extracts the message hash from a numeric index key under
+// prefix.
+func numericKeyHash(key, prefix []byte) (string, bool) {
+	offset := len(prefix) + numericKeyWidth + 1
+	if len(key) <= offset {
+		return "", false
+	}
+	return string(key[offset:]), true
+}
+
+// bounds returns the key range [start, limit) covering the filter.
+func (f RangeFilter) bounds(dataStructureID int) ([]byte, []byte) {
+	prefix := numericFieldPrefix(dataStructureID, f.Field)
+
+	start := prefix
+	if f.Min != nil {
+		start = append(append([]byte(nil), prefix...), encodeNumeric(f.Min)...)
+	}
+
+	var limit []byte
+	if f.Max != nil {
+		limit = append(append([]byte(nil), prefix...), encodeNumeric(f.Max)...)
+		limit = append(limit, ':'+1)
+	} else {
+		limit = append([]byte(nil), prefix...)
+		limit[len(limit)-1]++
+	}
+	return start, limit
+}
+
+// matchesQuery reports whether a message satisfies every range and equality
+// filter, comparing values the way the indexes store them.
+func matchesQuery(msg Message, ranges []RangeFilter, equals []FieldFilter) bool {
+	value := func(field string) (interface{}, bool) {
+		if field == "request_id" && msg.RequestID != "" {
+			return msg.RequestID, true
+		}
+		for i, name := range msg.DataStructureMeta {
+			if name == field && i < len(msg.Data) {
+				return msg.Data[i], true
+			}
+		}
+		return nil, false
+	}
+
+	for _, r := range ranges {
+		v, ok := value(r.Field)
+		if !ok {
+			return false
+		}
+		n, err := ParseInteger(v)
+		if err != nil {
+			return false
+		}
+		if (r.Min != nil && n.Cmp(r.Min) < 0) || (r.Max != nil && n.Cmp(r.Max) > 0) {
+			return false
+		}
+	}
+
+	for _, e := range equals {
+		v, ok := value(e.Field)
+		if !ok || fmt.Sprintf("%v", v) != e.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseInteger accepts Go integers, JSON numbers and decimal strings. Large values must be
+// sent as strings or decoded with json.Decoder.UseNumber to stay exact.
+func ParseInteger(raw interface{}) (*big.Int, error) {
+	var text string
+	switch v := raw.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("expected integer")
+		}
+		return big.NewInt(int64(v)), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case int8:
+		return big.NewInt(int64(v)), nil
+	case int16:
+		return big.NewInt(int64(v)), nil
+	case int32:
+		return big.NewInt(int64(v)), nil
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	default:
+		return nil, fmt.Errorf("expected integer")
+	}
+
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("expected integer")
+	}
+	return n, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	sequencePrefix = "seq:"
+	// sequenceHeadPrefix keeps the highest sequence number ever stored per
+	// data structure. Unlike the index it survives pruning, so numbering
+	// never restarts.
+	sequenceHeadPrefix = "seqhead:"
+)
+
+// SequenceGap is a run of sequence numbers, From through To, for which no
+// message is stored.
+type SequenceGap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// SequenceInfo describes the sequence numbers stored for a data structure.
+// Messages before First may have been pruned; only holes between First and
+// Last are reported as gaps.
+type SequenceInfo struct {
+	DataStructureID int           `json:"data_structure_id"`
+	First           uint64        `json:"first"`
+	Last            uint64        `json:"last"`
+	Missing         uint64        `json:"missing"`
+	Gaps            []SequenceGap `json:"gaps"`
+}
+
+// Complete reports whether no message is missing between First and Last.
+func (info SequenceInfo) Complete() bool {
+	return info.Missing == 0
+}
+
+// observe adds the next stored sequence number, in ascending order.
+func (info *SequenceInfo) observe(seq uint64) {
+	switch {
+	case info.Last == 0:
+		info.First = seq
+	case seq > info.Last+1:
+		info.Gaps = append(info.Gaps, SequenceGap{From: info.Last + 1, To: seq - 1})
+		info.Missing += seq - info.Last - 1
+	case seq <= info.Last:
+		return
+	}
+	info.Last = seq
+}
+
+// sequencePrefixFor starts the keys of a data structure's sequence index.
+// Sequence numbers are zero padded so the keys sort numerically, and the
+// message hash comes last, like in the other indexes.
+func sequencePrefixFor(dataStructureID int) []byte {
+	return []byte(fmt.Sprintf("%s%d:", sequencePrefix, dataStructureID))
+}
+
+func sequenceKey(dataStructureID int, seq uint64, hash string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%020d:%s", sequencePrefix, dataStructureID, seq, hash))
+}
+
+func sequenceHeadKey(dataStructureID int) []byte {
+	return []byte(fmt.Sprintf("%s%d", sequenceHeadPrefix, dataStructureID))
+}
+
+func parseSequenceKey(key []byte) (uint64, string, error) {
+	parts := strings.Split(string(key), ":")
+	if len(parts) != 4 {
+		return 0, "", fmt.Errorf("invalid sequence key %q", key)
+	}
+	seq, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid sequence key %q: %w", key, err)
+	}
+	return seq, parts[3], nil
+}
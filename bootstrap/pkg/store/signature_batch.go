@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultSignatureBatchDelay = 2 * time.Millisecond
+	defaultSignatureBatchSize  = 256
+)
+
+var errSignatureBatchClosed = errors.New("database is closed")
+
+// SignatureBatchConfig tunes how signatures are group committed. The zero
+// value uses the defaults; a MaxSize of 1 writes every signature on its own.
+type SignatureBatchConfig struct {
+	// MaxDelay is how long the first signature of a batch waits for others.
+	MaxDelay time.Duration `yaml:"max_delay"`
+	MaxSize  int           `yaml:"max_size"`
+}
+
+func (c SignatureBatchConfig) withDefaults() SignatureBatchConfig {
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultSignatureBatchDelay
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultSignatureBatchSize
+	}
+	return c
+}
+
+// signatureSet maps hash to signer to signature.
+type signatureSet map[string]map[string]string
+
+func (s signatureSet) add(hash, signer, signature string) {
+	if s[hash] == nil {
+		s[hash] = make(map[string]string)
+	}
+	s[hash][signer] = signature
+}
+
+type signatureWrite struct {
+	hash      string
+	signer    string
+	signature string
+	done      chan error
+}
+
+// signatureBatcher coalesces StoreSignature calls. When many signers answer
+// at once their signatures are merged and written in one batch instead of
+// one read-modify-write per signature. Every caller still waits until its
+// signature is written and gets the batch's error.
+type signatureBatcher struct {
+	cfg    SignatureBatchConfig
+	commit func(signatureSet) error
+	writes chan signatureWrite
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newSignatureBatcher(cfg SignatureBatchConfig, commit func(signatureSet) error) *signatureBatcher {
+	b := &signatureBatcher{
+		cfg:    cfg.withDefaults(),
+		commit: commit,
+		writes: make(chan signatureWrite),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *signatureBatcher) store(ctx context.Context, hash, signer, signature string) error {
+	w := signatureWrite{hash: hash, signer: signer, signature: signature, done: make(chan error, 1)}
+	select {
+	case b.writes <- w:
+	case <-b.stop:
+		return errSignatureBatchClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// Once handed over the signature is written even if ctx ends, so wait
+	// for the outcome rather than report a write that may have happened.
+	return <-w.done
+}
+
+func (b *signatureBatcher) run() {
+	defer close(b.done)
+
+	for {
+		var batch []signatureWrite
+		select {
+		case w := <-b.writes:
+			batch = append(batch, w)
+		case <-b.stop:
+			return
+		}
+
+		timer := time.NewTimer(b.cfg.MaxDelay)
+	collect:
+		for len(batch) < b.cfg.MaxSize {
+			select {
+			case w := <-b.writes:
+				batch = append(batch, w)
+			case <-timer.C:
+				break collect
+			case <-b.stop:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+	}
+}
+
+func (b *signatureBatcher) flush(batch []signatureWrite) {
+	sigs := make(signatureSet)
+	for _, w := range batch {
+		sigs.add(w.hash, w.signer, w.signature)
+	}
+
+	err := b.commit(sigs)
+	for _, w := range batch {
+		w.done <- err
+	}
+}
+
+// close flushes the batch being collected and stops the batcher.
+func (b *signatureBatcher) close() {
+	close(b.stop)
+	<-b.done
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"maps"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const defaultSignatureCacheSize = 4096
+
+// signatureCache keeps the signature maps of recently read hashes, which
+// list endpoints and stats otherwise decode from disk on every call. Each
+// entry records the version it holds, so a reader on an older snapshot can
+// tell that the entry is newer than what it may see. A nil cache caches
+// nothing.
+//
+// LevelDB has no per-key versions, so the cache counts writes itself: put,
+// remove and purge advance the version, and a read may only be cached while
+// no write happened since its snapshot. Badger passes the version of the
+// stored item to set instead.
+type signatureCache struct {
+	mu      sync.Mutex
+	version uint64
+	entries *lru.Cache[string, cachedSignatures]
+}
+
+type cachedSignatures struct {
+	sigs    map[string]string
+	version uint64
+}
+
+func newSignatureCache(size int) *signatureCache {
+	if size <= 0 {
+		size = defaultSignatureCacheSize
+	}
+	entries, _ := lru.New[string, cachedSignatures](size)
+	return &signatureCache{entries: entries}
+}
+
+// get returns a copy of the cached map, so callers may modify it, and the
+// version it was cached at.
+func (c *signatureCache) get(hash string) (map[string]string, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	entry, ok := c.entries.Get(hash)
+	if !ok {
+		return nil, 0, false
+	}
+	return maps.Clone(entry.sigs), entry.version, true
+}
+
+// writes returns the current write count.
+func (c *signatureCache) writes() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// put records a write of sigs.
+func (c *signatureCache) put(hash string, sigs map[string]string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.entries.Add(hash, cachedSignatures{sigs: maps.Clone(sigs), version: c.version})
+}
+
+// fill caches a map read from a snapshot taken at version, unless a write
+// happened since and the map may be stale.
+func (c *signatureCache) fill(hash string, sigs map[string]string, version uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		return
+	}
+	c.entries.Add(hash, cachedSignatures{sigs: maps.Clone(sigs), version: version})
+}
+
+// set caches the map stored at version, unless a newer one is cached.
+func (c *signatureCache) set(hash string, sigs map[string]string, version uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.entries.Peek(hash); ok && cached.version > version {
+		return
+	}
+	c.entries.Add(hash, cachedSignatures{sigs: maps.Clone(sigs), version: version})
+}
+
+func (c *signatureCache) remove(hash string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.entries.Remove(hash)
+}
+
+func (c *signatureCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.entries.Purge()
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ldbReader is what *leveldb.DB and *leveldb.Snapshot have in common.
+type ldbReader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	Has(key []byte, ro *opt.ReadOptions) (bool, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// ldbView reads LevelDB at one point in time. Methods that combine several
+// keys, such as a message and its signatures, read through a view so they
+// never mix states from before and after a write, without holding mu while
+// they scan.
+type ldbView struct {
+	ldbReader
+	ldb *LevelDBDatabase
+	// writes is the signature cache's write count when the view was taken.
+	writes  uint64
+	release func()
+}
+
+// view takes a snapshot. Release it when done.
+func (ldb *LevelDBDatabase) view() (*ldbView, error) {
+	// Writers hold mu across their write and the cache update, so the
+	// snapshot and the write count agree.
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	snap, err := ldb.db.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	return &ldbView{ldbReader: snap, ldb: ldb, writes: ldb.sigCache.writes(), release: snap.Release}, nil
+}
+
+// liveView reads the database directly, for callers holding mu.
+func (ldb *LevelDBDatabase) liveView() *ldbView {
+	return &ldbView{ldbReader: ldb.db, ldb: ldb, writes: ldb.sigCache.writes()}
+}
+
+func (v *ldbView) Release() {
+	if v.release != nil {
+		v.release()
+	}
+}
+
+func (v *ldbView) signatures(hash string) (map[string]string, bool) {
+	if sigs, version, ok := v.ldb.sigCache.get(hash); ok && version <= v.writes {
+		return sigs, true
+	}
+
+	sigData, err := v.Get([]byte(signaturePrefix+hash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return make(map[string]string), false
+		}
+		return nil, false
+	}
+
+	var sigs map[string]string
+	if err := json.Unmarshal(sigData, &sigs); err != nil {
+		return nil, false
+	}
+
+	v.ldb.sigCache.fill(hash, sigs, v.writes)
+	return sigs, true
+}
+
+// confirmed reports whether a message was marked confirmed.
+func (v *ldbView) confirmed(dataStructureID int, hash string) bool {
+	exists, err := v.Has(confirmedKey(dataStructureID, hash), nil)
+	return err == nil && exists
+}
+
+// message loads a message and attaches its signatures.
+func (v *ldbView) message(hash string) (Message, bool) {
+	data, err := v.Get([]byte(dataPrefix+hash), nil)
+	if err != nil {
+		return Message{}, false
+	}
+
+	var msg Message
+	if err := decodeMessage(data, &msg); err != nil {
+		return Message{}, false
+	}
+
+	if sigs, exists := v.signatures(hash); exists {
+		msg.Signatures = sigs
+	}
+
+	return msg, true
+}
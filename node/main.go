@@ -2,85 +2,29 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/rand"
-	"encoding/hex"
-	"fmt"
 	"log"
 	"os"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	cryptoeth "github.com/ethereum/go-ethereum/crypto"
 	"github.com/joho/godotenv"
-	"github.com/libp2p/go-libp2p/core/crypto"
-)
 
-const (
-	reconnectTimeout        = 5 * time.Second
-	maxReconnectAttempts    = 30
-	connectionCheckInterval = 10 * time.Second
-	subscriptionReadTimeout = 30 * time.Second
+	"listener_node/pkg/signer"
 )
 
-func getOrCreatePrivKey() (crypto.PrivKey, error) {
-	pk_str := os.Getenv("PRIVATE_KEY")
-	if pk_str == "" {
-		priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
-		if err != nil {
-			return nil, err
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "whoami" {
+		if err := signer.WhoamiCommand(os.Args[2:]); err != nil {
+			log.Fatalf("whoami: %v", err)
 		}
-
-		return priv, nil
-	}
-	pk, err := hex.DecodeString(pk_str)
-	if err != nil {
-		log.Println("Error decode PK")
+		return
 	}
-	return crypto.UnmarshalSecp256k1PrivateKey([]byte(pk))
-}
-
-type MemorySigner struct {
-	privKey      crypto.PrivKey
-	ecdsaPrivKey ecdsa.PrivateKey
-	address      string
-}
-
-func NewMemorySigner(privKey crypto.PrivKey) (*MemorySigner, error) {
-	raw, err := privKey.Raw()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get raw private key: %w", err)
-	}
-
-	ecdsaPrivKey, err := cryptoeth.ToECDSA(raw)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert to ECDSA key: %w", err)
-	}
-
-	address := cryptoeth.PubkeyToAddress(ecdsaPrivKey.PublicKey)
-	log.Println("Signer", address)
-
-	return &MemorySigner{
-		privKey:      privKey,
-		ecdsaPrivKey: *ecdsaPrivKey,
-		address:      address.Hex(),
-	}, nil
-}
-
-func (s *MemorySigner) Sign(message []byte) (string, error) {
-	signature, err := cryptoeth.Sign(message, &s.ecdsaPrivKey)
-	if err != nil {
-		return "", err
+	if len(os.Args) > 1 && os.Args[1] == "diagnose" {
+		if err := signer.DiagnoseCommand(os.Args[2:]); err != nil {
+			log.Fatalf("diagnose: %v", err)
+		}
+		return
 	}
 
-	return hexutil.Encode(signature), nil
-}
-
-func (s *MemorySigner) Address() string {
-	return s.address
-}
-
-func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -88,23 +32,26 @@ func main() {
 		log.Print("No .env file found")
 	}
 
-	operatorAddr := os.Getenv("BOOTSTRAP_NODE")
-	topic := os.Getenv("TOPIC")
-
-	privKey, err := getOrCreatePrivKey()
+	cfg, err := signer.LoadConfig(os.Args[1:])
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to load config: %v", err)
 	}
-	signer, err := NewMemorySigner(privKey)
-	if err != nil {
-		log.Fatal(err)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
 	}
 
-	node, err := NewNode(ctx, privKey, signer, topic, operatorAddr)
+	shutdownTracing, err := signer.SetupTracing(ctx)
 	if err != nil {
-		log.Fatalf("Failed to create regular node: %v", err)
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	<-ctx.Done()
-	node.wg.Wait()
+	if err := signer.Run(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Error shutting down tracing: %v", err)
+	}
 }
@@ -0,0 +1,140 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+)
+
+// priceScale is the fixed-point scale of the prices the operator publishes.
+var priceScale = new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+
+// priceFields name the field holding the price of stock quotes and basket
+// values, in that order of preference.
+var priceFields = []string{"price", "value"}
+
+// PriceBounds are the prices a signer is willing to attest for a ticker.
+// Zero fields are not checked.
+type PriceBounds struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// MaxChangePercent bounds the change from the last price signed for
+	// the ticker since the node started.
+	MaxChangePercent float64 `yaml:"max_change_percent"`
+}
+
+func (b PriceBounds) validate() error {
+	if b.Min < 0 || b.Max < 0 || b.MaxChangePercent < 0 {
+		return fmt.Errorf("bounds must not be negative")
+	}
+	if b.Max > 0 && b.Min > b.Max {
+		return fmt.Errorf("minimum %g above maximum %g", b.Min, b.Max)
+	}
+	return nil
+}
+
+// priceGuard refuses requests whose price breaks its ticker's bounds. A nil
+// guard allows everything.
+type priceGuard struct {
+	bounds map[string]PriceBounds
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+func newPriceGuard(bounds map[string]PriceBounds) *priceGuard {
+	if len(bounds) == 0 {
+		return nil
+	}
+	return &priceGuard{bounds: bounds, last: make(map[string]float64)}
+}
+
+// check returns the reason to refuse req, or "" to sign it. Requests
+// without data are refused, since there is no telling whether they are for
+// a bounded ticker. Only requests for a bounded ticker are looked into
+// further; their hash must cover their data, or the data says nothing about
+// what would be signed.
+func (g *priceGuard) check(req *SignRequest) (string, error) {
+	if g == nil {
+		return "", nil
+	}
+	if len(req.Data) == 0 {
+		return "no_data", fmt.Errorf("request carries no data to check bounds against")
+	}
+	fields := req.fields()
+	if fields == nil {
+		return "price_unverifiable", fmt.Errorf("request data is not a JSON array")
+	}
+	ticker, _ := fields["ticker"].(string)
+	bounds, ok := g.bounds[ticker]
+	if !ok {
+		return "", nil
+	}
+
+	if req.HashVersion > hashVersionJSON {
+		return "price_unverifiable", fmt.Errorf("hash version %d is not checked", req.HashVersion)
+	}
+	hash := packedJSONHash(req.Data, req.Timestamp)
+	if hex.EncodeToString(hash) != req.Hash {
+		return "hash_mismatch", fmt.Errorf("data does not hash to %s", req.Hash)
+	}
+	price, err := scaledPrice(fields)
+	if err != nil {
+		return "price_unverifiable", err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if bounds.Min > 0 && price < bounds.Min {
+		return "price_bounds", fmt.Errorf("%s at %g is below %g", ticker, price, bounds.Min)
+	}
+	if bounds.Max > 0 && price > bounds.Max {
+		return "price_bounds", fmt.Errorf("%s at %g is above %g", ticker, price, bounds.Max)
+	}
+	if last := g.last[ticker]; bounds.MaxChangePercent > 0 && last > 0 {
+		if change := math.Abs(price-last) / last * 100; change > bounds.MaxChangePercent {
+			return "price_bounds", fmt.Errorf("%s moved %.2f%% from %g to %g", ticker, change, last, price)
+		}
+	}
+	g.last[ticker] = price
+	return "", nil
+}
+
+// fields maps the request's field names to their values, numbers decoded
+// as json.Number.
+func (req *SignRequest) fields() map[string]interface{} {
+	var data []interface{}
+	dec := json.NewDecoder(bytes.NewReader(req.Data))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(data))
+	for i, name := range req.DataStructureMeta {
+		if i < len(data) {
+			fields[name] = data[i]
+		}
+	}
+	return fields
+}
+
+func scaledPrice(fields map[string]interface{}) (float64, error) {
+	for _, name := range priceFields {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		scaled, ok := new(big.Int).SetString(fmt.Sprint(raw), 10)
+		if !ok {
+			return 0, fmt.Errorf("%s %v is not an integer", name, raw)
+		}
+		price, _ := new(big.Float).Quo(new(big.Float).SetInt(scaled), priceScale).Float64()
+		return price, nil
+	}
+	return 0, fmt.Errorf("request carries no price")
+}
@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+// quoteRequest builds a stock_quote request for ticker at price, hashed the
+// way the operator hashes it.
+func quoteRequest(t *testing.T, ticker string, price string, timestamp int64) *SignRequest {
+	t.Helper()
+	data, err := json.Marshal([]interface{}{ticker, price, timestamp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SignRequest{
+		Type:              MsgTypeSignRequest,
+		Hash:              hex.EncodeToString(packedJSONHash(data, timestamp)),
+		Timestamp:         timestamp,
+		RequestID:         "req-1",
+		DataStructureId:   1,
+		Data:              data,
+		DataStructureMeta: []string{"ticker", "price", "timestamp"},
+		HashVersion:       hashVersionJSON,
+	}
+}
+
+func TestPriceGuardRefusesOutOfBoundsRebroadcast(t *testing.T) {
+	guard := newPriceGuard(map[string]PriceBounds{"SBER": {Max: 200}})
+
+	// 300 scaled by 1e18.
+	req := quoteRequest(t, "SBER", "300000000000000000000", 1700000000)
+	if reason, err := guard.check(req); reason != "price_bounds" || err == nil {
+		t.Fatalf("out-of-bounds request: got reason %q, err %v; want price_bounds", reason, err)
+	}
+
+	rebroadcast := *req
+	if reason, err := guard.check(&rebroadcast); reason != "price_bounds" || err == nil {
+		t.Fatalf("rebroadcast with data: got reason %q, err %v; want price_bounds", reason, err)
+	}
+
+	hashOnly := &SignRequest{
+		Type:      MsgTypeSignRequest,
+		Hash:      req.Hash,
+		Timestamp: req.Timestamp,
+		RequestID: req.RequestID,
+	}
+	if reason, err := guard.check(hashOnly); reason != "no_data" || err == nil {
+		t.Fatalf("hash-only rebroadcast: got reason %q, err %v; want no_data", reason, err)
+	}
+}
+
+func TestPriceGuardChecksData(t *testing.T) {
+	guard := newPriceGuard(map[string]PriceBounds{"SBER": {Min: 100, Max: 200}})
+
+	if reason, err := guard.check(quoteRequest(t, "SBER", "150000000000000000000", 1700000000)); err != nil {
+		t.Fatalf("in-bounds request refused: %s: %v", reason, err)
+	}
+	if reason, err := guard.check(quoteRequest(t, "GAZP", "900000000000000000000", 1700000000)); err != nil {
+		t.Fatalf("unbounded ticker refused: %s: %v", reason, err)
+	}
+
+	tampered := quoteRequest(t, "SBER", "150000000000000000000", 1700000000)
+	tampered.Hash = quoteRequest(t, "SBER", "300000000000000000000", 1700000000).Hash
+	if reason, _ := guard.check(tampered); reason != "hash_mismatch" {
+		t.Fatalf("data not covered by the hash: got reason %q, want hash_mismatch", reason)
+	}
+
+	var none *priceGuard
+	if reason, err := none.check(&SignRequest{Hash: "00"}); err != nil {
+		t.Fatalf("nil guard refused: %s: %v", reason, err)
+	}
+}
@@ -0,0 +1,125 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// syncProtocolID is the operator's catch-up protocol. It serves confirmed
+// messages by data structure and sequence range.
+const syncProtocolID = protocol.ID("/l0proof/sync/1.0.0")
+
+// syncTimeout bounds one catch-up request.
+const syncTimeout = 30 * time.Second
+
+// SyncRequest asks for the confirmed messages of a data structure with a
+// sequence number in [From, To]. A zero To means up to the latest.
+type SyncRequest struct {
+	DataStructureID int    `json:"data_structure_id"`
+	From            uint64 `json:"from"`
+	To              uint64 `json:"to,omitempty"`
+	Limit           int    `json:"limit,omitempty"`
+}
+
+// SyncedMessage is a confirmed message as served by the operator.
+type SyncedMessage struct {
+	Hash              string            `json:"hash"`
+	Data              []interface{}     `json:"data"`
+	DataStructure     []string          `json:"data_structure"`
+	DataStructureMeta []string          `json:"data_structure_meta"`
+	Signatures        map[string]string `json:"signatures"`
+	Timestamp         int64             `json:"timestamp"`
+	RequestID         string            `json:"request_id,omitempty"`
+	Sequence          uint64            `json:"sequence"`
+}
+
+// SyncResponse holds the messages found, lowest sequence first, and the
+// highest sequence number the operator has stored for the structure.
+type SyncResponse struct {
+	Messages []SyncedMessage `json:"messages"`
+	Last     uint64          `json:"last"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// FetchConfirmed asks the operator at p for the confirmed messages req
+// describes. A response holds a limited number of messages; ask again from
+// the last sequence received + 1 for the rest.
+func FetchConfirmed(ctx context.Context, h host.Host, p peer.ID, req SyncRequest) (SyncResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	s, err := h.NewStream(ctx, p, syncProtocolID)
+	if err != nil {
+		return SyncResponse{}, fmt.Errorf("failed to open sync stream: %w", err)
+	}
+	defer s.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(s).Encode(req); err != nil {
+		s.Reset()
+		return SyncResponse{}, fmt.Errorf("failed to send sync request: %w", err)
+	}
+	if err := s.CloseWrite(); err != nil {
+		s.Reset()
+		return SyncResponse{}, fmt.Errorf("failed to send sync request: %w", err)
+	}
+
+	var resp SyncResponse
+	if err := json.NewDecoder(s).Decode(&resp); err != nil {
+		s.Reset()
+		return SyncResponse{}, fmt.Errorf("failed to read sync response: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("sync refused: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// catchUp fetches the confirmed messages in a range of sequence numbers
+// the node missed from the bootstrap operator. Requests that never reached
+// their threshold are not served, so not all of the range may come back.
+func (n *Node) catchUp(dataStructureID int, from, to uint64) {
+	if n.bootstrap == "" {
+		return
+	}
+	maddr, err := multiaddr.NewMultiaddr(n.bootstrap)
+	if err != nil {
+		return
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return
+	}
+
+	recovered := 0
+	for from <= to {
+		resp, err := FetchConfirmed(n.ctx, n.host, info.ID, SyncRequest{DataStructureID: dataStructureID, From: from, To: to})
+		if err != nil {
+			if n.ctx.Err() == nil {
+				log.Printf("Catch-up sync for structure %d failed: %v", dataStructureID, err)
+			}
+			break
+		}
+		if len(resp.Messages) == 0 {
+			break
+		}
+		recovered += len(resp.Messages)
+		from = resp.Messages[len(resp.Messages)-1].Sequence + 1
+	}
+
+	if recovered > 0 {
+		n.sequences.recovered(dataStructureID, recovered)
+		catchUpRecovered.Add(float64(recovered))
+		log.Printf("🔄 Recovered %d confirmed messages for structure %d through catch-up sync", recovered, dataStructureID)
+	}
+}
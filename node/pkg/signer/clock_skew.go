@@ -0,0 +1,46 @@
+package signer
+
+import (
+	"log"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+const (
+	defaultMaxTimestampSkew = 5 * time.Minute
+	defaultNTPServer        = "pool.ntp.org"
+)
+
+// timestampAcceptable reports whether a request timestamp is within maxSkew
+// of the local clock. Requests outside it are refused rather than signed,
+// so a backdated or clock-skewed request cannot collect signatures.
+func (n *Node) timestampAcceptable(timestamp int64) bool {
+	diff := time.Since(time.Unix(timestamp, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= n.maxSkew
+}
+
+// checkClockDrift warns at startup when the local clock is off by more than
+// half the allowed skew according to an NTP server, since this signer would
+// then refuse requests from operators with accurate clocks. Failures to reach
+// the server only warn.
+func checkClockDrift(server string, skew time.Duration) {
+	resp, err := ntp.Query(server)
+	if err != nil {
+		log.Printf("⚠️ NTP drift check against %s failed: %v", server, err)
+		return
+	}
+
+	offset := resp.ClockOffset
+	if offset < 0 {
+		offset = -offset
+	}
+	if offset > skew/2 {
+		log.Printf("⚠️ Local clock is off by %s according to %s; sign requests may be refused (max skew %s)", resp.ClockOffset, server, skew)
+		return
+	}
+	log.Printf("✅ Clock offset %s according to %s", resp.ClockOffset, server)
+}
@@ -0,0 +1,192 @@
+package signer
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the signer configuration. LoadConfig fills it from, in
+// increasing precedence, the defaults, a YAML file, environment variables
+// and command-line flags.
+//
+// Durations are written like "90s" in the file and as whole seconds in
+// environment variables and flags, as they always have been.
+type Config struct {
+	PrivateKey string `yaml:"private_key"`
+	// BootstrapNode is the operator's multiaddr, /p2p suffix included.
+	BootstrapNode    string        `yaml:"bootstrap_node"`
+	Topic            string        `yaml:"topic"`
+	KeysPath         string        `yaml:"keys_path"`
+	RotateToKey      string        `yaml:"rotate_to_key"`
+	MaxTimestampSkew time.Duration `yaml:"max_timestamp_skew"`
+	NTPServer        string        `yaml:"ntp_server"`
+	StatusAddr       string        `yaml:"status_addr"`
+	// HeartbeatInterval is how often each signing address announces it is
+	// up; zero announces nothing.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	// FeedsPath names the feeds this node collects and originates sign
+	// requests for; empty originates none.
+	FeedsPath string `yaml:"feeds_path"`
+	// PriceBounds are the prices the signer attests per ticker; requests
+	// outside them go unsigned. Set in the file only.
+	PriceBounds map[string]PriceBounds `yaml:"price_bounds"`
+
+	Gossip GossipConfig `yaml:"gossip"`
+	Queue  QueueConfig  `yaml:"queue"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		KeysPath:          "config/keys.json",
+		MaxTimestampSkew:  defaultMaxTimestampSkew,
+		NTPServer:         defaultNTPServer,
+		HeartbeatInterval: defaultHeartbeatInterval,
+	}
+}
+
+// configVar binds one setting to its environment variable and flag. Both
+// take the same syntax.
+type configVar struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+}
+
+func (c *Config) vars() []configVar {
+	return []configVar{
+		{"PRIVATE_KEY", "private-key", "hex secp256k1 key; a fresh one is generated when empty", stringSetter(&c.PrivateKey)},
+		{"BOOTSTRAP_NODE", "bootstrap-node", "operator multiaddr to dial", stringSetter(&c.BootstrapNode)},
+		{"TOPIC", "topic", "pubsub topic", stringSetter(&c.Topic)},
+		{"KEYS_PATH", "keys-path", "signing keys file", stringSetter(&c.KeysPath)},
+		{"ROTATE_TO_KEY", "rotate-to-key", "hex key to rotate the private key to", stringSetter(&c.RotateToKey)},
+		{"MAX_TIMESTAMP_SKEW", "max-timestamp-skew", "seconds a sign request timestamp may be off", secondsSetter(&c.MaxTimestampSkew)},
+		{"NTP_SERVER", "ntp-server", "NTP server for the clock drift check", stringSetter(&c.NTPServer)},
+		{"STATUS_ADDR", "status-addr", "address of the status endpoint; empty disables it", stringSetter(&c.StatusAddr)},
+		{"HEARTBEAT_INTERVAL", "heartbeat-interval", "seconds between liveness heartbeats; 0 disables them", secondsSetter(&c.HeartbeatInterval)},
+		{"FEEDS_PATH", "feeds-path", "feeds file of data this node originates sign requests for", stringSetter(&c.FeedsPath)},
+		{"GOSSIP_MESSAGE_ID", "gossip-message-id", "gossipsub message ID scheme", stringSetter(&c.Gossip.MessageID)},
+		{"GOSSIP_SEEN_TTL", "gossip-seen-ttl", "seconds gossipsub remembers seen messages", secondsSetter(&c.Gossip.SeenTTL)},
+		{"SIGN_QUEUE_SIZE", "sign-queue-size", "sign request queue size", intSetter(&c.Queue.Size)},
+		{"SIGN_WORKERS", "sign-workers", "number of signing workers", intSetter(&c.Queue.Workers)},
+		{"SIGN_QUEUE_OVERFLOW", "sign-queue-overflow", "what to do when the sign queue is full", stringSetter(&c.Queue.Overflow)},
+	}
+}
+
+// LoadConfig reads the configuration. The file is named by the -config flag
+// or CONFIG_PATH and is optional.
+func LoadConfig(args []string) (Config, error) {
+	cfg := defaultConfig()
+	vars := cfg.vars()
+
+	fs := flag.NewFlagSet("node", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_PATH"), "YAML config file")
+	type flagValue struct {
+		v     configVar
+		value string
+	}
+	var flagValues []flagValue
+	for _, v := range vars {
+		v := v
+		fs.Func(v.flag, v.usage, func(value string) error {
+			flagValues = append(flagValues, flagValue{v, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return cfg, err
+		}
+	}
+	for _, v := range vars {
+		if value := os.Getenv(v.env); value != "" {
+			if err := v.set(value); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", v.env, err)
+			}
+		}
+	}
+	for _, f := range flagValues {
+		if err := f.v.set(f.value); err != nil {
+			return cfg, fmt.Errorf("invalid -%s: %w", f.v.flag, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports the first setting that would stop the signer from
+// starting.
+func (c Config) Validate() error {
+	if c.Topic == "" {
+		return fmt.Errorf("topic is not set")
+	}
+	if c.BootstrapNode != "" {
+		if _, err := peer.AddrInfoFromString(c.BootstrapNode); err != nil {
+			return fmt.Errorf("invalid bootstrap node address: %w", err)
+		}
+	}
+	if _, err := c.Gossip.Options(); err != nil {
+		return err
+	}
+	if _, err := c.Queue.withDefaults(); err != nil {
+		return err
+	}
+	for ticker, bounds := range c.PriceBounds {
+		if err := bounds.validate(); err != nil {
+			return fmt.Errorf("price bounds of %s: %w", ticker, err)
+		}
+	}
+	return nil
+}
+
+func stringSetter(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func intSetter(p *int) func(string) error {
+	return func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func secondsSetter(p *time.Duration) func(string) error {
+	return func(s string) error {
+		seconds, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = time.Duration(seconds) * time.Second
+		return nil
+	}
+}
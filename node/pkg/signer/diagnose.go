@@ -0,0 +1,118 @@
+package signer
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+// diagnoseWait gives gossipsub a few heartbeats to build the mesh before
+// the report is taken.
+const diagnoseWait = 10 * time.Second
+
+// DiagnoseCommand implements the `diagnose` subcommand. It runs a throwaway
+// peer with a fresh key, so it can be used next to a running signer, joins
+// the configured topic through the bootstrap node and prints what it sees.
+// It fails when it finds a problem.
+func DiagnoseCommand(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		log.Print("No .env file found")
+	}
+
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	privKey, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	if err != nil {
+		return err
+	}
+	h, err := libp2p.New(libp2p.Identity(privKey))
+	if err != nil {
+		return fmt.Errorf("failed to create host: %w", err)
+	}
+	defer h.Close()
+
+	monitor := newNetMonitor(h, cfg.BootstrapNode)
+	if err := monitor.watchReachability(ctx); err != nil {
+		return err
+	}
+	gossipOpts, err := cfg.Gossip.Options()
+	if err != nil {
+		return err
+	}
+	ps, err := pubsub.NewGossipSub(ctx, h, append(gossipOpts, pubsub.WithRawTracer(monitor))...)
+	if err != nil {
+		return fmt.Errorf("failed to create pubsub: %w", err)
+	}
+	monitor.ps = ps
+	topic, err := ps.Join(cfg.Topic)
+	if err != nil {
+		return fmt.Errorf("failed to join topic: %w", err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	defer sub.Cancel()
+
+	// The first check dials the bootstrap node so the mesh can form.
+	monitor.Diagnose(ctx)
+	fmt.Printf("Waiting %s for the gossip mesh...\n", diagnoseWait)
+	time.Sleep(diagnoseWait)
+
+	d := monitor.Diagnose(ctx)
+	printDiagnostics(d)
+
+	if problems := d.Problems(); len(problems) > 0 {
+		fmt.Println("problems:")
+		for _, p := range problems {
+			fmt.Printf("  ❌ %s\n", p)
+		}
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	fmt.Println("✅ no problems found")
+	return nil
+}
+
+func printDiagnostics(d NetDiagnostics) {
+	fmt.Printf("peer_id:    %s\n", d.PeerID)
+	fmt.Printf("nat_status: %s\n", d.NATStatus)
+	fmt.Println("listen_addrs:")
+	for _, addr := range d.ListenAddrs {
+		fmt.Printf("  %s\n", addr)
+	}
+	fmt.Println("observed_addrs:")
+	for _, addr := range d.ObservedAddrs {
+		fmt.Printf("  %s\n", addr)
+	}
+	fmt.Println("bootstrap:")
+	for _, b := range d.Bootstrap {
+		if b.Connected {
+			fmt.Printf("  ✅ %s (rtt %dms)\n", b.Addr, b.RTTMillis)
+		} else {
+			fmt.Printf("  ❌ %s: %s\n", b.Addr, b.Error)
+		}
+	}
+	fmt.Println("topics:")
+	for _, t := range d.Topics {
+		fmt.Printf("  %s: %d subscribed, %d in mesh\n", t.Topic, len(t.Subscribed), len(t.Mesh))
+		for _, p := range t.Mesh {
+			fmt.Printf("    %s\n", p)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// NetDiagnostics is what /debug/net and the diagnose command report: the
+// usual suspects when a signer produces no signatures.
+type NetDiagnostics struct {
+	PeerID string `json:"peer_id"`
+	// NATStatus is AutoNAT's verdict: unknown, public or private.
+	NATStatus     string             `json:"nat_status"`
+	ListenAddrs   []string           `json:"listen_addrs"`
+	ObservedAddrs []string           `json:"observed_addrs"`
+	Bootstrap     []BootstrapCheck   `json:"bootstrap"`
+	Topics        []TopicMesh        `json:"topics"`
+	LastSubError  *SubscriptionError `json:"last_subscription_error,omitempty"`
+}
+
+// BootstrapCheck is the outcome of reaching one configured bootstrap
+// address.
+type BootstrapCheck struct {
+	Addr      string `json:"addr"`
+	Connected bool   `json:"connected"`
+	RTTMillis int64  `json:"rtt_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TopicMesh lists the peers subscribed to a topic and the ones in our
+// gossipsub mesh for it. Messages only flow through mesh peers.
+type TopicMesh struct {
+	Topic      string   `json:"topic"`
+	Subscribed []string `json:"subscribed"`
+	Mesh       []string `json:"mesh"`
+}
+
+type SubscriptionError struct {
+	Error string `json:"error"`
+	At    int64  `json:"at"`
+}
+
+// netMonitor follows what the host and gossipsub learn about the network.
+// It is registered as a gossipsub raw tracer to see mesh grafts and prunes.
+type netMonitor struct {
+	host      host.Host
+	ps        *pubsub.PubSub
+	bootstrap string
+
+	mu           sync.RWMutex
+	reachability network.Reachability
+	mesh         map[string]map[peer.ID]bool
+	lastSubError *SubscriptionError
+}
+
+func newNetMonitor(h host.Host, bootstrap string) *netMonitor {
+	return &netMonitor{
+		host:      h,
+		bootstrap: bootstrap,
+		mesh:      make(map[string]map[peer.ID]bool),
+	}
+}
+
+// watchReachability records AutoNAT reachability changes until ctx is done.
+func (m *netMonitor) watchReachability(ctx context.Context) error {
+	sub, err := m.host.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to reachability events: %w", err)
+	}
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				m.mu.Lock()
+				m.reachability = e.(event.EvtLocalReachabilityChanged).Reachability
+				m.mu.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *netMonitor) recordSubscriptionError(err error) {
+	m.mu.Lock()
+	m.lastSubError = &SubscriptionError{Error: err.Error(), At: time.Now().Unix()}
+	m.mu.Unlock()
+}
+
+// Diagnose snapshots the monitor and checks every bootstrap address,
+// dialing the ones we are not connected to.
+func (m *netMonitor) Diagnose(ctx context.Context) NetDiagnostics {
+	d := NetDiagnostics{PeerID: m.host.ID().String()}
+
+	for _, addr := range m.host.Network().ListenAddresses() {
+		d.ListenAddrs = append(d.ListenAddrs, addr.String())
+	}
+	sort.Strings(d.ListenAddrs)
+	if ids, ok := m.host.(interface{ IDService() identify.IDService }); ok {
+		for _, addr := range ids.IDService().OwnObservedAddrs() {
+			d.ObservedAddrs = append(d.ObservedAddrs, addr.String())
+		}
+	}
+
+	m.mu.RLock()
+	d.NATStatus = reachabilityName(m.reachability)
+	if m.lastSubError != nil {
+		last := *m.lastSubError
+		d.LastSubError = &last
+	}
+	topics := make(map[string][]string, len(m.mesh))
+	for topic, peers := range m.mesh {
+		topics[topic] = peerIDStrings(peers)
+	}
+	m.mu.RUnlock()
+
+	for topic, mesh := range topics {
+		tm := TopicMesh{Topic: topic, Mesh: mesh}
+		if m.ps != nil {
+			for _, p := range m.ps.ListPeers(topic) {
+				tm.Subscribed = append(tm.Subscribed, p.String())
+			}
+			sort.Strings(tm.Subscribed)
+		}
+		d.Topics = append(d.Topics, tm)
+	}
+	sort.Slice(d.Topics, func(i, j int) bool { return d.Topics[i].Topic < d.Topics[j].Topic })
+
+	if m.bootstrap != "" {
+		d.Bootstrap = append(d.Bootstrap, m.checkBootstrap(ctx, m.bootstrap))
+	}
+	return d
+}
+
+func (m *netMonitor) checkBootstrap(ctx context.Context, addr string) BootstrapCheck {
+	check := BootstrapCheck{Addr: addr}
+
+	maddr, err := multiaddr.NewMultiaddr(addr)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid address: %v", err)
+		return check
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		check.Error = fmt.Sprintf("invalid address: %v", err)
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+
+	if m.host.Network().Connectedness(info.ID) != network.Connected {
+		if err := m.host.Connect(ctx, *info); err != nil {
+			check.Error = err.Error()
+			return check
+		}
+	}
+	check.Connected = true
+
+	select {
+	case res := <-ping.Ping(ctx, m.host, info.ID):
+		if res.Error != nil {
+			check.Error = fmt.Sprintf("ping failed: %v", res.Error)
+		} else {
+			check.RTTMillis = res.RTT.Milliseconds()
+		}
+	case <-ctx.Done():
+		check.Error = "ping timed out"
+	}
+	return check
+}
+
+// Problems explains, one line each, what in d would keep signatures from
+// reaching the operator.
+func (d NetDiagnostics) Problems() []string {
+	var problems []string
+	if len(d.Bootstrap) == 0 {
+		problems = append(problems, "no bootstrap node is configured; set BOOTSTRAP_NODE")
+	}
+	for _, b := range d.Bootstrap {
+		if !b.Connected {
+			problems = append(problems, fmt.Sprintf("bootstrap node %s is unreachable: %s", b.Addr, b.Error))
+		}
+	}
+	for _, t := range d.Topics {
+		if len(t.Mesh) == 0 {
+			problems = append(problems, fmt.Sprintf("no mesh peers on topic %s; check that the operator uses the same TOPIC", t.Topic))
+		}
+	}
+	if d.LastSubError != nil {
+		problems = append(problems, fmt.Sprintf("last subscription error at %s: %s", time.Unix(d.LastSubError.At, 0).Format(time.RFC3339), d.LastSubError.Error))
+	}
+	return problems
+}
+
+func reachabilityName(r network.Reachability) string {
+	switch r {
+	case network.ReachabilityPublic:
+		return "public"
+	case network.ReachabilityPrivate:
+		return "private"
+	default:
+		return "unknown"
+	}
+}
+
+func peerIDStrings(peers map[peer.ID]bool) []string {
+	ids := make([]string, 0, len(peers))
+	for p := range peers {
+		ids = append(ids, p.String())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *netMonitor) Join(topic string) {
+	m.mu.Lock()
+	if m.mesh[topic] == nil {
+		m.mesh[topic] = make(map[peer.ID]bool)
+	}
+	m.mu.Unlock()
+}
+
+func (m *netMonitor) Leave(topic string) {
+	m.mu.Lock()
+	delete(m.mesh, topic)
+	m.mu.Unlock()
+}
+
+func (m *netMonitor) Graft(p peer.ID, topic string) {
+	m.mu.Lock()
+	if m.mesh[topic] == nil {
+		m.mesh[topic] = make(map[peer.ID]bool)
+	}
+	m.mesh[topic][p] = true
+	m.mu.Unlock()
+}
+
+func (m *netMonitor) Prune(p peer.ID, topic string) {
+	m.mu.Lock()
+	delete(m.mesh[topic], p)
+	m.mu.Unlock()
+}
+
+func (m *netMonitor) RemovePeer(p peer.ID) {
+	m.mu.Lock()
+	for _, peers := range m.mesh {
+		delete(peers, p)
+	}
+	m.mu.Unlock()
+}
+
+func (m *netMonitor) AddPeer(p peer.ID, proto protocol.ID)        {}
+func (m *netMonitor) ValidateMessage(msg *pubsub.Message)         {}
+func (m *netMonitor) DeliverMessage(msg *pubsub.Message)          {}
+func (m *netMonitor) RejectMessage(msg *pubsub.Message, r string) {}
+func (m *netMonitor) DuplicateMessage(msg *pubsub.Message)        {}
+func (m *netMonitor) ThrottlePeer(p peer.ID)                      {}
+func (m *netMonitor) RecvRPC(rpc *pubsub.RPC)                     {}
+func (m *netMonitor) SendRPC(rpc *pubsub.RPC, p peer.ID)          {}
+func (m *netMonitor) DropRPC(rpc *pubsub.RPC, p peer.ID)          {}
+func (m *netMonitor) UndeliverableMessage(msg *pubsub.Message)    {}
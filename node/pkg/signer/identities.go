@@ -0,0 +1,145 @@
+package signer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+// SigningIdentity is one key the node signs with, such as one committee
+// seat, together with the policy deciding which requests it signs.
+type SigningIdentity struct {
+	Signer Signer
+	// Structures limits signing to these data structure IDs. Empty signs
+	// every structure.
+	Structures map[int]bool
+	// RotateTo is the identity this key is being rotated to. The node
+	// announces the rotation until it is restarted without the old key.
+	RotateTo *SigningIdentity
+
+	signatures atomic.Int64
+}
+
+// allows reports whether the identity's policy covers req. A scoped key
+// signs only requests that carry their data, since a bare hash with a zero
+// DataStructureId, as older operators rebroadcast, says nothing about which
+// structure it belongs to. The hash must also cover that data, or any peer
+// could relabel an arbitrary hash with a structure the key signs.
+func (id *SigningIdentity) allows(req *SignRequest) bool {
+	if len(id.Structures) == 0 {
+		return true
+	}
+	return len(req.Data) > 0 && req.hashCoversData() && id.Structures[req.DataStructureId]
+}
+
+// hashCoversData reports whether req's hash is the hash of its data, for
+// the hash versions the node can recompute.
+func (req *SignRequest) hashCoversData() bool {
+	if req.HashVersion < 0 || req.HashVersion > hashVersionJSON {
+		return false
+	}
+	return hex.EncodeToString(packedJSONHash(req.Data, req.Timestamp)) == req.Hash
+}
+
+type keyConfig struct {
+	PrivateKey string `json:"private_key"`
+	Structures []int  `json:"structures,omitempty"`
+	// RotateTo is the hex private key this seat is moving to.
+	RotateTo string `json:"rotate_to,omitempty"`
+}
+
+// newIdentity builds a signing identity from a hex secp256k1 private key.
+func newIdentity(privateKeyHex string, structures map[int]bool) (*SigningIdentity, error) {
+	raw, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	privKey, err := crypto.UnmarshalSecp256k1PrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewMemorySigner(privKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningIdentity{Signer: signer, Structures: structures}, nil
+}
+
+// withRotation makes identity rotate to the key rotateTo and returns both,
+// so the node keeps signing with the old key through the overlap window.
+func withRotation(identity *SigningIdentity, rotateTo string) ([]*SigningIdentity, error) {
+	next, err := newIdentity(rotateTo, identity.Structures)
+	if err != nil {
+		return nil, fmt.Errorf("rotation key: %w", err)
+	}
+	identity.RotateTo = next
+	return []*SigningIdentity{identity, next}, nil
+}
+
+// loadIdentities reads signing keys and their policies from a JSON file. A
+// missing file yields no identities so the caller can fall back to
+// PRIVATE_KEY.
+func loadIdentities(path string) ([]*SigningIdentity, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	var keys []keyConfig
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse keys file: %w", err)
+	}
+
+	identities := make([]*SigningIdentity, 0, len(keys))
+	for i, key := range keys {
+		var structures map[int]bool
+		if len(key.Structures) > 0 {
+			structures = make(map[int]bool, len(key.Structures))
+			for _, id := range key.Structures {
+				structures[id] = true
+			}
+		}
+
+		identity, err := newIdentity(key.PrivateKey, structures)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if key.RotateTo == "" {
+			identities = append(identities, identity)
+			continue
+		}
+
+		pair, err := withRotation(identity, key.RotateTo)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		identities = append(identities, pair...)
+	}
+	return identities, nil
+}
+
+// loadSigners returns the identities from keysPath, or the single privKey
+// identity (optionally rotating to rotateTo) when no keys file exists.
+func loadSigners(privKey crypto.PrivKey, keysPath, rotateTo string) ([]*SigningIdentity, error) {
+	signers, err := loadIdentities(keysPath)
+	if err != nil || len(signers) > 0 {
+		return signers, err
+	}
+
+	signer, err := NewMemorySigner(privKey)
+	if err != nil {
+		return nil, err
+	}
+	identity := &SigningIdentity{Signer: signer}
+	if rotateTo != "" {
+		return withRotation(identity, rotateTo)
+	}
+	return []*SigningIdentity{identity}, nil
+}
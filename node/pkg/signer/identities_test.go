@@ -0,0 +1,45 @@
+package signer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopedIdentityNeedsStructure(t *testing.T) {
+	scoped := &SigningIdentity{Structures: map[int]bool{0: true, 2: true}}
+	unscoped := &SigningIdentity{}
+	withStructure := func(id int) SignRequest {
+		req := *quoteRequest(t, "SBER", "1", 1700000000)
+		req.DataStructureId = id
+		return req
+	}
+	relabeled := withStructure(2)
+	relabeled.Hash = strings.Repeat("ab", 32)
+	packed := withStructure(2)
+	packed.HashVersion = hashVersionJSON + 1
+	legacy := withStructure(2)
+	legacy.HashVersion = 0
+
+	tests := []struct {
+		name     string
+		req      SignRequest
+		scoped   bool
+		unscoped bool
+	}{
+		{"in scope", withStructure(2), true, true},
+		{"structure zero in scope", withStructure(0), true, true},
+		{"out of scope", withStructure(3), false, true},
+		{"hash-only rebroadcast", SignRequest{Hash: "ab"}, false, true},
+		{"hash not of the data", relabeled, false, true},
+		{"hash version not recomputable", packed, false, true},
+		{"unversioned hash", legacy, true, true},
+	}
+	for _, tt := range tests {
+		if got := scoped.allows(&tt.req); got != tt.scoped {
+			t.Errorf("%s: scoped key allows = %v, want %v", tt.name, got, tt.scoped)
+		}
+		if got := unscoped.allows(&tt.req); got != tt.unscoped {
+			t.Errorf("%s: unscoped key allows = %v, want %v", tt.name, got, tt.unscoped)
+		}
+	}
+}
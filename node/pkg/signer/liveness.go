@@ -0,0 +1,80 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	MsgTypeSignerHeartbeat = "signer_heartbeat"
+
+	defaultHeartbeatInterval = 30 * time.Second
+)
+
+// SignerHeartbeat tells operators a signing address is up. Timestamp is in
+// Unix milliseconds so operators can estimate how long it took to arrive.
+type SignerHeartbeat struct {
+	Type      string `json:"type"`
+	Address   string `json:"address"`
+	Software  string `json:"software,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+}
+
+// signerHeartbeatDigest must match the operator's.
+func signerHeartbeatDigest(address, software string, timestamp int64) []byte {
+	payload := fmt.Sprintf("l0proof signer heartbeat:%s:%s:%d", strings.ToLower(address), software, timestamp)
+	return accounts.TextHash(cryptoeth.Keccak256([]byte(payload)))
+}
+
+// publishHeartbeats announces every signing identity each interval until
+// the node stops.
+func (n *Node) publishHeartbeats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, identity := range n.signers {
+			if err := n.publishHeartbeat(identity.Signer); err != nil {
+				log.Printf("Error publishing heartbeat of %s: %v", identity.Signer.Address(), err)
+			}
+		}
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) publishHeartbeat(signer Signer) error {
+	software := buildVersion()
+	timestamp := time.Now().UnixMilli()
+	signature, err := signer.Sign(signerHeartbeatDigest(signer.Address(), software, timestamp))
+	if err != nil {
+		return fmt.Errorf("failed to sign heartbeat: %w", err)
+	}
+
+	msg, err := json.Marshal(SignerHeartbeat{
+		Type:      MsgTypeSignerHeartbeat,
+		Address:   signer.Address(),
+		Software:  software,
+		Timestamp: timestamp,
+		Signature: signature,
+
+		ProtocolVersion: ProtocolVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+	return n.topic.Publish(n.ctx, msg)
+}
@@ -0,0 +1,71 @@
+package signer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	signRequestsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "sign_requests_received_total",
+		Help:      "Sign requests read from the topic.",
+	})
+
+	signRequestsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "sign_requests_dropped_total",
+		Help:      "Sign requests not signed, by reason.",
+	}, []string{"reason"})
+
+	originatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "originated_requests_total",
+		Help:      "Rounds of the node's own feeds, by outcome.",
+	}, []string{"result"})
+
+	signaturesProduced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "signatures_total",
+		Help:      "Sign responses published, by signing address.",
+	}, []string{"address"})
+
+	signaturesSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "signatures_skipped_total",
+		Help:      "Sign requests a signing address did not answer, by reason.",
+	}, []string{"address", "reason"})
+
+	missedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "missed_requests_total",
+		Help:      "Sign requests skipped in a data structure's sequence, by structure.",
+	}, []string{"structure"})
+
+	catchUpRecovered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "catch_up_recovered_total",
+		Help:      "Confirmed messages fetched from the operator to fill missed sequence numbers.",
+	})
+
+	signQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "sign_queue_depth",
+		Help:      "Sign requests waiting for a worker.",
+	})
+
+	connectedPeersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "l0proof",
+		Subsystem: "signer",
+		Name:      "connected_peers",
+		Help:      "Peers the signer is connected to.",
+	})
+)
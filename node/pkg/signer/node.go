@@ -0,0 +1,394 @@
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	MsgTypeSignRequest  = "sign_request"
+	MsgTypeSignResponse = "sign_response"
+)
+
+type SignRequest struct {
+	Type            string `json:"type"`
+	Hash            string `json:"hash"`
+	Timestamp       int64  `json:"timestamp"`
+	RequestID       string `json:"request_id,omitempty"`
+	DataStructureId int    `json:"data_structure_id"`
+	Sequence        uint64 `json:"sequence,omitempty"`
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	// Data is kept encoded as it was hashed; it is only decoded to check
+	// price bounds.
+	Data              json.RawMessage `json:"data,omitempty"`
+	DataStructureMeta []string        `json:"data_structure_meta,omitempty"`
+	HashVersion       int             `json:"hash_version,omitempty"`
+	// TraceContext carries the operator's span, which signing continues.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+type SignResponse struct {
+	Type      string `json:"type"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+	PeerID    string `json:"peer_id"`
+	Timestamp int64  `json:"timestamp"`
+	RequestID string `json:"request_id,omitempty"`
+
+	ProtocolVersion string            `json:"protocol_version,omitempty"`
+	TraceContext    map[string]string `json:"trace_context,omitempty"`
+}
+
+type Node struct {
+	ctx       context.Context
+	host      host.Host
+	topic     *pubsub.Topic
+	sub       *pubsub.Subscription
+	signers   []*SigningIdentity
+	bootstrap string
+	maxSkew   time.Duration
+	wg        sync.WaitGroup
+
+	// Sign requests are queued here so signing never blocks subscription
+	// reads.
+	queue       chan *SignRequest
+	queueConfig QueueConfig
+
+	// net backs /debug/net.
+	net *netMonitor
+
+	// sequences follows the request sequence numbers per data structure.
+	sequences *sequenceTracker
+
+	// versions holds the hello answers of connected peers.
+	versions *peerVersions
+
+	// prices refuses requests outside the configured price bounds.
+	prices *priceGuard
+
+	// Counters behind the /status endpoint.
+	signatures    atomic.Int64
+	lastRequest   lastRequest
+	lastRequestMu sync.RWMutex
+}
+
+type Signer interface {
+	Sign(message []byte) (string, error)
+	Address() string
+}
+
+func NewNode(ctx context.Context, privKey crypto.PrivKey, signers []*SigningIdentity, topicName, bootstrapAddr string, maxSkew time.Duration, gossip GossipConfig, queue QueueConfig, bounds map[string]PriceBounds) (*Node, error) {
+	queue, err := queue.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := libp2p.New(libp2p.Identity(privKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host: %w", err)
+	}
+
+	log.Println("✅ Node started.")
+
+	monitor := newNetMonitor(h, bootstrapAddr)
+	if err := monitor.watchReachability(ctx); err != nil {
+		return nil, err
+	}
+
+	gossipOpts, err := gossip.Options()
+	if err != nil {
+		return nil, err
+	}
+	ps, err := pubsub.NewGossipSub(ctx, h, append(gossipOpts, pubsub.WithRawTracer(monitor))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub: %w", err)
+	}
+	monitor.ps = ps
+
+	topic, err := ps.Join(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join topic: %w", err)
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	node := &Node{
+		ctx:       ctx,
+		host:      h,
+		topic:     topic,
+		sub:       sub,
+		signers:   signers,
+		bootstrap: bootstrapAddr,
+		maxSkew:   maxSkew,
+
+		queue:       make(chan *SignRequest, queue.Size),
+		queueConfig: queue,
+
+		net:       monitor,
+		sequences: newSequenceTracker(),
+		versions:  newPeerVersions(),
+		prices:    newPriceGuard(bounds),
+	}
+
+	h.SetStreamHandler(helloProtocolID, node.handleHelloStream)
+	node.setupNetworkNotifiers()
+	node.connectToBootstrap()
+	node.startSignWorkers()
+	for _, identity := range signers {
+		if identity.RotateTo != nil {
+			go node.announceKeyRotation(identity.Signer, identity.RotateTo.Signer)
+		}
+	}
+	go node.listen()
+	go node.connectionMonitor()
+	return node, nil
+}
+
+func (n *Node) setupNetworkNotifiers() {
+	n.host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(net network.Network, conn network.Conn) {
+			// Only the first connection to a peer announces our versions.
+			if len(net.ConnsToPeer(conn.RemotePeer())) == 1 {
+				go n.sayHello(conn.RemotePeer())
+			}
+		},
+		DisconnectedF: func(net network.Network, conn network.Conn) {
+			log.Printf("❌ Disconnected from peer: %s", conn.RemotePeer())
+			if net.Connectedness(conn.RemotePeer()) != network.Connected {
+				n.versions.forget(conn.RemotePeer())
+			}
+		},
+	})
+}
+
+func (n *Node) connectionMonitor() {
+	ticker := time.NewTicker(connectionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			connectedPeersGauge.Set(float64(len(n.host.Network().Peers())))
+			if n.bootstrap != "" && len(n.host.Network().Peers()) == 0 {
+				log.Println("⚠️ No peers connected, attempting to reconnect to bootstrap...")
+				n.connectToBootstrap()
+			}
+		}
+	}
+}
+
+func (n *Node) connectToBootstrap() {
+	if n.bootstrap == "" {
+		return
+	}
+
+	maddr, err := multiaddr.NewMultiaddr(n.bootstrap)
+	if err != nil {
+		log.Printf("Error parsing bootstrap address: %v", err)
+		return
+	}
+
+	peerInfo, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		log.Printf("Error getting bootstrap peer info: %v", err)
+		return
+	}
+
+	for {
+		ctx, cancel := context.WithTimeout(n.ctx, reconnectTimeout)
+		err := n.host.Connect(ctx, *peerInfo)
+		cancel()
+
+		if err == nil {
+			log.Println("✅ Connected to bootstrap node")
+			return
+		}
+
+		log.Printf("Reconnect attempt failed: %v", err)
+		time.Sleep(reconnectTimeout)
+	}
+
+}
+
+func (n *Node) resubscribe() error {
+	var err error
+	n.sub.Cancel()
+
+	for i := 0; i < maxReconnectAttempts; i++ {
+		n.sub, err = n.topic.Subscribe()
+		if err == nil {
+			return nil
+		}
+		log.Printf("Resubscribe attempt %d/%d failed: %v", i+1, maxReconnectAttempts, err)
+		time.Sleep(reconnectTimeout)
+	}
+
+	return fmt.Errorf("failed to resubscribe after %d attempts: %w", maxReconnectAttempts, err)
+}
+
+func (n *Node) listen() {
+	defer n.wg.Done()
+	n.wg.Add(1)
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		default:
+			ctx, cancel := context.WithTimeout(n.ctx, subscriptionReadTimeout)
+			msg, err := n.sub.Next(ctx)
+			cancel()
+
+			if err != nil {
+				if n.ctx.Err() == nil {
+					log.Printf("Error reading from subscription: %v", err)
+					n.net.recordSubscriptionError(err)
+					if err := n.resubscribe(); err != nil {
+						log.Printf("Failed to resubscribe: %v", err)
+						n.net.recordSubscriptionError(err)
+					}
+				}
+				continue
+			}
+
+			n.HandleMessage(msg.Data)
+		}
+	}
+}
+
+func (n *Node) HandleMessage(data []byte) {
+	var msg struct {
+		Type            string `json:"type"`
+		ProtocolVersion string `json:"protocol_version"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Error unmarshaling message: %v", err)
+		return
+	}
+	if msg.Type == MsgTypeSignRequest && !protocolCompatible(msg.ProtocolVersion) {
+		log.Printf("Refusing sign request: protocol %s is incompatible with %s", msg.ProtocolVersion, ProtocolVersion)
+		signRequestsDropped.WithLabelValues("protocol_version").Inc()
+		return
+	}
+
+	switch msg.Type {
+	case MsgTypeSignRequest:
+		var req SignRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			log.Printf("Error unmarshaling sign request: %v", err)
+			return
+		}
+		log.Printf("Processing sign request for: %s [req=%s]", req.Hash, req.RequestID)
+		n.recordRequest(&req)
+		n.enqueueSignRequest(&req)
+	default:
+	}
+}
+
+func (n *Node) handleSignRequest(req *SignRequest) {
+	ctx, span := tracer.Start(extractTraceContext(n.ctx, req.TraceContext), "sign_request",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("hash", req.Hash), attribute.String("request_id", req.RequestID)),
+	)
+	defer span.End()
+
+	if !n.timestampAcceptable(req.Timestamp) {
+		log.Printf("Refusing to sign %s: timestamp %d outside skew %s [req=%s]", req.Hash, req.Timestamp, n.maxSkew, req.RequestID)
+		signRequestsDropped.WithLabelValues("timestamp_skew").Inc()
+		span.SetStatus(codes.Error, "timestamp skew")
+		return
+	}
+	if reason, err := n.prices.check(req); err != nil {
+		log.Printf("Refusing to sign %s: %v [req=%s]", req.Hash, err, req.RequestID)
+		signRequestsDropped.WithLabelValues(reason).Inc()
+		recordSpanError(span, err)
+		return
+	}
+
+	// The hash comes from any peer on the topic; a malformed one is
+	// dropped, not trusted to be well formed.
+	hash, err := hex.DecodeString(req.Hash)
+	if err != nil || len(hash) != 32 {
+		log.Printf("Refusing to sign %q: not a 32-byte hex hash [req=%s]", req.Hash, req.RequestID)
+		signRequestsDropped.WithLabelValues("invalid_hash").Inc()
+		span.SetStatus(codes.Error, "invalid hash")
+		return
+	}
+	message := accounts.TextHash(hash)
+
+	for _, identity := range n.signers {
+		n.signWith(ctx, identity, req, message)
+	}
+}
+
+// signWith publishes one SignResponse for req signed by identity, unless
+// its policy excludes the request.
+func (n *Node) signWith(ctx context.Context, identity *SigningIdentity, req *SignRequest, message []byte) {
+	address := identity.Signer.Address()
+	if !identity.allows(req) {
+		signaturesSkipped.WithLabelValues(address, "policy").Inc()
+		return
+	}
+
+	ctx, span := tracer.Start(ctx, "sign", trace.WithAttributes(attribute.String("signer", address)))
+	defer span.End()
+
+	signature, err := identity.Signer.Sign(message)
+	if err != nil {
+		log.Printf("Error signing data with %s [req=%s]: %v", address, req.RequestID, err)
+		signaturesSkipped.WithLabelValues(address, "sign_error").Inc()
+		recordSpanError(span, err)
+		return
+	}
+
+	resp := SignResponse{
+		Type:      MsgTypeSignResponse,
+		Hash:      req.Hash,
+		Signature: signature,
+		PeerID:    address,
+		Timestamp: time.Now().Unix(),
+		RequestID: req.RequestID,
+
+		ProtocolVersion: ProtocolVersion,
+		TraceContext:    injectTraceContext(ctx),
+	}
+
+	msg, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling sign response: %v", err)
+		return
+	}
+
+	if err := n.topic.Publish(ctx, msg); err != nil {
+		log.Printf("Error publishing sign response from %s [req=%s]: %v", address, req.RequestID, err)
+		signaturesSkipped.WithLabelValues(address, "publish_error").Inc()
+		recordSpanError(span, err)
+		return
+	}
+	identity.signatures.Add(1)
+	n.signatures.Add(1)
+	signaturesProduced.WithLabelValues(address).Inc()
+}
@@ -0,0 +1,34 @@
+package signer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHandleSignRequestDropsMalformedHash(t *testing.T) {
+	n := &Node{ctx: context.Background(), maxSkew: time.Minute}
+	for _, hash := range []string{"not-hex", "0xabcd", "abcd"} {
+		// Must return without signing or panicking.
+		n.handleSignRequest(&SignRequest{Hash: hash, Timestamp: time.Now().Unix()})
+	}
+}
+
+func TestTimestampAcceptableWithinSkew(t *testing.T) {
+	n := &Node{maxSkew: time.Minute}
+	now := time.Now()
+	for _, tc := range []struct {
+		timestamp time.Time
+		want      bool
+	}{
+		{now, true},
+		{now.Add(-30 * time.Second), true},
+		{now.Add(30 * time.Second), true},
+		{now.Add(-2 * time.Minute), false},
+		{now.Add(2 * time.Minute), false},
+	} {
+		if got := n.timestampAcceptable(tc.timestamp.Unix()); got != tc.want {
+			t.Errorf("timestamp %v off now: got %v, want %v", tc.timestamp.Sub(now), got, tc.want)
+		}
+	}
+}
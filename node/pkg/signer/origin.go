@@ -0,0 +1,202 @@
+package signer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// hashVersionJSON is the operator's hash of the JSON encoded data
+	// followed by the timestamp.
+	hashVersionJSON = 1
+
+	feedFetchTimeout    = 10 * time.Second
+	maxFeedResponseSize = 1 << 20
+)
+
+// Feed is data this node collects itself and publishes sign requests for,
+// instead of waiting for the operator to. The operator only accepts them
+// when the node's address is one of its originators.
+type Feed struct {
+	StructureID int `json:"structure_id"`
+	// URL answers GET with a JSON object holding the fields by name.
+	URL             string      `json:"url"`
+	Fields          []FeedField `json:"fields"`
+	IntervalSeconds int         `json:"interval_seconds"`
+}
+
+// FeedField is one value of a feed's message, in message order.
+type FeedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// originatedRequest is a sign request as the operator builds it, signed by
+// the originating node.
+type originatedRequest struct {
+	Type              string        `json:"type"`
+	Hash              string        `json:"hash"`
+	Data              []interface{} `json:"data"`
+	DataStructure     []string      `json:"data_structure"`
+	DataStructureMeta []string      `json:"data_structure_meta"`
+	DataStructureId   int           `json:"data_structure_id"`
+	Timestamp         int64         `json:"timestamp"`
+	HashVersion       int           `json:"hash_version"`
+	RequestID         string        `json:"request_id"`
+	ProtocolVersion   string        `json:"protocol_version"`
+	Origin            string        `json:"origin"`
+	OriginSignature   string        `json:"origin_signature"`
+}
+
+// loadFeeds reads the feeds file. An empty path originates nothing.
+func loadFeeds(path string) ([]Feed, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feeds file: %w", err)
+	}
+	var feeds []Feed
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("failed to parse feeds file: %w", err)
+	}
+	for i, feed := range feeds {
+		if feed.URL == "" || len(feed.Fields) == 0 || feed.IntervalSeconds <= 0 {
+			return nil, fmt.Errorf("feed %d needs a url, fields and a positive interval", i)
+		}
+	}
+	return feeds, nil
+}
+
+// originate publishes a sign request for feed every interval, signed by
+// the node's first identity, until the node stops.
+func (n *Node) originate(feed Feed) {
+	origin := n.signers[0].Signer
+	ticker := time.NewTicker(time.Duration(feed.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := n.originateOnce(feed, origin); err != nil {
+			log.Printf("Error originating structure %d: %v", feed.StructureID, err)
+			originatedRequests.WithLabelValues("error").Inc()
+		} else {
+			originatedRequests.WithLabelValues("published").Inc()
+		}
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) originateOnce(feed Feed, origin Signer) error {
+	values, err := fetchFeed(n.ctx, feed.URL)
+	if err != nil {
+		return err
+	}
+
+	req := originatedRequest{
+		Type:              MsgTypeSignRequest,
+		DataStructure:     make([]string, len(feed.Fields)),
+		DataStructureMeta: make([]string, len(feed.Fields)),
+		Data:              make([]interface{}, len(feed.Fields)),
+		DataStructureId:   feed.StructureID,
+		Timestamp:         time.Now().Unix(),
+		HashVersion:       hashVersionJSON,
+		RequestID:         newRequestID(),
+		ProtocolVersion:   ProtocolVersion,
+		Origin:            origin.Address(),
+	}
+	for i, f := range feed.Fields {
+		v, ok := values[f.Name]
+		if !ok {
+			return fmt.Errorf("source has no field %s", f.Name)
+		}
+		req.DataStructure[i] = f.Type
+		req.DataStructureMeta[i] = f.Name
+		req.Data[i] = v
+	}
+
+	hash, err := jsonHash(req.Data, req.Timestamp)
+	if err != nil {
+		return err
+	}
+	req.Hash = hex.EncodeToString(hash)
+	if req.OriginSignature, err = origin.Sign(accounts.TextHash(hash)); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if err := n.topic.Publish(n.ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	log.Printf("📤 Originated %s for structure %d [req=%s]", req.Hash, req.DataStructureId, req.RequestID)
+	return nil
+}
+
+// fetchFeed reads the JSON object a feed source answers with. Numbers are
+// decoded as the operator decodes them, so both hash the same bytes.
+func fetchFeed(ctx context.Context, url string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, feedFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source answered %s", resp.Status)
+	}
+
+	var values map[string]interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedResponseSize)).Decode(&values); err != nil {
+		return nil, fmt.Errorf("malformed source response: %w", err)
+	}
+	return values, nil
+}
+
+// jsonHash is keccak256(abi.encodePacked(json(data), uint256(timestamp))).
+func jsonHash(data []interface{}, timestamp int64) ([]byte, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return packedJSONHash(encoded, timestamp), nil
+}
+
+// packedJSONHash is jsonHash of data already JSON encoded.
+func packedJSONHash(encoded []byte, timestamp int64) []byte {
+	return cryptoeth.Keccak256(encoded, common.LeftPadBytes(big.NewInt(timestamp).Bytes(), 32))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
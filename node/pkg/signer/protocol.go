@@ -0,0 +1,180 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// ProtocolVersion must match the operator's major version. Requests from an
+// operator on another major are not signed.
+const ProtocolVersion = "1.0"
+
+// legacyProtocolVersion is assumed for messages without a version.
+const legacyProtocolVersion = "1.0"
+
+// helloProtocolID is the operator's version announcement protocol.
+const helloProtocolID = protocol.ID("/l0proof/hello/1.0.0")
+
+const (
+	helloTimeout = 10 * time.Second
+	maxHelloSize = 4 << 10
+)
+
+// Version names the signer build. Release builds set it with
+// -ldflags "-X listener_node/pkg/signer.Version=v1.2.3".
+var Version string
+
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				return setting.Value[:12]
+			}
+		}
+	}
+	return "dev"
+}
+
+// Hello announces a peer's protocol and software versions and the addresses
+// it signs with.
+type Hello struct {
+	ProtocolVersion string   `json:"protocol_version"`
+	Software        string   `json:"software,omitempty"`
+	Role            string   `json:"role,omitempty"`
+	Addresses       []string `json:"addresses,omitempty"`
+}
+
+func protocolCompatible(v string) bool {
+	if v == "" {
+		v = legacyProtocolVersion
+	}
+	theirs, _, _ := strings.Cut(v, ".")
+	ours, _, _ := strings.Cut(ProtocolVersion, ".")
+	_, err := strconv.Atoi(theirs)
+	return err == nil && theirs == ours
+}
+
+// PeerVersion is what a peer answered to our hello.
+type PeerVersion struct {
+	PeerID          string `json:"peer_id"`
+	Role            string `json:"role,omitempty"`
+	ProtocolVersion string `json:"protocol_version"`
+	Software        string `json:"software,omitempty"`
+	Compatible      bool   `json:"compatible"`
+}
+
+// peerVersions remembers the answers of connected peers.
+type peerVersions struct {
+	mu    sync.Mutex
+	peers map[peer.ID]PeerVersion
+}
+
+func newPeerVersions() *peerVersions {
+	return &peerVersions{peers: make(map[peer.ID]PeerVersion)}
+}
+
+func (v *peerVersions) set(p peer.ID, version PeerVersion) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.peers[p] = version
+}
+
+func (v *peerVersions) forget(p peer.ID) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.peers, p)
+}
+
+func (v *peerVersions) snapshot() []PeerVersion {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	list := make([]PeerVersion, 0, len(v.peers))
+	for _, version := range v.peers {
+		list = append(list, version)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PeerID < list[j].PeerID })
+	return list
+}
+
+func (n *Node) hello() Hello {
+	hello := Hello{ProtocolVersion: ProtocolVersion, Software: buildVersion(), Role: "signer"}
+	for _, identity := range n.signers {
+		hello.Addresses = append(hello.Addresses, identity.Signer.Address())
+	}
+	return hello
+}
+
+// sayHello announces our versions to p and records its answer. Peers that
+// do not speak the protocol, such as signers before versioning, are skipped.
+func (n *Node) sayHello(p peer.ID) {
+	ctx, cancel := context.WithTimeout(n.ctx, helloTimeout)
+	defer cancel()
+
+	s, err := n.host.NewStream(ctx, p, helloProtocolID)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(helloTimeout))
+
+	if err := json.NewEncoder(s).Encode(n.hello()); err != nil {
+		s.Reset()
+		return
+	}
+	if err := s.CloseWrite(); err != nil {
+		s.Reset()
+		return
+	}
+
+	var reply Hello
+	if err := json.NewDecoder(io.LimitReader(s, maxHelloSize)).Decode(&reply); err != nil {
+		s.Reset()
+		log.Printf("Malformed hello reply from %s: %v", p, err)
+		return
+	}
+
+	compatible := protocolCompatible(reply.ProtocolVersion)
+	n.versions.set(p, PeerVersion{
+		PeerID:          p.String(),
+		Role:            reply.Role,
+		ProtocolVersion: reply.ProtocolVersion,
+		Software:        reply.Software,
+		Compatible:      compatible,
+	})
+	if !compatible {
+		log.Printf("⚠️ %s %s speaks protocol %s, incompatible with %s; its requests will not be signed", reply.Role, p, reply.ProtocolVersion, ProtocolVersion)
+		return
+	}
+	log.Printf("🤝 %s %s speaks protocol %s (%s)", reply.Role, p, reply.ProtocolVersion, reply.Software)
+}
+
+// handleHelloStream answers other signers' hellos.
+func (n *Node) handleHelloStream(s network.Stream) {
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(helloTimeout))
+
+	var hello Hello
+	if err := json.NewDecoder(io.LimitReader(s, maxHelloSize)).Decode(&hello); err != nil {
+		s.Reset()
+		return
+	}
+	if err := json.NewEncoder(s).Encode(n.hello()); err != nil {
+		s.Reset()
+	}
+}
@@ -0,0 +1,100 @@
+package signer
+
+import (
+	"fmt"
+	"log"
+)
+
+const (
+	// OverflowDropNewest rejects requests arriving while the queue is full.
+	OverflowDropNewest = "drop_newest"
+	// OverflowDropOldest evicts the oldest queued request to make room; the
+	// operator rebroadcasts anything still pending.
+	OverflowDropOldest = "drop_oldest"
+	// OverflowBlock waits for room, stalling subscription reads.
+	OverflowBlock = "block"
+
+	defaultSignQueueSize = 1024
+	defaultSignWorkers   = 4
+)
+
+// QueueConfig sizes the buffer between the subscription reader and the
+// signing workers.
+type QueueConfig struct {
+	Size     int    `yaml:"size"`
+	Workers  int    `yaml:"workers"`
+	Overflow string `yaml:"overflow"`
+}
+
+func (c QueueConfig) withDefaults() (QueueConfig, error) {
+	if c.Size <= 0 {
+		c.Size = defaultSignQueueSize
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultSignWorkers
+	}
+	switch c.Overflow {
+	case "":
+		c.Overflow = OverflowDropOldest
+	case OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		return c, fmt.Errorf("unknown sign queue overflow policy %q", c.Overflow)
+	}
+	return c, nil
+}
+
+// startSignWorkers runs the pool that drains the sign queue.
+func (n *Node) startSignWorkers() {
+	for i := 0; i < n.queueConfig.Workers; i++ {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			for {
+				select {
+				case <-n.ctx.Done():
+					return
+				case req := <-n.queue:
+					signQueueDepth.Set(float64(len(n.queue)))
+					n.handleSignRequest(req)
+				}
+			}
+		}()
+	}
+}
+
+// enqueueSignRequest hands req to the workers without blocking the
+// subscription reader, unless the overflow policy says to.
+func (n *Node) enqueueSignRequest(req *SignRequest) {
+	defer func() { signQueueDepth.Set(float64(len(n.queue))) }()
+
+	select {
+	case n.queue <- req:
+		return
+	default:
+	}
+
+	switch n.queueConfig.Overflow {
+	case OverflowBlock:
+		select {
+		case n.queue <- req:
+		case <-n.ctx.Done():
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case n.queue <- req:
+				return
+			default:
+			}
+			select {
+			case old := <-n.queue:
+				log.Printf("⚠️ Sign queue full, dropped oldest request %s [req=%s]", old.Hash, old.RequestID)
+				signRequestsDropped.WithLabelValues("queue_full").Inc()
+			default:
+			}
+		}
+	default:
+		log.Printf("⚠️ Sign queue full, dropped request %s [req=%s]", req.Hash, req.RequestID)
+		signRequestsDropped.WithLabelValues("queue_full").Inc()
+	}
+}
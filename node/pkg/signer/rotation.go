@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	MsgTypeKeyRotation = "key_rotation"
+
+	keyRotationAnnounceInterval = time.Minute
+)
+
+// KeyRotation announces that the seat held by OldAddress moves to
+// NewAddress. It is signed by the old key.
+type KeyRotation struct {
+	Type       string `json:"type"`
+	OldAddress string `json:"old_address"`
+	NewAddress string `json:"new_address"`
+	Timestamp  int64  `json:"timestamp"`
+	Signature  string `json:"signature"`
+
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+}
+
+// keyRotationDigest must match the operator's.
+func keyRotationDigest(oldAddress, newAddress string, timestamp int64) []byte {
+	payload := fmt.Sprintf("l0proof key rotation:%s:%s:%d", strings.ToLower(oldAddress), strings.ToLower(newAddress), timestamp)
+	return accounts.TextHash(cryptoeth.Keccak256([]byte(payload)))
+}
+
+// announceKeyRotation publishes the rotation from old to next periodically,
+// so operators that were offline or joined later still learn about it.
+// Operators ignore repeats of a rotation they already recorded.
+func (n *Node) announceKeyRotation(old, next Signer) {
+	ticker := time.NewTicker(keyRotationAnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.publishKeyRotation(old, next); err != nil {
+			log.Printf("Error announcing key rotation: %v", err)
+		}
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) publishKeyRotation(old, next Signer) error {
+	timestamp := time.Now().Unix()
+	signature, err := old.Sign(keyRotationDigest(old.Address(), next.Address(), timestamp))
+	if err != nil {
+		return fmt.Errorf("failed to sign rotation: %w", err)
+	}
+
+	msg, err := json.Marshal(KeyRotation{
+		Type:       MsgTypeKeyRotation,
+		OldAddress: old.Address(),
+		NewAddress: next.Address(),
+		Timestamp:  timestamp,
+		Signature:  signature,
+
+		ProtocolVersion: ProtocolVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rotation: %w", err)
+	}
+
+	if err := n.topic.Publish(n.ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish rotation: %w", err)
+	}
+	log.Printf("🔑 Announced key rotation %s -> %s", old.Address(), next.Address())
+	return nil
+}
@@ -0,0 +1,54 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Run starts a signer from cfg and blocks until ctx is done and the node has
+// stopped. cfg should have passed Validate.
+func Run(ctx context.Context, cfg Config) error {
+	privKey, err := getOrCreatePrivKey(cfg.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+	signers, err := loadSigners(privKey, cfg.KeysPath, cfg.RotateToKey)
+	if err != nil {
+		return err
+	}
+	log.Printf("Signing with %d identities", len(signers))
+	feeds, err := loadFeeds(cfg.FeedsPath)
+	if err != nil {
+		return err
+	}
+
+	go checkClockDrift(cfg.NTPServer, cfg.MaxTimestampSkew)
+
+	node, err := NewNode(ctx, privKey, signers, cfg.Topic, cfg.BootstrapNode, cfg.MaxTimestampSkew, cfg.Gossip, cfg.Queue, cfg.PriceBounds)
+	if err != nil {
+		return fmt.Errorf("failed to create regular node: %w", err)
+	}
+
+	if cfg.HeartbeatInterval > 0 {
+		go node.publishHeartbeats(cfg.HeartbeatInterval)
+	}
+	for _, feed := range feeds {
+		go node.originate(feed)
+	}
+	if len(feeds) > 0 {
+		log.Printf("📤 Originating %d feeds as %s", len(feeds), signers[0].Signer.Address())
+	}
+
+	if len(cfg.PriceBounds) > 0 {
+		log.Printf("🚧 Refusing prices outside the bounds of %d tickers", len(cfg.PriceBounds))
+	}
+
+	if cfg.StatusAddr != "" {
+		go node.serveStatus(cfg.StatusAddr)
+	}
+
+	<-ctx.Done()
+	node.wg.Wait()
+	return nil
+}
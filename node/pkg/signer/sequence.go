@@ -0,0 +1,86 @@
+package signer
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// SequenceStatus reports the sign request sequence of one data structure:
+// the highest number seen, how many below it never arrived and how many of
+// those were later fetched as confirmed messages through catch-up sync.
+type SequenceStatus struct {
+	DataStructureID int    `json:"data_structure_id"`
+	Last            uint64 `json:"last"`
+	Missed          uint64 `json:"missed"`
+	Recovered       uint64 `json:"recovered"`
+}
+
+// sequenceTracker notices when sign requests of a data structure arrive
+// with a jump in their sequence numbers, meaning rounds went unseen.
+// Rebroadcasts and late arrivals carry numbers already passed and are
+// ignored.
+type sequenceTracker struct {
+	mu         sync.Mutex
+	structures map[int]*SequenceStatus
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{structures: make(map[int]*SequenceStatus)}
+}
+
+// observe records req and returns the range of sequence numbers skipped
+// before it, if any.
+func (t *sequenceTracker) observe(req *SignRequest) (from, to uint64, missed bool) {
+	if req.Sequence == 0 {
+		return 0, 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, ok := t.structures[req.DataStructureId]
+	if !ok {
+		t.structures[req.DataStructureId] = &SequenceStatus{DataStructureID: req.DataStructureId, Last: req.Sequence}
+		return 0, 0, false
+	}
+	if req.Sequence <= status.Last {
+		return 0, 0, false
+	}
+	from, to = status.Last+1, req.Sequence-1
+	status.Last = req.Sequence
+	if from > to {
+		return 0, 0, false
+	}
+
+	count := to - from + 1
+	status.Missed += count
+	missedRequests.WithLabelValues(strconv.Itoa(req.DataStructureId)).Add(float64(count))
+	log.Printf("⚠️ Missed %d sign requests for structure %d: sequence %d to %d [req=%s]",
+		count, req.DataStructureId, from, to, req.RequestID)
+	return from, to, true
+}
+
+func (t *sequenceTracker) recovered(dataStructureID int, count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if status, ok := t.structures[dataStructureID]; ok {
+		status.Recovered += uint64(count)
+	}
+}
+
+func (t *sequenceTracker) snapshot() []SequenceStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]SequenceStatus, 0, len(t.structures))
+	for _, status := range t.structures {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].DataStructureID < statuses[j].DataStructureID
+	})
+	return statuses
+}
@@ -0,0 +1,81 @@
+// Package signer runs a signer node: it dials the operator, signs the
+// requests gossiped on the topic and publishes the signatures back.
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptoeth "github.com/ethereum/go-ethereum/crypto"
+	"github.com/libp2p/go-libp2p/core/crypto"
+)
+
+const (
+	reconnectTimeout        = 5 * time.Second
+	maxReconnectAttempts    = 30
+	connectionCheckInterval = 10 * time.Second
+	subscriptionReadTimeout = 30 * time.Second
+)
+
+// getOrCreatePrivKey decodes a hex secp256k1 key, generating a fresh one
+// when none is configured.
+func getOrCreatePrivKey(hexKey string) (crypto.PrivKey, error) {
+	if hexKey == "" {
+		priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return priv, nil
+	}
+	pk, err := hex.DecodeString(hexKey)
+	if err != nil {
+		log.Println("Error decode PK")
+	}
+	return crypto.UnmarshalSecp256k1PrivateKey([]byte(pk))
+}
+
+type MemorySigner struct {
+	privKey      crypto.PrivKey
+	ecdsaPrivKey ecdsa.PrivateKey
+	address      string
+}
+
+func NewMemorySigner(privKey crypto.PrivKey) (*MemorySigner, error) {
+	raw, err := privKey.Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get raw private key: %w", err)
+	}
+
+	ecdsaPrivKey, err := cryptoeth.ToECDSA(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to ECDSA key: %w", err)
+	}
+
+	address := cryptoeth.PubkeyToAddress(ecdsaPrivKey.PublicKey)
+	log.Println("Signer", address)
+
+	return &MemorySigner{
+		privKey:      privKey,
+		ecdsaPrivKey: *ecdsaPrivKey,
+		address:      address.Hex(),
+	}, nil
+}
+
+func (s *MemorySigner) Sign(message []byte) (string, error) {
+	signature, err := cryptoeth.Sign(message, &s.ecdsaPrivKey)
+	if err != nil {
+		return "", err
+	}
+
+	return hexutil.Encode(signature), nil
+}
+
+func (s *MemorySigner) Address() string {
+	return s.address
+}
@@ -0,0 +1,130 @@
+package signer
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type lastRequest struct {
+	Hash      string `json:"hash"`
+	RequestID string `json:"request_id,omitempty"`
+	SeenAt    int64  `json:"seen_at"`
+}
+
+// IdentityStatus reports one signing key.
+type IdentityStatus struct {
+	Address    string `json:"address"`
+	Structures []int  `json:"structures,omitempty"`
+	Signatures int64  `json:"signatures"`
+}
+
+// NodeStatus is what /status reports about the signer.
+type NodeStatus struct {
+	PeerID             string           `json:"peer_id"`
+	ProtocolVersion    string           `json:"protocol_version"`
+	Software           string           `json:"software"`
+	Identities         []IdentityStatus `json:"identities"`
+	Peers              int              `json:"peers"`
+	BootstrapConnected bool             `json:"bootstrap_connected"`
+	Signatures         int64            `json:"signatures"`
+	QueueDepth         int              `json:"queue_depth"`
+	LastRequest        *lastRequest     `json:"last_request,omitempty"`
+	Sequences          []SequenceStatus `json:"sequences,omitempty"`
+	// PeerVersions are the versions connected peers answered our hello with.
+	PeerVersions []PeerVersion `json:"peer_versions,omitempty"`
+}
+
+func (n *Node) recordRequest(req *SignRequest) {
+	signRequestsReceived.Inc()
+
+	n.lastRequestMu.Lock()
+	n.lastRequest = lastRequest{Hash: req.Hash, RequestID: req.RequestID, SeenAt: time.Now().Unix()}
+	n.lastRequestMu.Unlock()
+
+	if from, to, missed := n.sequences.observe(req); missed {
+		go n.catchUp(req.DataStructureId, from, to)
+	}
+}
+
+// Status snapshots the node's connectivity and signing activity.
+func (n *Node) Status() NodeStatus {
+	status := NodeStatus{
+		PeerID:          n.host.ID().String(),
+		ProtocolVersion: ProtocolVersion,
+		Software:        buildVersion(),
+		Peers:           len(n.host.Network().Peers()),
+		Signatures:      n.signatures.Load(),
+		QueueDepth:      len(n.queue),
+	}
+	status.BootstrapConnected = n.bootstrapConnected()
+
+	for _, identity := range n.signers {
+		is := IdentityStatus{Address: identity.Signer.Address(), Signatures: identity.signatures.Load()}
+		for id := range identity.Structures {
+			is.Structures = append(is.Structures, id)
+		}
+		sort.Ints(is.Structures)
+		status.Identities = append(status.Identities, is)
+	}
+
+	n.lastRequestMu.RLock()
+	if n.lastRequest.SeenAt != 0 {
+		last := n.lastRequest
+		status.LastRequest = &last
+	}
+	n.lastRequestMu.RUnlock()
+
+	status.Sequences = n.sequences.snapshot()
+	status.PeerVersions = n.versions.snapshot()
+	return status
+}
+
+func (n *Node) bootstrapConnected() bool {
+	if n.bootstrap == "" {
+		return false
+	}
+	maddr, err := multiaddr.NewMultiaddr(n.bootstrap)
+	if err != nil {
+		return false
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return false
+	}
+	return n.host.Network().Connectedness(info.ID) == network.Connected
+}
+
+// serveStatus exposes /status, /debug/net and /metrics on addr. It runs until the
+// process exits.
+func (n *Node) serveStatus(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(n.Status())
+	})
+	mux.HandleFunc("/debug/net", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(n.net.Diagnose(r.Context()))
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	log.Printf("Starting status server on %s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Status server failed: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package signer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSignResponseContinuesOperatorTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	net := mocknet.New()
+	defer net.Close()
+	h, err := net.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := ps.Join("l0proof-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewMemorySigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &Node{ctx: ctx, topic: topic, signers: []*SigningIdentity{{Signer: signer}}, maxSkew: time.Minute}
+
+	// The operator's publish span, as it travels in the sign request.
+	publishCtx, publish := tp.Tracer("operator").Start(ctx, "pubsub.publish sign_request")
+	publish.End()
+	hash := make([]byte, 32)
+	rand.Read(hash)
+	n.handleSignRequest(&SignRequest{
+		Type:         MsgTypeSignRequest,
+		Hash:         hex.EncodeToString(hash),
+		Timestamp:    time.Now().Unix(),
+		RequestID:    "req-1",
+		TraceContext: injectTraceContext(publishCtx),
+	})
+
+	readCtx, cancelRead := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelRead()
+	msg, err := sub.Next(readCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp SignResponse
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	traceID := publish.SpanContext().TraceID()
+	if got := trace.SpanContextFromContext(extractTraceContext(ctx, resp.TraceContext)).TraceID(); got != traceID {
+		t.Fatalf("sign response trace %s, want the request's %s", got, traceID)
+	}
+
+	parents := make(map[string]trace.SpanContext)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			parents[span.Name()] = span.Parent()
+		}
+	}
+	if parent, ok := parents["sign_request"]; !ok || parent.SpanID() != publish.SpanContext().SpanID() {
+		t.Fatalf("sign_request span: recorded %v, parent %v; want a child of the publish span", ok, parent.SpanID())
+	}
+	if _, ok := parents["sign"]; !ok {
+		t.Fatal("no sign span in the request's trace")
+	}
+}
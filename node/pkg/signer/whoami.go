@@ -0,0 +1,46 @@
+package signer
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// WhoamiCommand implements the `whoami` subcommand: it prints the peer ID and
+// the signing addresses to add to the operator's TRUSTED_ADDRESSES.
+func WhoamiCommand(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		log.Print("No .env file found")
+	}
+
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
+	}
+	if cfg.PrivateKey == "" {
+		log.Println("⚠️ PRIVATE_KEY is not set; a fresh key is generated on every start, so the peer ID will not be stable")
+	}
+	privKey, err := getOrCreatePrivKey(cfg.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+	id, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return fmt.Errorf("failed to derive peer ID: %w", err)
+	}
+
+	signers, err := loadSigners(privKey, cfg.KeysPath, cfg.RotateToKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("peer_id: %s\n", id)
+	fmt.Println("signing addresses:")
+	for _, identity := range signers {
+		fmt.Printf("  %s\n", identity.Signer.Address())
+	}
+	fmt.Println("multiaddrs: none (signers dial the operator and listen on ephemeral ports)")
+	return nil
+}